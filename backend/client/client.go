@@ -0,0 +1,192 @@
+// Package client is a typed Go client for the GoMusic API, for scripts and
+// internal tools. The types and methods in client_gen.go are generated from
+// the OpenAPI document in internal/openapi; run go generate after changing it.
+package client
+
+//go:generate go run music-player-gin/internal/openapi/clientgen -o client_gen.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxErrorBody caps how much of an error response is read
+const maxErrorBody = 1 << 20
+
+// Client calls the API at BaseURL, authenticating with Token when it is set.
+// Token may be a login token or a personal access token.
+type Client struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// New returns a client for the API at baseURL
+func New(baseURL, token string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), Token: token, HTTPClient: http.DefaultClient}
+}
+
+// Error is a response with a status outside 2xx. The API describes errors
+// as RFC 7807 problems; their members are copied into the fields below.
+type Error struct {
+	StatusCode int
+	Code       string       // Machine readable code such as song_not_found
+	Message    string       // The problem's detail
+	Fields     []FieldError // Rejected fields of the request body
+	RequestID  string       // ID to quote when reporting the error
+	Body       []byte
+}
+
+// FieldError is a rejected field of a request body
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("gomusic: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	if len(e.Fields) > 0 {
+		fields := make([]string, len(e.Fields))
+		for i, f := range e.Fields {
+			fields[i] = f.Field + " " + f.Message
+		}
+		return fmt.Sprintf("gomusic: %d %s: %s", e.StatusCode, e.Message, strings.Join(fields, "; "))
+	}
+	return fmt.Sprintf("gomusic: %d %s", e.StatusCode, e.Message)
+}
+
+// RequestOption changes a request before it is sent
+type RequestOption func(*http.Request)
+
+// WithHeader sets a header on the request, such as Range or If-Match
+func WithHeader(key, value string) RequestOption {
+	return func(req *http.Request) {
+		req.Header.Set(key, value)
+	}
+}
+
+// File is a file to upload in a multipart form
+type File struct {
+	Name    string
+	Content io.Reader
+}
+
+// requestBody encodes a request body and reports its content type
+type requestBody interface {
+	encode() (io.Reader, string, error)
+}
+
+type jsonBody struct {
+	v any
+}
+
+func (b jsonBody) encode() (io.Reader, string, error) {
+	data, err := json.Marshal(b.v)
+	if err != nil {
+		return nil, "", err
+	}
+	return bytes.NewReader(data), "application/json", nil
+}
+
+type multipartBody struct {
+	fields map[string]string
+	files  map[string]*File
+}
+
+func (b multipartBody) encode() (io.Reader, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for name, value := range b.fields {
+		if err := w.WriteField(name, value); err != nil {
+			return nil, "", err
+		}
+	}
+	for name, file := range b.files {
+		part, err := w.CreateFormFile(name, file.Name)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := io.Copy(part, file.Content); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return &buf, w.FormDataContentType(), nil
+}
+
+// send makes a request and returns the response if its status is 2xx or
+// 3xx. Other statuses are returned as an *Error with the body closed.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body requestBody, opts []RequestOption) (*http.Response, error) {
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	contentType := ""
+	if body != nil {
+		var err error
+		if reader, contentType, err = body.encode(); err != nil {
+			return nil, fmt.Errorf("gomusic: encode request: %w", err)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 400 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	apiErr.Body, _ = io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	var payload struct {
+		Code      string       `json:"code"`
+		Detail    string       `json:"detail"`
+		Errors    []FieldError `json:"errors"`
+		RequestID string       `json:"request_id"`
+	}
+	if json.Unmarshal(apiErr.Body, &payload) == nil {
+		apiErr.Code, apiErr.Message, apiErr.Fields, apiErr.RequestID = payload.Code, payload.Detail, payload.Errors, payload.RequestID
+	}
+	return nil, apiErr
+}
+
+// decode reads a JSON response into out and closes the body
+func decode(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("gomusic: decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,1534 @@
+// Code generated by clientgen from the OpenAPI document. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// AccessToken: A personal access token
+type AccessToken struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// Start of the token, to tell tokens apart
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type AddRoomSongsRequest struct {
+	SongIDs []int `json:"song_ids"`
+}
+
+type AddSongToPlaylistRequest struct {
+	PlaylistID int `json:"playlist_id"`
+	SongID     int `json:"song_id"`
+}
+
+type AddSongToPlaylistResponse struct {
+	Message  string   `json:"message"`
+	Playlist Playlist `json:"playlist"`
+}
+
+type AppendQueueRequest struct {
+	// Version of the queue the change is based on; may be sent as If-Match instead
+	Version *int  `json:"version,omitempty"`
+	SongIDs []int `json:"song_ids"`
+	// Position to insert at; the end of the queue if omitted
+	Index *int `json:"index,omitempty"`
+}
+
+// AuthResponse: A successful registration or login
+type AuthResponse struct {
+	Message string `json:"message"`
+	// Token to send as a bearer token
+	Token string  `json:"token"`
+	User  Profile `json:"user"`
+}
+
+type AvatarUpload struct {
+	// PNG, JPEG or GIF image of at most 2 MB and 4096x4096 pixels
+	Avatar *File
+}
+
+type ChangeEmailRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type ConfirmPasswordResetRequest struct {
+	// Token from the reset link
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type CreateAccessTokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// Tokens without an expiry never expire
+	ExpiresInDays *int `json:"expires_in_days,omitempty"`
+}
+
+type CreatePlaylistRequest struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	IsPublic    *bool   `json:"is_public,omitempty"`
+}
+
+type CreateRoomRequest struct {
+	Name *string `json:"name,omitempty"`
+}
+
+// CreatedAccessToken: A new personal access token. The token itself is only returned once.
+type CreatedAccessToken struct {
+	AccessToken
+	Token string `json:"token"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+type FavoriteResponse struct {
+	Message string `json:"message"`
+	Song    int    `json:"song"`
+	// Whether the song is now a favourite
+	IsFavourited bool `json:"isFavourited"`
+}
+
+type FeedActor struct {
+	ID          int     `json:"id"`
+	Username    string  `json:"username"`
+	DisplayName string  `json:"display_name"`
+	AvatarURL   *string `json:"avatar_url"`
+}
+
+// FeedItem: An entry in the feed. playlist is set for playlist items and song for uploads and favourites.
+type FeedItem struct {
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Actor     FeedActor `json:"actor"`
+	Playlist  *Playlist `json:"playlist,omitempty"`
+	Song      *Song     `json:"song,omitempty"`
+}
+
+type FeedPage struct {
+	Items []FeedItem `json:"items"`
+	// Cursor for the next page, empty on the last page
+	NextCursor string `json:"next_cursor"`
+}
+
+type FollowPage struct {
+	Users []Follower `json:"users"`
+	// Cursor for the next page, empty on the last page
+	NextCursor string `json:"next_cursor"`
+}
+
+type FollowResponse struct {
+	Following bool `json:"following"`
+}
+
+type Follower struct {
+	PublicProfile
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// JWK: A public key in JSON Web Key format
+type JWK struct {
+	Kty string  `json:"kty"`
+	Kid *string `json:"kid,omitempty"`
+	Use *string `json:"use,omitempty"`
+	Alg *string `json:"alg,omitempty"`
+	N   *string `json:"n,omitempty"`
+	E   *string `json:"e,omitempty"`
+	Crv *string `json:"crv,omitempty"`
+	X   *string `json:"x,omitempty"`
+	Y   *string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Job: A unit of background work
+type Job struct {
+	ID   int    `json:"id"`
+	Type string `json:"type"`
+	// Song the job belongs to, if any
+	SongID      *int   `json:"song_id,omitempty"`
+	Status      string `json:"status"`
+	Attempts    int    `json:"attempts"`
+	MaxAttempts int    `json:"max_attempts"`
+	// Earliest time the job may be picked up
+	RunAt      time.Time  `json:"run_at"`
+	LastError  *string    `json:"last_error,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+type JoinRoomRequest struct {
+	Code string `json:"code"`
+}
+
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Message: Confirms that an action succeeded
+type Message struct {
+	Message string `json:"message"`
+}
+
+type MoveQueueItemRequest struct {
+	// Version of the queue the change is based on; may be sent as If-Match instead
+	Version *int `json:"version,omitempty"`
+	From    int  `json:"from"`
+	To      int  `json:"to"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+// PlayQueue: The user's play queue, shared by all of their devices
+type PlayQueue struct {
+	UserID          int     `json:"user_id"`
+	CurrentIndex    int     `json:"current_index"`
+	PositionSeconds float64 `json:"position_seconds"`
+	Shuffle         bool    `json:"shuffle"`
+	RepeatMode      string  `json:"repeat_mode"`
+	// Increases on every change
+	Version   int             `json:"version"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Items     []PlayQueueItem `json:"items"`
+}
+
+type PlayQueueItem struct {
+	Position int  `json:"position"`
+	SongID   int  `json:"song_id"`
+	Song     Song `json:"song"`
+}
+
+type Playlist struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	UserID      int    `json:"user_id"`
+	// Public playlists appear in followers' feeds
+	IsPublic bool `json:"is_public"`
+	// Songs in the playlist, only sent by responses that include them
+	Songs     []Song    `json:"songs,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Profile: The current user's own view of their account
+type Profile struct {
+	ID            int    `json:"id"`
+	Username      string `json:"username"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	// New address waiting to be confirmed
+	PendingEmail string    `json:"pending_email"`
+	DisplayName  string    `json:"display_name"`
+	Bio          string    `json:"bio"`
+	AvatarURL    *string   `json:"avatar_url"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// PublicProfile: What other users see of an account
+type PublicProfile struct {
+	ID          int       `json:"id"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   *string   `json:"avatar_url"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type RadioResponse struct {
+	Session RadioSession `json:"session"`
+	Songs   []Song       `json:"songs"`
+}
+
+type RadioSession struct {
+	ID       string `json:"id"`
+	UserID   int    `json:"user_id"`
+	SeedType string `json:"seed_type"`
+	// Song or playlist ID, artist or genre name
+	SeedValue string `json:"seed_value"`
+	// Share of songs the user already knows, from 0 to 1
+	Familiarity float64 `json:"familiarity"`
+	// Number of songs handed out so far
+	Served    int       `json:"served"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type RegisterRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type ReplaceQueueRequest struct {
+	// Version of the queue the change is based on; may be sent as If-Match instead
+	Version         *int     `json:"version,omitempty"`
+	SongIDs         []int    `json:"song_ids,omitempty"`
+	CurrentIndex    *int     `json:"current_index,omitempty"`
+	PositionSeconds *float64 `json:"position_seconds,omitempty"`
+	Shuffle         *bool    `json:"shuffle,omitempty"`
+	RepeatMode      *string  `json:"repeat_mode,omitempty"`
+}
+
+// Room: A listening party
+type Room struct {
+	Code         string `json:"code"`
+	Name         string `json:"name"`
+	HostID       int    `json:"host_id"`
+	CurrentIndex int    `json:"current_index"`
+	// Position at state_at
+	PositionSeconds float64 `json:"position_seconds"`
+	Playing         bool    `json:"playing"`
+	// Server time the playback state was recorded
+	StateAt   time.Time       `json:"state_at"`
+	Version   int             `json:"version"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Items     []RoomQueueItem `json:"items"`
+	Members   []RoomMember    `json:"members"`
+}
+
+type RoomMember struct {
+	UserID   int       `json:"user_id"`
+	Username string    `json:"username"`
+	Online   bool      `json:"online"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+type RoomPlaybackRequest struct {
+	Action          string   `json:"action"`
+	PositionSeconds *float64 `json:"position_seconds,omitempty"`
+	Index           *int     `json:"index,omitempty"`
+}
+
+type RoomQueueItem struct {
+	Position int  `json:"position"`
+	SongID   int  `json:"song_id"`
+	Song     Song `json:"song"`
+}
+
+type RoomResponse struct {
+	Room       Room      `json:"room"`
+	ServerTime time.Time `json:"server_time"`
+	// Playback position at server_time
+	CurrentPosition float64 `json:"current_position"`
+}
+
+// ScoredSong: A song with how strongly it is recommended
+type ScoredSong struct {
+	Song
+	Score float64 `json:"score"`
+}
+
+type Song struct {
+	ID     int    `json:"id"`
+	Title  string `json:"title"`
+	Artist string `json:"artist"`
+	Album  string `json:"album"`
+	Genre  string `json:"genre"`
+	// Length in seconds
+	Duration int `json:"duration"`
+	// Size of the file in bytes
+	FileSize int64 `json:"file_size"`
+	// User who uploaded the song, or 0 if their account was deleted
+	UserID int `json:"user_id"`
+	// Whether a low bitrate stream is available with quality=low
+	HasLowQuality bool `json:"has_low_quality"`
+	// Integrated loudness in LUFS
+	Loudness *float64 `json:"loudness,omitempty"`
+	// True peak in dBTP
+	TruePeak *float64 `json:"true_peak,omitempty"`
+	// ReplayGain track gain in dB, relative to -18 LUFS
+	TrackGain *float64 `json:"track_gain,omitempty"`
+	// Track true peak as a linear amplitude
+	TrackPeak *float64 `json:"track_peak,omitempty"`
+	// ReplayGain album gain in dB
+	AlbumGain *float64 `json:"album_gain,omitempty"`
+	// Album true peak as a linear amplitude
+	AlbumPeak        *float64  `json:"album_peak,omitempty"`
+	ProcessingStatus string    `json:"processing_status"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+type SongJobs struct {
+	SongID           int    `json:"song_id"`
+	ProcessingStatus string `json:"processing_status"`
+	Jobs             []Job  `json:"jobs"`
+}
+
+type SongUpload struct {
+	// MP3 file
+	File   *File
+	Title  string
+	Artist string
+	Album  string
+	Genre  string
+	// Length in seconds
+	Duration string
+}
+
+type StartRadioRequest struct {
+	SeedType    string   `json:"seed_type"`
+	SeedValue   string   `json:"seed_value"`
+	Familiarity *float64 `json:"familiarity,omitempty"`
+	Count       *int     `json:"count,omitempty"`
+}
+
+type TokenRequest struct {
+	// Token from the link in the email
+	Token string `json:"token"`
+}
+
+type TransferHostRequest struct {
+	UserID int `json:"user_id"`
+}
+
+// UpdatePlaybackRequest: Changes the fields that are set
+type UpdatePlaybackRequest struct {
+	// Version of the queue the change is based on; may be sent as If-Match instead
+	Version         *int     `json:"version,omitempty"`
+	CurrentIndex    *int     `json:"current_index,omitempty"`
+	PositionSeconds *float64 `json:"position_seconds,omitempty"`
+	Shuffle         *bool    `json:"shuffle,omitempty"`
+	RepeatMode      *string  `json:"repeat_mode,omitempty"`
+}
+
+// UpdatePlaylistRequest: Changes the fields that are set
+type UpdatePlaylistRequest struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	IsPublic    *bool   `json:"is_public,omitempty"`
+}
+
+// UpdateProfileRequest: Changes the fields that are set
+type UpdateProfileRequest struct {
+	DisplayName *string `json:"display_name,omitempty"`
+	Bio         *string `json:"bio,omitempty"`
+}
+
+type UploadSongResponse struct {
+	Message string `json:"message"`
+	Song    Song   `json:"song"`
+	Jobs    []Job  `json:"jobs"`
+}
+
+type UserProfile struct {
+	PublicProfile
+	Followers int64 `json:"followers"`
+	Following int64 `json:"following"`
+	// Whether the current user follows them
+	IsFollowing bool `json:"is_following"`
+}
+
+// Waveform: Peak data in audiowaveform JSON format
+type Waveform struct {
+	Version         int   `json:"version"`
+	Channels        int   `json:"channels"`
+	SampleRate      int   `json:"sample_rate"`
+	SamplesPerPixel int   `json:"samples_per_pixel"`
+	Bits            int   `json:"bits"`
+	Length          int   `json:"length"`
+	Data            []int `json:"data"`
+}
+
+// GetJWKS sends GET /.well-known/jwks.json: public keys tokens are signed with.
+func (c *Client) GetJWKS(ctx context.Context, opts ...RequestOption) (*JWKS, error) {
+	path := "/.well-known/jwks.json"
+	resp, err := c.send(ctx, http.MethodGet, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out JWKS
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ConfirmEmail sends POST /api/v1/auth/confirm-email: switch to the new address from an email change link.
+func (c *Client) ConfirmEmail(ctx context.Context, body TokenRequest, opts ...RequestOption) (*Profile, error) {
+	path := "/api/v1/auth/confirm-email"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out Profile
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Login sends POST /api/v1/auth/login: log in with a username and password.
+func (c *Client) Login(ctx context.Context, body LoginRequest, opts ...RequestOption) (*AuthResponse, error) {
+	path := "/api/v1/auth/login"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out AuthResponse
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// OIDCCallbackParams holds the query parameters of OIDCCallback
+type OIDCCallbackParams struct {
+	// Authorization code
+	Code *string
+	// State from the login
+	State *string
+	// Error reported by the provider
+	Error *string
+}
+
+// OIDCCallback sends GET /api/v1/auth/oidc/callback: return from the identity provider.
+//
+// Only available when an identity provider is configured.
+// The caller must close the response body.
+func (c *Client) OIDCCallback(ctx context.Context, params *OIDCCallbackParams, opts ...RequestOption) (*http.Response, error) {
+	path := "/api/v1/auth/oidc/callback"
+	query := url.Values{}
+	if params != nil {
+		if params.Code != nil {
+			query.Set("code", fmt.Sprint(*params.Code))
+		}
+		if params.State != nil {
+			query.Set("state", fmt.Sprint(*params.State))
+		}
+		if params.Error != nil {
+			query.Set("error", fmt.Sprint(*params.Error))
+		}
+	}
+	return c.send(ctx, http.MethodGet, path, query, nil, opts)
+}
+
+// OIDCLoginParams holds the query parameters of OIDCLogin
+type OIDCLoginParams struct {
+	// Account to preselect at the provider
+	LoginHint *string
+}
+
+// OIDCLogin sends GET /api/v1/auth/oidc/login: sign in with the identity provider.
+//
+// Only available when an identity provider is configured.
+// The caller must close the response body.
+func (c *Client) OIDCLogin(ctx context.Context, params *OIDCLoginParams, opts ...RequestOption) (*http.Response, error) {
+	path := "/api/v1/auth/oidc/login"
+	query := url.Values{}
+	if params != nil {
+		if params.LoginHint != nil {
+			query.Set("login_hint", fmt.Sprint(*params.LoginHint))
+		}
+	}
+	return c.send(ctx, http.MethodGet, path, query, nil, opts)
+}
+
+// RequestPasswordReset sends POST /api/v1/auth/password-reset: email a password reset link.
+func (c *Client) RequestPasswordReset(ctx context.Context, body PasswordResetRequest, opts ...RequestOption) (*Message, error) {
+	path := "/api/v1/auth/password-reset"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out Message
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ConfirmPasswordReset sends POST /api/v1/auth/password-reset/confirm: set a new password with the token from a reset link.
+func (c *Client) ConfirmPasswordReset(ctx context.Context, body ConfirmPasswordResetRequest, opts ...RequestOption) (*Message, error) {
+	path := "/api/v1/auth/password-reset/confirm"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out Message
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Register sends POST /api/v1/auth/register: create an account.
+func (c *Client) Register(ctx context.Context, body RegisterRequest, opts ...RequestOption) (*AuthResponse, error) {
+	path := "/api/v1/auth/register"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out AuthResponse
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// VerifyEmail sends POST /api/v1/auth/verify-email: verify an email address with the token from the link.
+func (c *Client) VerifyEmail(ctx context.Context, body TokenRequest, opts ...RequestOption) (*Message, error) {
+	path := "/api/v1/auth/verify-email"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out Message
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StreamEventsParams holds the query parameters of StreamEvents
+type StreamEventsParams struct {
+	// Token, for clients that cannot set the Authorization header
+	AccessToken *string
+	// Resume after this event
+	LastEventID *int
+}
+
+// StreamEvents sends GET /api/v1/events: stream the user's events with server-sent events.
+// The caller must close the response body.
+func (c *Client) StreamEvents(ctx context.Context, params *StreamEventsParams, opts ...RequestOption) (*http.Response, error) {
+	path := "/api/v1/events"
+	query := url.Values{}
+	if params != nil {
+		if params.AccessToken != nil {
+			query.Set("access_token", fmt.Sprint(*params.AccessToken))
+		}
+		if params.LastEventID != nil {
+			query.Set("last_event_id", fmt.Sprint(*params.LastEventID))
+		}
+	}
+	return c.send(ctx, http.MethodGet, path, query, nil, opts)
+}
+
+// GetFeedParams holds the query parameters of GetFeed
+type GetFeedParams struct {
+	// Number of results, from 1 to 100; defaults to 20
+	Limit *int
+	// next_cursor of the previous page
+	Cursor *string
+}
+
+// GetFeed sends GET /api/v1/feed: recent activity of the users the current user follows.
+func (c *Client) GetFeed(ctx context.Context, params *GetFeedParams, opts ...RequestOption) (*FeedPage, error) {
+	path := "/api/v1/feed"
+	query := url.Values{}
+	if params != nil {
+		if params.Limit != nil {
+			query.Set("limit", fmt.Sprint(*params.Limit))
+		}
+		if params.Cursor != nil {
+			query.Set("cursor", fmt.Sprint(*params.Cursor))
+		}
+	}
+	resp, err := c.send(ctx, http.MethodGet, path, query, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out FeedPage
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetJob sends GET /api/v1/jobs/{id}: get a background job.
+//
+// Only jobs working on the caller's own uploads are found.
+func (c *Client) GetJob(ctx context.Context, id int, opts ...RequestOption) (*Job, error) {
+	path := "/api/v1/jobs/" + url.PathEscape(fmt.Sprint(id))
+	resp, err := c.send(ctx, http.MethodGet, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out Job
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetProfile sends GET /api/v1/me: get the current user's profile.
+func (c *Client) GetProfile(ctx context.Context, opts ...RequestOption) (*Profile, error) {
+	path := "/api/v1/me"
+	resp, err := c.send(ctx, http.MethodGet, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out Profile
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteAccount sends DELETE /api/v1/me: delete the account and its personal data.
+func (c *Client) DeleteAccount(ctx context.Context, body DeleteAccountRequest, opts ...RequestOption) (*Message, error) {
+	path := "/api/v1/me"
+	resp, err := c.send(ctx, http.MethodDelete, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out Message
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateProfile sends PATCH /api/v1/me: change the display name and bio.
+func (c *Client) UpdateProfile(ctx context.Context, body UpdateProfileRequest, opts ...RequestOption) (*Profile, error) {
+	path := "/api/v1/me"
+	resp, err := c.send(ctx, http.MethodPatch, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out Profile
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UploadAvatar sends PUT /api/v1/me/avatar: set the avatar image.
+func (c *Client) UploadAvatar(ctx context.Context, form AvatarUpload, opts ...RequestOption) (*Profile, error) {
+	path := "/api/v1/me/avatar"
+	fields := map[string]string{}
+	files := map[string]*File{}
+	if form.Avatar != nil {
+		files["avatar"] = form.Avatar
+	}
+	resp, err := c.send(ctx, http.MethodPut, path, nil, multipartBody{fields: fields, files: files}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out Profile
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteAvatar sends DELETE /api/v1/me/avatar: remove the avatar image.
+func (c *Client) DeleteAvatar(ctx context.Context, opts ...RequestOption) (*Profile, error) {
+	path := "/api/v1/me/avatar"
+	resp, err := c.send(ctx, http.MethodDelete, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out Profile
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ChangeEmail sends POST /api/v1/me/email: send a confirmation link to a new email address.
+func (c *Client) ChangeEmail(ctx context.Context, body ChangeEmailRequest, opts ...RequestOption) (*Profile, error) {
+	path := "/api/v1/me/email"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out Profile
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ResendVerification sends POST /api/v1/me/email/verify: send the email verification link again.
+func (c *Client) ResendVerification(ctx context.Context, opts ...RequestOption) (*Message, error) {
+	path := "/api/v1/me/email/verify"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out Message
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ChangePassword sends POST /api/v1/me/password: change the password.
+func (c *Client) ChangePassword(ctx context.Context, body ChangePasswordRequest, opts ...RequestOption) (*Message, error) {
+	path := "/api/v1/me/password"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out Message
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetQueue sends GET /api/v1/me/queue: get the play queue.
+func (c *Client) GetQueue(ctx context.Context, opts ...RequestOption) (*PlayQueue, error) {
+	path := "/api/v1/me/queue"
+	resp, err := c.send(ctx, http.MethodGet, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out PlayQueue
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReplaceQueue sends PUT /api/v1/me/queue: replace the whole queue and playback state.
+func (c *Client) ReplaceQueue(ctx context.Context, body ReplaceQueueRequest, opts ...RequestOption) (*PlayQueue, error) {
+	path := "/api/v1/me/queue"
+	resp, err := c.send(ctx, http.MethodPut, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out PlayQueue
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AddToQueue sends POST /api/v1/me/queue/items: insert songs into the queue.
+func (c *Client) AddToQueue(ctx context.Context, body AppendQueueRequest, opts ...RequestOption) (*PlayQueue, error) {
+	path := "/api/v1/me/queue/items"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out PlayQueue
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RemoveQueueItemParams holds the query parameters of RemoveQueueItem
+type RemoveQueueItemParams struct {
+	// Version of the queue the change is based on
+	Version *int
+}
+
+// RemoveQueueItem sends DELETE /api/v1/me/queue/items/{position}: remove an entry from the queue.
+func (c *Client) RemoveQueueItem(ctx context.Context, position int, params *RemoveQueueItemParams, opts ...RequestOption) (*PlayQueue, error) {
+	path := "/api/v1/me/queue/items/" + url.PathEscape(fmt.Sprint(position))
+	query := url.Values{}
+	if params != nil {
+		if params.Version != nil {
+			query.Set("version", fmt.Sprint(*params.Version))
+		}
+	}
+	resp, err := c.send(ctx, http.MethodDelete, path, query, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out PlayQueue
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// MoveQueueItem sends POST /api/v1/me/queue/move: move an entry within the queue.
+func (c *Client) MoveQueueItem(ctx context.Context, body MoveQueueItemRequest, opts ...RequestOption) (*PlayQueue, error) {
+	path := "/api/v1/me/queue/move"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out PlayQueue
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateQueuePlayback sends PATCH /api/v1/me/queue/playback: update the current song, position and play modes.
+func (c *Client) UpdateQueuePlayback(ctx context.Context, body UpdatePlaybackRequest, opts ...RequestOption) (*PlayQueue, error) {
+	path := "/api/v1/me/queue/playback"
+	resp, err := c.send(ctx, http.MethodPatch, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out PlayQueue
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListAccessTokens sends GET /api/v1/me/tokens: list the user's personal access tokens.
+func (c *Client) ListAccessTokens(ctx context.Context, opts ...RequestOption) ([]AccessToken, error) {
+	path := "/api/v1/me/tokens"
+	resp, err := c.send(ctx, http.MethodGet, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out []AccessToken
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateAccessToken sends POST /api/v1/me/tokens: create a personal access token.
+func (c *Client) CreateAccessToken(ctx context.Context, body CreateAccessTokenRequest, opts ...RequestOption) (*CreatedAccessToken, error) {
+	path := "/api/v1/me/tokens"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out CreatedAccessToken
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RevokeAccessToken sends DELETE /api/v1/me/tokens/{id}: revoke a personal access token.
+func (c *Client) RevokeAccessToken(ctx context.Context, id int, opts ...RequestOption) (*Message, error) {
+	path := "/api/v1/me/tokens/" + url.PathEscape(fmt.Sprint(id))
+	resp, err := c.send(ctx, http.MethodDelete, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out Message
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListPlaylists sends GET /api/v1/playlists: list the current user's playlists.
+func (c *Client) ListPlaylists(ctx context.Context, opts ...RequestOption) ([]Playlist, error) {
+	path := "/api/v1/playlists"
+	resp, err := c.send(ctx, http.MethodGet, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out []Playlist
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreatePlaylist sends POST /api/v1/playlists: create a playlist.
+func (c *Client) CreatePlaylist(ctx context.Context, body CreatePlaylistRequest, opts ...RequestOption) (*Playlist, error) {
+	path := "/api/v1/playlists"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out Playlist
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AddSongToPlaylist sends POST /api/v1/playlists/add-song: add a song to one of the user's playlists.
+func (c *Client) AddSongToPlaylist(ctx context.Context, body AddSongToPlaylistRequest, opts ...RequestOption) (*AddSongToPlaylistResponse, error) {
+	path := "/api/v1/playlists/add-song"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out AddSongToPlaylistResponse
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdatePlaylist sends PATCH /api/v1/playlists/{playlist_id}: rename a playlist or change whether it is public.
+func (c *Client) UpdatePlaylist(ctx context.Context, playlistID int, body UpdatePlaylistRequest, opts ...RequestOption) (*Playlist, error) {
+	path := "/api/v1/playlists/" + url.PathEscape(fmt.Sprint(playlistID))
+	resp, err := c.send(ctx, http.MethodPatch, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out Playlist
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListPlaylistSongs sends GET /api/v1/playlists/{playlist_id}/songs: list the songs in a playlist.
+//
+// Other users' playlists are only visible while public.
+func (c *Client) ListPlaylistSongs(ctx context.Context, playlistID int, opts ...RequestOption) ([]Song, error) {
+	path := "/api/v1/playlists/" + url.PathEscape(fmt.Sprint(playlistID)) + "/songs"
+	resp, err := c.send(ctx, http.MethodGet, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out []Song
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StartRadio sends POST /api/v1/radio: start a radio session from a seed.
+func (c *Client) StartRadio(ctx context.Context, body StartRadioRequest, opts ...RequestOption) (*RadioResponse, error) {
+	path := "/api/v1/radio"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out RadioResponse
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StopRadio sends DELETE /api/v1/radio/{session_id}: end a radio session.
+func (c *Client) StopRadio(ctx context.Context, sessionID string, opts ...RequestOption) (*Message, error) {
+	path := "/api/v1/radio/" + url.PathEscape(fmt.Sprint(sessionID))
+	resp, err := c.send(ctx, http.MethodDelete, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out Message
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// NextRadioSongsParams holds the query parameters of NextRadioSongs
+type NextRadioSongsParams struct {
+	// Number of songs, from 1 to 50; defaults to 10
+	Count *int
+}
+
+// NextRadioSongs sends GET /api/v1/radio/{session_id}/next: get the next songs of a radio session.
+func (c *Client) NextRadioSongs(ctx context.Context, sessionID string, params *NextRadioSongsParams, opts ...RequestOption) (*RadioResponse, error) {
+	path := "/api/v1/radio/" + url.PathEscape(fmt.Sprint(sessionID)) + "/next"
+	query := url.Values{}
+	if params != nil {
+		if params.Count != nil {
+			query.Set("count", fmt.Sprint(*params.Count))
+		}
+	}
+	resp, err := c.send(ctx, http.MethodGet, path, query, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out RadioResponse
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListRecommendationsParams holds the query parameters of ListRecommendations
+type ListRecommendationsParams struct {
+	// Number of results, from 1 to 100; defaults to 20
+	Limit *int
+}
+
+// ListRecommendations sends GET /api/v1/recommendations: list songs recommended for the user.
+func (c *Client) ListRecommendations(ctx context.Context, params *ListRecommendationsParams, opts ...RequestOption) ([]ScoredSong, error) {
+	path := "/api/v1/recommendations"
+	query := url.Values{}
+	if params != nil {
+		if params.Limit != nil {
+			query.Set("limit", fmt.Sprint(*params.Limit))
+		}
+	}
+	resp, err := c.send(ctx, http.MethodGet, path, query, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out []ScoredSong
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateRoom sends POST /api/v1/rooms: open a listening party hosted by the user.
+func (c *Client) CreateRoom(ctx context.Context, body CreateRoomRequest, opts ...RequestOption) (*RoomResponse, error) {
+	path := "/api/v1/rooms"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out RoomResponse
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// JoinRoom sends POST /api/v1/rooms/join: join a room by its code.
+func (c *Client) JoinRoom(ctx context.Context, body JoinRoomRequest, opts ...RequestOption) (*RoomResponse, error) {
+	path := "/api/v1/rooms/join"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out RoomResponse
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetRoom sends GET /api/v1/rooms/{code}: get a room's queue, members and playback state.
+func (c *Client) GetRoom(ctx context.Context, code string, opts ...RequestOption) (*RoomResponse, error) {
+	path := "/api/v1/rooms/" + url.PathEscape(fmt.Sprint(code))
+	resp, err := c.send(ctx, http.MethodGet, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out RoomResponse
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// TransferRoomHost sends POST /api/v1/rooms/{code}/host: hand the room to another member.
+func (c *Client) TransferRoomHost(ctx context.Context, code string, body TransferHostRequest, opts ...RequestOption) (*RoomResponse, error) {
+	path := "/api/v1/rooms/" + url.PathEscape(fmt.Sprint(code)) + "/host"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out RoomResponse
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// LeaveRoom sends POST /api/v1/rooms/{code}/leave: leave a room.
+func (c *Client) LeaveRoom(ctx context.Context, code string, opts ...RequestOption) (*Message, error) {
+	path := "/api/v1/rooms/" + url.PathEscape(fmt.Sprint(code)) + "/leave"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out Message
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ControlRoomPlayback sends POST /api/v1/rooms/{code}/playback: play, pause, seek or change track for everyone.
+//
+// Only the host may control playback.
+func (c *Client) ControlRoomPlayback(ctx context.Context, code string, body RoomPlaybackRequest, opts ...RequestOption) (*RoomResponse, error) {
+	path := "/api/v1/rooms/" + url.PathEscape(fmt.Sprint(code)) + "/playback"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out RoomResponse
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AddRoomSongs sends POST /api/v1/rooms/{code}/queue: add songs to a room's queue.
+func (c *Client) AddRoomSongs(ctx context.Context, code string, body AddRoomSongsRequest, opts ...RequestOption) (*RoomResponse, error) {
+	path := "/api/v1/rooms/" + url.PathEscape(fmt.Sprint(code)) + "/queue"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, jsonBody{body}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out RoomResponse
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RemoveRoomSong sends DELETE /api/v1/rooms/{code}/queue/{position}: remove an entry from a room's queue.
+func (c *Client) RemoveRoomSong(ctx context.Context, code string, position int, opts ...RequestOption) (*RoomResponse, error) {
+	path := "/api/v1/rooms/" + url.PathEscape(fmt.Sprint(code)) + "/queue/" + url.PathEscape(fmt.Sprint(position))
+	resp, err := c.send(ctx, http.MethodDelete, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out RoomResponse
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListSongs sends GET /api/v1/songs: list all songs.
+func (c *Client) ListSongs(ctx context.Context, opts ...RequestOption) ([]Song, error) {
+	path := "/api/v1/songs"
+	resp, err := c.send(ctx, http.MethodGet, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out []Song
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UploadSong sends POST /api/v1/songs: upload an MP3 file.
+func (c *Client) UploadSong(ctx context.Context, form SongUpload, opts ...RequestOption) (*UploadSongResponse, error) {
+	path := "/api/v1/songs"
+	fields := map[string]string{}
+	files := map[string]*File{}
+	if form.File != nil {
+		files["file"] = form.File
+	}
+	if form.Title != "" {
+		fields["title"] = form.Title
+	}
+	if form.Artist != "" {
+		fields["artist"] = form.Artist
+	}
+	if form.Album != "" {
+		fields["album"] = form.Album
+	}
+	if form.Genre != "" {
+		fields["genre"] = form.Genre
+	}
+	if form.Duration != "" {
+		fields["duration"] = form.Duration
+	}
+	resp, err := c.send(ctx, http.MethodPost, path, nil, multipartBody{fields: fields, files: files}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out UploadSongResponse
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSong sends GET /api/v1/songs/{id}: get a song.
+func (c *Client) GetSong(ctx context.Context, id int, opts ...RequestOption) (*Song, error) {
+	path := "/api/v1/songs/" + url.PathEscape(fmt.Sprint(id))
+	resp, err := c.send(ctx, http.MethodGet, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out Song
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ToggleFavorite sends POST /api/v1/songs/{id}/favorite: add a song to or remove it from the user's favourites.
+func (c *Client) ToggleFavorite(ctx context.Context, id int, opts ...RequestOption) (*FavoriteResponse, error) {
+	path := "/api/v1/songs/" + url.PathEscape(fmt.Sprint(id)) + "/favorite"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out FavoriteResponse
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSongJobs sends GET /api/v1/songs/{id}/jobs: get a song's processing status and jobs.
+//
+// Only the song's uploader can see its jobs.
+func (c *Client) GetSongJobs(ctx context.Context, id int, opts ...RequestOption) (*SongJobs, error) {
+	path := "/api/v1/songs/" + url.PathEscape(fmt.Sprint(id)) + "/jobs"
+	resp, err := c.send(ctx, http.MethodGet, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out SongJobs
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PlaySongParams holds the query parameters of PlaySong
+type PlaySongParams struct {
+	// low streams the transcoded file when there is one
+	Quality *string
+}
+
+// PlaySong sends GET /api/v1/songs/{id}/play: stream a song's audio.
+//
+// A request that starts at the beginning of the file counts as a play.
+// The caller must close the response body.
+func (c *Client) PlaySong(ctx context.Context, id int, params *PlaySongParams, opts ...RequestOption) (*http.Response, error) {
+	path := "/api/v1/songs/" + url.PathEscape(fmt.Sprint(id)) + "/play"
+	query := url.Values{}
+	if params != nil {
+		if params.Quality != nil {
+			query.Set("quality", fmt.Sprint(*params.Quality))
+		}
+	}
+	return c.send(ctx, http.MethodGet, path, query, nil, opts)
+}
+
+// ListSimilarSongsParams holds the query parameters of ListSimilarSongs
+type ListSimilarSongsParams struct {
+	// Number of results, from 1 to 100; defaults to 20
+	Limit *int
+}
+
+// ListSimilarSongs sends GET /api/v1/songs/{id}/similar: list songs similar to a song.
+//
+// Songs the caller played in the last week are left out.
+func (c *Client) ListSimilarSongs(ctx context.Context, id int, params *ListSimilarSongsParams, opts ...RequestOption) ([]ScoredSong, error) {
+	path := "/api/v1/songs/" + url.PathEscape(fmt.Sprint(id)) + "/similar"
+	query := url.Values{}
+	if params != nil {
+		if params.Limit != nil {
+			query.Set("limit", fmt.Sprint(*params.Limit))
+		}
+	}
+	resp, err := c.send(ctx, http.MethodGet, path, query, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out []ScoredSong
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetWaveformParams holds the query parameters of GetWaveform
+type GetWaveformParams struct {
+	// Zoom level; the stored resolution if omitted
+	PixelsPerSecond *float64
+	// Sample size
+	Bits *int
+	// dat returns audiowaveform binary data
+	Format *string
+}
+
+// GetWaveform sends GET /api/v1/songs/{id}/waveform: get a song's peak data.
+//
+// Responds with 404 and the song's processing_status until the waveform has been generated.
+func (c *Client) GetWaveform(ctx context.Context, id int, params *GetWaveformParams, opts ...RequestOption) (*Waveform, error) {
+	path := "/api/v1/songs/" + url.PathEscape(fmt.Sprint(id)) + "/waveform"
+	query := url.Values{}
+	if params != nil {
+		if params.PixelsPerSecond != nil {
+			query.Set("pixels_per_second", fmt.Sprint(*params.PixelsPerSecond))
+		}
+		if params.Bits != nil {
+			query.Set("bits", fmt.Sprint(*params.Bits))
+		}
+		if params.Format != nil {
+			query.Set("format", fmt.Sprint(*params.Format))
+		}
+	}
+	resp, err := c.send(ctx, http.MethodGet, path, query, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out Waveform
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetUser sends GET /api/v1/users/{id}: get a user's public profile.
+func (c *Client) GetUser(ctx context.Context, id int, opts ...RequestOption) (*UserProfile, error) {
+	path := "/api/v1/users/" + url.PathEscape(fmt.Sprint(id))
+	resp, err := c.send(ctx, http.MethodGet, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out UserProfile
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetAvatar sends GET /api/v1/users/{id}/avatar: get a user's avatar image.
+// The caller must close the response body.
+func (c *Client) GetAvatar(ctx context.Context, id int, opts ...RequestOption) (*http.Response, error) {
+	path := "/api/v1/users/" + url.PathEscape(fmt.Sprint(id)) + "/avatar"
+	return c.send(ctx, http.MethodGet, path, nil, nil, opts)
+}
+
+// FollowUser sends POST /api/v1/users/{id}/follow: follow a user.
+func (c *Client) FollowUser(ctx context.Context, id int, opts ...RequestOption) (*FollowResponse, error) {
+	path := "/api/v1/users/" + url.PathEscape(fmt.Sprint(id)) + "/follow"
+	resp, err := c.send(ctx, http.MethodPost, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out FollowResponse
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UnfollowUser sends DELETE /api/v1/users/{id}/follow: stop following a user.
+func (c *Client) UnfollowUser(ctx context.Context, id int, opts ...RequestOption) (*FollowResponse, error) {
+	path := "/api/v1/users/" + url.PathEscape(fmt.Sprint(id)) + "/follow"
+	resp, err := c.send(ctx, http.MethodDelete, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out FollowResponse
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListFollowersParams holds the query parameters of ListFollowers
+type ListFollowersParams struct {
+	// Number of results, from 1 to 100; defaults to 20
+	Limit *int
+	// next_cursor of the previous page
+	Cursor *string
+}
+
+// ListFollowers sends GET /api/v1/users/{id}/followers: list who follows a user, most recent first.
+func (c *Client) ListFollowers(ctx context.Context, id int, params *ListFollowersParams, opts ...RequestOption) (*FollowPage, error) {
+	path := "/api/v1/users/" + url.PathEscape(fmt.Sprint(id)) + "/followers"
+	query := url.Values{}
+	if params != nil {
+		if params.Limit != nil {
+			query.Set("limit", fmt.Sprint(*params.Limit))
+		}
+		if params.Cursor != nil {
+			query.Set("cursor", fmt.Sprint(*params.Cursor))
+		}
+	}
+	resp, err := c.send(ctx, http.MethodGet, path, query, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out FollowPage
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListFollowingParams holds the query parameters of ListFollowing
+type ListFollowingParams struct {
+	// Number of results, from 1 to 100; defaults to 20
+	Limit *int
+	// next_cursor of the previous page
+	Cursor *string
+}
+
+// ListFollowing sends GET /api/v1/users/{id}/following: list who a user follows, most recent first.
+func (c *Client) ListFollowing(ctx context.Context, id int, params *ListFollowingParams, opts ...RequestOption) (*FollowPage, error) {
+	path := "/api/v1/users/" + url.PathEscape(fmt.Sprint(id)) + "/following"
+	query := url.Values{}
+	if params != nil {
+		if params.Limit != nil {
+			query.Set("limit", fmt.Sprint(*params.Limit))
+		}
+		if params.Cursor != nil {
+			query.Set("cursor", fmt.Sprint(*params.Cursor))
+		}
+	}
+	resp, err := c.send(ctx, http.MethodGet, path, query, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out FollowPage
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListUserPlaylists sends GET /api/v1/users/{id}/playlists: list a user's public playlists, or all of them for their owner.
+func (c *Client) ListUserPlaylists(ctx context.Context, id int, opts ...RequestOption) ([]Playlist, error) {
+	path := "/api/v1/users/" + url.PathEscape(fmt.Sprint(id)) + "/playlists"
+	resp, err := c.send(ctx, http.MethodGet, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out []Playlist
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetDocs sends GET /docs: interactive API documentation.
+// The caller must close the response body.
+func (c *Client) GetDocs(ctx context.Context, opts ...RequestOption) (*http.Response, error) {
+	path := "/docs"
+	return c.send(ctx, http.MethodGet, path, nil, nil, opts)
+}
+
+// GetMetrics sends GET /metrics: server metrics in the Prometheus text format.
+//
+// Request counts and latencies by route and status, streaming and upload figures, database query durations and Go runtime metrics. When the server has a METRICS_TOKEN it must be sent as a bearer token.
+// The caller must close the response body.
+func (c *Client) GetMetrics(ctx context.Context, opts ...RequestOption) (*http.Response, error) {
+	path := "/metrics"
+	return c.send(ctx, http.MethodGet, path, nil, nil, opts)
+}
+
+// GetOpenAPI sends GET /openapi.json: this OpenAPI document.
+func (c *Client) GetOpenAPI(ctx context.Context, opts ...RequestOption) (map[string]any, error) {
+	path := "/openapi.json"
+	resp, err := c.send(ctx, http.MethodGet, path, nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]any
+	if err := decode(resp, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
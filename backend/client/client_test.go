@@ -0,0 +1,116 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+
+	"music-player-gin/client"
+	"music-player-gin/internal/api/apitest"
+	"music-player-gin/internal/openapi"
+)
+
+func TestGeneratedClientIsCurrent(t *testing.T) {
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := openapi.GenerateClient(doc, "client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("client_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("client_gen.go is out of date with the OpenAPI document; run go generate ./client")
+	}
+}
+
+func TestClient(t *testing.T) {
+	h := apitest.New(t)
+	ctx := context.Background()
+	anon := client.New(h.Server().URL, "")
+
+	reg, err := anon.Register(ctx, client.RegisterRequest{Username: "alice", Email: "alice@example.com", Password: apitest.DefaultPassword})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reg.Token == "" || reg.User.Username != "alice" {
+		t.Fatalf("register: %+v", reg)
+	}
+	login, err := anon.Login(ctx, client.LoginRequest{Username: "alice", Password: apitest.DefaultPassword})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := client.New(h.Server().URL, login.Token)
+
+	uploaded, err := alice.UploadSong(ctx, client.SongUpload{
+		File:     &client.File{Name: "intro.mp3", Content: bytes.NewReader(apitest.FixtureMP3(2))},
+		Title:    "Intro",
+		Artist:   "The Fixtures",
+		Duration: "2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uploaded.Song.Title != "Intro" || uploaded.Song.Duration != 2 {
+		t.Errorf("uploaded song: %+v", uploaded.Song)
+	}
+
+	songs, err := alice.ListSongs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(songs) != 1 || songs[0].ID != uploaded.Song.ID {
+		t.Errorf("songs: %+v", songs)
+	}
+
+	name := "Mix"
+	playlist, err := alice.CreatePlaylist(ctx, client.CreatePlaylistRequest{Name: &name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.AddSongToPlaylist(ctx, client.AddSongToPlaylistRequest{PlaylistID: playlist.ID, SongID: uploaded.Song.ID}); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := alice.ListPlaylistSongs(ctx, playlist.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Title != "Intro" {
+		t.Errorf("playlist songs: %+v", entries)
+	}
+
+	resp, err := alice.PlaySong(ctx, uploaded.Song.ID, nil, client.WithHeader("Range", "bytes=0-9"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent || len(data) != 10 {
+		t.Errorf("play: status %d, %d bytes", resp.StatusCode, len(data))
+	}
+}
+
+func TestClientError(t *testing.T) {
+	h := apitest.New(t)
+	ctx := context.Background()
+
+	_, err := client.New(h.Server().URL, "").ListPlaylists(ctx)
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Message == "" {
+		t.Fatalf("got %v, want a 401 *client.Error", err)
+	}
+
+	alice := client.New(h.Server().URL, h.Register("alice").Token)
+	_, err = alice.GetSong(ctx, 404)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Code != "song_not_found" || apiErr.RequestID == "" {
+		t.Errorf("got %+v, want a 404 *client.Error", err)
+	}
+}
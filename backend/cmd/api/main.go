@@ -1,58 +1,183 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"github.com/glebarez/sqlite"
 	"github.com/joho/godotenv"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 
 	"music-player-gin/internal/api/routes"
+	"music-player-gin/internal/database"
+	"music-player-gin/internal/events"
+	"music-player-gin/internal/jobs"
+	"music-player-gin/internal/jwtauth"
+	"music-player-gin/internal/logging"
+	"music-player-gin/internal/mail"
+	"music-player-gin/internal/metrics"
 	"music-player-gin/internal/models"
+	"music-player-gin/internal/oidc"
+	"music-player-gin/internal/processing"
+	"music-player-gin/internal/ratelimit"
+	"music-player-gin/internal/recommend"
+	"music-player-gin/internal/tracing"
 )
 
 func init() {
 	// Load .env file
-	err := godotenv.Load()
+	envErr := godotenv.Load()
+
+	// Log as configured from here on, which needs the .env file
+	logConfig, err := logging.ConfigFromEnv()
 	if err != nil {
-		log.Println("Error loading .env file:", err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logging.New(os.Stdout, logConfig))
+
+	if envErr != nil {
+		slog.Info("not loading .env file", "err", envErr)
 	} else {
-		log.Println("Environment variables loaded from .env file")
+		slog.Info("environment variables loaded from .env file")
 	}
 }
 
+// fatal logs a failure to start and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
+}
+
 func initDB() (*gorm.DB, error) {
-	db, err := gorm.Open(sqlite.Open("albums.db"), &gorm.Config{})
+	cfg, err := database.ConfigFromEnv()
 	if err != nil {
 		return nil, err
 	}
-	
-	// Auto migrating models
-	err = db.AutoMigrate(&models.Song{}, &models.Playlist{}, &models.User{})
+	db, err := database.Open(cfg)
 	if err != nil {
 		return nil, err
 	}
+	
+	// Favourites carry a timestamp, so the join table needs its own model
+	if err := db.SetupJoinTable(&models.User{}, "FavoriteSongs", &models.UserFavoriteSong{}); err != nil {
+		return nil, err
+	}
 
 	return db, nil
 }
 
+// rateLimitFromEnv reads a limit such as 5/1m from the environment
+func rateLimitFromEnv(key, def string) ratelimit.Limit {
+	value := os.Getenv(key)
+	if value == "" {
+		value = def
+	}
+	limit, err := ratelimit.ParseLimit(value)
+	if err != nil {
+		slog.Error("invalid rate limit", "key", key, "err", err)
+		os.Exit(1)
+	}
+	return limit
+}
+
 func main() {
 	db, err := initDB()
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("failed to connect to database", err)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(db, os.Args[2:]))
+	}
+	if err := migrateOnStart(db); err != nil {
+		fatal("failed to migrate database", err)
+	}
+	serverMetrics := metrics.New()
+	if err := db.Use(serverMetrics.Gorm()); err != nil {
+		fatal("failed to set up query metrics", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Background work such as jobs is traced with the same provider as
+	// requests and queries
+	tracerProvider, err := tracing.FromEnv(ctx)
+	if err != nil {
+		fatal("failed to configure tracing", err)
+	}
+	var tracer trace.TracerProvider
+	if tracerProvider != nil {
+		tracer = tracerProvider
+		otel.SetTracerProvider(tracerProvider)
+		otel.SetTextMapPropagator(tracing.Propagator)
+		if err := db.Use(tracing.Gorm(tracerProvider)); err != nil {
+			fatal("failed to set up query tracing", err)
+		}
+		ctx = tracing.WithProvider(ctx, tracerProvider)
+	}
+
+	// Start background job workers
+	workers, _ := strconv.Atoi(os.Getenv("JOB_WORKERS"))
+	if workers == 0 {
+		workers = 2
 	}
+	hub := events.NewHub()
+
+	mailer, err := mail.FromEnv()
+	if err != nil {
+		fatal("failed to configure mail", err)
+	}
+	keyConfig, err := jwtauth.ConfigFromEnv()
+	if err != nil {
+		fatal("failed to configure token signing", err)
+	}
+	keys, err := jwtauth.NewKeyRing(db, keyConfig)
+	if err != nil {
+		fatal("failed to load signing keys", err)
+	}
+	keys.Schedule(ctx)
+	oidcClient, err := oidc.FromEnv()
+	if err != nil {
+		fatal("failed to configure OIDC", err)
+	}
+	queue := jobs.NewQueue(db, workers)
+	processor := processing.NewProcessor(db, queue, hub)
+	recommender := recommend.NewEngine(db, queue)
+	if err := queue.Start(ctx); err != nil {
+		fatal("failed to start job queue", err)
+	}
+
+	// Rebuild the similarity model periodically
+	interval, err := time.ParseDuration(os.Getenv("RECOMMEND_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = time.Hour
+	}
+	recommender.Schedule(ctx, interval)
 
 	// Initialize router
-	router := gin.Default()
+	router := gin.New()
 
 	// Configure CORS
 	config := cors.DefaultConfig()
-	config.AllowOrigins = []string{
+	allowedOrigins := []string{
 		"http://localhost:3000",
 	}
+	config.AllowOrigins = allowedOrigins
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
-    config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
+    config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", "If-Match", "Last-Event-ID", "X-Request-ID", "traceparent", "tracestate"}
+    config.ExposeHeaders = []string{"ETag", "Retry-After", "RateLimit-Policy", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "X-Request-ID", "Deprecation", "Sunset", "Link"}
     config.AllowCredentials = true
     router.Use(cors.New(config))
 
@@ -62,9 +187,52 @@ func main() {
 	})
 
 	// Setup routes
-	routes.SetupRoutes(router, db)
+	routes.SetupRoutes(router, routes.Dependencies{
+		DB:             db,
+		Processor:      processor,
+		Recommender:    recommender,
+		Hub:            hub,
+		Keys:           keys,
+		Mailer:         mailer,
+		AllowedOrigins: allowedOrigins,
+		RateLimits: routes.RateLimits{
+			Store:         ratelimit.NewMemoryStore(),
+			LoginIP:       rateLimitFromEnv("RATE_LIMIT_LOGIN_IP", "20/1m"),
+			LoginUsername: rateLimitFromEnv("RATE_LIMIT_LOGIN_USERNAME", "5/1m"),
+			RegisterIP:    rateLimitFromEnv("RATE_LIMIT_REGISTER_IP", "5/1h"),
+			ResetIP:       rateLimitFromEnv("RATE_LIMIT_RESET_IP", "10/1h"),
+			ResetAddress:  rateLimitFromEnv("RATE_LIMIT_RESET_ADDRESS", "3/1h"),
+			VerifyResend:  rateLimitFromEnv("RATE_LIMIT_VERIFY_RESEND", "3/1h"),
+		},
+		OIDC:           oidcClient,
+		OIDCProvision:  os.Getenv("OIDC_AUTO_PROVISION") != "false",
+		Logger:         slog.Default(),
+		Metrics:        serverMetrics,
+		MetricsToken:   os.Getenv("METRICS_TOKEN"),
+		TracerProvider: tracer,
+	})
 
 	// Start server
-	router.Run(":8080")
+	server := &http.Server{Addr: ":8080", Handler: router}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("server error", err)
+		}
+	}()
+
+	<-ctx.Done()
+	slog.Info("shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("server shutdown failed", "err", err)
+	}
+	queue.Stop()
+	if tracerProvider != nil {
+		if err := tracerProvider.Shutdown(shutdownCtx); err != nil {
+			slog.Error("failed to export remaining spans", "err", err)
+		}
+	}
 
 }
\ No newline at end of file
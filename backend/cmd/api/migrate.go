@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"gorm.io/gorm"
+
+	"music-player-gin/internal/migrations"
+)
+
+const migrateUsage = `usage: api migrate <command>
+
+commands:
+  up [version]             apply pending migrations, up to version if given
+  down [steps]             roll back the last steps migrations (default 1)
+  status                   list migrations and when they were applied
+  create [-dir dir] <name> write a new empty migration (default dir internal/migrations)
+`
+
+// migrateOnStart applies pending migrations, unless AUTO_MIGRATE is false,
+// in which case it refuses to run against an outdated schema
+func migrateOnStart(db *gorm.DB) error {
+	if os.Getenv("AUTO_MIGRATE") == "false" {
+		pending, err := migrations.Pending(db)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d migrations pending, starting with %04d_%s; run the migrate command",
+				len(pending), pending[0].Version, pending[0].Name)
+		}
+		return nil
+	}
+	applied, err := migrations.Up(db, 0)
+	for _, m := range applied {
+		slog.Info("applied migration", "version", m.Version, "name", m.Name)
+	}
+	return err
+}
+
+// runMigrate runs the migrate subcommand and returns the exit code
+func runMigrate(db *gorm.DB, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	// number parses the optional numeric argument of up and down
+	number := func(def int) (int, bool) {
+		if len(args) < 2 {
+			return def, true
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			fmt.Fprintf(os.Stderr, "%s: %q is not a positive number\n", args[0], args[1])
+			return 0, false
+		}
+		return n, true
+	}
+
+	switch args[0] {
+	case "up":
+		target, ok := number(0)
+		if !ok {
+			return 2
+		}
+		applied, err := migrations.Up(db, target)
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("nothing to apply")
+		}
+	case "down":
+		steps, ok := number(1)
+		if !ok {
+			return 2
+		}
+		reverted, err := migrations.Down(db, steps)
+		for _, m := range reverted {
+			fmt.Printf("rolled back %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(reverted) == 0 {
+			fmt.Println("nothing to roll back")
+		}
+	case "status":
+		states, err := migrations.Status(db)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, s := range states {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", s.Version, s.Name, applied)
+		}
+	case "create":
+		flags := flag.NewFlagSet("create", flag.ContinueOnError)
+		dir := flags.String("dir", "internal/migrations", "directory holding the migrations")
+		if err := flags.Parse(args[1:]); err != nil || flags.NArg() != 1 {
+			fmt.Fprint(os.Stderr, migrateUsage)
+			return 2
+		}
+		path, err := migrations.Create(*dir, flags.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Println("created", path)
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,26 @@
+// Command mock-oidc runs a local OpenID Connect provider for trying out OIDC
+// login. Every sign-in succeeds at once; pass login_hint to choose the user.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"music-player-gin/internal/oidc/oidctest"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:9000", "address to listen on")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer URL the provider is reachable at")
+	clientID := flag.String("client-id", "gomusic", "client ID to accept")
+	clientSecret := flag.String("client-secret", "", "client secret to require, empty for a public client")
+	flag.Parse()
+
+	provider, err := oidctest.NewProvider(*issuer, *clientID, *clientSecret)
+	if err != nil {
+		log.Fatalf("Failed to create provider: %v", err)
+	}
+	log.Printf("Mock OIDC provider for client %q listening on %s, issuer %s", *clientID, *addr, *issuer)
+	log.Fatal(http.ListenAndServe(*addr, provider))
+}
@@ -0,0 +1,204 @@
+// Package apitest runs the API router against a throwaway in-memory
+// database and upload directory so tests can drive it end to end over HTTP.
+// A Harness is wired the way main does it, except that background jobs only
+// run when a test starts them and mail is captured instead of sent.
+package apitest
+
+import (
+	"context"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+
+	"music-player-gin/internal/api/routes"
+	"music-player-gin/internal/events"
+	"music-player-gin/internal/jobs"
+	"music-player-gin/internal/jwtauth"
+	"music-player-gin/internal/logging"
+	"music-player-gin/internal/metrics"
+	"music-player-gin/internal/oidc"
+	"music-player-gin/internal/processing"
+	"music-player-gin/internal/ratelimit"
+	"music-player-gin/internal/recommend"
+	"music-player-gin/internal/tracing"
+)
+
+// DefaultPassword is the password Register gives new users
+const DefaultPassword = "secret123"
+
+// generous is the rate limit used for limits a test does not set, high
+// enough that ordinary tests never hit it
+var generous = ratelimit.Limit{Requests: 10000, Period: time.Minute}
+
+// Options change how a harness is built. The zero value is what New uses.
+type Options struct {
+	// RateLimits replace the generous default for each limit that is set
+	RateLimits routes.RateLimits
+
+	// OIDC enables OIDC login against the given client
+	OIDC          *oidc.Client
+	OIDCProvision bool
+
+	// AllowedOrigins are accepted for WebSocket connections
+	AllowedOrigins []string
+
+	// MetricsToken protects /metrics when set
+	MetricsToken string
+}
+
+// Harness is a router with its own database, upload directory and outbox
+type Harness struct {
+	T         testing.TB
+	DB        *gorm.DB
+	Router    *gin.Engine
+	Hub       *events.Hub
+	Keys      *jwtauth.KeyRing
+	Queue     *jobs.Queue
+	Mail      *Outbox
+	UploadDir string
+	// Logs holds everything the server logged, as JSON lines at debug level
+	Logs    *LogBuffer
+	Metrics *metrics.Metrics
+	// Spans holds the spans of every request, query and job, all of which
+	// are traced
+	Spans          *SpanRecorder
+	TracerProvider *sdktrace.TracerProvider
+
+	server     *httptest.Server
+	jobsCancel context.CancelFunc
+}
+
+// New builds a harness with the default options
+func New(t testing.TB) *Harness {
+	return NewWith(t, Options{})
+}
+
+// NewWith builds a harness. Everything it creates is removed when the test ends.
+func NewWith(t testing.TB, opts Options) *Harness {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	uploadDir := t.TempDir()
+	t.Setenv("UPLOAD_DIR", uploadDir)
+
+	db := openDB(t)
+	m := metrics.New()
+	if err := db.Use(m.Gorm()); err != nil {
+		t.Fatalf("apitest: %v", err)
+	}
+	keys, err := jwtauth.NewKeyRing(db, jwtauth.Config{
+		Algorithm:     jwtauth.EdDSA,
+		Issuer:        "gomusic",
+		Audience:      "gomusic-api",
+		Rotation:      24 * time.Hour,
+		EncryptionKey: make([]byte, 32),
+	})
+	if err != nil {
+		t.Fatalf("apitest: %v", err)
+	}
+
+	// Spans are recorded as they end, so tests read them with nothing to flush
+	spans := &SpanRecorder{tracetest.NewSpanRecorder()}
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	if err := db.Use(tracing.Gorm(tracerProvider)); err != nil {
+		t.Fatalf("apitest: %v", err)
+	}
+
+	hub := events.NewHub()
+	queue := jobs.NewQueue(db, 1)
+	h := &Harness{
+		T:              t,
+		DB:             db,
+		Router:         gin.New(),
+		Hub:            hub,
+		Keys:           keys,
+		Queue:          queue,
+		Mail:           &Outbox{},
+		UploadDir:      uploadDir,
+		Logs:           &LogBuffer{},
+		Metrics:        m,
+		Spans:          spans,
+		TracerProvider: tracerProvider,
+	}
+
+	allowedOrigins := opts.AllowedOrigins
+	if allowedOrigins == nil {
+		allowedOrigins = []string{"http://localhost:3000"}
+	}
+	limits := opts.RateLimits
+	if limits.Store == nil {
+		limits.Store = ratelimit.NewMemoryStore()
+	}
+	for _, limit := range []*ratelimit.Limit{
+		&limits.LoginIP, &limits.LoginUsername, &limits.RegisterIP,
+		&limits.ResetIP, &limits.ResetAddress, &limits.VerifyResend,
+	} {
+		if limit.Requests == 0 {
+			*limit = generous
+		}
+	}
+
+	routes.SetupRoutes(h.Router, routes.Dependencies{
+		DB:             db,
+		Processor:      processing.NewProcessor(db, queue, hub),
+		Recommender:    recommend.NewEngine(db, queue),
+		Hub:            hub,
+		Keys:           keys,
+		Mailer:         h.Mail,
+		AllowedOrigins: allowedOrigins,
+		RateLimits:     limits,
+		OIDC:           opts.OIDC,
+		OIDCProvision:  opts.OIDCProvision,
+		Logger:         logging.New(h.Logs, logging.Config{Level: slog.LevelDebug}),
+		Metrics:        m,
+		MetricsToken:   opts.MetricsToken,
+		TracerProvider: tracerProvider,
+	})
+
+	t.Cleanup(h.close)
+	return h
+}
+
+// StartJobs runs the background job workers until the test ends
+func (h *Harness) StartJobs() {
+	h.T.Helper()
+	if h.jobsCancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(tracing.WithProvider(context.Background(), h.TracerProvider))
+	if err := h.Queue.Start(ctx); err != nil {
+		cancel()
+		h.T.Fatalf("apitest: start jobs: %v", err)
+	}
+	h.jobsCancel = cancel
+}
+
+// Server serves the router over a real connection, for streaming endpoints
+// such as Server-Sent Events and WebSockets
+func (h *Harness) Server() *httptest.Server {
+	if h.server == nil {
+		h.server = httptest.NewServer(h.Router)
+	}
+	return h.server
+}
+
+func (h *Harness) close() {
+	if h.server != nil {
+		h.server.CloseClientConnections()
+		h.server.Close()
+	}
+	if h.jobsCancel != nil {
+		h.jobsCancel()
+		h.Queue.Stop()
+	}
+	if sqlDB, err := h.DB.DB(); err == nil {
+		sqlDB.Close()
+	}
+	h.TracerProvider.Shutdown(context.Background())
+}
@@ -0,0 +1,18 @@
+package apitest
+
+import "bytes"
+
+// frameSize is the length of a 128 kbps, 44.1 kHz MPEG-1 Layer III frame
+const frameSize = 417
+
+// framesPerSecond is roughly how many frames make up a second of audio
+const framesPerSecond = 38
+
+// FixtureMP3 returns a silent mono MP3 of about the given length. Its frames
+// carry no audio data, which decoders play as silence.
+func FixtureMP3(seconds int) []byte {
+	frame := make([]byte, frameSize)
+	// Sync word, MPEG-1 Layer III without CRC, 128 kbps, 44.1 kHz, mono
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0xC4})
+	return bytes.Repeat(frame, max(seconds, 1)*framesPerSecond)
+}
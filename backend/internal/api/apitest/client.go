@@ -0,0 +1,204 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/dto"
+)
+
+// Client makes requests to the harness as one user, or anonymously when
+// Token is empty
+type Client struct {
+	h *Harness
+
+	ID       uint
+	Username string
+	Email    string
+	Password string
+	Token    string
+}
+
+// Anonymous returns a client that sends no credentials
+func (h *Harness) Anonymous() *Client {
+	return &Client{h: h}
+}
+
+// As returns a client that authenticates with the given bearer token
+func (h *Harness) As(token string) *Client {
+	return &Client{h: h, Token: token}
+}
+
+// Register signs up a user with DefaultPassword and an address at
+// example.com, failing the test if that does not succeed
+func (h *Harness) Register(username string) *Client {
+	h.T.Helper()
+	c := &Client{h: h, Username: username, Email: username + "@example.com", Password: DefaultPassword}
+	rec := h.Anonymous().Post("/api/v1/auth/register", map[string]string{
+		"username": c.Username,
+		"email":    c.Email,
+		"password": c.Password,
+	})
+	Expect(h.T, rec, http.StatusCreated)
+
+	var body struct {
+		Token string `json:"token"`
+		User  struct {
+			ID uint `json:"id"`
+		} `json:"user"`
+	}
+	Decode(h.T, rec, &body)
+	c.ID, c.Token = body.User.ID, body.Token
+	return c
+}
+
+// Login logs in with a username and password and returns the response
+func (h *Harness) Login(username, password string) *httptest.ResponseRecorder {
+	return h.Anonymous().Post("/api/v1/auth/login", map[string]string{"username": username, "password": password})
+}
+
+// Do sends a request through the router. The client's token is added
+// unless the request already has an Authorization header.
+func (c *Client) Do(req *http.Request) *httptest.ResponseRecorder {
+	if c.Token != "" && req.Header.Get("Authorization") == "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	rec := httptest.NewRecorder()
+	c.h.Router.ServeHTTP(rec, req)
+	c.h.checkSpec(req, rec)
+	return rec
+}
+
+// Request sends a request with body encoded as JSON, or no body if it is nil
+func (c *Client) Request(method, path string, body any) *httptest.ResponseRecorder {
+	c.h.T.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			c.h.T.Fatalf("apitest: encode body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.Do(req)
+}
+
+func (c *Client) Get(path string) *httptest.ResponseRecorder {
+	return c.Request(http.MethodGet, path, nil)
+}
+
+func (c *Client) Post(path string, body any) *httptest.ResponseRecorder {
+	return c.Request(http.MethodPost, path, body)
+}
+
+func (c *Client) Put(path string, body any) *httptest.ResponseRecorder {
+	return c.Request(http.MethodPut, path, body)
+}
+
+func (c *Client) Patch(path string, body any) *httptest.ResponseRecorder {
+	return c.Request(http.MethodPatch, path, body)
+}
+
+func (c *Client) Delete(path string, body any) *httptest.ResponseRecorder {
+	return c.Request(http.MethodDelete, path, body)
+}
+
+// File is a file part of a multipart form
+type File struct {
+	Field string
+	Name  string
+	Data  []byte
+}
+
+// Multipart sends a multipart form with the given fields and files
+func (c *Client) Multipart(method, path string, fields map[string]string, files ...File) *httptest.ResponseRecorder {
+	c.h.T.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for name, value := range fields {
+		w.WriteField(name, value)
+	}
+	for _, f := range files {
+		part, err := w.CreateFormFile(f.Field, f.Name)
+		if err != nil {
+			c.h.T.Fatalf("apitest: create form file: %v", err)
+		}
+		part.Write(f.Data)
+	}
+	w.Close()
+
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return c.Do(req)
+}
+
+// Upload uploads an MP3 with the given form fields and returns the response
+func (c *Client) Upload(fields map[string]string, name string, data []byte) *httptest.ResponseRecorder {
+	return c.Multipart(http.MethodPost, "/api/v1/songs", fields, File{Field: "file", Name: name, Data: data})
+}
+
+// UploadSong uploads a few seconds of FixtureMP3 with the given title and
+// returns the created song, failing the test if the upload is refused
+func (c *Client) UploadSong(title, artist string) dto.Song {
+	c.h.T.Helper()
+	rec := c.Upload(map[string]string{"title": title, "artist": artist, "album": "Fixtures", "genre": "Test"}, "fixture.mp3", FixtureMP3(3))
+	Expect(c.h.T, rec, http.StatusCreated)
+
+	var body struct {
+		Song dto.Song `json:"song"`
+	}
+	Decode(c.h.T, rec, &body)
+	return body.Song
+}
+
+// Expect fails the test unless the response has the given status
+func Expect(t testing.TB, rec *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, status, rec.Body.String())
+	}
+}
+
+// Decode unmarshals the JSON response body into v
+func Decode(t testing.TB, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("apitest: decode response: %v; body: %s", err, rec.Body.String())
+	}
+}
+
+// JSON decodes a JSON object response body
+func JSON(t testing.TB, rec *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var body map[string]any
+	Decode(t, rec, &body)
+	return body
+}
+
+// ErrorOf returns the detail of a problem response
+func ErrorOf(t testing.TB, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	return ProblemOf(t, rec).Detail
+}
+
+// ProblemOf decodes a problem response, failing the test if the response
+// is not application/problem+json
+func ProblemOf(t testing.TB, rec *httptest.ResponseRecorder) problem.Problem {
+	t.Helper()
+	if contentType := rec.Header().Get("Content-Type"); contentType != problem.ContentType {
+		t.Fatalf("Content-Type = %q, want %s; body: %s", contentType, problem.ContentType, rec.Body.String())
+	}
+	var p problem.Problem
+	Decode(t, rec, &p)
+	return p
+}
@@ -0,0 +1,110 @@
+package apitest
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+
+	"music-player-gin/internal/database"
+	"music-player-gin/internal/migrations"
+	"music-player-gin/internal/models"
+)
+
+// dbCounter keeps the databases of different harnesses apart
+var dbCounter atomic.Int64
+
+// openDB creates a private database with the schema applied. It is an
+// in-memory SQLite database unless TEST_DB_DRIVER and TEST_DB_DSN point the
+// tests at a PostgreSQL or MySQL server, where each harness gets a schema or
+// database of its own that is dropped when the test ends.
+func openDB(t testing.TB) *gorm.DB {
+	t.Helper()
+	var cfg database.Config
+	switch dialect := database.Dialect(os.Getenv("TEST_DB_DRIVER")); dialect {
+	case "", database.SQLite:
+		name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+		cfg = database.Config{
+			Dialect: database.SQLite,
+			DSN:     fmt.Sprintf("file:%s_%d?mode=memory&cache=shared&_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)", name, dbCounter.Add(1)),
+		}
+	case database.Postgres, database.MySQL:
+		cfg = scratchDB(t, dialect, os.Getenv("TEST_DB_DSN"))
+	default:
+		t.Fatalf("apitest: unsupported TEST_DB_DRIVER %q, use sqlite, postgres or mysql", dialect)
+	}
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		t.Fatalf("apitest: open database: %v", err)
+	}
+	if _, err := migrations.Up(db, 0); err != nil {
+		t.Fatalf("apitest: %v", err)
+	}
+	if err := db.SetupJoinTable(&models.User{}, "FavoriteSongs", &models.UserFavoriteSong{}); err != nil {
+		t.Fatalf("apitest: %v", err)
+	}
+	return db
+}
+
+// scratchDB creates an empty schema on PostgreSQL, or database on MySQL, on
+// the server dsn points to, and returns the config to reach it. It is
+// dropped once the harness using it has closed.
+func scratchDB(t testing.TB, dialect database.Dialect, dsn string) database.Config {
+	t.Helper()
+	if dsn == "" {
+		t.Fatalf("apitest: TEST_DB_DSN is required for %s", dialect)
+	}
+	// The process ID keeps packages tested in parallel apart
+	name := fmt.Sprintf("apitest_%d_%d", os.Getpid(), dbCounter.Add(1))
+
+	admin, err := database.Open(database.Config{Dialect: dialect, DSN: dsn})
+	if err != nil {
+		t.Fatalf("apitest: open database: %v", err)
+	}
+	create, drop := "CREATE SCHEMA "+name, "DROP SCHEMA "+name+" CASCADE"
+	if dialect == database.MySQL {
+		create, drop = "CREATE DATABASE "+name, "DROP DATABASE "+name
+	}
+	if err := admin.Exec(create).Error; err != nil {
+		t.Fatalf("apitest: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := admin.Exec(drop).Error; err != nil {
+			t.Errorf("apitest: %v", err)
+		}
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	switch dialect {
+	case database.Postgres:
+		// search_path is passed to the server as a connection parameter
+		if strings.Contains(dsn, "://") {
+			u, err := url.Parse(dsn)
+			if err != nil {
+				t.Fatalf("apitest: TEST_DB_DSN: %v", err)
+			}
+			q := u.Query()
+			q.Set("search_path", name)
+			u.RawQuery = q.Encode()
+			dsn = u.String()
+		} else {
+			dsn += " search_path=" + name
+		}
+	case database.MySQL:
+		cfg, err := mysqldriver.ParseDSN(dsn)
+		if err != nil {
+			t.Fatalf("apitest: TEST_DB_DSN: %v", err)
+		}
+		cfg.DBName = name
+		dsn = cfg.FormatDSN()
+	}
+	return database.Config{Dialect: dialect, DSN: dsn}
+}
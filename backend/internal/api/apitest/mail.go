@@ -0,0 +1,88 @@
+package apitest
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"music-player-gin/internal/mail"
+)
+
+// mailWait is how long WaitFor gives mail sent in the background to arrive
+const mailWait = 5 * time.Second
+
+var tokenPattern = regexp.MustCompile(`token=([A-Za-z0-9_\-.]+)`)
+
+// Outbox is a Mailer that keeps every message for tests to read
+type Outbox struct {
+	mu       sync.Mutex
+	messages []mail.Message
+	err      error
+}
+
+func (o *Outbox) Send(ctx context.Context, msg mail.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.err != nil {
+		return o.err
+	}
+	o.messages = append(o.messages, msg)
+	return nil
+}
+
+// Fail makes every later Send return err instead of delivering, until it is
+// called again with nil
+func (o *Outbox) Fail(err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.err = err
+}
+
+// Messages returns the messages sent so far, oldest first
+func (o *Outbox) Messages() []mail.Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]mail.Message(nil), o.messages...)
+}
+
+// Find returns the latest message to the address with the given subject
+func (o *Outbox) Find(to, subject string) (mail.Message, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i := len(o.messages) - 1; i >= 0; i-- {
+		if msg := o.messages[i]; msg.To == to && msg.Subject == subject {
+			return msg, true
+		}
+	}
+	return mail.Message{}, false
+}
+
+// WaitFor waits for a message to the address with the given subject. Some
+// mail, such as the verification link sent on registration, is sent after
+// the response.
+func (o *Outbox) WaitFor(t testing.TB, to, subject string) mail.Message {
+	t.Helper()
+	deadline := time.Now().Add(mailWait)
+	for {
+		if msg, ok := o.Find(to, subject); ok {
+			return msg
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("apitest: no %q message to %s", subject, to)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Token waits for a message like WaitFor and returns the token in its link
+func (o *Outbox) Token(t testing.TB, to, subject string) string {
+	t.Helper()
+	msg := o.WaitFor(t, to, subject)
+	match := tokenPattern.FindStringSubmatch(msg.Body)
+	if match == nil {
+		t.Fatalf("apitest: %q message to %s has no token link", subject, to)
+	}
+	return match[1]
+}
@@ -0,0 +1,32 @@
+package apitest
+
+import (
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// SpanRecorder keeps every span ended for tests to read
+type SpanRecorder struct {
+	*tracetest.SpanRecorder
+}
+
+// Named returns the ended spans with the given name
+func (r *SpanRecorder) Named(name string) []sdktrace.ReadOnlySpan {
+	var named []sdktrace.ReadOnlySpan
+	for _, span := range r.Ended() {
+		if span.Name() == name {
+			named = append(named, span)
+		}
+	}
+	return named
+}
+
+// Attribute returns the value of one of a span's attributes, or nil
+func Attribute(span sdktrace.ReadOnlySpan, key string) any {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value.AsInterface()
+		}
+	}
+	return nil
+}
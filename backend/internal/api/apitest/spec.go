@@ -0,0 +1,37 @@
+package apitest
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"music-player-gin/internal/api/routes"
+	"music-player-gin/internal/openapi"
+)
+
+// checkSpec fails the test if a response does not match the OpenAPI
+// document, so every request a test makes also checks the document
+func (h *Harness) checkSpec(req *http.Request, rec *httptest.ResponseRecorder) {
+	h.T.Helper()
+	doc, err := openapi.Load()
+	if err != nil {
+		h.T.Fatalf("apitest: %v", err)
+	}
+
+	path := req.URL.Path
+	if rec.Header().Get("Deprecation") != "" {
+		// Deprecated aliases are documented under the version they stand for
+		path = routes.V1Prefix + path
+	}
+	op, _ := doc.Find(req.Method, path)
+	if op == nil {
+		// The router's own reply to a path it has no route for
+		if rec.Code == http.StatusNotFound {
+			return
+		}
+		h.T.Errorf("openapi: %s %s is not documented", req.Method, req.URL.Path)
+		return
+	}
+	if err := doc.ValidateResponse(op, rec.Code, rec.Header().Get("Content-Type"), rec.Body.Bytes()); err != nil {
+		h.T.Errorf("openapi: %s %s (%s): %v", req.Method, req.URL.Path, op.OperationID, err)
+	}
+}
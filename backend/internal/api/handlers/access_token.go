@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/tokens"
+)
+
+// maxAccessTokens caps how many personal access tokens one user can hold
+const maxAccessTokens = 50
+
+type AccessTokenHandler struct {
+	db *gorm.DB
+}
+
+func NewAccessTokenHandler(db *gorm.DB) *AccessTokenHandler {
+	return &AccessTokenHandler{db: db}
+}
+
+// CreateAccessTokenRequest names a new token and what it may do.
+// Tokens without expires_in_days never expire.
+type CreateAccessTokenRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=3650"`
+}
+
+// accessToken is how a token is shown to its owner
+func accessToken(token models.AccessToken) gin.H {
+	return gin.H{
+		"id":           token.ID,
+		"name":         token.Name,
+		"prefix":       token.Prefix,
+		"scopes":       token.ScopeList(),
+		"last_used_at": token.LastUsedAt,
+		"expires_at":   token.ExpiresAt,
+		"created_at":   token.CreatedAt,
+	}
+}
+
+// ListAccessTokens returns the current user's personal access tokens
+func (h *AccessTokenHandler) ListAccessTokens(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+
+	var found []models.AccessToken
+	if err := h.db.WithContext(c.Request.Context()).Where("user_id = ?", userID).Order("created_at DESC").Find(&found).Error; err != nil {
+		problem.Abort(c, problem.Internal("Failed to fetch tokens"))
+		return
+	}
+	result := make([]gin.H, len(found))
+	for i, token := range found {
+		result[i] = accessToken(token)
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// CreateAccessToken mints a personal access token. The token itself is only
+// returned in this response.
+func (h *AccessTokenHandler) CreateAccessToken(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+
+	var req CreateAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.Validation(err))
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		problem.Abort(c, problem.BadRequest("token_name_required", "Token name is required"))
+		return
+	}
+	var scopes []string
+	for _, scope := range req.Scopes {
+		if !slices.Contains(models.AccessScopes, scope) {
+			problem.Abort(c, problem.BadRequest("unknown_scope", "Unknown scope "+strconv.Quote(scope)).
+				With("scopes", models.AccessScopes))
+			return
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	var count int64
+	if err := h.db.WithContext(c.Request.Context()).Model(&models.AccessToken{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		problem.Abort(c, problem.Internal("Failed to create token"))
+		return
+	}
+	if count >= maxAccessTokens {
+		problem.Abort(c, problem.Conflict("too_many_access_tokens", "Too many access tokens, revoke one first"))
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		t := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		expiresAt = &t
+	}
+	token, raw, err := tokens.CreateAccess(h.db.WithContext(c.Request.Context()), userID.(uint), name, scopes, expiresAt)
+	if err != nil {
+		problem.Abort(c, problem.Internal("Failed to create token"))
+		return
+	}
+
+	response := accessToken(*token)
+	response["token"] = raw
+	c.JSON(http.StatusCreated, response)
+}
+
+// RevokeAccessToken deletes one of the current user's personal access tokens
+func (h *AccessTokenHandler) RevokeAccessToken(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		problem.Abort(c, problem.BadRequest("invalid_token_id", "Invalid token ID"))
+		return
+	}
+
+	res := h.db.WithContext(c.Request.Context()).Where("id = ? AND user_id = ?", id, userID).Delete(&models.AccessToken{})
+	if res.Error != nil {
+		problem.Abort(c, problem.Internal("Failed to revoke token"))
+		return
+	}
+	if res.RowsAffected == 0 {
+		problem.Abort(c, problem.NotFound("token_not_found", "Token not found"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked"})
+}
@@ -1,23 +1,71 @@
 package handlers
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/dto"
+	"music-player-gin/internal/jwtauth"
+	"music-player-gin/internal/logging"
+	"music-player-gin/internal/mail"
 	"music-player-gin/internal/models"
+	"music-player-gin/internal/ratelimit"
+	"music-player-gin/internal/tokens"
 	"net/http"
-	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+const (
+	// emailVerifyTTL is how long a link to verify an email address stays valid
+	emailVerifyTTL = 72 * time.Hour
+
+	// passwordResetTTL is how long a password reset link stays valid
+	passwordResetTTL = time.Hour
+
+	// mailTimeout bounds sending an email in the background
+	mailTimeout = 30 * time.Second
+
+	// lockoutThreshold is how many wrong passwords in a row lock an account
+	lockoutThreshold = 5
+
+	// lockoutBase is the first lock's length; it doubles with every further failure up to lockoutMax
+	lockoutBase = time.Minute
+	lockoutMax  = time.Hour
+)
+
 type AuthHandler struct {
-	db *gorm.DB
+	db     *gorm.DB
+	keys   *jwtauth.KeyRing
+	mailer mail.Mailer
+
+	// resetLimiter limits reset emails per address so nobody can be flooded with them
+	resetLimiter *ratelimit.Limiter
+}
+
+func NewAuthHandler(db *gorm.DB, keys *jwtauth.KeyRing, mailer mail.Mailer, resetLimiter *ratelimit.Limiter) *AuthHandler {
+	return &AuthHandler{db: db, keys: keys, mailer: mailer, resetLimiter: resetLimiter}
+}
+
+// VerifyEmailRequest carries the token from a verification link
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// PasswordResetRequest asks for a reset link to be sent to an address
+type PasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email"`
 }
 
-func NewAuthHandler(db *gorm.DB) *AuthHandler {
-	return &AuthHandler{db: db}
+// ConfirmPasswordResetRequest sets a new password using the token from a reset link
+type ConfirmPasswordResetRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
 }
 
 // RegisterRequest represents the user registration request body
@@ -33,56 +81,32 @@ type LoginRequest struct {
     Password string `json:"password" binding:"required"`
 }
 
-// JWTClaims represents the claims in the JWT token
-type JWTClaims struct {
-    UserID   uint   `json:"user_id"`
-    Username string `json:"username"`
-    jwt.RegisteredClaims
-}
-
-// Generate JWT token
-func generateJWT(user models.User) (string, error) {
-    // Get the JWT secret key from environment variable or use a default one
-    secretKey := os.Getenv("JWT_SECRET_KEY")
-	fmt.Println("------- JWT secret key:", secretKey)
-
-    claims := JWTClaims{
-        UserID:   user.ID,
-        Username: user.Username,
-        RegisteredClaims: jwt.RegisteredClaims{
-            ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-            IssuedAt:  jwt.NewNumericDate(time.Now()),
-        },
-    }
-
-    // Create the token
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-    tokenString, err := token.SignedString([]byte(secretKey))
-    if err != nil {
-        return "", err
-    }
-
-    return tokenString, nil
+// normalizeEmail returns an email address the way it is stored. Addresses
+// are kept in lower case so each can only belong to one account, however it
+// is typed.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
 }
 
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON((&req)); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Abort(c, problem.Validation(err))
 		return
 	}
+	req.Email = normalizeEmail(req.Email)
 
 	// Checking if username already exists
 	var existingUser models.User
-	if err := h.db.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
+	if err := h.db.WithContext(c.Request.Context()).Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
+		problem.Abort(c, problem.Conflict("username_taken", "Username already exists"))
 		return
 	}
 
 	// Checking if email already exists
 	var existingEmail models.User
-	if err := h.db.Where("email = ?", req.Email).First(&existingEmail).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Email already exists"})
+	if err := h.db.WithContext(c.Request.Context()).Where("email = ?", req.Email).First(&existingEmail).Error; err == nil {
+		problem.Abort(c, problem.Conflict("email_taken", "Email already exists"))
 		return
 	}
 
@@ -94,68 +118,261 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 	// Hash the password
 	if err := user.HashPassword(req.Password); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		problem.Abort(c, problem.Internal("Failed to hash password"))
 		return
 	}
 
 	// Save the user to the database
-	if err := h.db.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+	if err := h.db.WithContext(c.Request.Context()).Create(&user).Error; err != nil {
+		problem.Abort(c, problem.Internal("Failed to create user"))
 		return
 	}
 
+	// Send the verification link without making the client wait for the mail server
+	// The request's logger is kept, but not its cancellation
+	logCtx := context.WithoutCancel(c.Request.Context())
+	go func(user models.User) {
+		ctx, cancel := context.WithTimeout(logCtx, mailTimeout)
+		defer cancel()
+		if err := sendVerificationEmail(ctx, h.db, h.mailer, user); err != nil {
+			logging.FromContext(ctx).Error("failed to send verification email", "user_id", user.ID, "err", err)
+		}
+	}(user)
+
 	// Generate JWT token
-	token, err := generateJWT(user)
+	token, err := h.keys.IssueUserToken(user.ID, user.Username)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		problem.Abort(c, problem.Internal("Failed to generate token"))
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "User registered successfully",
 		"token": token,
-		"user": gin.H{
-			"id": user.ID,
-			"username": user.Username,
-			"email": user.Email,
-		},
+		"user": dto.NewProfile(user),
 	})
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Abort(c, problem.Validation(err))
 		return
 	}
 
 	// Find the user by username
 	var user models.User
-	if err := h.db.Where("username = ?", req.Username).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+	if err := h.db.WithContext(c.Request.Context()).Where("username = ?", req.Username).First(&user).Error; err != nil {
+		// Hash anyway so unknown usernames take as long as wrong passwords
+		bcrypt.CompareHashAndPassword(dummyHash(), []byte(req.Password))
+		problem.Abort(c, problem.Unauthorized("invalid_credentials", "Invalid username or password"))
+		return
+	}
+
+	// A locked account is refused before the password is checked, with the
+	// same response and timing as an unknown user so that a lock does not
+	// reveal the username exists
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		bcrypt.CompareHashAndPassword(dummyHash(), []byte(req.Password))
+		problem.Abort(c, problem.Unauthorized("invalid_credentials", "Invalid username or password"))
 		return
 	}
 
 	// Check password
 	if err := user.CheckPassword(req.Password); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+		h.recordFailedLogin(c.Request.Context(), &user)
+		problem.Abort(c, problem.Unauthorized("invalid_credentials", "Invalid username or password"))
 		return
 	}
 
+	if user.FailedLogins > 0 || user.LockedUntil != nil {
+		h.db.WithContext(c.Request.Context()).Model(&user).Updates(map[string]any{"failed_logins": 0, "locked_until": nil})
+	}
+
 	// Generate JWT token
-	token, err := generateJWT(user)
+	token, err := h.keys.IssueUserToken(user.ID, user.Username)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		problem.Abort(c, problem.Internal("Failed to generate token"))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Login successful",
 		"token": token,
-		"user": gin.H{
-			"id": user.ID,
-			"username": user.Username,
-			"email": user.Email,
-		},
+		"user": dto.NewProfile(user),
+	})
+}
+
+// recordFailedLogin counts a wrong password and locks the account once there
+// have been lockoutThreshold failures in a row, for twice as long each time
+func (h *AuthHandler) recordFailedLogin(ctx context.Context, user *models.User) {
+	db := h.db.WithContext(ctx)
+	// Incrementing in the database keeps concurrent attempts from being lost
+	err := db.Model(user).Update("failed_logins", gorm.Expr("failed_logins + 1")).Error
+	if err == nil {
+		err = db.Select("failed_logins").First(user, user.ID).Error
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to record failed login", "user_id", user.ID, "err", err)
+		return
+	}
+	if user.FailedLogins < lockoutThreshold {
+		return
+	}
+
+	lock := lockoutBase << (user.FailedLogins - lockoutThreshold)
+	if lock <= 0 || lock > lockoutMax {
+		lock = lockoutMax
+	}
+	if err := db.Model(user).Update("locked_until", time.Now().Add(lock)).Error; err != nil {
+		logging.FromContext(ctx).Error("failed to lock user", "user_id", user.ID, "err", err)
+	}
+}
+
+// dummyHash is compared against when a login names an unknown user
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// VerifyEmail marks the address a verification token was sent to as verified
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.Validation(err))
+		return
+	}
+
+	token, err := tokens.Consume(h.db.WithContext(c.Request.Context()), req.Token, models.TokenEmailVerify)
+	if err != nil {
+		respondTokenError(c, err)
+		return
+	}
+
+	// The token is only good for the address it was sent to
+	res := h.db.WithContext(c.Request.Context()).Model(&models.User{}).
+		Where("id = ? AND email = ?", token.UserID, token.Data).
+		Update("email_verified_at", time.Now())
+	if res.Error != nil {
+		problem.Abort(c, problem.Internal("Failed to verify email"))
+		return
+	}
+	if res.RowsAffected == 0 {
+		problem.Abort(c, errInvalidLink)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+// RequestPasswordReset emails a reset link if the address belongs to an
+// account. The response is the same either way so it cannot be used to find
+// out which addresses are registered.
+func (h *AuthHandler) RequestPasswordReset(c *gin.Context) {
+	var req PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.Validation(err))
+		return
+	}
+
+	// The same address is limited and looked up however it is capitalised
+	email := normalizeEmail(req.Email)
+	// A refused or failed limit check is not reported so the response stays the same
+	if result, err := h.resetLimiter.Allow(c.Request.Context(), email); err == nil && result.Allowed {
+		// Looking the user up and mailing them in the background keeps the
+		// response time the same whether or not the account exists
+		logCtx := context.WithoutCancel(c.Request.Context())
+		go func() {
+			ctx, cancel := context.WithTimeout(logCtx, mailTimeout)
+			defer cancel()
+			if err := h.sendPasswordReset(ctx, email); err != nil {
+				logging.FromContext(ctx).Error("failed to send password reset", "err", err)
+			}
+		}()
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If an account uses that address, a reset link has been sent to it"})
+}
+
+// sendPasswordReset mails a reset link to the account using email, which
+// must be normalised
+func (h *AuthHandler) sendPasswordReset(ctx context.Context, email string) error {
+	var user models.User
+	err := h.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := tokens.Issue(h.db.WithContext(ctx), user.ID, models.TokenPasswordReset, user.Email, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+	return h.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your GoMusic password",
+		Body: "Follow this link within an hour to choose a new password:\n\n" +
+			mail.AppURL() + "/reset-password?token=" + token + "\n\nIf you did not ask for this, you can ignore this email.",
 	})
-}
\ No newline at end of file
+}
+
+// ConfirmPasswordReset sets a new password using a reset token
+func (h *AuthHandler) ConfirmPasswordReset(c *gin.Context) {
+	var req ConfirmPasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.Validation(err))
+		return
+	}
+
+	token, err := tokens.Consume(h.db.WithContext(c.Request.Context()), req.Token, models.TokenPasswordReset)
+	if err != nil {
+		respondTokenError(c, err)
+		return
+	}
+
+	var user models.User
+	if err := h.db.WithContext(c.Request.Context()).First(&user, token.UserID).Error; err != nil || user.Email != token.Data {
+		problem.Abort(c, errInvalidLink)
+		return
+	}
+	if err := user.HashPassword(req.Password); err != nil {
+		problem.Abort(c, problem.Internal("Failed to hash password"))
+		return
+	}
+
+	// A successful reset also lifts any lockout
+	updates := map[string]any{"password_hash": user.PasswordHash, "failed_logins": 0, "locked_until": nil}
+	if user.EmailVerifiedAt == nil {
+		// Receiving the link proves the user owns the address
+		updates["email_verified_at"] = time.Now()
+	}
+	if err := h.db.WithContext(c.Request.Context()).Model(&user).Updates(updates).Error; err != nil {
+		problem.Abort(c, problem.Internal("Failed to reset password"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
+
+// sendVerificationEmail issues a verification token for the user's current
+// address and mails them the link
+func sendVerificationEmail(ctx context.Context, db *gorm.DB, mailer mail.Mailer, user models.User) error {
+	token, err := tokens.Issue(db.WithContext(ctx), user.ID, models.TokenEmailVerify, user.Email, emailVerifyTTL)
+	if err != nil {
+		return err
+	}
+	return mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: "Welcome to GoMusic! Follow this link to verify your email address:\n\n" +
+			mail.AppURL() + "/verify-email?token=" + token,
+	})
+}
+
+func respondTokenError(c *gin.Context, err error) {
+	if errors.Is(err, tokens.ErrInvalid) {
+		problem.Abort(c, errInvalidLink)
+		return
+	}
+	problem.Abort(c, problem.Internal("Failed to check token"))
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/events"
+)
+
+const (
+	heartbeatInterval = 15 * time.Second
+	writeTimeout      = 10 * time.Second
+)
+
+type EventHandler struct {
+	hub      *events.Hub
+	upgrader websocket.Upgrader
+}
+
+func NewEventHandler(hub *events.Hub, allowedOrigins []string) *EventHandler {
+	return &EventHandler{hub: hub, upgrader: newUpgrader(allowedOrigins)}
+}
+
+// newUpgrader accepts WebSocket connections from the allowed origins only.
+// Browsers send cookies with cross-site WebSocket requests, so only our own
+// frontends are trusted.
+func newUpgrader(allowedOrigins []string) websocket.Upgrader {
+	origins := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		origins[origin] = true
+	}
+	return websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return origin == "" || origins[origin]
+		},
+	}
+}
+
+// subscribe attaches the current user to the hub, resuming after the
+// Last-Event-ID header or last_event_id query parameter when present
+func (h *EventHandler) subscribe(c *gin.Context) (*events.Subscription, []events.Event, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return nil, nil, false
+	}
+
+	lastID, ok := lastEventID(c)
+	if !ok {
+		return nil, nil, false
+	}
+
+	sub, replay := h.hub.Subscribe([]string{events.UserTopic(userID.(uint))}, lastID)
+	return sub, replay, true
+}
+
+// lastEventID reads the Last-Event-ID header or last_event_id query parameter
+func lastEventID(c *gin.Context) (uint64, bool) {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+	if value == "" {
+		return 0, true
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		problem.Abort(c, problem.BadRequest("invalid_last_event_id", "Invalid Last-Event-ID"))
+		return 0, false
+	}
+	return id, true
+}
+
+// Stream sends the user's events as Server-Sent Events
+func (h *EventHandler) Stream(c *gin.Context) {
+	sub, replay, ok := h.subscribe(c)
+	if !ok {
+		return
+	}
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Stop reverse proxies from buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// Ask EventSource to reconnect quickly if the connection drops
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+	for _, e := range replay {
+		if err := writeSSE(c, e); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case e, open := <-sub.C:
+			if !open {
+				return
+			}
+			if err := writeSSE(c, e); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+func writeSSE(c *gin.Context, e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
+
+// WebSocket sends the user's events as JSON text messages over a WebSocket
+func (h *EventHandler) WebSocket(c *gin.Context) {
+	sub, replay, ok := h.subscribe(c)
+	if !ok {
+		return
+	}
+	defer sub.Close()
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already written an error response
+		return
+	}
+	defer conn.Close()
+
+	// The connection is send-only, but reading is needed to process pongs and notice closes
+	closed := make(chan struct{})
+	conn.SetReadDeadline(time.Now().Add(2 * heartbeatInterval))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * heartbeatInterval))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(e events.Event) error {
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		return conn.WriteJSON(e)
+	}
+	for _, e := range replay {
+		if err := send(e); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case e, open := <-sub.C:
+			if !open {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "fell behind, reconnect with last_event_id"),
+					time.Now().Add(writeTimeout))
+				return
+			}
+			if err := send(e); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
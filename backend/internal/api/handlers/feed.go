@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/dto"
+	"music-player-gin/internal/feed"
+)
+
+const (
+	defaultFeedCount = 20
+	maxFeedCount     = 100
+)
+
+type FeedHandler struct {
+	db *gorm.DB
+}
+
+func NewFeedHandler(db *gorm.DB) *FeedHandler {
+	return &FeedHandler{db: db}
+}
+
+// GetFeed returns recent activity of the users the current user follows.
+// Pass next_cursor from a response as cursor to get the following page.
+func (h *FeedHandler) GetFeed(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+	limit, ok := parseCount(c, "limit", defaultFeedCount, maxFeedCount)
+	if !ok {
+		return
+	}
+
+	var after *feed.Cursor
+	if raw := c.Query("cursor"); raw != "" {
+		var err error
+		if after, err = feed.DecodeCursor(raw); err != nil {
+			problem.Abort(c, problem.BadRequest("invalid_cursor", err.Error()))
+			return
+		}
+	}
+
+	items, next, err := feed.Page(c.Request.Context(), h.db, userID.(uint), after, limit)
+	if err != nil {
+		problem.Abort(c, problem.Internal("Failed to fetch feed"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": dto.NewFeedItems(items), "next_cursor": next})
+}
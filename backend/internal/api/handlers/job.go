@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/dto"
+	"music-player-gin/internal/models"
+)
+
+var errJobNotFound = problem.NotFound("job_not_found", "Job not found")
+
+type JobHandler struct {
+	db *gorm.DB
+}
+
+func NewJobHandler(db *gorm.DB) *JobHandler {
+	return &JobHandler{db: db}
+}
+
+// GetJob returns the current state of a single background job. Only jobs
+// working on the caller's own uploads are found.
+func (h *JobHandler) GetJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		problem.Abort(c, errJobNotFound)
+		return
+	}
+	userID, _ := c.Get("user_id")
+	var job models.Job
+	err = h.db.WithContext(c.Request.Context()).
+		Joins("JOIN songs ON songs.id = jobs.song_id AND songs.deleted_at IS NULL").
+		Where("songs.user_id = ?", userID).
+		First(&job, uint(id)).Error
+	if err != nil {
+		problem.Abort(c, errJobNotFound)
+		return
+	}
+	c.JSON(http.StatusOK, dto.NewJob(job))
+}
+
+// GetSongJobs returns a song's processing status along with each of its jobs.
+// Only the uploader can see them.
+func (h *JobHandler) GetSongJobs(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		problem.Abort(c, errSongNotFound)
+		return
+	}
+	userID, _ := c.Get("user_id")
+	var song models.Song
+	if err := h.db.WithContext(c.Request.Context()).Where("user_id = ?", userID).First(&song, uint(id)).Error; err != nil {
+		problem.Abort(c, errSongNotFound)
+		return
+	}
+
+	var jobs []models.Job
+	if err := h.db.WithContext(c.Request.Context()).Where("song_id = ?", song.ID).Order("id").Find(&jobs).Error; err != nil {
+		problem.Abort(c, problem.Internal("Failed to fetch jobs"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"song_id":           song.ID,
+		"processing_status": song.ProcessingStatus,
+		"jobs":              dto.NewJobs(jobs),
+	})
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"music-player-gin/internal/jwtauth"
+)
+
+type JWKSHandler struct {
+	keys *jwtauth.KeyRing
+}
+
+func NewJWKSHandler(keys *jwtauth.KeyRing) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// GetJWKS publishes the public keys tokens are signed with, so other services
+// can verify them. Keys appear here before they are first used.
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
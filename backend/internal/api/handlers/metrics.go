@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/metrics"
+)
+
+type MetricsHandler struct {
+	metrics http.Handler
+	token   string
+}
+
+// NewMetricsHandler serves m. With a token, scrapers must send it as a bearer
+// token; it is separate from user tokens so monitoring needs no account.
+func NewMetricsHandler(m *metrics.Metrics, token string) *MetricsHandler {
+	return &MetricsHandler{metrics: m.Handler(), token: token}
+}
+
+// GetMetrics writes the metrics in the Prometheus text format
+func (h *MetricsHandler) GetMetrics(c *gin.Context) {
+	if h.token != "" {
+		given, _ := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(h.token)) != 1 {
+			problem.Abort(c, problem.Unauthorized("invalid_metrics_token", "A valid metrics token is required"))
+			return
+		}
+	}
+
+	h.metrics.ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/jwtauth"
+	"music-player-gin/internal/logging"
+	"music-player-gin/internal/mail"
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/oidc"
+)
+
+const (
+	// oidcCookie carries the state, nonce and PKCE verifier of a login in progress
+	oidcCookie = "gomusic_oidc"
+
+	// oidcFlowTTL is how long a user has to finish signing in at the provider
+	oidcFlowTTL = 10 * time.Minute
+
+	// oidcFlowAudience keeps the cookie's token from passing as a user token and vice versa
+	oidcFlowAudience = "gomusic-oidc-flow"
+)
+
+var (
+	errOIDCNoEmail       = errors.New("identity provider did not share an email address")
+	errOIDCAccountExists = errors.New("an account with this email exists but the provider has not verified the address")
+	errOIDCNoAccount     = errors.New("no account is linked to this identity")
+)
+
+type OIDCHandler struct {
+	db     *gorm.DB
+	keys   *jwtauth.KeyRing
+	client *oidc.Client
+
+	// provision creates accounts for identities that match no user
+	provision bool
+}
+
+func NewOIDCHandler(db *gorm.DB, keys *jwtauth.KeyRing, client *oidc.Client, provision bool) *OIDCHandler {
+	return &OIDCHandler{db: db, keys: keys, client: client, provision: provision}
+}
+
+// oidcFlow is what the cookie holds between Login and Callback. It is
+// signed like any other token so the browser cannot alter it.
+type oidcFlow struct {
+	oidc.Flow
+	jwt.RegisteredClaims
+}
+
+// Login sends the browser to the identity provider. An optional login_hint
+// is passed on to preselect the account there.
+func (h *OIDCHandler) Login(c *gin.Context) {
+	flow, err := oidc.NewFlow()
+	if err != nil {
+		problem.Abort(c, problem.Internal("Failed to start login"))
+		return
+	}
+	target, err := h.client.AuthCodeURL(c.Request.Context(), flow, c.Query("login_hint"))
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("identity provider unavailable", "err", err)
+		problem.Abort(c, problem.New(http.StatusBadGateway, "identity_provider_unavailable", "Identity provider is unavailable"))
+		return
+	}
+
+	value, err := h.keys.Sign(oidcFlow{Flow: flow, RegisteredClaims: h.keys.Registered(oidcFlowAudience, oidcFlowTTL)})
+	if err != nil {
+		problem.Abort(c, problem.Internal("Failed to start login"))
+		return
+	}
+	h.setCookie(c, value, int(oidcFlowTTL.Seconds()))
+	c.Redirect(http.StatusFound, target)
+}
+
+// Callback is where the provider returns the browser. It signs the user in
+// and hands the GoMusic token to the frontend in the URL fragment, which
+// browsers do not send to servers.
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	cookie, _ := c.Cookie(oidcCookie)
+	h.setCookie(c, "", -1)
+
+	if reason := c.Query("error"); reason != "" {
+		h.finish(c, url.Values{"error": {reason}, "error_description": {c.Query("error_description")}})
+		return
+	}
+	var flow oidcFlow
+	err := h.keys.Parse(cookie, &flow, oidcFlowAudience)
+	if err != nil || c.Query("state") == "" || !hmac.Equal([]byte(c.Query("state")), []byte(flow.State)) {
+		h.finish(c, url.Values{"error": {"invalid_state"}, "error_description": {"Login expired or was started elsewhere, try again"}})
+		return
+	}
+
+	claims, err := h.client.Exchange(c.Request.Context(), c.Query("code"), flow.Flow)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Warn("oidc code exchange failed", "err", err)
+		h.finish(c, url.Values{"error": {"login_failed"}, "error_description": {"Could not sign in with the identity provider"}})
+		return
+	}
+
+	user, err := h.userFor(c.Request.Context(), claims)
+	if err != nil {
+		code, description := "login_failed", err.Error()
+		switch {
+		case errors.Is(err, errOIDCNoEmail):
+			code = "email_required"
+		case errors.Is(err, errOIDCAccountExists):
+			code = "account_exists"
+		case errors.Is(err, errOIDCNoAccount):
+			code = "no_account"
+		default:
+			logging.FromContext(c.Request.Context()).Error("oidc sign in failed", "err", err)
+			description = "Failed to sign in"
+		}
+		h.finish(c, url.Values{"error": {code}, "error_description": {description}})
+		return
+	}
+
+	token, err := h.keys.IssueUserToken(user.ID, user.Username)
+	if err != nil {
+		h.finish(c, url.Values{"error": {"login_failed"}, "error_description": {"Failed to generate token"}})
+		return
+	}
+	h.finish(c, url.Values{"token": {token}})
+}
+
+// finish redirects to the frontend's OIDC page with the outcome in the fragment
+func (h *OIDCHandler) finish(c *gin.Context, result url.Values) {
+	c.Redirect(http.StatusFound, mail.AppURL()+"/auth/oidc#"+result.Encode())
+}
+
+func (h *OIDCHandler) setCookie(c *gin.Context, value string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	// Lax lets the cookie come back on the provider's top-level redirect
+	c.SetSameSite(http.SameSiteLaxMode)
+	// The callback may be mounted under another prefix than the login route,
+	// so the cookie is sent on every path
+	c.SetCookie(oidcCookie, value, maxAge, "/", "", secure, true)
+}
+
+// userFor finds the user linked to the identity, links an existing user with
+// the same verified email, or creates a new user
+func (h *OIDCHandler) userFor(ctx context.Context, claims *oidc.Claims) (*models.User, error) {
+	var user models.User
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var identity models.UserIdentity
+		err := tx.Where("issuer = ? AND subject = ?", h.client.Issuer(), claims.Subject).First(&identity).Error
+		if err == nil {
+			if identity.Email != claims.Email {
+				tx.Model(&identity).Update("email", claims.Email)
+			}
+			return tx.First(&user, identity.UserID).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if claims.Email == "" {
+			return errOIDCNoEmail
+		}
+		err = tx.Where("email = ?", normalizeEmail(claims.Email)).First(&user).Error
+		switch {
+		case err == nil:
+			// Only an address the provider vouches for may take over an existing account
+			if !claims.EmailVerified {
+				return errOIDCAccountExists
+			}
+			if user.EmailVerifiedAt == nil {
+				now := time.Now()
+				if err := tx.Model(&user).Update("email_verified_at", now).Error; err != nil {
+					return err
+				}
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if !h.provision {
+				return errOIDCNoAccount
+			}
+			if err := h.createUser(tx, claims, &user); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:  user.ID,
+			Issuer:  h.client.Issuer(),
+			Subject: claims.Subject,
+			Email:   claims.Email,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// createUser provisions an account for a new identity. It gets a random
+// password, which the user can replace through a password reset.
+func (h *OIDCHandler) createUser(tx *gorm.DB, claims *oidc.Claims, user *models.User) error {
+	username, err := uniqueUsername(tx, claims)
+	if err != nil {
+		return err
+	}
+	password := make([]byte, 32)
+	if _, err := rand.Read(password); err != nil {
+		return err
+	}
+
+	*user = models.User{Username: username, Email: normalizeEmail(claims.Email), DisplayName: claims.Name}
+	if claims.EmailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	if err := user.HashPassword(base64.RawURLEncoding.EncodeToString(password)); err != nil {
+		return err
+	}
+	return tx.Create(user).Error
+}
+
+// uniqueUsername derives a free username from the preferred username or email
+func uniqueUsername(tx *gorm.DB, claims *oidc.Claims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+	base = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_' || r == '-' {
+			return r
+		}
+		return -1
+	}, base)
+	if runes := []rune(base); len(runes) > 40 {
+		base = string(runes[:40])
+	}
+	if len(base) < 3 {
+		base = "user"
+	}
+
+	for i := 1; i <= 100; i++ {
+		candidate := base
+		if i > 1 {
+			candidate = fmt.Sprintf("%s%d", base, i)
+		}
+		var count int64
+		if err := tx.Model(&models.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+	}
+	return "", errors.New("no free username")
+}
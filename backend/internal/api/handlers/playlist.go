@@ -1,73 +1,77 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/dto"
 	"music-player-gin/internal/models"
-
-	"gorm.io/gorm"
+	"music-player-gin/internal/repository"
+	"music-player-gin/internal/service"
 )
 
 
 type PlaylistHandler struct {
-	db *gorm.DB
+	playlists service.PlaylistService
 }
 
-func NewPlaylistHandler(db *gorm.DB) *PlaylistHandler {
-	return &PlaylistHandler{db: db}
+func NewPlaylistHandler(playlists service.PlaylistService) *PlaylistHandler {
+	return &PlaylistHandler{playlists: playlists}
 }
 
 func (h *PlaylistHandler) GetAllPlaylists(c *gin.Context) {
     // Get the user ID from the context
     userID, exists := c.Get("user_id")
     if !exists {
-        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+        problem.Abort(c, errNoUser)
         return
     }
 
-    var playlists []models.Playlist
-    if err := h.db.Where("user_id = ?", userID).Find(&playlists).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch playlists"})
+    playlists, err := h.playlists.List(c.Request.Context(), userID.(uint))
+    if err != nil {
+        problem.Abort(c, problem.Internal("Failed to fetch playlists"))
         return
     }
 
-    c.JSON(http.StatusOK, playlists)
+    c.JSON(http.StatusOK, dto.NewPlaylists(playlists))
 }
 
 func (h *PlaylistHandler) CreatePlaylist(c *gin.Context) {
-    // Add debugging
-    fmt.Println("CreatePlaylist called")
-    
     // Get the user ID from the context
     userID, exists := c.Get("user_id")
-    fmt.Println("UserID from context:", userID, "Exists:", exists)
-    
     if !exists {
-        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+        problem.Abort(c, errNoUser)
         return
     }
     
     var playlist models.Playlist
     if err := c.ShouldBindJSON(&playlist); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+        problem.Abort(c, problem.Validation(err))
         return
     }
     
     // Set user ID
     playlist.UserID = userID.(uint)
     
-    if err := h.db.Create(&playlist).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create playlist"})
+    if err := h.playlists.Create(c.Request.Context(), &playlist); err != nil {
+        problem.Abort(c, problem.Internal("Failed to create playlist"))
         return
     }
 
-    c.JSON(http.StatusCreated, playlist)
+    c.JSON(http.StatusCreated, dto.NewPlaylist(playlist))
 }
 
 func (h *PlaylistHandler) AddSongToPlaylist(c *gin.Context) {
+    userID, exists := c.Get("user_id")
+    if !exists {
+        problem.Abort(c, errNoUser)
+        return
+    }
+
     type SongPlaylistRequest struct {
         PlaylistID uint `json:"playlist_id" binding:"required"`
         SongID     uint `json:"song_id" binding:"required"`
@@ -75,47 +79,90 @@ func (h *PlaylistHandler) AddSongToPlaylist(c *gin.Context) {
     
     var request SongPlaylistRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+        problem.Abort(c, problem.Validation(err))
         return
     }
     
-    var playlist models.Playlist
-    if err := h.db.First(&playlist, request.PlaylistID).Error; err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
+    playlist, err := h.playlists.AddSong(c.Request.Context(), userID.(uint), request.PlaylistID, request.SongID)
+    switch {
+    case errors.Is(err, service.ErrPlaylistNotFound):
+        problem.Abort(c, errPlaylistNotFound)
         return
-    }
-    
-    var song models.Song
-    if err := h.db.First(&song, request.SongID).Error; err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+    case errors.Is(err, service.ErrSongNotFound):
+        problem.Abort(c, errSongNotFound)
         return
-    }
-    
-    // Use the Association method for cleaner many-to-many handling
-    if err := h.db.Model(&playlist).Association("Songs").Append(&song); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add song to playlist"})
-        return
-    }
-    
-    // Return the updated playlist with songs
-    if err := h.db.Preload("Songs").First(&playlist, request.PlaylistID).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated playlist"})
+    case err != nil:
+        problem.Abort(c, problem.Internal("Failed to add song to playlist"))
         return
     }
     
     c.JSON(http.StatusOK, gin.H{
         "message": "Song added to playlist successfully",
-        "playlist": playlist,
+        "playlist": dto.NewPlaylist(*playlist),
     })
 }
 
 func (h *PlaylistHandler) GetSongsFromPlaylist(c *gin.Context) {
-	playlistID := c.Param("playlist_id")
-	var playlist models.Playlist
-	if err := h.db.Preload("Songs").First(&playlist, playlistID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
 		return
 	}
-	c.JSON(http.StatusOK, playlist.Songs)
 
-}
\ No newline at end of file
+	playlistID, err := strconv.ParseUint(c.Param("playlist_id"), 10, 0)
+	if err != nil {
+		problem.Abort(c, errPlaylistNotFound)
+		return
+	}
+	songs, err := h.playlists.Songs(c.Request.Context(), userID.(uint), uint(playlistID))
+	if err != nil {
+		if errors.Is(err, service.ErrPlaylistNotFound) {
+			problem.Abort(c, errPlaylistNotFound)
+		} else {
+			problem.Abort(c, problem.Internal("Failed to fetch playlist"))
+		}
+		return
+	}
+	c.JSON(http.StatusOK, dto.NewSongs(songs))
+
+}
+
+// UpdatePlaylistRequest changes the fields that are set
+type UpdatePlaylistRequest struct {
+	Name        *string `json:"name" binding:"omitempty,min=1,max=100"`
+	Description *string `json:"description" binding:"omitempty,max=1000"`
+	IsPublic    *bool   `json:"is_public"`
+}
+
+// UpdatePlaylist renames a playlist or changes whether it is public
+func (h *PlaylistHandler) UpdatePlaylist(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+
+	var req UpdatePlaylistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.Validation(err))
+		return
+	}
+
+	playlistID, err := strconv.ParseUint(c.Param("playlist_id"), 10, 0)
+	if err != nil {
+		problem.Abort(c, errPlaylistNotFound)
+		return
+	}
+	changes := repository.PlaylistChanges{Name: req.Name, Description: req.Description, IsPublic: req.IsPublic}
+	playlist, err := h.playlists.Update(c.Request.Context(), userID.(uint), uint(playlistID), changes)
+	if err != nil {
+		if errors.Is(err, service.ErrPlaylistNotFound) {
+			problem.Abort(c, errPlaylistNotFound)
+		} else {
+			problem.Abort(c, problem.Internal("Failed to update playlist"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewPlaylist(*playlist))
+}
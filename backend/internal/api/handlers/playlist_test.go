@@ -0,0 +1,146 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"music-player-gin/internal/api/handlers"
+	"music-player-gin/internal/api/middleware"
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/dto"
+	"music-player-gin/internal/events"
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/repository/memory"
+	"music-player-gin/internal/service"
+)
+
+// playlistRouter serves the playlist handlers over store. Requests are made
+// as the user whose ID is in the X-User-ID header, standing in for the
+// authentication middleware.
+func playlistRouter(store *memory.Store) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := handlers.NewPlaylistHandler(service.NewPlaylistService(store.Playlists(), store.Songs(), events.NewHub()))
+
+	r := gin.New()
+	r.Use(middleware.Errors(), func(c *gin.Context) {
+		if id, err := strconv.ParseUint(c.GetHeader("X-User-ID"), 10, 0); err == nil {
+			c.Set("user_id", uint(id))
+		}
+	})
+	r.GET("/playlists", h.GetAllPlaylists)
+	r.POST("/playlists", h.CreatePlaylist)
+	r.POST("/playlists/add-song", h.AddSongToPlaylist)
+	r.PATCH("/playlists/:playlist_id", h.UpdatePlaylist)
+	r.GET("/playlists/:playlist_id/songs", h.GetSongsFromPlaylist)
+	return r
+}
+
+func serve(r *gin.Engine, userID uint, method, path string, body any) *httptest.ResponseRecorder {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	if userID != 0 {
+		req.Header.Set("X-User-ID", strconv.FormatUint(uint64(userID), 10))
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestPlaylistHandlers(t *testing.T) {
+	store := memory.New()
+	r := playlistRouter(store)
+	bob := store.AddUser(models.User{Username: "bob"})
+	song := store.AddSong(models.Song{Title: "Song", Artist: "Band", FilePath: "/srv/songs/song.mp3"})
+
+	rec := serve(r, bob.ID, http.MethodPost, "/playlists", map[string]any{"name": "Mix"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d, body %s", rec.Code, rec.Body)
+	}
+	var playlist dto.Playlist
+	if err := json.Unmarshal(rec.Body.Bytes(), &playlist); err != nil {
+		t.Fatal(err)
+	}
+	if playlist.ID == 0 || playlist.Name != "Mix" || playlist.UserID != bob.ID {
+		t.Errorf("created %+v", playlist)
+	}
+
+	add := map[string]uint{"playlist_id": playlist.ID, "song_id": song.ID}
+	if rec := serve(r, bob.ID, http.MethodPost, "/playlists/add-song", add); rec.Code != http.StatusOK {
+		t.Fatalf("add song: status %d, body %s", rec.Code, rec.Body)
+	}
+
+	songsPath := fmt.Sprintf("/playlists/%d/songs", playlist.ID)
+	rec = serve(r, bob.ID, http.MethodGet, songsPath, nil)
+	var songs []dto.Song
+	if err := json.Unmarshal(rec.Body.Bytes(), &songs); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || len(songs) != 1 || songs[0].ID != song.ID {
+		t.Errorf("songs: status %d, %+v", rec.Code, songs)
+	}
+	if strings.Contains(rec.Body.String(), "file_path") {
+		t.Errorf("songs reveal the file path: %s", rec.Body)
+	}
+
+	rec = serve(r, bob.ID, http.MethodPatch, fmt.Sprintf("/playlists/%d", playlist.ID), map[string]any{"name": "Renamed", "is_public": true})
+	if err := json.Unmarshal(rec.Body.Bytes(), &playlist); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || playlist.Name != "Renamed" || !playlist.IsPublic {
+		t.Errorf("update: status %d, %+v", rec.Code, playlist)
+	}
+}
+
+func TestPlaylistHandlerErrors(t *testing.T) {
+	store := memory.New()
+	r := playlistRouter(store)
+	bob := store.AddUser(models.User{Username: "bob"})
+	alice := store.AddUser(models.User{Username: "alice"})
+	song := store.AddSong(models.Song{Title: "Song"})
+
+	rec := serve(r, bob.ID, http.MethodPost, "/playlists", map[string]any{"name": "Private"})
+	var playlist dto.Playlist
+	if err := json.Unmarshal(rec.Body.Bytes(), &playlist); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		userID uint
+		method string
+		path   string
+		body   any
+		status int
+		code   string
+	}{
+		{"no user", 0, http.MethodGet, "/playlists", nil, http.StatusUnauthorized, problem.CodeUnauthenticated},
+		{"not an object", bob.ID, http.MethodPost, "/playlists", "Mix", http.StatusBadRequest, problem.CodeInvalidRequest},
+		{"missing song", bob.ID, http.MethodPost, "/playlists/add-song", map[string]uint{"playlist_id": playlist.ID, "song_id": 9999}, http.StatusNotFound, "song_not_found"},
+		{"someone else's playlist", alice.ID, http.MethodPost, "/playlists/add-song", map[string]uint{"playlist_id": playlist.ID, "song_id": song.ID}, http.StatusNotFound, "playlist_not_found"},
+		{"private songs", alice.ID, http.MethodGet, fmt.Sprintf("/playlists/%d/songs", playlist.ID), nil, http.StatusNotFound, "playlist_not_found"},
+		{"invalid ID", bob.ID, http.MethodGet, "/playlists/abc/songs", nil, http.StatusNotFound, "playlist_not_found"},
+		{"rename someone else's", alice.ID, http.MethodPatch, fmt.Sprintf("/playlists/%d", playlist.ID), map[string]any{"name": "Mine"}, http.StatusNotFound, "playlist_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(r, tt.userID, tt.method, tt.path, tt.body)
+			var p problem.Problem
+			json.Unmarshal(rec.Body.Bytes(), &p)
+			if rec.Code != tt.status || p.Code != tt.code {
+				t.Errorf("status %d, code %q, want %d and %q", rec.Code, p.Code, tt.status, tt.code)
+			}
+		})
+	}
+}
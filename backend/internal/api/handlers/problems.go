@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/tokens"
+)
+
+// Problems raised by several handlers
+var (
+	// errNoUser means a route that needs a user was reached without AuthMiddleware
+	errNoUser = problem.Unauthorized(problem.CodeUnauthenticated, "User ID not found in context")
+
+	errSongNotFound     = problem.NotFound("song_not_found", "Song not found")
+	errPlaylistNotFound = problem.NotFound("playlist_not_found", "Playlist not found")
+	errUserNotFound     = problem.NotFound("user_not_found", "User not found")
+
+	// errInvalidLink is a verification or reset token from an emailed link that cannot be used
+	errInvalidLink = problem.BadRequest("invalid_link", tokens.ErrInvalid.Error())
+)
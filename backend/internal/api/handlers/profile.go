@@ -0,0 +1,491 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/dto"
+	"music-player-gin/internal/logging"
+	"music-player-gin/internal/mail"
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/party"
+	"music-player-gin/internal/ratelimit"
+	"music-player-gin/internal/storage"
+	"music-player-gin/internal/tokens"
+)
+
+const (
+	maxAvatarBytes     = 2 << 20
+	maxAvatarDimension = 4096
+
+	// emailChangeTTL is how long a link to confirm a new email address stays valid
+	emailChangeTTL = 24 * time.Hour
+)
+
+// avatarTypes maps the accepted image types to the extension they are stored with
+var avatarTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+}
+
+var errAvatarNotFound = problem.NotFound("avatar_not_found", "Avatar not found")
+
+type ProfileHandler struct {
+	db     *gorm.DB
+	mailer mail.Mailer
+	party  *party.Service
+
+	// verifyLimiter limits how often a user can have the verification email resent
+	verifyLimiter *ratelimit.Limiter
+}
+
+func NewProfileHandler(db *gorm.DB, mailer mail.Mailer, party *party.Service, verifyLimiter *ratelimit.Limiter) *ProfileHandler {
+	return &ProfileHandler{db: db, mailer: mailer, party: party, verifyLimiter: verifyLimiter}
+}
+
+// UpdateProfileRequest changes the fields that are set
+type UpdateProfileRequest struct {
+	DisplayName *string `json:"display_name" binding:"omitempty,max=100"`
+	Bio         *string `json:"bio" binding:"omitempty,max=1000"`
+}
+
+// ChangePasswordRequest represents the body of a password change
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+// ChangeEmailRequest starts a change of email address
+type ChangeEmailRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+// ConfirmEmailRequest completes a change of email address
+type ConfirmEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// DeleteAccountRequest confirms account deletion with the user's password
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// currentUser loads the authenticated user, writing an error response if that fails
+func (h *ProfileHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return nil, false
+	}
+
+	var user models.User
+	if err := h.db.WithContext(c.Request.Context()).First(&user, userID.(uint)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			problem.Abort(c, problem.Unauthorized("invalid_token", "User no longer exists"))
+		} else {
+			problem.Abort(c, problem.Internal("Failed to fetch user"))
+		}
+		return nil, false
+	}
+	return &user, true
+}
+
+// GetProfile returns the current user's profile
+func (h *ProfileHandler) GetProfile(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, dto.NewProfile(*user))
+}
+
+// UpdateProfile changes the display name and bio
+func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.Validation(err))
+		return
+	}
+
+	updates := map[string]any{}
+	if req.DisplayName != nil {
+		updates["display_name"] = strings.TrimSpace(*req.DisplayName)
+	}
+	if req.Bio != nil {
+		updates["bio"] = strings.TrimSpace(*req.Bio)
+	}
+	if len(updates) > 0 {
+		if err := h.db.WithContext(c.Request.Context()).Model(user).Updates(updates).Error; err != nil {
+			problem.Abort(c, problem.Internal("Failed to update profile"))
+			return
+		}
+	}
+	c.JSON(http.StatusOK, dto.NewProfile(*user))
+}
+
+// UploadAvatar stores a PNG, JPEG or GIF image as the user's avatar
+func (h *ProfileHandler) UploadAvatar(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	file, err := c.FormFile("avatar")
+	if err != nil {
+		problem.Abort(c, problem.BadRequest("avatar_required", "Avatar image is required"))
+		return
+	}
+	if file.Size > maxAvatarBytes {
+		problem.Abort(c, problem.New(http.StatusRequestEntityTooLarge, "avatar_too_large", "Avatar must be at most 2 MB"))
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		problem.Abort(c, problem.BadRequest("invalid_avatar", "Failed to read avatar"))
+		return
+	}
+	defer src.Close()
+	data, err := io.ReadAll(io.LimitReader(src, maxAvatarBytes+1))
+	if err != nil || len(data) > maxAvatarBytes {
+		problem.Abort(c, problem.BadRequest("invalid_avatar", "Failed to read avatar"))
+		return
+	}
+
+	// Trust the content rather than the file name or the client's content type
+	ext, allowed := avatarTypes[http.DetectContentType(data)]
+	if !allowed {
+		problem.Abort(c, problem.BadRequest("unsupported_avatar_type", "Avatar must be a PNG, JPEG or GIF image"))
+		return
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		problem.Abort(c, problem.BadRequest("invalid_avatar", "Avatar is not a valid image"))
+		return
+	}
+	if config.Width > maxAvatarDimension || config.Height > maxAvatarDimension {
+		problem.Abort(c, problem.BadRequest("avatar_too_large", fmt.Sprintf("Avatar must be at most %dx%d pixels", maxAvatarDimension, maxAvatarDimension)))
+		return
+	}
+
+	dir, err := storage.Dir(storage.AvatarsDir)
+	if err != nil {
+		problem.Abort(c, problem.Internal("Failed to create avatar directory"))
+		return
+	}
+	path := filepath.Join(dir, strconv.FormatUint(uint64(user.ID), 10)+"_"+strconv.FormatInt(time.Now().UnixNano(), 10)+ext)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		problem.Abort(c, problem.Internal("Failed to save avatar"))
+		return
+	}
+
+	previous := user.AvatarPath
+	if err := h.db.WithContext(c.Request.Context()).Model(user).Update("avatar_path", path).Error; err != nil {
+		os.Remove(path)
+		problem.Abort(c, problem.Internal("Failed to save avatar"))
+		return
+	}
+	if previous != "" {
+		os.Remove(previous)
+	}
+	c.JSON(http.StatusOK, dto.NewProfile(*user))
+}
+
+// DeleteAvatar removes the user's avatar
+func (h *ProfileHandler) DeleteAvatar(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	previous := user.AvatarPath
+	if err := h.db.WithContext(c.Request.Context()).Model(user).Update("avatar_path", "").Error; err != nil {
+		problem.Abort(c, problem.Internal("Failed to remove avatar"))
+		return
+	}
+	if previous != "" {
+		os.Remove(previous)
+	}
+	c.JSON(http.StatusOK, dto.NewProfile(*user))
+}
+
+// GetAvatar serves a user's avatar image. It needs no token so the URL works
+// as an image source.
+func (h *ProfileHandler) GetAvatar(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		problem.Abort(c, errAvatarNotFound)
+		return
+	}
+	var user models.User
+	if err := h.db.WithContext(c.Request.Context()).Select("id", "avatar_path").First(&user, uint(id)).Error; err != nil || user.AvatarPath == "" {
+		problem.Abort(c, errAvatarNotFound)
+		return
+	}
+	// Avatar URLs change with every upload, so the image can be cached anywhere
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.File(user.AvatarPath)
+}
+
+// ChangePassword sets a new password after checking the current one
+func (h *ProfileHandler) ChangePassword(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.Validation(err))
+		return
+	}
+	if err := user.CheckPassword(req.CurrentPassword); err != nil {
+		problem.Abort(c, problem.Forbidden("incorrect_password", "Current password is incorrect"))
+		return
+	}
+
+	if err := user.HashPassword(req.NewPassword); err != nil {
+		problem.Abort(c, problem.Internal("Failed to hash password"))
+		return
+	}
+	if err := h.db.WithContext(c.Request.Context()).Model(user).Update("password_hash", user.PasswordHash).Error; err != nil {
+		problem.Abort(c, problem.Internal("Failed to change password"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed"})
+}
+
+// ChangeEmail sends a confirmation link to the new address. The email only
+// changes once the link is followed.
+func (h *ProfileHandler) ChangeEmail(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.Validation(err))
+		return
+	}
+	if err := user.CheckPassword(req.Password); err != nil {
+		problem.Abort(c, problem.Forbidden("incorrect_password", "Password is incorrect"))
+		return
+	}
+	req.Email = normalizeEmail(req.Email)
+	if req.Email == user.Email {
+		problem.Abort(c, problem.BadRequest("email_unchanged", "That is already your email address"))
+		return
+	}
+
+	var taken int64
+	if err := h.db.WithContext(c.Request.Context()).Model(&models.User{}).Where("email = ?", req.Email).Count(&taken).Error; err != nil {
+		problem.Abort(c, problem.Internal("Failed to change email"))
+		return
+	}
+	if taken > 0 {
+		problem.Abort(c, problem.Conflict("email_taken", "Email already exists"))
+		return
+	}
+
+	token, err := tokens.Issue(h.db.WithContext(c.Request.Context()), user.ID, models.TokenEmailChange, req.Email, emailChangeTTL)
+	if err != nil {
+		problem.Abort(c, problem.Internal("Failed to change email"))
+		return
+	}
+	if err := h.db.WithContext(c.Request.Context()).Model(user).Update("pending_email", req.Email).Error; err != nil {
+		problem.Abort(c, problem.Internal("Failed to change email"))
+		return
+	}
+
+	err = h.mailer.Send(c.Request.Context(), mail.Message{
+		To:      req.Email,
+		Subject: "Confirm your new email address",
+		Body: "Follow this link within 24 hours to start using this address for your GoMusic account:\n\n" +
+			mail.AppURL() + "/confirm-email?token=" + token + "\n\nIf you did not ask for this, you can ignore this email.",
+	})
+	if err != nil {
+		// Without the email the change cannot be confirmed, so it is forgotten.
+		// Issuing the token revoked any earlier one, so no change is left pending.
+		if err := h.db.WithContext(c.Request.Context()).Model(user).Update("pending_email", "").Error; err != nil {
+			logging.FromContext(c.Request.Context()).Error("failed to roll back pending email", "err", err)
+		}
+		if err := tokens.Revoke(h.db.WithContext(c.Request.Context()), user.ID, models.TokenEmailChange); err != nil {
+			logging.FromContext(c.Request.Context()).Error("failed to revoke email change token", "err", err)
+		}
+		problem.Abort(c, problem.New(http.StatusBadGateway, "mail_failed", "Failed to send confirmation email"))
+		return
+	}
+
+	// Let the old address know in case the account has been taken over
+	err = h.mailer.Send(c.Request.Context(), mail.Message{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body:    "Someone asked to change the email address of your GoMusic account to " + req.Email + ". If this was not you, change your password.",
+	})
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to notify old address of email change", "err", err)
+	}
+
+	c.JSON(http.StatusAccepted, dto.NewProfile(*user))
+}
+
+// ResendVerification sends the email verification link again
+func (h *ProfileHandler) ResendVerification(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if user.EmailVerifiedAt != nil {
+		problem.Abort(c, problem.Conflict("email_already_verified", "Email is already verified"))
+		return
+	}
+
+	result, err := h.verifyLimiter.Allow(c.Request.Context(), strconv.FormatUint(uint64(user.ID), 10))
+	if err != nil {
+		problem.Abort(c, problem.Internal("Failed to send verification email"))
+		return
+	}
+	ratelimit.WriteHeaders(c.Writer.Header(), h.verifyLimiter.Limit(), result)
+	if !result.Allowed {
+		problem.Abort(c, problem.New(http.StatusTooManyRequests, problem.CodeRateLimited, "Too many requests, try again later"))
+		return
+	}
+
+	if err := sendVerificationEmail(c.Request.Context(), h.db, h.mailer, *user); err != nil {
+		problem.Abort(c, problem.New(http.StatusBadGateway, "mail_failed", "Failed to send verification email"))
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Verification email sent"})
+}
+
+// ConfirmEmail switches the account to the address a confirmation token was issued for
+func (h *ProfileHandler) ConfirmEmail(c *gin.Context) {
+	var req ConfirmEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.Validation(err))
+		return
+	}
+
+	token, err := tokens.Consume(h.db.WithContext(c.Request.Context()), req.Token, models.TokenEmailChange)
+	if err != nil {
+		respondTokenError(c, err)
+		return
+	}
+
+	var user models.User
+	if err := h.db.WithContext(c.Request.Context()).First(&user, token.UserID).Error; err != nil || user.PendingEmail != token.Data {
+		problem.Abort(c, errInvalidLink)
+		return
+	}
+
+	// Following the link proves the user owns the new address
+	err = h.db.WithContext(c.Request.Context()).Model(&user).Updates(map[string]any{"email": token.Data, "pending_email": "", "email_verified_at": time.Now()}).Error
+	switch {
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		// The address may have been registered by someone else in the meantime
+		problem.Abort(c, problem.Conflict("email_taken", "Email already exists"))
+		return
+	case err != nil:
+		problem.Abort(c, problem.Internal("Failed to change email"))
+		return
+	}
+	c.JSON(http.StatusOK, dto.NewProfile(user))
+}
+
+// DeleteAccount removes the user and their personal data. Their playlists,
+// favourites, history and queues are deleted; songs they uploaded stay in
+// the library without an uploader so other users' playlists keep working.
+func (h *ProfileHandler) DeleteAccount(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.Validation(err))
+		return
+	}
+	if err := user.CheckPassword(req.Password); err != nil {
+		problem.Abort(c, problem.Forbidden("incorrect_password", "Password is incorrect"))
+		return
+	}
+
+	// Leaving rooms first hands any the user hosts to someone else
+	if err := h.party.LeaveAll(c.Request.Context(), user.ID); err != nil {
+		problem.Abort(c, problem.Internal("Failed to delete account"))
+		return
+	}
+
+	err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		return deleteUserData(tx, user.ID)
+	})
+	if err != nil {
+		problem.Abort(c, problem.Internal("Failed to delete account"))
+		return
+	}
+
+	if user.AvatarPath != "" {
+		os.Remove(user.AvatarPath)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted"})
+}
+
+// deleteUserData removes everything tied to a user and then the user itself
+func deleteUserData(tx *gorm.DB, userID uint) error {
+	playlists := tx.Model(&models.Playlist{}).Unscoped().Select("id").Where("user_id = ?", userID)
+	queues := tx.Model(&models.PlayQueue{}).Select("id").Where("user_id = ?", userID)
+	sessions := tx.Model(&models.RadioSession{}).Select("id").Where("user_id = ?", userID)
+
+	steps := []func() error{
+		func() error { return tx.Exec("DELETE FROM playlist_songs WHERE playlist_id IN (?)", playlists).Error },
+		func() error { return tx.Unscoped().Where("user_id = ?", userID).Delete(&models.Playlist{}).Error },
+		func() error { return tx.Where("user_id = ?", userID).Delete(&models.UserFavoriteSong{}).Error },
+		func() error { return tx.Where("user_id = ?", userID).Delete(&models.PlayHistory{}).Error },
+		func() error { return tx.Where("queue_id IN (?)", queues).Delete(&models.PlayQueueItem{}).Error },
+		func() error { return tx.Where("user_id = ?", userID).Delete(&models.PlayQueue{}).Error },
+		func() error { return tx.Where("session_id IN (?)", sessions).Delete(&models.RadioSessionSong{}).Error },
+		func() error { return tx.Where("user_id = ?", userID).Delete(&models.RadioSession{}).Error },
+		func() error { return tx.Where("user_id = ?", userID).Delete(&models.UserToken{}).Error },
+		func() error { return tx.Where("user_id = ?", userID).Delete(&models.AccessToken{}).Error },
+		func() error { return tx.Where("user_id = ?", userID).Delete(&models.UserIdentity{}).Error },
+		func() error {
+			return tx.Where("follower_id = ? OR followee_id = ?", userID, userID).Delete(&models.Follow{}).Error
+		},
+		func() error {
+			return tx.Model(&models.Song{}).Unscoped().Where("user_id = ?", userID).Update("user_id", 0).Error
+		},
+		// Removing the row outright frees the username and email for reuse
+		func() error { return tx.Unscoped().Delete(&models.User{}, userID).Error },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
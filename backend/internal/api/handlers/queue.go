@@ -0,0 +1,407 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/dto"
+	"music-player-gin/internal/events"
+	"music-player-gin/internal/models"
+)
+
+// errVersionConflict means the queue changed since the client last read it
+var errVersionConflict = errors.New("queue has been modified by another device")
+
+// queueError is a validation failure while applying a queue change
+type queueError struct {
+	message string
+}
+
+func (e *queueError) Error() string { return e.message }
+
+type QueueHandler struct {
+	db  *gorm.DB
+	hub *events.Hub
+}
+
+func NewQueueHandler(db *gorm.DB, hub *events.Hub) *QueueHandler {
+	return &QueueHandler{db: db, hub: hub}
+}
+
+// queueState is the editable part of a play queue
+type queueState struct {
+	SongIDs         []uint
+	CurrentIndex    int
+	PositionSeconds float64
+	Shuffle         bool
+	RepeatMode      string
+}
+
+// ReplaceQueueRequest replaces the whole queue and playback state
+type ReplaceQueueRequest struct {
+	Version         *int    `json:"version"`
+	SongIDs         []uint  `json:"song_ids"`
+	CurrentIndex    int     `json:"current_index" binding:"min=0"`
+	PositionSeconds float64 `json:"position_seconds" binding:"min=0"`
+	Shuffle         bool    `json:"shuffle"`
+	RepeatMode      string  `json:"repeat_mode" binding:"omitempty,oneof=off one all"`
+}
+
+// AppendQueueRequest adds songs to the queue, at the end unless Index is set
+type AppendQueueRequest struct {
+	Version *int   `json:"version"`
+	SongIDs []uint `json:"song_ids" binding:"required,min=1"`
+	Index   *int   `json:"index" binding:"omitempty,min=0"`
+}
+
+// MoveQueueItemRequest moves the item at From so it ends up at To
+type MoveQueueItemRequest struct {
+	Version *int `json:"version"`
+	From    int  `json:"from" binding:"min=0"`
+	To      int  `json:"to" binding:"min=0"`
+}
+
+// UpdatePlaybackRequest updates the playback state without touching the items
+type UpdatePlaybackRequest struct {
+	Version         *int     `json:"version"`
+	CurrentIndex    *int     `json:"current_index" binding:"omitempty,min=0"`
+	PositionSeconds *float64 `json:"position_seconds" binding:"omitempty,min=0"`
+	Shuffle         *bool    `json:"shuffle"`
+	RepeatMode      *string  `json:"repeat_mode" binding:"omitempty,oneof=off one all"`
+}
+
+// GetQueue returns the user's play queue, creating an empty one on first use
+func (h *QueueHandler) GetQueue(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+
+	queue, err := h.loadQueue(h.db.WithContext(c.Request.Context()), userID.(uint))
+	if err != nil {
+		problem.Abort(c, problem.Internal("Failed to fetch queue"))
+		return
+	}
+	h.respond(c, queue)
+}
+
+// ReplaceQueue swaps in a new list of songs and playback state
+func (h *QueueHandler) ReplaceQueue(c *gin.Context) {
+	var req ReplaceQueueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.Validation(err))
+		return
+	}
+
+	h.update(c, req.Version, func(state *queueState) error {
+		state.SongIDs = req.SongIDs
+		state.CurrentIndex = req.CurrentIndex
+		state.PositionSeconds = req.PositionSeconds
+		state.Shuffle = req.Shuffle
+		if req.RepeatMode != "" {
+			state.RepeatMode = req.RepeatMode
+		}
+		return nil
+	})
+}
+
+// AppendToQueue inserts songs into the queue, keeping the current song playing
+func (h *QueueHandler) AppendToQueue(c *gin.Context) {
+	var req AppendQueueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.Validation(err))
+		return
+	}
+
+	h.update(c, req.Version, func(state *queueState) error {
+		index := len(state.SongIDs)
+		if req.Index != nil {
+			if *req.Index > len(state.SongIDs) {
+				return &queueError{"index is out of range"}
+			}
+			index = *req.Index
+		}
+
+		songIDs := make([]uint, 0, len(state.SongIDs)+len(req.SongIDs))
+		songIDs = append(songIDs, state.SongIDs[:index]...)
+		songIDs = append(songIDs, req.SongIDs...)
+		songIDs = append(songIDs, state.SongIDs[index:]...)
+
+		if len(state.SongIDs) > 0 && index <= state.CurrentIndex {
+			state.CurrentIndex += len(req.SongIDs)
+		}
+		state.SongIDs = songIDs
+		return nil
+	})
+}
+
+// MoveQueueItem reorders a single item, keeping the current song playing
+func (h *QueueHandler) MoveQueueItem(c *gin.Context) {
+	var req MoveQueueItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.Validation(err))
+		return
+	}
+
+	h.update(c, req.Version, func(state *queueState) error {
+		n := len(state.SongIDs)
+		if req.From >= n || req.To >= n {
+			return &queueError{"from and to must be positions in the queue"}
+		}
+
+		song := state.SongIDs[req.From]
+		songIDs := append(state.SongIDs[:req.From:req.From], state.SongIDs[req.From+1:]...)
+		songIDs = append(songIDs[:req.To], append([]uint{song}, songIDs[req.To:]...)...)
+		state.SongIDs = songIDs
+
+		switch {
+		case state.CurrentIndex == req.From:
+			state.CurrentIndex = req.To
+		case req.From < state.CurrentIndex && req.To >= state.CurrentIndex:
+			state.CurrentIndex--
+		case req.From > state.CurrentIndex && req.To <= state.CurrentIndex:
+			state.CurrentIndex++
+		}
+		return nil
+	})
+}
+
+// RemoveQueueItem removes the item at the given position
+func (h *QueueHandler) RemoveQueueItem(c *gin.Context) {
+	position, err := strconv.Atoi(c.Param("position"))
+	if err != nil || position < 0 {
+		problem.Abort(c, problem.BadRequest("invalid_position", "Invalid position"))
+		return
+	}
+
+	var version *int
+	if v, err := strconv.Atoi(c.Query("version")); err == nil {
+		version = &v
+	}
+
+	h.update(c, version, func(state *queueState) error {
+		if position >= len(state.SongIDs) {
+			return &queueError{"position is out of range"}
+		}
+		state.SongIDs = append(state.SongIDs[:position:position], state.SongIDs[position+1:]...)
+
+		switch {
+		case position < state.CurrentIndex:
+			state.CurrentIndex--
+		case position == state.CurrentIndex:
+			// The next song moves into place, so start it from the top
+			state.PositionSeconds = 0
+		}
+		return nil
+	})
+}
+
+// UpdatePlayback records the current song, position and play modes
+func (h *QueueHandler) UpdatePlayback(c *gin.Context) {
+	var req UpdatePlaybackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.Validation(err))
+		return
+	}
+
+	h.update(c, req.Version, func(state *queueState) error {
+		if req.CurrentIndex != nil {
+			state.CurrentIndex = *req.CurrentIndex
+		}
+		if req.PositionSeconds != nil {
+			state.PositionSeconds = *req.PositionSeconds
+		}
+		if req.Shuffle != nil {
+			state.Shuffle = *req.Shuffle
+		}
+		if req.RepeatMode != nil {
+			state.RepeatMode = *req.RepeatMode
+		}
+		return nil
+	})
+}
+
+// update applies a change to the user's queue if the client's version is
+// current. The version comes from the request body or the If-Match header.
+func (h *QueueHandler) update(c *gin.Context, version *int, apply func(state *queueState) error) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+
+	if version == nil {
+		version = ifMatchVersion(c.GetHeader("If-Match"))
+	}
+	if version == nil {
+		problem.Abort(c, problem.New(http.StatusPreconditionRequired, "version_required", "The queue version is required, send it as version or If-Match"))
+		return
+	}
+
+	var queue *models.PlayQueue
+	err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		current, err := h.loadQueue(tx, userID.(uint))
+		if err != nil {
+			return err
+		}
+		if current.Version != *version {
+			queue = current
+			return errVersionConflict
+		}
+
+		state := queueState{
+			SongIDs:         make([]uint, len(current.Items)),
+			CurrentIndex:    current.CurrentIndex,
+			PositionSeconds: current.PositionSeconds,
+			Shuffle:         current.Shuffle,
+			RepeatMode:      current.RepeatMode,
+		}
+		for i, item := range current.Items {
+			state.SongIDs[i] = item.SongID
+		}
+
+		if err := apply(&state); err != nil {
+			return err
+		}
+		if err := h.validate(tx, &state); err != nil {
+			return err
+		}
+
+		// The version check in the WHERE clause guards against a concurrent writer
+		res := tx.Model(&models.PlayQueue{}).
+			Where("id = ? AND version = ?", current.ID, current.Version).
+			Updates(map[string]any{
+				"current_index":    state.CurrentIndex,
+				"position_seconds": state.PositionSeconds,
+				"shuffle":          state.Shuffle,
+				"repeat_mode":      state.RepeatMode,
+				"version":          current.Version + 1,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errVersionConflict
+		}
+
+		if err := tx.Where("queue_id = ?", current.ID).Delete(&models.PlayQueueItem{}).Error; err != nil {
+			return err
+		}
+		if len(state.SongIDs) > 0 {
+			items := make([]models.PlayQueueItem, len(state.SongIDs))
+			for i, songID := range state.SongIDs {
+				items[i] = models.PlayQueueItem{QueueID: current.ID, Position: i, SongID: songID}
+			}
+			if err := tx.Omit("Song").Create(&items).Error; err != nil {
+				return err
+			}
+		}
+
+		queue, err = h.loadQueue(tx, userID.(uint))
+		return err
+	})
+
+	var validationErr *queueError
+	switch {
+	case errors.Is(err, errVersionConflict):
+		if queue != nil {
+			c.Header("ETag", etag(queue.Version))
+		}
+		conflict := problem.Conflict("version_conflict", err.Error())
+		if queue != nil {
+			conflict = conflict.With("queue", dto.NewPlayQueue(*queue))
+		}
+		problem.Abort(c, conflict)
+	case errors.As(err, &validationErr):
+		problem.Abort(c, problem.BadRequest("invalid_queue", validationErr.Error()))
+	case err != nil:
+		problem.Abort(c, problem.Internal("Failed to update queue"))
+	default:
+		// Other devices only need the playback state and version; they refetch items when it changes
+		h.hub.Publish(events.UserTopic(queue.UserID), events.QueueUpdated, gin.H{
+			"version":          queue.Version,
+			"current_index":    queue.CurrentIndex,
+			"position_seconds": queue.PositionSeconds,
+			"shuffle":          queue.Shuffle,
+			"repeat_mode":      queue.RepeatMode,
+			"length":           len(queue.Items),
+		})
+		h.respond(c, queue)
+	}
+}
+
+// validate checks that the songs exist and the playback state points into the queue
+func (h *QueueHandler) validate(tx *gorm.DB, state *queueState) error {
+	if state.RepeatMode == "" {
+		state.RepeatMode = models.RepeatOff
+	}
+
+	if len(state.SongIDs) == 0 {
+		state.CurrentIndex = 0
+		state.PositionSeconds = 0
+		return nil
+	}
+	if state.CurrentIndex >= len(state.SongIDs) {
+		return &queueError{"current_index is out of range"}
+	}
+
+	unique := make(map[uint]bool)
+	for _, id := range state.SongIDs {
+		unique[id] = true
+	}
+	ids := make([]uint, 0, len(unique))
+	for id := range unique {
+		ids = append(ids, id)
+	}
+
+	var count int64
+	if err := tx.Model(&models.Song{}).Where("id IN ?", ids).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(ids) {
+		return &queueError{"one or more songs do not exist"}
+	}
+	return nil
+}
+
+// loadQueue fetches the user's queue with its songs in order, creating it if needed
+func (h *QueueHandler) loadQueue(db *gorm.DB, userID uint) (*models.PlayQueue, error) {
+	queue := models.PlayQueue{UserID: userID, RepeatMode: models.RepeatOff, Version: 1}
+	err := db.Where("user_id = ?", userID).
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Items.Song").
+		FirstOrCreate(&queue).Error
+	if err != nil {
+		return nil, err
+	}
+	if queue.Items == nil {
+		queue.Items = []models.PlayQueueItem{}
+	}
+	return &queue, nil
+}
+
+func (h *QueueHandler) respond(c *gin.Context, queue *models.PlayQueue) {
+	c.Header("ETag", etag(queue.Version))
+	c.JSON(http.StatusOK, dto.NewPlayQueue(*queue))
+}
+
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ifMatchVersion parses a version from an If-Match header set to a queue ETag
+func ifMatchVersion(header string) *int {
+	header = strings.TrimPrefix(strings.TrimSpace(header), "W/")
+	v, err := strconv.Atoi(strings.Trim(header, `"`))
+	if err != nil {
+		return nil
+	}
+	return &v
+}
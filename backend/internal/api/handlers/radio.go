@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/dto"
+	"music-player-gin/internal/radio"
+)
+
+const (
+	defaultRadioCount = 10
+	maxRadioCount     = 50
+)
+
+type RadioHandler struct {
+	radio *radio.Service
+}
+
+func NewRadioHandler(service *radio.Service) *RadioHandler {
+	return &RadioHandler{radio: service}
+}
+
+// StartRadioRequest represents the body of a request to start a radio session
+type StartRadioRequest struct {
+	SeedType    string   `json:"seed_type" binding:"required,oneof=song artist genre playlist"`
+	SeedValue   string   `json:"seed_value" binding:"required"`
+	Familiarity *float64 `json:"familiarity" binding:"omitempty,min=0,max=1"`
+	Count       int      `json:"count" binding:"omitempty,min=1,max=50"`
+}
+
+// StartRadio creates a radio session and returns its first songs
+func (h *RadioHandler) StartRadio(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+
+	var req StartRadioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.Validation(err))
+		return
+	}
+
+	familiarity := radio.DefaultFamiliarity
+	if req.Familiarity != nil {
+		familiarity = *req.Familiarity
+	}
+	count := req.Count
+	if count == 0 {
+		count = defaultRadioCount
+	}
+
+	session, err := h.radio.Start(c.Request.Context(), userID.(uint), req.SeedType, req.SeedValue, familiarity)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	songs, err := h.radio.Next(c.Request.Context(), session, count)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"session": dto.NewRadioSession(*session),
+		"songs":   dto.NewSongs(songs),
+	})
+}
+
+// NextRadioSongs extends a radio session with the next batch of songs
+func (h *RadioHandler) NextRadioSongs(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+	count, ok := parseCount(c, "count", defaultRadioCount, maxRadioCount)
+	if !ok {
+		return
+	}
+
+	session, err := h.radio.Get(c.Request.Context(), userID.(uint), c.Param("session_id"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	songs, err := h.radio.Next(c.Request.Context(), session, count)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session": dto.NewRadioSession(*session),
+		"songs":   dto.NewSongs(songs),
+	})
+}
+
+// StopRadio ends a radio session
+func (h *RadioHandler) StopRadio(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+
+	session, err := h.radio.Get(c.Request.Context(), userID.(uint), c.Param("session_id"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	if err := h.radio.Delete(c.Request.Context(), session); err != nil {
+		problem.Abort(c, problem.Internal("Failed to stop radio"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Radio stopped"})
+}
+
+func (h *RadioHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, radio.ErrInvalidSeed), errors.Is(err, radio.ErrInvalidFamiliarity):
+		problem.Abort(c, problem.BadRequest(problem.CodeInvalidRequest, err.Error()))
+	case errors.Is(err, radio.ErrSessionNotFound):
+		problem.Abort(c, problem.NotFound("radio_not_found", err.Error()))
+	case errors.Is(err, radio.ErrSeedNotFound), errors.Is(err, radio.ErrEmptyLibrary):
+		problem.Abort(c, problem.NotFound("seed_not_found", err.Error()))
+	default:
+		problem.Abort(c, problem.Internal("Failed to generate radio"))
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/dto"
+	"music-player-gin/internal/recommend"
+)
+
+const (
+	defaultRecommendationLimit = 20
+	maxRecommendationLimit     = 100
+)
+
+type RecommendationHandler struct {
+	engine *recommend.Engine
+}
+
+func NewRecommendationHandler(engine *recommend.Engine) *RecommendationHandler {
+	return &RecommendationHandler{engine: engine}
+}
+
+// GetSimilarSongs returns songs similar to the one in the path that the
+// current user has not played recently
+func (h *RecommendationHandler) GetSimilarSongs(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+	songID, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		problem.Abort(c, problem.BadRequest("invalid_song_id", "Invalid song ID"))
+		return
+	}
+	limit, ok := parseCount(c, "limit", defaultRecommendationLimit, maxRecommendationLimit)
+	if !ok {
+		return
+	}
+
+	songs, err := h.engine.Similar(c.Request.Context(), userID.(uint), uint(songID), limit)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		problem.Abort(c, errSongNotFound)
+		return
+	}
+	if err != nil {
+		problem.Abort(c, problem.Internal("Failed to fetch similar songs"))
+		return
+	}
+	c.JSON(http.StatusOK, dto.NewScoredSongs(songs))
+}
+
+// GetRecommendations returns songs recommended for the current user
+func (h *RecommendationHandler) GetRecommendations(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+	limit, ok := parseCount(c, "limit", defaultRecommendationLimit, maxRecommendationLimit)
+	if !ok {
+		return
+	}
+
+	songs, err := h.engine.ForUser(c.Request.Context(), userID.(uint), limit)
+	if err != nil {
+		problem.Abort(c, problem.Internal("Failed to fetch recommendations"))
+		return
+	}
+	c.JSON(http.StatusOK, dto.NewScoredSongs(songs))
+}
+
+// parseCount reads a positive integer query parameter such as limit, writing
+// a 400 response and returning false when it is invalid
+func parseCount(c *gin.Context, key string, def, maximum int) (int, bool) {
+	valueStr := c.Query(key)
+	if valueStr == "" {
+		return def, true
+	}
+	value, err := strconv.Atoi(valueStr)
+	if err != nil || value < 1 || value > maximum {
+		problem.Abort(c, problem.BadRequest("invalid_"+key, key+" must be between 1 and "+strconv.Itoa(maximum)))
+		return 0, false
+	}
+	return value, true
+}
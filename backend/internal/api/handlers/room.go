@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/dto"
+	"music-player-gin/internal/events"
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/party"
+)
+
+// Messages exchanged over a room WebSocket besides the room's events
+const (
+	roomSnapshot = "room.snapshot"
+	roomTime     = "time"
+)
+
+type RoomHandler struct {
+	party    *party.Service
+	hub      *events.Hub
+	upgrader websocket.Upgrader
+}
+
+func NewRoomHandler(service *party.Service, hub *events.Hub, allowedOrigins []string) *RoomHandler {
+	return &RoomHandler{party: service, hub: hub, upgrader: newUpgrader(allowedOrigins)}
+}
+
+// CreateRoomRequest represents the body of a request to open a room
+type CreateRoomRequest struct {
+	Name string `json:"name" binding:"max=100"`
+}
+
+// JoinRoomRequest represents the body of a request to join a room by code
+type JoinRoomRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// RoomPlaybackRequest is a playback command from the host
+type RoomPlaybackRequest struct {
+	Action          string   `json:"action" binding:"required,oneof=play pause seek track next"`
+	PositionSeconds *float64 `json:"position_seconds" binding:"omitempty,min=0"`
+	Index           *int     `json:"index" binding:"omitempty,min=0"`
+}
+
+// AddRoomSongsRequest appends songs to a room's queue
+type AddRoomSongsRequest struct {
+	SongIDs []uint `json:"song_ids" binding:"required,min=1"`
+}
+
+// TransferHostRequest hands the room to another member
+type TransferHostRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
+}
+
+// roomTimeMessage lets clients estimate their clock offset from the server.
+// Clients send client_time and the server echoes it with its own time, both
+// in milliseconds since the Unix epoch.
+type roomTimeMessage struct {
+	Type       string `json:"type"`
+	ClientTime int64  `json:"client_time"`
+	ServerTime int64  `json:"server_time,omitempty"`
+}
+
+// CreateRoom opens a room hosted by the current user
+func (h *RoomHandler) CreateRoom(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+
+	var req CreateRoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.Validation(err))
+		return
+	}
+
+	room, err := h.party.Create(c.Request.Context(), userID.(uint), req.Name)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	h.respond(c, http.StatusCreated, room)
+}
+
+// JoinRoom adds the current user to the room with the given code
+func (h *RoomHandler) JoinRoom(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+
+	var req JoinRoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.Validation(err))
+		return
+	}
+
+	room, err := h.party.Join(c.Request.Context(), userID.(uint), req.Code)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, room)
+}
+
+// GetRoom returns the room's queue, members and playback state
+func (h *RoomHandler) GetRoom(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+
+	room, err := h.party.Get(c.Request.Context(), userID.(uint), c.Param("code"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, room)
+}
+
+// LeaveRoom removes the current user from the room
+func (h *RoomHandler) LeaveRoom(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+
+	if err := h.party.Leave(c.Request.Context(), userID.(uint), c.Param("code")); err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Left room"})
+}
+
+// ControlPlayback plays, pauses, seeks or changes track for everyone in the room
+func (h *RoomHandler) ControlPlayback(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+
+	var req RoomPlaybackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.Validation(err))
+		return
+	}
+
+	room, err := h.party.Control(c.Request.Context(), userID.(uint), c.Param("code"), party.Command{
+		Action:          req.Action,
+		PositionSeconds: req.PositionSeconds,
+		Index:           req.Index,
+	})
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, room)
+}
+
+// AddRoomSongs appends songs to the room's queue
+func (h *RoomHandler) AddRoomSongs(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+
+	var req AddRoomSongsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.Validation(err))
+		return
+	}
+
+	room, err := h.party.AddSongs(c.Request.Context(), userID.(uint), c.Param("code"), req.SongIDs)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, room)
+}
+
+// RemoveRoomSong removes the queue entry at the given position
+func (h *RoomHandler) RemoveRoomSong(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+
+	position, err := strconv.Atoi(c.Param("position"))
+	if err != nil || position < 0 {
+		problem.Abort(c, problem.BadRequest("invalid_position", "Invalid position"))
+		return
+	}
+
+	room, err := h.party.RemoveItem(c.Request.Context(), userID.(uint), c.Param("code"), position)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, room)
+}
+
+// TransferHost hands control of the room to another member
+func (h *RoomHandler) TransferHost(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+
+	var req TransferHostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.Validation(err))
+		return
+	}
+
+	room, err := h.party.TransferHost(c.Request.Context(), userID.(uint), c.Param("code"), req.UserID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, room)
+}
+
+// Connect streams the room's events over a WebSocket. The first message is
+// a snapshot of the room; clients may send time messages to sync their clock.
+// The connection also marks the member as present for host hand-off.
+func (h *RoomHandler) Connect(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+	lastID, ok := lastEventID(c)
+	if !ok {
+		return
+	}
+
+	room, err := h.party.Get(c.Request.Context(), userID.(uint), c.Param("code"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	sub, replay := h.hub.Subscribe([]string{events.RoomTopic(room.Code)}, lastID)
+	defer sub.Close()
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	h.party.Connect(room, userID.(uint))
+	defer h.party.Disconnect(room, userID.(uint))
+
+	// Replies are handed to the writing loop since a connection allows one writer at a time
+	replies := make(chan roomTimeMessage, 8)
+	closed := make(chan struct{})
+	conn.SetReadDeadline(time.Now().Add(2 * heartbeatInterval))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * heartbeatInterval))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var msg roomTimeMessage
+			if json.Unmarshal(data, &msg) != nil {
+				continue
+			}
+			if msg.Type != roomTime {
+				continue
+			}
+			msg.ServerTime = time.Now().UnixMilli()
+			select {
+			case replies <- msg:
+			default:
+			}
+		}
+	}()
+
+	send := func(v any) error {
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		return conn.WriteJSON(v)
+	}
+	if err := send(events.Event{Type: roomSnapshot, Data: dto.NewRoom(*room), Time: time.Now()}); err != nil {
+		return
+	}
+	for _, e := range replay {
+		if err := send(e); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case msg := <-replies:
+			if err := send(msg); err != nil {
+				return
+			}
+		case e, open := <-sub.C:
+			if !open {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "fell behind, reconnect with last_event_id"),
+					time.Now().Add(writeTimeout))
+				return
+			}
+			if err := send(e); err != nil {
+				return
+			}
+			if e.Type == events.RoomClosed {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, "room closed"),
+					time.Now().Add(writeTimeout))
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// respond sends the room along with the server time and the current playback
+// position so clients can start in sync without a WebSocket
+func (h *RoomHandler) respond(c *gin.Context, status int, room *models.Room) {
+	now := time.Now()
+	c.JSON(status, gin.H{
+		"room":             dto.NewRoom(*room),
+		"server_time":      now,
+		"current_position": party.Position(room, now),
+	})
+}
+
+func (h *RoomHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, party.ErrInvalidInput):
+		problem.Abort(c, problem.BadRequest(problem.CodeInvalidRequest, err.Error()))
+	case errors.Is(err, party.ErrNotMember):
+		problem.Abort(c, problem.Forbidden("not_member", err.Error()))
+	case errors.Is(err, party.ErrNotHost):
+		problem.Abort(c, problem.Forbidden("not_host", err.Error()))
+	case errors.Is(err, party.ErrRoomNotFound):
+		problem.Abort(c, problem.NotFound("room_not_found", err.Error()))
+	case errors.Is(err, party.ErrSongNotFound):
+		problem.Abort(c, problem.NotFound("song_not_found", err.Error()))
+	default:
+		problem.Abort(c, problem.Internal("Failed to update room"))
+	}
+}
@@ -1,44 +1,73 @@
 package handlers
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/dto"
+	"music-player-gin/internal/metrics"
 	"music-player-gin/internal/models"
+	"music-player-gin/internal/service"
+	"music-player-gin/internal/storage"
+	"music-player-gin/internal/tracing"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type SongHandler struct {
-	db *gorm.DB
+	songs   service.SongService
+	metrics *metrics.Metrics
 }
 
-func NewSongHandler(db *gorm.DB) *SongHandler {
-	return &SongHandler{db: db}
+func NewSongHandler(songs service.SongService, m *metrics.Metrics) *SongHandler {
+	return &SongHandler{songs: songs, metrics: m}
+}
+
+// lookupSong loads the song named by the :id parameter, writing an error response if that fails
+func (h *SongHandler) lookupSong(c *gin.Context) (*models.Song, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		problem.Abort(c, errSongNotFound)
+		return nil, false
+	}
+	song, err := h.songs.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, service.ErrSongNotFound) {
+			problem.Abort(c, errSongNotFound)
+		} else {
+			problem.Abort(c, problem.Internal("Failed to fetch song"))
+		}
+		return nil, false
+	}
+	return song, true
 }
 
 func (h *SongHandler) GetAllSongs(c *gin.Context) {
-	var songs []models.Song
-	if err := h.db.Find(&songs).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch songs"})
+	songs, err := h.songs.List(c.Request.Context())
+	if err != nil {
+		problem.Abort(c, problem.Internal("Failed to fetch songs"))
 		return
 	}
-	c.JSON(http.StatusOK, songs)
+	c.JSON(http.StatusOK, dto.NewSongs(songs))
 }
 
 
 func (h *SongHandler) GetSongByID(c *gin.Context) {
-	id := c.Param("id")
-	var song models.Song
-	if err := h.db.First(&song, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+	song, ok := h.lookupSong(c)
+	if !ok {
 		return
 	}
-	c.JSON(http.StatusOK, song)
+	c.JSON(http.StatusOK, dto.NewSong(*song))
 }
 
 func (h *SongHandler) UploadSong(c *gin.Context) {
@@ -54,38 +83,58 @@ func (h *SongHandler) UploadSong(c *gin.Context) {
 		var err error
 		duration, err = strconv.Atoi(durationStr)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid duration"})
+			problem.Abort(c, problem.BadRequest("invalid_duration", "Invalid duration"))
 			return
 		}
 	}
 
 	file, err := c.FormFile("file")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+		problem.Abort(c, problem.BadRequest("file_required", "File is required"))
 		return
 	}
 
 	if !strings.HasSuffix(file.Filename, ".mp3") {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Only MP3 files are allowed"})
+		problem.Abort(c, problem.BadRequest("unsupported_file_type", "Only MP3 files are allowed"))
 		return
 	}
 
-	uploadDir := "./uploads/songs"
-	if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload directory"})
+	uploadDir, err := storage.Dir(storage.SongsDir)
+	if err != nil {
+		problem.Abort(c, problem.Internal("Failed to create upload directory"))
 		return
 	}
 
+	// A random suffix keeps uploads of the same file name apart, however close together
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		problem.Abort(c, problem.Internal("Failed to save file"))
+		return
+	}
 	fileExt := filepath.Ext(file.Filename)
-	fileName := strings.TrimSuffix(file.Filename, fileExt) + "_" + strconv.FormatInt(time.Now().Unix(), 10) + fileExt
+	fileName := strings.TrimSuffix(file.Filename, fileExt) + "_" + hex.EncodeToString(suffix) + fileExt
 	filePath := filepath.Join(uploadDir, fileName)
 
-	if err := c.SaveUploadedFile(file, filePath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+	_, span := tracing.Start(c.Request.Context(), "storage.write", trace.WithAttributes(
+		attribute.String("file.path", filePath),
+		attribute.Int64("file.size", file.Size),
+	))
+	err = c.SaveUploadedFile(file, filePath)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	if err != nil {
+		problem.Abort(c, problem.Internal("Failed to save file"))
 		return
 	}
 
+	userID, _ := c.Get("user_id")
+	uploaderID, _ := userID.(uint)
+
 	song := models.Song{
+		UserID:   uploaderID,
 		Title:    title,
 		Artist:   artist,
 		Album:    album,
@@ -95,91 +144,112 @@ func (h *SongHandler) UploadSong(c *gin.Context) {
 		FileSize: file.Size,
 	}
 
-	if err := h.db.Create(&song).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create song"})
+	queued, err := h.songs.Create(c.Request.Context(), &song)
+	if err != nil {
+		os.Remove(filePath)
+		problem.Abort(c, problem.Internal("Failed to create song"))
 		return
 	}
+	h.metrics.Uploads.Inc()
+	h.metrics.UploadBytes.Observe(float64(file.Size))
 
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "Song uploaded successfully",
-		"song":    song,
+		"message": "Song uploaded successfully, processing has started",
+		"song":    dto.NewSong(song),
+		"jobs":    dto.NewJobs(queued),
 	})
 }
 
 func (h *SongHandler) PlaySong(c *gin.Context) {
-	id := c.Param("id")
-
-	var song models.Song
-	if err := h.db.First(&song, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		problem.Abort(c, errSongNotFound)
 		return
 	}
 
-	// Check if file exists
-	if _, err := os.Stat(song.FilePath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+	userID, exists := c.Get("user_id")
+	uid, _ := userID.(uint)
+	low := c.Query("quality") == "low"
+	filePath, err := h.songs.Play(c.Request.Context(), uid, uint(id), low, exists && startsPlayback(c.GetHeader("Range")))
+	switch {
+	case errors.Is(err, service.ErrSongNotFound):
+		problem.Abort(c, errSongNotFound)
+		return
+	case errors.Is(err, service.ErrSongFileMissing):
+		problem.Abort(c, problem.NotFound("file_not_found", "File not found"))
+		return
+	case err != nil:
+		problem.Abort(c, problem.Internal("Failed to record play"))
 		return
 	}
 
 	// Set appropriate headers for MP3 file
 	c.Header("Content-Description", "File Transfer")
 	c.Header("Content-Transfer-Encoding", "binary")
-	c.Header("Content-Disposition", "attachment; filename="+filepath.Base(song.FilePath))
+	c.Header("Content-Disposition", "attachment; filename="+filepath.Base(filePath))
 	c.Header("Content-Type", "audio/mpeg")
 
-	// Serve the file
-	c.File(song.FilePath)
+	// Serve the file, counting bytes as they are sent so long streams show up
+	// before they finish
+	quality := "original"
+	if low {
+		quality = "low"
+	}
+	h.metrics.ActiveStreams.Inc()
+	defer h.metrics.ActiveStreams.Dec()
+	c.Writer = &countingWriter{ResponseWriter: c.Writer, counter: h.metrics.StreamedBytes.WithLabelValues(quality)}
+	_, span := tracing.Start(c.Request.Context(), "storage.read", trace.WithAttributes(
+		attribute.String("file.path", filePath),
+		attribute.String("quality", quality),
+	))
+	c.File(filePath)
+	span.SetAttributes(attribute.Int("file.bytes_sent", max(c.Writer.Size(), 0)))
+	span.End()
+}
+
+// countingWriter adds the bytes of the body written through it to a counter
+type countingWriter struct {
+	gin.ResponseWriter
+	counter prometheus.Counter
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.counter.Add(float64(n))
+	return n, err
+}
+
+// startsPlayback reports whether a request with the given Range header reads
+// the file from the beginning
+func startsPlayback(rangeHeader string) bool {
+	return rangeHeader == "" || strings.HasPrefix(strings.ReplaceAll(rangeHeader, " ", ""), "bytes=0-")
 }
 
 func (h *SongHandler) AddToFavourites(c *gin.Context) {
     userId, exists := c.Get("user_id")
-    var isFavourited bool = false
 
     if !exists {
-        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
-        return
-    }
-
-    songIDStr := c.Param("id")
-    songID, err := strconv.Atoi(songIDStr)
-    if err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid song ID"})
+        problem.Abort(c, errNoUser)
         return
     }
 
-    // Check if the song exists
-    var song models.Song
-    if err := h.db.First(&song, songID).Error; err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+    songID, err := strconv.Atoi(c.Param("id"))
+    if err != nil || songID < 1 {
+        problem.Abort(c, problem.BadRequest("invalid_song_id", "Invalid song ID"))
         return
     }
 
-    // Find the user
-    var user models.User
-    if err := h.db.First(&user, userId).Error; err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-        return
-    }
-
-    // Check if the song is already in favorites
-    var count int64
-    h.db.Table("user_favourite_songs").
-        Where("user_id = ? AND song_id = ?", user.ID, song.ID).
-        Count(&count)
-
-    if count > 0 {
-        // Song is already in favorites, remove it
-        if err := h.db.Exec("DELETE FROM user_favourite_songs WHERE user_id = ? AND song_id = ?", user.ID, song.ID).Error; err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove song from favorites"})
-            return
-        }
-    } else {
-        // Song is not in favorites, add it
-        if err := h.db.Exec("INSERT INTO user_favourite_songs (user_id, song_id) VALUES (?, ?)", user.ID, song.ID).Error; err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add song to favorites"})
-            return
+    isFavourited, err := h.songs.ToggleFavourite(c.Request.Context(), userId.(uint), uint(songID))
+    if err != nil {
+        switch {
+        case errors.Is(err, service.ErrSongNotFound):
+            problem.Abort(c, errSongNotFound)
+        case errors.Is(err, service.ErrUserNotFound):
+            problem.Abort(c, errUserNotFound)
+        default:
+            problem.Abort(c, problem.Internal("Failed to update favorites"))
         }
-        isFavourited = true
+        return
     }
 
     c.JSON(http.StatusOK, gin.H{
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/dto"
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/repository"
+	"music-player-gin/internal/service"
+)
+
+const (
+	defaultFollowCount = 20
+	maxFollowCount     = 100
+)
+
+type UserHandler struct {
+	users     service.UserService
+	playlists service.PlaylistService
+}
+
+func NewUserHandler(users service.UserService, playlists service.PlaylistService) *UserHandler {
+	return &UserHandler{users: users, playlists: playlists}
+}
+
+// followCursor marks the last follow of a page of followers or following
+type followCursor struct {
+	At     time.Time `json:"t"`
+	UserID uint      `json:"u"`
+}
+
+// lookupUser loads the user named by the :id parameter, writing an error response if that fails
+func (h *UserHandler) lookupUser(c *gin.Context) (*models.User, bool) {
+	id, ok := userID(c)
+	if !ok {
+		return nil, false
+	}
+
+	user, err := h.users.Get(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err, "Failed to fetch user")
+		return nil, false
+	}
+	return user, true
+}
+
+// userID reads the :id parameter, writing an error response if it is invalid
+func userID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		problem.Abort(c, problem.BadRequest("invalid_user_id", "Invalid user ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// respondError writes the response for a failed user service call
+func (h *UserHandler) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		problem.Abort(c, errUserNotFound)
+	case errors.Is(err, service.ErrFollowSelf):
+		problem.Abort(c, problem.BadRequest("cannot_follow_self", "You cannot follow yourself"))
+	default:
+		problem.Abort(c, problem.Internal(message))
+	}
+}
+
+// GetUser returns a user's public profile with follower counts and whether
+// the current user follows them
+func (h *UserHandler) GetUser(c *gin.Context) {
+	viewerID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+
+	profile, err := h.users.Profile(c.Request.Context(), viewerID.(uint), id)
+	if err != nil {
+		h.respondError(c, err, "Failed to fetch user")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.UserProfile{
+		User:        dto.NewUser(profile.User),
+		Followers:   profile.Followers,
+		Following:   profile.Following,
+		IsFollowing: profile.IsFollowing,
+	})
+}
+
+// Follow makes the current user follow another user. Following twice is harmless.
+func (h *UserHandler) Follow(c *gin.Context) {
+	viewerID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.users.Follow(c.Request.Context(), viewerID.(uint), id); err != nil {
+		h.respondError(c, err, "Failed to follow user")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"following": true})
+}
+
+// Unfollow stops the current user following another user
+func (h *UserHandler) Unfollow(c *gin.Context) {
+	viewerID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.users.Unfollow(c.Request.Context(), viewerID.(uint), id); err != nil {
+		h.respondError(c, err, "Failed to unfollow user")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"following": false})
+}
+
+// GetFollowers lists who follows the user, most recent first
+func (h *UserHandler) GetFollowers(c *gin.Context) {
+	h.listFollows(c, h.users.Followers)
+}
+
+// GetFollowing lists who the user follows, most recent first
+func (h *UserHandler) GetFollowing(c *gin.Context) {
+	h.listFollows(c, h.users.Following)
+}
+
+// followLister is UserService.Followers or UserService.Following
+type followLister func(ctx context.Context, userID uint, after *repository.FollowPosition, limit int) (*service.FollowPage, error)
+
+// listFollows writes the page of follows list returns for the user
+func (h *UserHandler) listFollows(c *gin.Context, list followLister) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+	limit, ok := parseCount(c, "limit", defaultFollowCount, maxFollowCount)
+	if !ok {
+		return
+	}
+
+	var after *repository.FollowPosition
+	if raw := c.Query("cursor"); raw != "" {
+		var cursor followCursor
+		data, err := base64.RawURLEncoding.DecodeString(raw)
+		if err != nil || json.Unmarshal(data, &cursor) != nil {
+			problem.Abort(c, problem.BadRequest("invalid_cursor", "Invalid cursor"))
+			return
+		}
+		after = &repository.FollowPosition{At: cursor.At, UserID: cursor.UserID}
+	}
+
+	page, err := list(c.Request.Context(), id, after, limit)
+	if err != nil {
+		h.respondError(c, err, "Failed to fetch follows")
+		return
+	}
+
+	next := ""
+	if page.Next != nil {
+		data, _ := json.Marshal(followCursor{At: page.Next.At, UserID: page.Next.UserID})
+		next = base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	result := make([]dto.Follow, 0, len(page.Users))
+	for _, f := range page.Users {
+		result = append(result, dto.Follow{User: dto.NewUser(f.User), FollowedAt: f.FollowedAt})
+	}
+	c.JSON(http.StatusOK, gin.H{"users": result, "next_cursor": next})
+}
+
+// GetUserPlaylists lists a user's public playlists, or all of them for their owner
+func (h *UserHandler) GetUserPlaylists(c *gin.Context) {
+	viewerID, exists := c.Get("user_id")
+	if !exists {
+		problem.Abort(c, errNoUser)
+		return
+	}
+	user, ok := h.lookupUser(c)
+	if !ok {
+		return
+	}
+
+	playlists, err := h.playlists.ListVisible(c.Request.Context(), viewerID.(uint), user.ID)
+	if err != nil {
+		problem.Abort(c, problem.Internal("Failed to fetch playlists"))
+		return
+	}
+	c.JSON(http.StatusOK, dto.NewPlaylists(playlists))
+}
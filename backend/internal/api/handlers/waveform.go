@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/waveform"
+)
+
+// GetWaveform serves a song's peak data in audiowaveform JSON (default) or
+// binary format. pixels_per_second picks the zoom level; omitting it returns
+// the data at full stored resolution.
+func (h *SongHandler) GetWaveform(c *gin.Context) {
+	song, ok := h.lookupSong(c)
+	if !ok {
+		return
+	}
+
+	if song.WaveformPath == "" {
+		problem.Abort(c, problem.NotFound("waveform_not_available", "Waveform not available").
+			With("processing_status", song.ProcessingStatus))
+		return
+	}
+
+	bits := 16
+	if bitsStr := c.Query("bits"); bitsStr != "" {
+		if bitsStr != "8" && bitsStr != "16" {
+			problem.Abort(c, problem.BadRequest("invalid_bits", "bits must be 8 or 16"))
+			return
+		}
+		bits, _ = strconv.Atoi(bitsStr)
+	}
+
+	f, err := os.Open(song.WaveformPath)
+	if err != nil {
+		problem.Abort(c, problem.NotFound("waveform_not_available", "Waveform not available"))
+		return
+	}
+	defer f.Close()
+
+	wf, err := waveform.ReadBinary(f)
+	if err != nil {
+		problem.Abort(c, problem.Internal("Failed to read waveform"))
+		return
+	}
+
+	if ppsStr := c.Query("pixels_per_second"); ppsStr != "" {
+		pps, err := strconv.ParseFloat(ppsStr, 64)
+		if err != nil || pps <= 0 {
+			problem.Abort(c, problem.BadRequest("invalid_pixels_per_second", "Invalid pixels_per_second"))
+			return
+		}
+
+		maxPPS := float64(wf.SampleRate) / float64(wf.SamplesPerPixel)
+		if pps > maxPPS {
+			problem.Abort(c, problem.BadRequest("invalid_pixels_per_second", "pixels_per_second is higher than the stored resolution").
+				With("max_pixels_per_second", maxPPS))
+			return
+		}
+
+		wf, err = wf.Resample(int(math.Round(float64(wf.SampleRate) / pps)))
+		if err != nil {
+			problem.Abort(c, problem.BadRequest("invalid_pixels_per_second", err.Error()))
+			return
+		}
+	}
+
+	// Peak data only changes if the song is re-uploaded, which creates a new ID
+	c.Header("Cache-Control", "private, max-age=86400")
+
+	if c.Query("format") == "dat" || strings.Contains(c.GetHeader("Accept"), "application/octet-stream") {
+		c.Header("Content-Type", "application/octet-stream")
+		c.Status(http.StatusOK)
+		wf.WriteBinary(c.Writer, bits)
+		return
+	}
+
+	c.JSON(http.StatusOK, wf.ToJSON(bits))
+}
@@ -2,90 +2,85 @@ package middleware
 
 import (
 	"errors"
-	"fmt"
-	"net/http"
-	"os"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/jwtauth"
+	"music-player-gin/internal/logging"
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/tokens"
 )
 
-// AuthMiddleware verifies the JWT token and sets user information in the context
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware verifies the JWT token or personal access token and sets user information in the context
+func AuthMiddleware(db *gorm.DB, keys *jwtauth.KeyRing) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Add debug output
-		fmt.Println("Auth middleware processing request:", c.Request.URL.Path)
-		
 		// Get the Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			fmt.Println("No Authorization header found")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
+			problem.Abort(c, problem.Unauthorized(problem.CodeUnauthenticated, "Authorization header required"))
 			return
 		}
 
 		// Check if the format is "Bearer <token>"
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			fmt.Println("Invalid Authorization format:", authHeader)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be Bearer <token>"})
-			c.Abort()
+			problem.Abort(c, problem.Unauthorized("invalid_authorization_header", "Authorization header format must be Bearer <token>"))
 			return
 		}
 
 		// Get the token string
 		tokenString := parts[1]
-		fmt.Println("Token received:", tokenString[:10], "...")
-
-		// Parse and validate the token
-		claims := &jwt.MapClaims{}
-
-		// Get the JWT secret key from environment variable or use a default one
-		secretKey := os.Getenv("JWT_SECRET_KEY")
-		if secretKey == "" {
-			secretKey = "your-secret-key-change-in-production"
-		}
-
-		// Parse the token
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			// Validate the alg
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, errors.New("unexpected signing method")
-			}
-			return []byte(secretKey), nil
-		})
-
-		if err != nil {
-			fmt.Println("Token parsing error:", err)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
+		if tokens.IsAccessToken(tokenString) {
+			authenticateAccessToken(c, db, tokenString)
 			return
 		}
 
-		if !token.Valid {
-			fmt.Println("Token is invalid")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+		// Parse and validate the token, including its issuer, audience and times
+		claims, err := keys.ParseUserToken(tokenString)
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Debug("rejected token", "err", err)
+			problem.Abort(c, problem.Unauthorized("invalid_token", "Invalid or expired token"))
 			return
 		}
 
 		// Set user information in the context
-		userID, ok := (*claims)["user_id"]
-		if !ok {
-			fmt.Println("No user_id found in token claims")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
-			return
-		}
-
-		// Convert user_id to uint and set in context
-		userIDValue := uint(userID.(float64))
-		fmt.Println("Setting user_id in context:", userIDValue)
-		c.Set("user_id", userIDValue)
-		c.Set("username", (*claims)["username"])
+		setUser(c, claims.UserID, claims.Username)
 
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// authenticateAccessToken accepts a personal access token. The token is kept
+// in the context so RequireScopes can check what it may do.
+func authenticateAccessToken(c *gin.Context, db *gorm.DB, raw string) {
+	db = db.WithContext(c.Request.Context())
+	token, err := tokens.Authenticate(db, raw)
+	if errors.Is(err, tokens.ErrInvalid) {
+		problem.Abort(c, problem.Unauthorized("invalid_token", "Invalid or expired token"))
+		return
+	}
+	if err != nil {
+		problem.Abort(c, problem.Internal("Failed to check token"))
+		return
+	}
+
+	var user models.User
+	if err := db.Select("id", "username").First(&user, token.UserID).Error; err != nil {
+		problem.Abort(c, problem.Unauthorized("invalid_token", "Invalid or expired token"))
+		return
+	}
+
+	setUser(c, user.ID, user.Username)
+	c.Set("access_token", token)
+	c.Next()
+}
+
+// setUser records who the request is from, for handlers and in the request's logger
+func setUser(c *gin.Context, userID uint, username string) {
+	c.Set("user_id", userID)
+	c.Set("username", username)
+	c.Request = c.Request.WithContext(logging.With(c.Request.Context(), "user_id", userID))
+}
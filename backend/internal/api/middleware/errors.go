@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/logging"
+)
+
+// Errors writes the error a handler aborted with as a problem response.
+// Errors that are not problems are logged and reported as internal errors
+// without their details. Nothing is written if the handler already responded.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := c.Errors.Last().Err
+		var p *problem.Problem
+		if !errors.As(err, &p) {
+			logging.FromContext(c.Request.Context()).Error("unhandled error", "err", err)
+			p = problem.Internal("Internal server error")
+		}
+		problem.Write(c, p)
+	}
+}
+
+// Recover turns a panicking handler into an internal error problem and logs
+// the panic with its stack to the request's logger
+func Recover() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		logging.FromContext(c.Request.Context()).Error("panic",
+			"panic", recovered,
+			"stack", string(debug.Stack()),
+		)
+		problem.Write(c, problem.Internal("Internal server error"))
+		c.Abort()
+	})
+}
+
+// NoRoute answers requests for paths the API does not have
+func NoRoute(c *gin.Context) {
+	problem.Abort(c, problem.NotFound(problem.CodeNotFound, "No such endpoint"))
+}
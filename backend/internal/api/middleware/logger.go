@@ -1,18 +1,52 @@
 package middleware
 
 import (
-	"log"
+	"log/slog"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-)
+	"go.opentelemetry.io/otel/trace"
 
+	"music-player-gin/internal/logging"
+)
 
-func LoggerMiddleware() gin.HandlerFunc {
+// LoggerMiddleware gives each request a logger carrying its ID, method, route
+// and trace, which handlers reach with logging.FromContext, and logs the
+// request once it is done. Server errors are logged as errors. Only the path
+// is logged since query strings can hold tokens. A nil logger uses the default.
+func LoggerMiddleware(logger *slog.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
+		base := logger
+		if base == nil {
+			base = slog.Default()
+		}
+		requestLogger := base.With(
+			"request_id", c.GetString("request_id"),
+			"method", c.Request.Method,
+			"route", c.FullPath(),
+		)
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+			requestLogger = requestLogger.With("trace_id", sc.TraceID().String(), "span_id", sc.SpanID().String())
+		}
+		c.Request = c.Request.WithContext(logging.WithLogger(c.Request.Context(), requestLogger))
+
 		c.Next()
-		duration := time.Since(start)
-		log.Printf("Request: %s %s took %v", c.Request.Method, c.Request.URL.Path, duration)
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		// Read the logger back as authentication adds the user to it
+		ctx := c.Request.Context()
+		logging.FromContext(ctx).LogAttrs(ctx, level, "request",
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("client_ip", c.ClientIP()),
+		)
 	}
-}
\ No newline at end of file
+}
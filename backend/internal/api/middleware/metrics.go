@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"music-player-gin/internal/metrics"
+)
+
+// Metrics counts requests and times them by method, route and status. Paths
+// without a route share the route label "unmatched" so scanners cannot add
+// a series per path they try.
+func Metrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		labels := []string{c.Request.Method, route, strconv.Itoa(c.Writer.Status())}
+		m.Requests.WithLabelValues(labels...).Inc()
+		m.RequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/logging"
+	"music-player-gin/internal/ratelimit"
+)
+
+// KeyFunc picks what a request is rate limited by. An empty key skips the
+// limit. A KeyFunc may also abort the request, which ends it there.
+type KeyFunc func(c *gin.Context) string
+
+// maxKeyBody is the largest request body ByJSONField reads
+const maxKeyBody = 1 << 20
+
+// ByIP limits each client IP separately
+func ByIP(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// ByJSONField limits by a field of the JSON request body, such as the
+// username being logged in to. The body is left in place for the handler.
+// Bodies over 1 MB are refused, since cutting them short would let a client
+// pad the body so the field is never read and the limit never applies.
+//
+// The field is decoded into a struct tagged with its name, so keys match as
+// they do when the handler binds the body, whatever their case.
+func ByJSONField(field string) KeyFunc {
+	target := reflect.StructOf([]reflect.StructField{{
+		Name: "Value",
+		Type: reflect.TypeOf(""),
+		Tag:  reflect.StructTag(`json:"` + field + `"`),
+	}})
+	return func(c *gin.Context) string {
+		if c.Request.Body == nil {
+			return ""
+		}
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxKeyBody+1))
+		if err != nil {
+			return ""
+		}
+		if len(body) > maxKeyBody {
+			problem.Abort(c, problem.New(http.StatusRequestEntityTooLarge, "body_too_large", "Request body must be at most 1 MB"))
+			return ""
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		fields := reflect.New(target)
+		if json.Unmarshal(body, fields.Interface()) != nil {
+			return ""
+		}
+		value := fields.Elem().Field(0).String()
+		return strings.ToLower(strings.TrimSpace(value))
+	}
+}
+
+// RateLimit rejects requests whose key has used up its allowance and reports
+// the remaining allowance in RateLimit-* headers
+func RateLimit(limiter *ratelimit.Limiter, key KeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		k := key(c)
+		if c.IsAborted() {
+			return
+		}
+		if k == "" {
+			c.Next()
+			return
+		}
+
+		result, err := limiter.Allow(c.Request.Context(), k)
+		if err != nil {
+			// Failing open keeps the API usable if the limiter's store is down
+			logging.FromContext(c.Request.Context()).Warn("rate limiter unavailable", "err", err)
+			c.Next()
+			return
+		}
+
+		ratelimit.WriteHeaders(c.Writer.Header(), limiter.Limit(), result)
+		if !result.Allowed {
+			problem.Abort(c, problem.New(http.StatusTooManyRequests, problem.CodeRateLimited, "Too many requests, try again later"))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/models"
+)
+
+// RequireScopes limits what requests made with a personal access token may
+// do. Reads need the read scope. Other requests need the scope writes lists
+// for their route, written as "METHOD /path" the way the route was
+// registered; routes missing from writes are only open to logged-in
+// sessions. Requests authenticated with a JWT are not restricted.
+func RequireScopes(writes map[string]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get("access_token")
+		if !ok {
+			c.Next()
+			return
+		}
+		token := value.(*models.AccessToken)
+
+		scope := models.ScopeRead
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			scope = writes[c.Request.Method+" "+c.FullPath()]
+		}
+		if scope == "" {
+			problem.Abort(c, problem.Forbidden("access_token_not_allowed", "This endpoint cannot be used with an access token"))
+			return
+		}
+		if !token.HasScope(scope) {
+			problem.Abort(c, problem.Forbidden("insufficient_scope", "Access token lacks the "+scope+" scope").
+				With("scope", scope))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"music-player-gin/internal/api/problem"
+	"music-player-gin/internal/tracing"
+)
+
+// Tracing starts a server span for each request, continuing the trace named
+// in an incoming traceparent header. Handlers start child spans from the
+// request's context. A nil provider turns tracing off.
+func Tracing(provider trace.TracerProvider) gin.HandlerFunc {
+	if provider == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return otelgin.Middleware(tracing.DefaultServiceName,
+		otelgin.WithTracerProvider(provider),
+		otelgin.WithPropagators(tracing.Propagator),
+	)
+}
+
+// SpanDetails completes the server span Tracing started. It names the span
+// after the method and route, and records the request ID and the
+// authenticated user.
+func SpanDetails() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		span := trace.SpanFromContext(c.Request.Context())
+		if !span.IsRecording() {
+			c.Next()
+			return
+		}
+		name := c.Request.Method
+		if route := c.FullPath(); route != "" {
+			name += " " + route
+		}
+		span.SetName(name)
+		span.SetAttributes(attribute.String("request_id", c.GetString("request_id")))
+
+		c.Next()
+
+		if userID, ok := c.Get("user_id"); ok {
+			if id, ok := userID.(uint); ok {
+				span.SetAttributes(attribute.Int64("enduser.id", int64(id)))
+			}
+		}
+		// otelgin fails the span for any error left on the context, but
+		// problems answered with 4xx are the client's and have been written
+		// by now, so only those of the server are left to mark it
+		if c.Writer.Status() < http.StatusInternalServerError {
+			errs := c.Errors[:0]
+			for _, err := range c.Errors {
+				if _, ok := err.Err.(*problem.Problem); !ok {
+					errs = append(errs, err)
+				}
+			}
+			c.Errors = errs
+		}
+	}
+}
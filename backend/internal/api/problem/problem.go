@@ -0,0 +1,180 @@
+// Package problem is the API's error model. Every error response is an RFC
+// 7807 problem details object served as application/problem+json, with a
+// machine readable code, the request ID and, for rejected request bodies, an
+// error per field.
+package problem
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType is the media type problems are served as
+const ContentType = "application/problem+json"
+
+// Codes shared by many endpoints. Others are named where they are used.
+const (
+	CodeInvalidRequest  = "invalid_request"
+	CodeUnauthenticated = "unauthenticated"
+	CodeRateLimited     = "rate_limited"
+	CodeNotFound        = "not_found"
+	CodeInternal        = "internal_error"
+)
+
+// Problem describes why a request failed. Status, Code and Detail are set
+// where the problem is raised; Write fills in the rest.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+
+	// Extensions are further members specific to the problem, such as the
+	// current queue on a version conflict
+	Extensions map[string]any `json:"-"`
+}
+
+// FieldError is a problem with one field of the request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// New returns a problem with the given status, code and human readable detail
+func New(status int, code, detail string) *Problem {
+	return &Problem{Status: status, Code: code, Detail: detail}
+}
+
+func BadRequest(code, detail string) *Problem {
+	return New(http.StatusBadRequest, code, detail)
+}
+
+func Unauthorized(code, detail string) *Problem {
+	return New(http.StatusUnauthorized, code, detail)
+}
+
+func Forbidden(code, detail string) *Problem {
+	return New(http.StatusForbidden, code, detail)
+}
+
+func NotFound(code, detail string) *Problem {
+	return New(http.StatusNotFound, code, detail)
+}
+
+func Conflict(code, detail string) *Problem {
+	return New(http.StatusConflict, code, detail)
+}
+
+// Internal is a failure on the server's side. The detail should say what
+// failed without revealing why.
+func Internal(detail string) *Problem {
+	return New(http.StatusInternalServerError, CodeInternal, detail)
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Code + ": " + p.Detail
+	}
+	return p.Code
+}
+
+// With returns a copy of the problem with an extension member added
+func (p *Problem) With(key string, value any) *Problem {
+	cp := *p
+	cp.Extensions = maps.Clone(p.Extensions)
+	if cp.Extensions == nil {
+		cp.Extensions = map[string]any{}
+	}
+	cp.Extensions[key] = value
+	return &cp
+}
+
+// MarshalJSON writes the standard members together with the extensions
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type plain Problem
+	data, err := json.Marshal((*plain)(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return data, err
+	}
+
+	members := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+	for key, value := range p.Extensions {
+		// Extensions cannot replace the standard members
+		if _, ok := members[key]; ok {
+			continue
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		members[key] = raw
+	}
+	return json.Marshal(members)
+}
+
+// UnmarshalJSON reads a problem, keeping members it does not know as extensions
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	type plain Problem
+	if err := json.Unmarshal(data, (*plain)(p)); err != nil {
+		return err
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	for _, key := range []string{"type", "title", "status", "detail", "instance", "code", "request_id", "errors"} {
+		delete(members, key)
+	}
+	p.Extensions = nil
+	for key, raw := range members {
+		var value any
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return err
+		}
+		if p.Extensions == nil {
+			p.Extensions = map[string]any{}
+		}
+		p.Extensions[key] = value
+	}
+	return nil
+}
+
+// Abort stops the request with a problem. The Errors middleware writes it
+// once the handlers have returned.
+func Abort(c *gin.Context, p *Problem) {
+	c.Error(p)
+	c.Abort()
+}
+
+// Write sends a problem as the response, completing it with the request's
+// path and ID
+func Write(c *gin.Context, p *Problem) {
+	cp := *p
+	if cp.Type == "" {
+		cp.Type = "about:blank"
+	}
+	if cp.Title == "" {
+		cp.Title = http.StatusText(cp.Status)
+	}
+	cp.Instance = c.Request.URL.Path
+	cp.RequestID = c.GetString("request_id")
+
+	data, err := json.Marshal(&cp)
+	if err != nil {
+		// Only an extension can fail to encode
+		cp.Extensions = nil
+		data, _ = json.Marshal(&cp)
+	}
+	c.Data(cp.Status, ContentType, data)
+}
@@ -0,0 +1,78 @@
+package problem
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+func TestExtensions(t *testing.T) {
+	p := NotFound("song_not_found", "Song not found").With("processing_status", "pending").With("code", "hijacked")
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := string(data)
+	if !strings.Contains(body, `"processing_status":"pending"`) || !strings.Contains(body, `"code":"song_not_found"`) {
+		t.Errorf("body = %s", body)
+	}
+
+	var got Problem
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Code != "song_not_found" || got.Status != 404 || got.Extensions["processing_status"] != "pending" || len(got.Extensions) != 1 {
+		t.Errorf("decoded = %+v", got)
+	}
+
+	// With leaves the original untouched
+	base := BadRequest("bad", "Bad")
+	base.With("a", 1)
+	if base.Extensions != nil {
+		t.Errorf("With changed the original: %v", base.Extensions)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	type item struct {
+		Name string `json:"name" binding:"required"`
+	}
+	type request struct {
+		Title  string   `json:"title" binding:"required,max=5"`
+		Tags   []string `json:"tags" binding:"min=1"`
+		Rating int      `json:"rating" binding:"max=5"`
+		Mode   string   `json:"mode" binding:"omitempty,oneof=a b"`
+		Items  []item   `json:"items" binding:"dive"`
+	}
+
+	bind := func(body string) *Problem {
+		var req request
+		err := json.Unmarshal([]byte(body), &req)
+		if err == nil {
+			err = binding.Validator.ValidateStruct(&req)
+		}
+		return Validation(err)
+	}
+
+	p := bind(`{"title": "Too long", "tags": [], "rating": 9, "mode": "c", "items": [{}]}`)
+	want := []FieldError{
+		{"title", "must have at most 5 characters"},
+		{"tags", "must have at least 1 item"},
+		{"rating", "must be at most 5"},
+		{"mode", "must be one of a, b"},
+		{"items[0].name", "is required"},
+	}
+	if !reflect.DeepEqual(p.Errors, want) {
+		t.Errorf("errors = %+v, want %+v", p.Errors, want)
+	}
+
+	if p := bind(`{"rating": "high"}`); len(p.Errors) != 1 || p.Errors[0] != (FieldError{"rating", "must be an integer"}) {
+		t.Errorf("type error = %+v", p.Errors)
+	}
+	if p := bind(`{"title": `); p.Detail != "Request body is not valid JSON" || p.Errors != nil {
+		t.Errorf("syntax error = %+v", p)
+	}
+}
@@ -0,0 +1,112 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Report fields by their JSON names rather than Go struct field names
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
+
+// Validation describes why a request body could not be bound, with an entry
+// per invalid field. Decoder and validator messages are not passed on as they
+// name Go types and struct fields.
+func Validation(err error) *Problem {
+	p := BadRequest(CodeInvalidRequest, "Invalid request format")
+
+	var fields validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &fields):
+		for _, fe := range fields {
+			p.Errors = append(p.Errors, FieldError{Field: fieldPath(fe), Message: fieldMessage(fe)})
+		}
+	case errors.As(err, &typeErr):
+		p.Errors = []FieldError{{Field: typeErr.Field, Message: "must be " + jsonType(typeErr.Type)}}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		p.Detail = "Request body is not valid JSON"
+	case errors.Is(err, io.EOF):
+		p.Detail = "Request body is empty"
+	}
+	return p
+}
+
+// fieldPath is the field's path below the request struct, such as song_ids[2]
+func fieldPath(fe validator.FieldError) string {
+	if _, path, ok := strings.Cut(fe.Namespace(), "."); ok {
+		return path
+	}
+	return fe.Field()
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	// Sizes are lengths for strings, counts for lists and values for numbers
+	var unit string
+	switch fe.Kind() {
+	case reflect.String:
+		unit = " character"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " item"
+	}
+	if unit != "" && fe.Param() != "1" {
+		unit += "s"
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		if unit == "" {
+			return "must be at least " + fe.Param()
+		}
+		return "must have at least " + fe.Param() + unit
+	case "max":
+		if unit == "" {
+			return "must be at most " + fe.Param()
+		}
+		return "must have at most " + fe.Param() + unit
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	}
+	return "is invalid"
+}
+
+// jsonType names the JSON type a Go type is decoded from
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
@@ -0,0 +1,275 @@
+package routes_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"music-player-gin/internal/api/apitest"
+	"music-player-gin/internal/api/routes"
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/ratelimit"
+)
+
+func TestRegisterAndLogin(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	if bob.ID == 0 || bob.Token == "" {
+		t.Fatalf("register returned id %d and token %q", bob.ID, bob.Token)
+	}
+
+	rec := h.Login("bob", apitest.DefaultPassword)
+	apitest.Expect(t, rec, http.StatusOK)
+	body := apitest.JSON(t, rec)
+	if body["token"] == "" {
+		t.Fatal("login returned no token")
+	}
+	user := body["user"].(map[string]any)
+	if user["username"] != "bob" || user["email"] != "bob@example.com" {
+		t.Errorf("login user = %v", user)
+	}
+
+	// The token from logging in works like the one from registering
+	apitest.Expect(t, h.As(body["token"].(string)).Get("/api/v1/me"), http.StatusOK)
+}
+
+func TestRegisterValidation(t *testing.T) {
+	h := apitest.New(t)
+	h.Register("bob")
+
+	tests := []struct {
+		name   string
+		body   map[string]string
+		status int
+	}{
+		{"missing password", map[string]string{"username": "carol", "email": "carol@example.com"}, http.StatusBadRequest},
+		{"short password", map[string]string{"username": "carol", "email": "carol@example.com", "password": "abc"}, http.StatusBadRequest},
+		{"invalid email", map[string]string{"username": "carol", "email": "carol", "password": "secret123"}, http.StatusBadRequest},
+		{"short username", map[string]string{"username": "cj", "email": "carol@example.com", "password": "secret123"}, http.StatusBadRequest},
+		{"taken username", map[string]string{"username": "bob", "email": "other@example.com", "password": "secret123"}, http.StatusConflict},
+		{"taken email", map[string]string{"username": "carol", "email": "bob@example.com", "password": "secret123"}, http.StatusConflict},
+		{"taken email in capitals", map[string]string{"username": "carol", "email": "Bob@Example.COM", "password": "secret123"}, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apitest.Expect(t, h.Anonymous().Post("/api/v1/auth/register", tt.body), tt.status)
+		})
+	}
+}
+
+func TestRegisterStoresEmailInLowerCase(t *testing.T) {
+	h := apitest.New(t)
+
+	rec := h.Anonymous().Post("/api/v1/auth/register", map[string]string{"username": "carol", "email": "Carol@Example.COM", "password": "secret123"})
+	apitest.Expect(t, rec, http.StatusCreated)
+	var user models.User
+	h.DB.Where("username = ?", "carol").First(&user)
+	if user.Email != "carol@example.com" {
+		t.Errorf("stored email = %q", user.Email)
+	}
+}
+
+func TestLoginFailures(t *testing.T) {
+	h := apitest.New(t)
+	h.Register("bob")
+
+	apitest.Expect(t, h.Login("bob", "wrong-password"), http.StatusUnauthorized)
+	apitest.Expect(t, h.Login("nobody", apitest.DefaultPassword), http.StatusUnauthorized)
+	apitest.Expect(t, h.Anonymous().Post("/api/v1/auth/login", map[string]string{"username": "bob"}), http.StatusBadRequest)
+}
+
+func TestLoginLockout(t *testing.T) {
+	h := apitest.New(t)
+	h.Register("bob")
+
+	for i := 0; i < 5; i++ {
+		apitest.Expect(t, h.Login("bob", "wrong-password"), http.StatusUnauthorized)
+	}
+	var user models.User
+	h.DB.First(&user, "username = ?", "bob")
+	if user.LockedUntil == nil || !user.LockedUntil.After(time.Now()) {
+		t.Fatalf("account not locked after 5 failures, locked until %v", user.LockedUntil)
+	}
+
+	// The right password is refused too until the lock expires, with the
+	// answer an unknown user gets so the lock does not give the username away
+	locked := h.Login("bob", apitest.DefaultPassword)
+	unknown := h.Login("nobody", apitest.DefaultPassword)
+	apitest.Expect(t, locked, http.StatusUnauthorized)
+	lp, up := apitest.ProblemOf(t, locked), apitest.ProblemOf(t, unknown)
+	if lp.Code != up.Code || lp.Title != up.Title || lp.Detail != up.Detail {
+		t.Errorf("locked account answered %+v, unknown user %+v", lp, up)
+	}
+	if locked.Header().Get("Retry-After") != "" {
+		t.Error("locked login has a Retry-After header")
+	}
+}
+
+func TestLoginBodyTooLarge(t *testing.T) {
+	h := apitest.New(t)
+	h.Register("bob")
+
+	// Padding must not push the username past what the per-username limit reads
+	body := map[string]string{"padding": strings.Repeat("a", 1<<20), "username": "bob", "password": apitest.DefaultPassword}
+	apitest.Expect(t, h.Anonymous().Post("/api/v1/auth/login", body), http.StatusRequestEntityTooLarge)
+}
+
+func TestLoginRateLimit(t *testing.T) {
+	h := apitest.NewWith(t, apitest.Options{RateLimits: routes.RateLimits{
+		LoginUsername: ratelimit.Limit{Requests: 2, Period: time.Hour},
+	}})
+	h.Register("bob")
+
+	apitest.Expect(t, h.Login("bob", apitest.DefaultPassword), http.StatusOK)
+	apitest.Expect(t, h.Login("bob", apitest.DefaultPassword), http.StatusOK)
+	rec := h.Login("bob", apitest.DefaultPassword)
+	apitest.Expect(t, rec, http.StatusTooManyRequests)
+	if rec.Header().Get("Retry-After") == "" || rec.Header().Get("RateLimit-Policy") == "" {
+		t.Errorf("rate limited response headers = %v", rec.Header())
+	}
+	// Binding matches keys whatever their case, and so does the limit
+	body := map[string]string{"Username": "bob", "password": apitest.DefaultPassword}
+	apitest.Expect(t, h.Anonymous().Post("/api/v1/auth/login", body), http.StatusTooManyRequests)
+
+	// Other usernames have their own allowance
+	h.Register("alice")
+	apitest.Expect(t, h.Login("alice", apitest.DefaultPassword), http.StatusOK)
+}
+
+func TestAuthRequired(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{"missing header", ""},
+		{"not bearer", "Basic Ym9iOnNlY3JldA=="},
+		{"extra parts", "Bearer a b"},
+		{"garbage token", "Bearer not-a-valid-json-web-token"},
+		{"tampered token", "Bearer " + bob.Token[:len(bob.Token)-4] + "AAAA"},
+		{"unknown access token", "Bearer gmp_0000000000000000000000000000000000000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newRequest(http.MethodGet, "/api/v1/songs")
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			apitest.Expect(t, h.Anonymous().Do(req), http.StatusUnauthorized)
+		})
+	}
+}
+
+func TestDeletedUserTokenIsRefused(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+
+	apitest.Expect(t, bob.Delete("/api/v1/me", map[string]string{"password": bob.Password}), http.StatusOK)
+	// The JWT is still valid, but there is no user behind it any more
+	apitest.Expect(t, bob.Get("/api/v1/me"), http.StatusUnauthorized)
+	apitest.Expect(t, h.Login("bob", bob.Password), http.StatusUnauthorized)
+}
+
+func TestVerifyEmail(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	token := h.Mail.Token(t, bob.Email, "Verify your email address")
+
+	apitest.Expect(t, h.Anonymous().Post("/api/v1/auth/verify-email", map[string]string{"token": "bogus"}), http.StatusBadRequest)
+	apitest.Expect(t, h.Anonymous().Post("/api/v1/auth/verify-email", map[string]string{"token": token}), http.StatusOK)
+	if verified := apitest.JSON(t, bob.Get("/api/v1/me"))["email_verified"]; verified != true {
+		t.Errorf("email_verified = %v after verifying", verified)
+	}
+
+	// Tokens can only be used once
+	apitest.Expect(t, h.Anonymous().Post("/api/v1/auth/verify-email", map[string]string{"token": token}), http.StatusBadRequest)
+	// And verifying again is refused
+	apitest.Expect(t, bob.Post("/api/v1/me/email/verify", nil), http.StatusConflict)
+}
+
+func TestPasswordReset(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+
+	// Unknown addresses get the same answer so they cannot be discovered
+	apitest.Expect(t, h.Anonymous().Post("/api/v1/auth/password-reset", map[string]string{"email": "nobody@example.com"}), http.StatusAccepted)
+	apitest.Expect(t, h.Anonymous().Post("/api/v1/auth/password-reset", map[string]string{"email": "not an address"}), http.StatusBadRequest)
+
+	// Addresses are matched however they are capitalised
+	apitest.Expect(t, h.Anonymous().Post("/api/v1/auth/password-reset", map[string]string{"email": strings.ToUpper(bob.Email)}), http.StatusAccepted)
+	token := h.Mail.Token(t, bob.Email, "Reset your GoMusic password")
+
+	confirm := func(token, password string) int {
+		return h.Anonymous().Post("/api/v1/auth/password-reset/confirm", map[string]string{"token": token, "password": password}).Code
+	}
+	if status := confirm(token, "abc"); status != http.StatusBadRequest {
+		t.Errorf("short password: status = %d", status)
+	}
+	if status := confirm("bogus", "new-secret"); status != http.StatusBadRequest {
+		t.Errorf("bogus token: status = %d", status)
+	}
+	if status := confirm(token, "new-secret"); status != http.StatusOK {
+		t.Fatalf("reset: status = %d", status)
+	}
+	if status := confirm(token, "newer-secret"); status != http.StatusBadRequest {
+		t.Errorf("reused token: status = %d", status)
+	}
+
+	apitest.Expect(t, h.Login("bob", apitest.DefaultPassword), http.StatusUnauthorized)
+	apitest.Expect(t, h.Login("bob", "new-secret"), http.StatusOK)
+
+	// Receiving the link proves the address, so the account is verified too
+	if verified := apitest.JSON(t, bob.Get("/api/v1/me"))["email_verified"]; verified != true {
+		t.Errorf("email_verified = %v after a reset", verified)
+	}
+}
+
+func TestPasswordResetLimitPerAddress(t *testing.T) {
+	h := apitest.NewWith(t, apitest.Options{RateLimits: routes.RateLimits{
+		ResetAddress: ratelimit.Limit{Requests: 1, Period: time.Hour},
+	}})
+	bob := h.Register("bob")
+
+	// Capitalising the address differently does not get round the limit
+	for _, email := range []string{bob.Email, strings.ToUpper(bob.Email), strings.ToUpper(bob.Email[:1]) + bob.Email[1:]} {
+		apitest.Expect(t, h.Anonymous().Post("/api/v1/auth/password-reset", map[string]string{"email": email}), http.StatusAccepted)
+	}
+	h.Mail.WaitFor(t, bob.Email, "Reset your GoMusic password")
+	time.Sleep(50 * time.Millisecond)
+
+	resets := 0
+	for _, msg := range h.Mail.Messages() {
+		if msg.To == bob.Email && strings.HasPrefix(msg.Subject, "Reset") {
+			resets++
+		}
+	}
+	if resets != 1 {
+		t.Errorf("sent %d reset emails, want 1", resets)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	h := apitest.New(t)
+	rec := h.Anonymous().Get("/.well-known/jwks.json")
+	apitest.Expect(t, rec, http.StatusOK)
+
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+		} `json:"keys"`
+	}
+	apitest.Decode(t, rec, &set)
+	if len(set.Keys) == 0 || set.Keys[0].Kid == "" {
+		t.Fatalf("jwks = %s", rec.Body.String())
+	}
+
+	var count int64
+	h.DB.Model(&models.SigningKey{}).Count(&count)
+	if len(set.Keys) != int(count) {
+		t.Errorf("jwks has %d keys, database has %d", len(set.Keys), count)
+	}
+}
@@ -0,0 +1,99 @@
+package routes_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"music-player-gin/internal/api/apitest"
+	"music-player-gin/internal/api/middleware"
+	"music-player-gin/internal/api/problem"
+)
+
+func TestProblemResponses(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+
+	rec := bob.Get("/api/v1/songs/9999")
+	apitest.Expect(t, rec, http.StatusNotFound)
+	p := apitest.ProblemOf(t, rec)
+	if p.Code != "song_not_found" || p.Status != http.StatusNotFound || p.Title != "Not Found" || p.Type != "about:blank" {
+		t.Errorf("problem = %+v", p)
+	}
+	if p.Instance != "/api/v1/songs/9999" {
+		t.Errorf("instance = %q", p.Instance)
+	}
+	if p.RequestID == "" || p.RequestID != rec.Header().Get(middleware.RequestIDHeader) {
+		t.Errorf("request id %q, header %q", p.RequestID, rec.Header().Get(middleware.RequestIDHeader))
+	}
+
+	// Missing authentication is a problem too, raised by middleware
+	rec = h.Anonymous().Get("/api/v1/playlists")
+	apitest.Expect(t, rec, http.StatusUnauthorized)
+	if p := apitest.ProblemOf(t, rec); p.Code != problem.CodeUnauthenticated {
+		t.Errorf("code = %q", p.Code)
+	}
+
+	// Paths without a route get a problem rather than gin's plain text
+	rec = bob.Get("/no/such/path")
+	apitest.Expect(t, rec, http.StatusNotFound)
+	if p := apitest.ProblemOf(t, rec); p.Code != problem.CodeNotFound {
+		t.Errorf("code = %q", p.Code)
+	}
+}
+
+func TestValidationProblem(t *testing.T) {
+	h := apitest.New(t)
+
+	rec := h.Anonymous().Post("/api/v1/auth/register", map[string]string{"username": "bo", "email": "not an email"})
+	apitest.Expect(t, rec, http.StatusBadRequest)
+	p := apitest.ProblemOf(t, rec)
+	if p.Code != problem.CodeInvalidRequest {
+		t.Errorf("code = %q", p.Code)
+	}
+	want := map[string]string{
+		"username": "must have at least 3 characters",
+		"email":    "must be a valid email address",
+		"password": "is required",
+	}
+	if len(p.Errors) != len(want) {
+		t.Fatalf("errors = %+v", p.Errors)
+	}
+	for _, e := range p.Errors {
+		if want[e.Field] != e.Message {
+			t.Errorf("%s: %q, want %q", e.Field, e.Message, want[e.Field])
+		}
+	}
+	// Messages name the JSON field, not the Go struct
+	if strings.Contains(rec.Body.String(), "RegisterRequest") {
+		t.Errorf("problem leaks struct names: %s", rec.Body.String())
+	}
+
+	rec = h.Anonymous().Post("/api/v1/auth/register", map[string]any{"username": 42})
+	apitest.Expect(t, rec, http.StatusBadRequest)
+	if p := apitest.ProblemOf(t, rec); len(p.Errors) != 1 || p.Errors[0].Field != "username" || p.Errors[0].Message != "must be a string" {
+		t.Errorf("type error = %+v", p.Errors)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	h := apitest.New(t)
+
+	req := newRequest(http.MethodGet, "/api/v1/songs/9999")
+	req.Header.Set(middleware.RequestIDHeader, "trace-123")
+	rec := h.Anonymous().Do(req)
+	if got := rec.Header().Get(middleware.RequestIDHeader); got != "trace-123" {
+		t.Errorf("request id = %q, want the one sent", got)
+	}
+	if p := apitest.ProblemOf(t, rec); p.RequestID != "trace-123" {
+		t.Errorf("problem request id = %q", p.RequestID)
+	}
+
+	// IDs that cannot be echoed safely are replaced
+	req = newRequest(http.MethodGet, "/openapi.json")
+	req.Header.Set(middleware.RequestIDHeader, strings.Repeat("x", 200))
+	rec = h.Anonymous().Do(req)
+	if got := rec.Header().Get(middleware.RequestIDHeader); got == "" || len(got) > 128 {
+		t.Errorf("request id = %q", got)
+	}
+}
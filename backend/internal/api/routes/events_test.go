@@ -0,0 +1,146 @@
+package routes_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"music-player-gin/internal/api/apitest"
+	"music-player-gin/internal/events"
+)
+
+// wsURL is the WebSocket address of a path on the harness server
+func wsURL(h *apitest.Harness, path string, query url.Values) string {
+	return "ws" + strings.TrimPrefix(h.Server().URL, "http") + path + "?" + query.Encode()
+}
+
+// readEvent reads WebSocket messages until one of the given type arrives
+func readEvent(t *testing.T, conn *websocket.Conn, eventType string) events.Event {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var e events.Event
+		if err := conn.ReadJSON(&e); err != nil {
+			t.Fatalf("waiting for %s: %v", eventType, err)
+		}
+		if e.Type == eventType {
+			return e
+		}
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, h.Server().URL+"/api/v1/events?access_token="+bob.Token, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	createPlaylist(t, bob, "Live", false)
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if scanner.Text() == "event: "+events.PlaylistCreated {
+			return
+		}
+	}
+	t.Fatalf("stream ended without a %s event: %v", events.PlaylistCreated, scanner.Err())
+}
+
+func TestEventStreamAuth(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+
+	apitest.Expect(t, h.Anonymous().Get("/api/v1/events"), http.StatusUnauthorized)
+	apitest.Expect(t, h.Anonymous().Get("/api/v1/events?access_token=not-a-valid-json-web-token"), http.StatusUnauthorized)
+	apitest.Expect(t, bob.Get("/api/v1/events?last_event_id=abc"), http.StatusBadRequest)
+}
+
+func TestEventWebSocket(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	alice := h.Register("alice")
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL(h, "/api/v1/events/ws", url.Values{"access_token": {bob.Token}}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Users only receive their own events
+	createPlaylist(t, alice, "Not for bob", false)
+	createPlaylist(t, bob, "Bob's", false)
+	e := readEvent(t, conn, events.PlaylistCreated)
+	if data := e.Data.(map[string]any); data["name"] != "Bob's" {
+		t.Errorf("received %v", data)
+	}
+
+	// Pages on other sites may not open a socket with the user's credentials
+	header := http.Header{"Origin": {"https://evil.example.com"}}
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL(h, "/api/v1/events/ws", url.Values{"access_token": {bob.Token}}), header)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("cross-origin socket: err %v, response %v", err, resp)
+	}
+}
+
+func TestRoomWebSocket(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	alice := h.Register("alice")
+	code := createRoom(t, bob)
+	song := bob.UploadSong("Queued", "Bob")
+	apitest.Expect(t, bob.Post("/api/v1/rooms/"+code+"/queue", map[string]any{"song_ids": []uint{song.ID}}), http.StatusOK)
+
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL(h, "/api/v1/events/rooms/"+code, url.Values{"access_token": {alice.Token}}), nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("non-member socket: err %v, response %v", err, resp)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL(h, "/api/v1/events/rooms/"+code, url.Values{"access_token": {bob.Token}}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// The snapshot is the room as the API returns it, without storage details
+	snapshot, _ := json.Marshal(readEvent(t, conn, "room.snapshot").Data)
+	if !strings.Contains(string(snapshot), `"Queued"`) || strings.Contains(string(snapshot), "file_path") {
+		t.Errorf("snapshot = %s", snapshot)
+	}
+
+	// The server answers time messages for clock sync
+	if err := conn.WriteJSON(map[string]any{"type": "time", "client_time": 1234}); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg map[string]any
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg["type"] == "time" {
+			if msg["client_time"] != float64(1234) || msg["server_time"] == nil {
+				t.Errorf("time reply = %v", msg)
+			}
+			break
+		}
+	}
+
+	apitest.Expect(t, alice.Post("/api/v1/rooms/join", map[string]string{"code": code}), http.StatusOK)
+	readEvent(t, conn, events.RoomMembers)
+}
@@ -0,0 +1,23 @@
+package routes_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"music-player-gin/internal/api/apitest"
+)
+
+// newRequest builds a request without a body
+func newRequest(method, path string) *http.Request {
+	return httptest.NewRequest(method, path, nil)
+}
+
+// createAccessToken creates a personal access token for the user and returns it
+func createAccessToken(t *testing.T, c *apitest.Client, name string, scopes ...string) string {
+	t.Helper()
+	rec := c.Post("/api/v1/me/tokens", map[string]any{"name": name, "scopes": scopes})
+	apitest.Expect(t, rec, http.StatusCreated)
+	token, _ := apitest.JSON(t, rec)["token"].(string)
+	return token
+}
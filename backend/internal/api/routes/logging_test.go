@@ -0,0 +1,69 @@
+package routes_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"music-player-gin/internal/api/apitest"
+	"music-player-gin/internal/api/middleware"
+)
+
+func TestRequestLog(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+
+	rec := bob.Get("/api/v1/playlists")
+	apitest.Expect(t, rec, http.StatusOK)
+	requestID := rec.Header().Get(middleware.RequestIDHeader)
+
+	var entry map[string]any
+	for _, e := range h.Logs.Entries("request") {
+		if e["request_id"] == requestID {
+			entry = e
+		}
+	}
+	if entry == nil {
+		t.Fatalf("no request log for %s in:\n%s", requestID, h.Logs)
+	}
+	want := map[string]any{
+		"level":   "INFO",
+		"method":  "GET",
+		"route":   "/api/v1/playlists",
+		"path":    "/api/v1/playlists",
+		"status":  float64(http.StatusOK),
+		"user_id": float64(bob.ID),
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("%s = %v, want %v", key, entry[key], value)
+		}
+	}
+
+	// Queries logged while handling the request carry its ID too
+	var queries int
+	for _, e := range h.Logs.Entries("query") {
+		if e["request_id"] == requestID {
+			queries++
+		}
+	}
+	if queries == 0 {
+		t.Error("no queries logged for the request")
+	}
+}
+
+func TestLogsHoldNoSecrets(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	apitest.Expect(t, h.Login("bob", apitest.DefaultPassword), http.StatusOK)
+	apitest.Expect(t, h.Login("bob", "wrong password"), http.StatusUnauthorized)
+	apitest.Expect(t, bob.Get("/api/v1/playlists?access_token="+bob.Token), http.StatusOK)
+	apitest.Expect(t, h.As("not-a-token").Get("/api/v1/playlists"), http.StatusUnauthorized)
+
+	logs := h.Logs.String()
+	for _, secret := range []string{apitest.DefaultPassword, "wrong password", bob.Token, bob.Token[:10], "not-a-token"} {
+		if strings.Contains(logs, secret) {
+			t.Errorf("logs contain %q", secret)
+		}
+	}
+}
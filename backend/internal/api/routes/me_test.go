@@ -0,0 +1,226 @@
+package routes_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"music-player-gin/internal/api/apitest"
+	"music-player-gin/internal/api/routes"
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/ratelimit"
+)
+
+// pngImage encodes a blank image of the given size
+func pngImage(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProfile(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+
+	body := apitest.JSON(t, bob.Get("/api/v1/me"))
+	if body["username"] != "bob" || body["email"] != bob.Email || body["email_verified"] != false {
+		t.Errorf("profile = %v", body)
+	}
+
+	rec := bob.Patch("/api/v1/me", map[string]string{"display_name": "  Bob B.  ", "bio": "Listens to everything"})
+	apitest.Expect(t, rec, http.StatusOK)
+	body = apitest.JSON(t, rec)
+	if body["display_name"] != "Bob B." || body["bio"] != "Listens to everything" {
+		t.Errorf("updated profile = %v", body)
+	}
+
+	long := string(bytes.Repeat([]byte("x"), 101))
+	apitest.Expect(t, bob.Patch("/api/v1/me", map[string]string{"display_name": long}), http.StatusBadRequest)
+	apitest.Expect(t, h.Anonymous().Get("/api/v1/me"), http.StatusUnauthorized)
+}
+
+func TestAvatar(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	alice := h.Register("alice")
+	avatarPath := fmt.Sprintf("/api/v1/users/%d/avatar", bob.ID)
+
+	apitest.Expect(t, alice.Get(avatarPath), http.StatusNotFound)
+
+	upload := func(name string, data []byte) int {
+		return bob.Multipart(http.MethodPut, "/api/v1/me/avatar", nil, apitest.File{Field: "avatar", Name: name, Data: data}).Code
+	}
+	if status := upload("avatar.png", []byte("not an image")); status != http.StatusBadRequest {
+		t.Errorf("text avatar: status = %d", status)
+	}
+	if status := upload("avatar.png", pngImage(t, 5000, 1)); status != http.StatusBadRequest {
+		t.Errorf("oversized avatar: status = %d", status)
+	}
+	if status := upload("avatar.png", append([]byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}, make([]byte, 20)...)); status != http.StatusBadRequest {
+		t.Errorf("corrupt avatar: status = %d", status)
+	}
+	apitest.Expect(t, bob.Multipart(http.MethodPut, "/api/v1/me/avatar", nil), http.StatusBadRequest)
+
+	// The file name does not matter, only the content
+	avatar := pngImage(t, 16, 16)
+	if status := upload("avatar.gif", avatar); status != http.StatusOK {
+		t.Fatalf("avatar upload: status = %d", status)
+	}
+	if body := apitest.JSON(t, bob.Get("/api/v1/me")); body["avatar_url"] == nil {
+		t.Error("profile has no avatar_url after upload")
+	}
+
+	rec := alice.Get(avatarPath)
+	apitest.Expect(t, rec, http.StatusOK)
+	if !bytes.Equal(rec.Body.Bytes(), avatar) {
+		t.Error("served avatar differs from the upload")
+	}
+	// An <img> tag sends no token
+	apitest.Expect(t, h.Anonymous().Get(avatarPath), http.StatusOK)
+	// An ID that is not a number is not passed on as SQL
+	apitest.Expect(t, h.Anonymous().Get("/api/v1/users/0)%20OR%20(1=1/avatar"), http.StatusNotFound)
+
+	var user models.User
+	h.DB.First(&user, bob.ID)
+	apitest.Expect(t, bob.Delete("/api/v1/me/avatar", nil), http.StatusOK)
+	if _, err := os.Stat(user.AvatarPath); !os.IsNotExist(err) {
+		t.Errorf("avatar file still exists after deleting: %v", err)
+	}
+	apitest.Expect(t, alice.Get(avatarPath), http.StatusNotFound)
+}
+
+func TestChangePassword(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+
+	apitest.Expect(t, bob.Post("/api/v1/me/password", map[string]string{"current_password": "wrong", "new_password": "new-secret"}), http.StatusForbidden)
+	apitest.Expect(t, bob.Post("/api/v1/me/password", map[string]string{"current_password": bob.Password, "new_password": "abc"}), http.StatusBadRequest)
+	apitest.Expect(t, bob.Post("/api/v1/me/password", map[string]string{"current_password": bob.Password, "new_password": "new-secret"}), http.StatusOK)
+
+	apitest.Expect(t, h.Login("bob", bob.Password), http.StatusUnauthorized)
+	apitest.Expect(t, h.Login("bob", "new-secret"), http.StatusOK)
+}
+
+func TestChangeEmail(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	alice := h.Register("alice")
+
+	apitest.Expect(t, bob.Post("/api/v1/me/email", map[string]string{"email": "new@example.com", "password": "wrong"}), http.StatusForbidden)
+	apitest.Expect(t, bob.Post("/api/v1/me/email", map[string]string{"email": bob.Email, "password": bob.Password}), http.StatusBadRequest)
+	apitest.Expect(t, bob.Post("/api/v1/me/email", map[string]string{"email": alice.Email, "password": bob.Password}), http.StatusConflict)
+
+	// A change whose confirmation could not be sent is not left pending
+	h.Mail.Fail(errors.New("smtp down"))
+	apitest.Expect(t, bob.Post("/api/v1/me/email", map[string]string{"email": "lost@example.com", "password": bob.Password}), http.StatusBadGateway)
+	h.Mail.Fail(nil)
+	if body := apitest.JSON(t, bob.Get("/api/v1/me")); body["pending_email"] != nil && body["pending_email"] != "" {
+		t.Errorf("pending_email = %v after the mail failed", body["pending_email"])
+	}
+
+	rec := bob.Post("/api/v1/me/email", map[string]string{"email": "new@example.com", "password": bob.Password})
+	apitest.Expect(t, rec, http.StatusAccepted)
+	if body := apitest.JSON(t, rec); body["pending_email"] != "new@example.com" || body["email"] != bob.Email {
+		t.Errorf("profile while pending = %v", body)
+	}
+	// The old address is told about the change
+	h.Mail.WaitFor(t, bob.Email, "Your email address is being changed")
+
+	token := h.Mail.Token(t, "new@example.com", "Confirm your new email address")
+	apitest.Expect(t, h.Anonymous().Post("/api/v1/auth/confirm-email", map[string]string{"token": "bogus"}), http.StatusBadRequest)
+	rec = h.Anonymous().Post("/api/v1/auth/confirm-email", map[string]string{"token": token})
+	apitest.Expect(t, rec, http.StatusOK)
+	if body := apitest.JSON(t, rec); body["email"] != "new@example.com" || body["email_verified"] != true {
+		t.Errorf("profile after confirming = %v", body)
+	}
+	apitest.Expect(t, h.Anonymous().Post("/api/v1/auth/confirm-email", map[string]string{"token": token}), http.StatusBadRequest)
+
+	// The address can be taken while the confirmation waits to be followed
+	apitest.Expect(t, alice.Post("/api/v1/me/email", map[string]string{"email": "contested@example.com", "password": alice.Password}), http.StatusAccepted)
+	token = h.Mail.Token(t, "contested@example.com", "Confirm your new email address")
+	h.Register("contested")
+	h.DB.Model(&models.User{}).Where("username = ?", "contested").Update("email", "contested@example.com")
+	apitest.Expect(t, h.Anonymous().Post("/api/v1/auth/confirm-email", map[string]string{"token": token}), http.StatusConflict)
+}
+
+func TestResendVerificationLimit(t *testing.T) {
+	h := apitest.NewWith(t, apitest.Options{RateLimits: routes.RateLimits{
+		VerifyResend: ratelimit.Limit{Requests: 1, Period: time.Hour},
+	}})
+	bob := h.Register("bob")
+
+	apitest.Expect(t, bob.Post("/api/v1/me/email/verify", nil), http.StatusAccepted)
+	rec := bob.Post("/api/v1/me/email/verify", nil)
+	apitest.Expect(t, rec, http.StatusTooManyRequests)
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("limited response has no Retry-After header")
+	}
+}
+
+func TestDeleteAccount(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	alice := h.Register("alice")
+	song := bob.UploadSong("Left behind", "Bob")
+	playlist := createPlaylist(t, bob, "Gone", true)
+	apitest.Expect(t, bob.Post(fmt.Sprintf("/api/v1/users/%d/follow", alice.ID), nil), http.StatusOK)
+
+	apitest.Expect(t, bob.Delete("/api/v1/me", map[string]string{"password": "wrong"}), http.StatusForbidden)
+	apitest.Expect(t, bob.Delete("/api/v1/me", nil), http.StatusBadRequest)
+	apitest.Expect(t, bob.Delete("/api/v1/me", map[string]string{"password": bob.Password}), http.StatusOK)
+
+	// Uploaded songs stay in the library, personal data goes
+	rec := alice.Get(fmt.Sprintf("/api/v1/songs/%d", song.ID))
+	apitest.Expect(t, rec, http.StatusOK)
+	apitest.Expect(t, alice.Get(fmt.Sprintf("/api/v1/playlists/%d/songs", playlist.ID)), http.StatusNotFound)
+	apitest.Expect(t, alice.Get(fmt.Sprintf("/api/v1/users/%d", bob.ID)), http.StatusNotFound)
+	if body := apitest.JSON(t, alice.Get(fmt.Sprintf("/api/v1/users/%d", alice.ID))); body["followers"] != float64(0) {
+		t.Errorf("deleted user still counts as a follower: %v", body)
+	}
+}
+
+func TestAccessTokens(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	alice := h.Register("alice")
+
+	rec := bob.Post("/api/v1/me/tokens", map[string]any{"name": "script", "scopes": []string{"read", "read"}, "expires_in_days": 30})
+	apitest.Expect(t, rec, http.StatusCreated)
+	created := apitest.JSON(t, rec)
+	if created["token"] == "" || created["expires_at"] == nil || len(created["scopes"].([]any)) != 1 {
+		t.Fatalf("created token = %v", created)
+	}
+
+	apitest.Expect(t, bob.Post("/api/v1/me/tokens", map[string]any{"name": "bad", "scopes": []string{"admin"}}), http.StatusBadRequest)
+	apitest.Expect(t, bob.Post("/api/v1/me/tokens", map[string]any{"name": "   ", "scopes": []string{"read"}}), http.StatusBadRequest)
+	apitest.Expect(t, bob.Post("/api/v1/me/tokens", map[string]any{"name": "none"}), http.StatusBadRequest)
+
+	var listed []map[string]any
+	apitest.Decode(t, bob.Get("/api/v1/me/tokens"), &listed)
+	if len(listed) != 1 || listed[0]["token"] != nil {
+		t.Fatalf("listed tokens = %v", listed)
+	}
+
+	token := h.As(created["token"].(string))
+	apitest.Expect(t, token.Get("/api/v1/me"), http.StatusOK)
+	// Tokens cannot manage tokens
+	apitest.Expect(t, token.Post("/api/v1/me/tokens", map[string]any{"name": "child", "scopes": []string{"read"}}), http.StatusForbidden)
+
+	id := fmt.Sprintf("/api/v1/me/tokens/%v", created["id"])
+	// Another user's token looks like it does not exist
+	apitest.Expect(t, alice.Delete(id, nil), http.StatusNotFound)
+	apitest.Expect(t, bob.Delete("/api/v1/me/tokens/abc", nil), http.StatusBadRequest)
+	apitest.Expect(t, bob.Delete(id, nil), http.StatusOK)
+	apitest.Expect(t, bob.Delete(id, nil), http.StatusNotFound)
+
+	apitest.Expect(t, token.Get("/api/v1/me"), http.StatusUnauthorized)
+}
@@ -0,0 +1,82 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"music-player-gin/internal/api/apitest"
+)
+
+// scrape fetches /metrics and returns its samples by series
+func scrape(t *testing.T, c *apitest.Client) map[string]string {
+	t.Helper()
+	rec := c.Get("/metrics")
+	apitest.Expect(t, rec, http.StatusOK)
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("content type = %q", ct)
+	}
+	samples := map[string]string{}
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		samples[line[:i]] = line[i+1:]
+	}
+	return samples
+}
+
+func TestMetrics(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	song := bob.UploadSong("Song", "Artist")
+
+	rec := bob.Get(fmt.Sprintf("/api/v1/songs/%d/play", song.ID))
+	apitest.Expect(t, rec, http.StatusOK)
+	streamed := rec.Body.Len()
+	apitest.Expect(t, bob.Get("/no/such/path"), http.StatusNotFound)
+
+	samples := scrape(t, h.Anonymous())
+	want := map[string]string{
+		`http_requests_total{method="POST",route="/api/v1/songs",status="201"}`:                 "1",
+		`http_requests_total{method="GET",route="/api/v1/songs/:id/play",status="200"}`:         "1",
+		`http_requests_total{method="GET",route="unmatched",status="404"}`:                      "1",
+		`http_request_duration_seconds_count{method="POST",route="/api/v1/songs",status="201"}`: "1",
+		`songs_streamed_bytes_total{quality="original"}`:                                        fmt.Sprint(streamed),
+		`songs_active_streams`:         "0",
+		`song_uploads_total`:           "1",
+		`song_upload_size_bytes_count`: "1",
+	}
+	for series, value := range want {
+		if samples[series] != value {
+			t.Errorf("%s = %q, want %q", series, samples[series], value)
+		}
+	}
+	for _, series := range []string{
+		`db_query_duration_seconds_count{operation="create",table="songs"}`,
+		`db_query_duration_seconds_count{operation="query",table="songs"}`,
+		`go_goroutines`,
+		`go_memstats_alloc_bytes`,
+		`process_start_time_seconds`,
+	} {
+		if _, ok := samples[series]; !ok {
+			t.Errorf("%s missing", series)
+		}
+	}
+}
+
+func TestMetricsToken(t *testing.T) {
+	h := apitest.NewWith(t, apitest.Options{MetricsToken: "scrape-secret"})
+	bob := h.Register("bob")
+
+	for _, c := range []*apitest.Client{h.Anonymous(), h.As("wrong"), bob} {
+		rec := c.Get("/metrics")
+		apitest.Expect(t, rec, http.StatusUnauthorized)
+		if p := apitest.ProblemOf(t, rec); p.Code != "invalid_metrics_token" {
+			t.Errorf("code = %q", p.Code)
+		}
+	}
+	scrape(t, h.As("scrape-secret"))
+}
@@ -0,0 +1,135 @@
+package routes_test
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"music-player-gin/internal/api/apitest"
+	"music-player-gin/internal/oidc"
+	"music-player-gin/internal/oidc/oidctest"
+)
+
+const oidcRedirect = "http://api.test/api/v1/auth/oidc/callback"
+
+// newOIDCHarness builds a harness that signs in through a mock provider
+func newOIDCHarness(t *testing.T, provision bool, users ...oidctest.User) *apitest.Harness {
+	t.Helper()
+	provider, err := oidctest.NewProvider("http://placeholder", "gomusic", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.Users = users
+	server := httptest.NewServer(provider)
+	t.Cleanup(server.Close)
+	provider.Issuer = server.URL
+
+	client := oidc.NewClient(oidc.Config{
+		Issuer:       server.URL,
+		ClientID:     "gomusic",
+		ClientSecret: "secret",
+		RedirectURL:  oidcRedirect,
+		Scopes:       []string{"openid", "email", "profile"},
+	}, nil)
+	return apitest.NewWith(t, apitest.Options{OIDC: client, OIDCProvision: provision})
+}
+
+// oidcLogin runs the login flow for the hinted user and returns the
+// fragment parameters the frontend receives
+func oidcLogin(t *testing.T, h *apitest.Harness, hint string) url.Values {
+	t.Helper()
+	// A cookie jar applies the same path and domain rules as a browser
+	jar, _ := cookiejar.New(nil)
+	login, _ := url.Parse("http://api.test/api/v1/auth/oidc/login?login_hint=" + url.QueryEscape(hint))
+	rec := h.Anonymous().Get(login.RequestURI())
+	apitest.Expect(t, rec, http.StatusFound)
+	jar.SetCookies(login, rec.Result().Cookies())
+
+	// The provider signs in immediately and redirects back with a code
+	noFollow := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := noFollow.Get(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || !strings.HasPrefix(callback.String(), oidcRedirect) {
+		t.Fatalf("provider redirected to %q", resp.Header.Get("Location"))
+	}
+
+	req := newRequest(http.MethodGet, callback.RequestURI())
+	for _, cookie := range jar.Cookies(callback) {
+		req.AddCookie(cookie)
+	}
+	rec = h.Anonymous().Do(req)
+	apitest.Expect(t, rec, http.StatusFound)
+	target, _ := url.Parse(rec.Header().Get("Location"))
+	result, _ := url.ParseQuery(target.Fragment)
+	return result
+}
+
+func TestOIDCLogin(t *testing.T) {
+	h := newOIDCHarness(t, true)
+
+	result := oidcLogin(t, h, "dana@example.com")
+	if result.Get("token") == "" {
+		t.Fatalf("login failed: %v", result)
+	}
+	body := apitest.JSON(t, h.As(result.Get("token")).Get("/api/v1/me"))
+	if body["email"] != "dana@example.com" || body["email_verified"] != true {
+		t.Errorf("provisioned profile = %v", body)
+	}
+
+	// Signing in again finds the same account
+	again := oidcLogin(t, h, "dana@example.com")
+	if id := apitest.JSON(t, h.As(again.Get("token")).Get("/api/v1/me"))["id"]; id != body["id"] {
+		t.Errorf("second login signed in as %v, first as %v", id, body["id"])
+	}
+}
+
+func TestOIDCLinksVerifiedEmail(t *testing.T) {
+	h := newOIDCHarness(t, true,
+		oidctest.User{Subject: "verified", Email: "bob@example.com", EmailVerified: true, PreferredUsername: "bob"},
+		oidctest.User{Subject: "unverified", Email: "alice@example.com", EmailVerified: false, PreferredUsername: "alice"},
+	)
+	bob := h.Register("bob")
+	h.Register("alice")
+
+	result := oidcLogin(t, h, "bob@example.com")
+	if id := apitest.JSON(t, h.As(result.Get("token")).Get("/api/v1/me"))["id"]; id != float64(bob.ID) {
+		t.Errorf("signed in as user %v, want %d", id, bob.ID)
+	}
+
+	// An unverified address at the provider must not take over the account
+	if result := oidcLogin(t, h, "alice@example.com"); result.Get("error") != "account_exists" {
+		t.Errorf("unverified login result = %v", result)
+	}
+}
+
+func TestOIDCWithoutProvisioning(t *testing.T) {
+	h := newOIDCHarness(t, false)
+	if result := oidcLogin(t, h, "stranger@example.com"); result.Get("error") != "no_account" {
+		t.Errorf("login result = %v", result)
+	}
+}
+
+func TestOIDCCallbackRejectsForgedState(t *testing.T) {
+	h := newOIDCHarness(t, true)
+
+	// Without the cookie from starting the login the callback is refused
+	rec := h.Anonymous().Get("/api/v1/auth/oidc/callback?code=stolen&state=guessed")
+	apitest.Expect(t, rec, http.StatusFound)
+	target, _ := url.Parse(rec.Header().Get("Location"))
+	result, _ := url.ParseQuery(target.Fragment)
+	if result.Get("error") != "invalid_state" || result.Get("token") != "" {
+		t.Errorf("forged callback result = %v", result)
+	}
+}
+
+func TestOIDCDisabled(t *testing.T) {
+	h := apitest.New(t)
+	apitest.Expect(t, h.Anonymous().Get("/api/v1/auth/oidc/login"), http.StatusNotFound)
+}
@@ -0,0 +1,70 @@
+package routes_test
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"music-player-gin/internal/api/apitest"
+	"music-player-gin/internal/api/routes"
+	"music-player-gin/internal/oidc"
+	"music-player-gin/internal/openapi"
+)
+
+// ginParam matches a path parameter in a gin route
+var ginParam = regexp.MustCompile(`:(\w+)`)
+
+func TestOpenAPIDescribesEveryRoute(t *testing.T) {
+	// With OIDC configured every route is registered
+	client := oidc.NewClient(oidc.Config{Issuer: "http://idp.test", ClientID: "gomusic", RedirectURL: oidcRedirect}, nil)
+	h := apitest.NewWith(t, apitest.Options{OIDC: client})
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registered := map[string]bool{}
+	for _, route := range h.Router.Routes() {
+		path := ginParam.ReplaceAllString(route.Path, "{$1}")
+		if len(path) > 1 {
+			path = strings.TrimSuffix(path, "/")
+		}
+		key := route.Method + " " + path
+		registered[key] = true
+		method := strings.ToLower(route.Method)
+		if item := doc.Paths[path]; item != nil && item[method] != nil {
+			continue
+		}
+		// Unversioned aliases are described by the v1 route they stand for
+		if item := doc.Paths[routes.V1Prefix+path]; item != nil && item[method] != nil {
+			continue
+		}
+		t.Errorf("%s is not in the OpenAPI document", key)
+	}
+	for _, route := range doc.Operations() {
+		if key := route.Method + " " + route.Path; !registered[key] {
+			t.Errorf("%s is documented but has no route", key)
+		}
+	}
+}
+
+func TestOpenAPIServed(t *testing.T) {
+	h := apitest.New(t)
+
+	rec := h.Anonymous().Get("/openapi.json")
+	apitest.Expect(t, rec, http.StatusOK)
+	doc, err := openapi.Parse(rec.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Info.Title == "" || len(doc.Paths) == 0 {
+		t.Errorf("served document has no title or paths")
+	}
+
+	rec = h.Anonymous().Get("/docs")
+	apitest.Expect(t, rec, http.StatusOK)
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") || !strings.Contains(rec.Body.String(), "openapi.json") {
+		t.Errorf("docs page: %s %s", rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+}
@@ -0,0 +1,154 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"music-player-gin/internal/api/apitest"
+	"music-player-gin/internal/dto"
+	"music-player-gin/internal/models"
+)
+
+// createPlaylist creates a playlist for the user and returns it
+func createPlaylist(t *testing.T, c *apitest.Client, name string, public bool) dto.Playlist {
+	t.Helper()
+	rec := c.Post("/api/v1/playlists", map[string]any{"name": name, "is_public": public})
+	apitest.Expect(t, rec, http.StatusCreated)
+	var playlist dto.Playlist
+	apitest.Decode(t, rec, &playlist)
+	return playlist
+}
+
+func TestPlaylists(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	song := bob.UploadSong("Track", "Bob")
+
+	playlist := createPlaylist(t, bob, "Mix", false)
+	if playlist.ID == 0 || playlist.UserID != bob.ID || playlist.Name != "Mix" || playlist.IsPublic {
+		t.Fatalf("playlist = %+v", playlist)
+	}
+
+	add := map[string]uint{"playlist_id": playlist.ID, "song_id": song.ID}
+	rec := bob.Post("/api/v1/playlists/add-song", add)
+	apitest.Expect(t, rec, http.StatusOK)
+	var added struct {
+		Playlist dto.Playlist `json:"playlist"`
+	}
+	apitest.Decode(t, rec, &added)
+	if len(added.Playlist.Songs) != 1 || added.Playlist.Songs[0].ID != song.ID {
+		t.Errorf("playlist songs = %+v", added.Playlist.Songs)
+	}
+	// Adding a song twice keeps a single entry
+	apitest.Expect(t, bob.Post("/api/v1/playlists/add-song", add), http.StatusOK)
+
+	var songs []dto.Song
+	rec = bob.Get(fmt.Sprintf("/api/v1/playlists/%d/songs", playlist.ID))
+	apitest.Expect(t, rec, http.StatusOK)
+	apitest.Decode(t, rec, &songs)
+	if len(songs) != 1 {
+		t.Errorf("playlist has %d songs, want 1", len(songs))
+	}
+
+	var playlists []dto.Playlist
+	apitest.Decode(t, bob.Get("/api/v1/playlists"), &playlists)
+	if len(playlists) != 1 || playlists[0].ID != playlist.ID {
+		t.Errorf("playlists = %+v", playlists)
+	}
+
+	rec = bob.Patch(fmt.Sprintf("/api/v1/playlists/%d", playlist.ID), map[string]any{"name": "Renamed", "is_public": true})
+	apitest.Expect(t, rec, http.StatusOK)
+	var updated dto.Playlist
+	apitest.Decode(t, rec, &updated)
+	if updated.Name != "Renamed" || !updated.IsPublic {
+		t.Errorf("updated playlist = %+v", updated)
+	}
+}
+
+func TestPlaylistValidation(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	song := bob.UploadSong("Track", "Bob")
+	playlist := createPlaylist(t, bob, "Mix", false)
+
+	apitest.Expect(t, bob.Post("/api/v1/playlists", "not an object"), http.StatusBadRequest)
+	apitest.Expect(t, bob.Post("/api/v1/playlists/add-song", map[string]uint{"song_id": song.ID}), http.StatusBadRequest)
+	apitest.Expect(t, bob.Post("/api/v1/playlists/add-song", map[string]uint{"playlist_id": 9999, "song_id": song.ID}), http.StatusNotFound)
+	apitest.Expect(t, bob.Post("/api/v1/playlists/add-song", map[string]uint{"playlist_id": playlist.ID, "song_id": 9999}), http.StatusNotFound)
+	apitest.Expect(t, bob.Get("/api/v1/playlists/9999/songs"), http.StatusNotFound)
+	apitest.Expect(t, bob.Get("/api/v1/playlists/abc/songs"), http.StatusNotFound)
+
+	path := fmt.Sprintf("/api/v1/playlists/%d", playlist.ID)
+	apitest.Expect(t, bob.Patch(path, map[string]string{"name": ""}), http.StatusBadRequest)
+	apitest.Expect(t, bob.Patch("/api/v1/playlists/9999", map[string]string{"name": "Ghost"}), http.StatusNotFound)
+}
+
+func TestPlaylistCrossUserAccess(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	alice := h.Register("alice")
+	song := alice.UploadSong("Alice's track", "Alice")
+
+	private := createPlaylist(t, bob, "Private", false)
+	public := createPlaylist(t, bob, "Public", true)
+	apitest.Expect(t, bob.Post("/api/v1/playlists/add-song", map[string]uint{"playlist_id": private.ID, "song_id": song.ID}), http.StatusOK)
+
+	// Alice's own list does not include Bob's playlists
+	var playlists []dto.Playlist
+	apitest.Decode(t, alice.Get("/api/v1/playlists"), &playlists)
+	if len(playlists) != 0 {
+		t.Errorf("alice sees %d playlists, want 0", len(playlists))
+	}
+
+	// Others' private playlists look like they do not exist
+	apitest.Expect(t, alice.Get(fmt.Sprintf("/api/v1/playlists/%d/songs", private.ID)), http.StatusNotFound)
+	apitest.Expect(t, alice.Get(fmt.Sprintf("/api/v1/playlists/%d/songs", public.ID)), http.StatusOK)
+
+	// Only the owner changes a playlist, public or not
+	for _, playlist := range []dto.Playlist{private, public} {
+		rec := alice.Post("/api/v1/playlists/add-song", map[string]uint{"playlist_id": playlist.ID, "song_id": song.ID})
+		apitest.Expect(t, rec, http.StatusNotFound)
+		rec = alice.Patch(fmt.Sprintf("/api/v1/playlists/%d", playlist.ID), map[string]string{"name": "Taken"})
+		apitest.Expect(t, rec, http.StatusNotFound)
+	}
+
+	var songs []dto.Song
+	apitest.Decode(t, bob.Get(fmt.Sprintf("/api/v1/playlists/%d/songs", public.ID)), &songs)
+	if len(songs) != 0 {
+		t.Errorf("alice added %d songs to bob's public playlist", len(songs))
+	}
+	var got models.Playlist
+	h.DB.First(&got, private.ID)
+	if got.Name != "Private" {
+		t.Errorf("alice renamed bob's playlist to %q", got.Name)
+	}
+
+	// The user's playlist listing shows others only the public ones
+	apitest.Decode(t, alice.Get(fmt.Sprintf("/api/v1/users/%d/playlists", bob.ID)), &playlists)
+	if len(playlists) != 1 || playlists[0].ID != public.ID {
+		t.Errorf("alice sees bob's playlists %+v", playlists)
+	}
+	apitest.Decode(t, bob.Get(fmt.Sprintf("/api/v1/users/%d/playlists", bob.ID)), &playlists)
+	if len(playlists) != 2 {
+		t.Errorf("bob sees %d of their playlists, want 2", len(playlists))
+	}
+}
+
+func TestPlaylistAccessTokenScopes(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	playlist := createPlaylist(t, bob, "Mix", false)
+	path := fmt.Sprintf("/api/v1/playlists/%d", playlist.ID)
+
+	reader := h.As(createAccessToken(t, bob, "reader", "read"))
+	apitest.Expect(t, reader.Get("/api/v1/playlists"), http.StatusOK)
+	apitest.Expect(t, reader.Post("/api/v1/playlists", map[string]string{"name": "Nope"}), http.StatusForbidden)
+	apitest.Expect(t, reader.Patch(path, map[string]string{"name": "Nope"}), http.StatusForbidden)
+
+	writer := h.As(createAccessToken(t, bob, "writer", "playlists:write"))
+	apitest.Expect(t, writer.Post("/api/v1/playlists", map[string]string{"name": "Yes"}), http.StatusCreated)
+	apitest.Expect(t, writer.Patch(path, map[string]string{"name": "Yes"}), http.StatusOK)
+	// Reading needs the read scope even with write access
+	apitest.Expect(t, writer.Get("/api/v1/playlists"), http.StatusForbidden)
+}
@@ -0,0 +1,124 @@
+package routes_test
+
+import (
+	"net/http"
+	"testing"
+
+	"music-player-gin/internal/api/apitest"
+	"music-player-gin/internal/dto"
+)
+
+func TestQueue(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	one := bob.UploadSong("One", "Bob")
+	two := bob.UploadSong("Two", "Bob")
+	three := bob.UploadSong("Three", "Bob")
+
+	rec := bob.Get("/api/v1/me/queue")
+	apitest.Expect(t, rec, http.StatusOK)
+	var queue dto.PlayQueue
+	apitest.Decode(t, rec, &queue)
+	if len(queue.Items) != 0 || queue.Version != 1 || rec.Header().Get("ETag") != `"1"` {
+		t.Fatalf("new queue = %+v, etag %s", queue, rec.Header().Get("ETag"))
+	}
+
+	// Changes need the current version
+	apitest.Expect(t, bob.Put("/api/v1/me/queue", map[string]any{"song_ids": []uint{one.ID}}), http.StatusPreconditionRequired)
+
+	rec = bob.Put("/api/v1/me/queue", map[string]any{"version": 1, "song_ids": []uint{one.ID, two.ID}, "current_index": 1})
+	apitest.Expect(t, rec, http.StatusOK)
+	apitest.Decode(t, rec, &queue)
+	if len(queue.Items) != 2 || queue.CurrentIndex != 1 || queue.Version != 2 {
+		t.Fatalf("replaced queue = %+v", queue)
+	}
+
+	// Inserting before the current song keeps it playing
+	rec = bob.Post("/api/v1/me/queue/items", map[string]any{"version": 2, "song_ids": []uint{three.ID}, "index": 0})
+	apitest.Expect(t, rec, http.StatusOK)
+	apitest.Decode(t, rec, &queue)
+	if queue.Items[0].SongID != three.ID || queue.CurrentIndex != 2 {
+		t.Errorf("after insert: current %d, first song %d", queue.CurrentIndex, queue.Items[0].SongID)
+	}
+
+	rec = bob.Post("/api/v1/me/queue/move", map[string]any{"version": 3, "from": 2, "to": 0})
+	apitest.Expect(t, rec, http.StatusOK)
+	apitest.Decode(t, rec, &queue)
+	if queue.Items[0].SongID != two.ID || queue.CurrentIndex != 0 {
+		t.Errorf("after move: current %d, first song %d", queue.CurrentIndex, queue.Items[0].SongID)
+	}
+
+	// The version can also come from If-Match
+	req := newRequest(http.MethodDelete, "/api/v1/me/queue/items/2")
+	req.Header.Set("If-Match", `"4"`)
+	rec = bob.Do(req)
+	apitest.Expect(t, rec, http.StatusOK)
+	apitest.Decode(t, rec, &queue)
+	if len(queue.Items) != 2 {
+		t.Errorf("after remove: %d items", len(queue.Items))
+	}
+
+	rec = bob.Patch("/api/v1/me/queue/playback", map[string]any{"version": 5, "position_seconds": 42.5, "shuffle": true, "repeat_mode": "all"})
+	apitest.Expect(t, rec, http.StatusOK)
+	apitest.Decode(t, rec, &queue)
+	if queue.PositionSeconds != 42.5 || !queue.Shuffle || queue.RepeatMode != "all" {
+		t.Errorf("after playback update: %+v", queue)
+	}
+}
+
+func TestQueueConflictsAndValidation(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	song := bob.UploadSong("One", "Bob")
+
+	// A stale version is refused along with the current queue
+	apitest.Expect(t, bob.Put("/api/v1/me/queue", map[string]any{"version": 1, "song_ids": []uint{song.ID}}), http.StatusOK)
+	rec := bob.Put("/api/v1/me/queue", map[string]any{"version": 1, "song_ids": []uint{}})
+	apitest.Expect(t, rec, http.StatusConflict)
+	conflict := apitest.ProblemOf(t, rec)
+	if rec.Header().Get("ETag") != `"2"` || conflict.Code != "version_conflict" || conflict.Extensions["queue"] == nil {
+		t.Errorf("conflict response: etag %s, body %s", rec.Header().Get("ETag"), rec.Body.String())
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+	}{
+		{"unknown song", http.MethodPut, "/api/v1/me/queue", map[string]any{"version": 2, "song_ids": []uint{9999}}},
+		{"current index out of range", http.MethodPut, "/api/v1/me/queue", map[string]any{"version": 2, "song_ids": []uint{song.ID}, "current_index": 3}},
+		{"bad repeat mode", http.MethodPut, "/api/v1/me/queue", map[string]any{"version": 2, "repeat_mode": "sometimes"}},
+		{"insert out of range", http.MethodPost, "/api/v1/me/queue/items", map[string]any{"version": 2, "song_ids": []uint{song.ID}, "index": 5}},
+		{"no songs to insert", http.MethodPost, "/api/v1/me/queue/items", map[string]any{"version": 2, "song_ids": []uint{}}},
+		{"move out of range", http.MethodPost, "/api/v1/me/queue/move", map[string]any{"version": 2, "from": 0, "to": 4}},
+		{"remove out of range", http.MethodDelete, "/api/v1/me/queue/items/7?version=2", nil},
+		{"invalid position", http.MethodDelete, "/api/v1/me/queue/items/abc?version=2", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apitest.Expect(t, bob.Request(tt.method, tt.path, tt.body), http.StatusBadRequest)
+		})
+	}
+}
+
+func TestQueueIsPerUser(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	alice := h.Register("alice")
+	song := bob.UploadSong("One", "Bob")
+
+	apitest.Expect(t, bob.Put("/api/v1/me/queue", map[string]any{"version": 1, "song_ids": []uint{song.ID}}), http.StatusOK)
+
+	var queue dto.PlayQueue
+	apitest.Decode(t, alice.Get("/api/v1/me/queue"), &queue)
+	if len(queue.Items) != 0 {
+		t.Errorf("alice sees %d items of bob's queue", len(queue.Items))
+	}
+	apitest.Expect(t, h.Anonymous().Get("/api/v1/me/queue"), http.StatusUnauthorized)
+
+	// Queues are for logged-in sessions only
+	reader := h.As(createAccessToken(t, bob, "reader", "read"))
+	apitest.Expect(t, reader.Get("/api/v1/me/queue"), http.StatusOK)
+	apitest.Expect(t, reader.Put("/api/v1/me/queue", map[string]any{"version": 2}), http.StatusForbidden)
+}
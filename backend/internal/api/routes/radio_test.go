@@ -0,0 +1,178 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+
+	"music-player-gin/internal/api/apitest"
+	"music-player-gin/internal/dto"
+	"music-player-gin/internal/models"
+)
+
+// radioResponse is a radio session with a batch of songs
+type radioResponse struct {
+	Session dto.RadioSession `json:"session"`
+	Songs   []dto.Song       `json:"songs"`
+}
+
+func TestRadio(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	seed := bob.UploadSong("Seed", "Band")
+	for i := 0; i < 4; i++ {
+		bob.UploadSong(fmt.Sprintf("Song %d", i), "Band")
+	}
+
+	rec := bob.Post("/api/v1/radio", map[string]any{"seed_type": "song", "seed_value": strconv.FormatUint(uint64(seed.ID), 10), "count": 3})
+	apitest.Expect(t, rec, http.StatusCreated)
+	var started radioResponse
+	apitest.Decode(t, rec, &started)
+	if started.Session.ID == "" || len(started.Songs) == 0 || len(started.Songs) > 3 {
+		t.Fatalf("started radio = %+v", started)
+	}
+
+	path := "/api/v1/radio/" + started.Session.ID
+	rec = bob.Get(path + "/next?count=2")
+	apitest.Expect(t, rec, http.StatusOK)
+	var next radioResponse
+	apitest.Decode(t, rec, &next)
+	if next.Session.Served <= started.Session.Served {
+		t.Errorf("served %d songs after next, %d before", next.Session.Served, started.Session.Served)
+	}
+	apitest.Expect(t, bob.Get(path+"/next?count=100"), http.StatusBadRequest)
+
+	apitest.Expect(t, bob.Post("/api/v1/radio", map[string]any{"seed_type": "artist", "seed_value": "Band"}), http.StatusCreated)
+	apitest.Expect(t, bob.Post("/api/v1/radio", map[string]any{"seed_type": "genre", "seed_value": "Test"}), http.StatusCreated)
+
+	apitest.Expect(t, bob.Delete(path, nil), http.StatusOK)
+	apitest.Expect(t, bob.Get(path+"/next"), http.StatusNotFound)
+	apitest.Expect(t, bob.Delete(path, nil), http.StatusNotFound)
+}
+
+func TestRadioConcurrentNext(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	seed := bob.UploadSong("Seed", "Band")
+	// More songs than the calls below ask for, so none has to repeat
+	for i := 0; i < 20; i++ {
+		bob.UploadSong(fmt.Sprintf("Song %d", i), "Band")
+	}
+	rec := bob.Post("/api/v1/radio", map[string]any{"seed_type": "song", "seed_value": strconv.FormatUint(uint64(seed.ID), 10), "count": 1})
+	apitest.Expect(t, rec, http.StatusCreated)
+	var started radioResponse
+	apitest.Decode(t, rec, &started)
+
+	// Devices asking at once must not be handed the same positions or songs
+	const callers = 8
+	var wg sync.WaitGroup
+	codes := make([]int, callers)
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = bob.Get("/api/v1/radio/" + started.Session.ID + "/next?count=2").Code
+		}()
+	}
+	wg.Wait()
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("call %d: status = %d", i, code)
+		}
+	}
+
+	var rows []models.RadioSessionSong
+	h.DB.Where("session_id = ?", started.Session.ID).Order("position").Find(&rows)
+	seen := make(map[uint]bool, len(rows))
+	for i, row := range rows {
+		if row.Position != i {
+			t.Fatalf("positions = %+v", rows)
+		}
+		if seen[row.SongID] {
+			t.Errorf("song %d was served twice", row.SongID)
+		}
+		seen[row.SongID] = true
+	}
+	var session models.RadioSession
+	h.DB.First(&session, "id = ?", started.Session.ID)
+	if session.Served != len(rows) || len(rows) != 1+callers*2 {
+		t.Errorf("served %d with %d songs stored", session.Served, len(rows))
+	}
+}
+
+func TestRadioValidation(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+
+	// An empty library has nothing to seed from
+	apitest.Expect(t, bob.Post("/api/v1/radio", map[string]any{"seed_type": "artist", "seed_value": "Nobody"}), http.StatusNotFound)
+
+	song := bob.UploadSong("Seed", "Band")
+	id := strconv.FormatUint(uint64(song.ID), 10)
+	tests := []struct {
+		name   string
+		body   map[string]any
+		status int
+	}{
+		{"unknown seed type", map[string]any{"seed_type": "mood", "seed_value": "happy"}, http.StatusBadRequest},
+		{"missing seed value", map[string]any{"seed_type": "song"}, http.StatusBadRequest},
+		{"non-numeric song", map[string]any{"seed_type": "song", "seed_value": "abc"}, http.StatusBadRequest},
+		{"familiarity too high", map[string]any{"seed_type": "song", "seed_value": id, "familiarity": 2}, http.StatusBadRequest},
+		{"count too high", map[string]any{"seed_type": "song", "seed_value": id, "count": 500}, http.StatusBadRequest},
+		{"missing song", map[string]any{"seed_type": "song", "seed_value": "9999"}, http.StatusNotFound},
+		{"missing playlist", map[string]any{"seed_type": "playlist", "seed_value": "9999"}, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apitest.Expect(t, bob.Post("/api/v1/radio", tt.body), tt.status)
+		})
+	}
+}
+
+func TestRadioCrossUserAccess(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	alice := h.Register("alice")
+	song := bob.UploadSong("Secret", "Bob")
+
+	private := createPlaylist(t, bob, "Private", false)
+	public := createPlaylist(t, bob, "Public", true)
+	for _, p := range []dto.Playlist{private, public} {
+		apitest.Expect(t, bob.Post("/api/v1/playlists/add-song", map[string]uint{"playlist_id": p.ID, "song_id": song.ID}), http.StatusOK)
+	}
+
+	seed := func(c *apitest.Client, p dto.Playlist) *radioResponse {
+		rec := c.Post("/api/v1/radio", map[string]any{"seed_type": "playlist", "seed_value": strconv.FormatUint(uint64(p.ID), 10)})
+		if rec.Code != http.StatusCreated {
+			return nil
+		}
+		var r radioResponse
+		apitest.Decode(t, rec, &r)
+		return &r
+	}
+
+	// Another user's private playlist cannot seed a radio, which would reveal its songs
+	if seed(alice, private) != nil {
+		t.Error("alice started a radio from bob's private playlist")
+	}
+	if seed(alice, public) == nil {
+		t.Error("alice could not start a radio from bob's public playlist")
+	}
+	own := seed(bob, private)
+	if own == nil {
+		t.Fatal("bob could not start a radio from their own playlist")
+	}
+
+	// Visibility is checked when the seed is used, not when the playlist was shared
+	apitest.Expect(t, bob.Patch(fmt.Sprintf("/api/v1/playlists/%d", public.ID), map[string]bool{"is_public": false}), http.StatusOK)
+	if seed(alice, public) != nil {
+		t.Error("alice started a radio from a playlist bob made private")
+	}
+
+	// Sessions belong to the user who started them
+	apitest.Expect(t, alice.Get("/api/v1/radio/"+own.Session.ID+"/next"), http.StatusNotFound)
+	apitest.Expect(t, alice.Delete("/api/v1/radio/"+own.Session.ID, nil), http.StatusNotFound)
+	apitest.Expect(t, bob.Get("/api/v1/radio/"+own.Session.ID+"/next"), http.StatusOK)
+}
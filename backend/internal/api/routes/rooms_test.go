@@ -0,0 +1,243 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"music-player-gin/internal/api/apitest"
+	"music-player-gin/internal/dto"
+)
+
+// roomResponse is a room with the server's view of its playback position
+type roomResponse struct {
+	Room            dto.Room `json:"room"`
+	CurrentPosition float64  `json:"current_position"`
+}
+
+// createRoom opens a room hosted by the user and returns its code
+func createRoom(t *testing.T, c *apitest.Client) string {
+	t.Helper()
+	rec := c.Post("/api/v1/rooms", map[string]string{"name": "Party"})
+	apitest.Expect(t, rec, http.StatusCreated)
+	var r roomResponse
+	apitest.Decode(t, rec, &r)
+	if r.Room.Code == "" {
+		t.Fatalf("room has no code: %s", rec.Body.String())
+	}
+	return r.Room.Code
+}
+
+func TestRooms(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	alice := h.Register("alice")
+	one := bob.UploadSong("One", "Bob")
+	two := bob.UploadSong("Two", "Bob")
+
+	code := createRoom(t, bob)
+	path := "/api/v1/rooms/" + code
+
+	apitest.Expect(t, alice.Post("/api/v1/rooms/join", map[string]string{"code": code}), http.StatusOK)
+	var r roomResponse
+	apitest.Decode(t, alice.Get(path), &r)
+	if r.Room.HostID != bob.ID || len(r.Room.Members) != 2 {
+		t.Fatalf("room = %+v", r.Room)
+	}
+
+	rec := bob.Post(path+"/queue", map[string]any{"song_ids": []uint{one.ID, two.ID}})
+	apitest.Expect(t, rec, http.StatusOK)
+	apitest.Decode(t, rec, &r)
+	if len(r.Room.Items) != 2 {
+		t.Fatalf("room queue has %d items", len(r.Room.Items))
+	}
+
+	rec = bob.Post(path+"/playback", map[string]any{"action": "play"})
+	apitest.Expect(t, rec, http.StatusOK)
+	apitest.Decode(t, rec, &r)
+	if !r.Room.Playing {
+		t.Error("room is not playing after play")
+	}
+	rec = bob.Post(path+"/playback", map[string]any{"action": "seek", "position_seconds": 30})
+	apitest.Expect(t, rec, http.StatusOK)
+	apitest.Decode(t, rec, &r)
+	if r.CurrentPosition < 30 {
+		t.Errorf("position after seek = %v", r.CurrentPosition)
+	}
+
+	rec = bob.Delete(path+"/queue/1", nil)
+	apitest.Expect(t, rec, http.StatusOK)
+	apitest.Decode(t, rec, &r)
+	if len(r.Room.Items) != 1 {
+		t.Errorf("room queue has %d items after removing one", len(r.Room.Items))
+	}
+
+	rec = bob.Post(path+"/host", map[string]uint{"user_id": alice.ID})
+	apitest.Expect(t, rec, http.StatusOK)
+	apitest.Decode(t, rec, &r)
+	if r.Room.HostID != alice.ID {
+		t.Errorf("host = %d after transfer, want %d", r.Room.HostID, alice.ID)
+	}
+	// Control passes with the host role
+	apitest.Expect(t, bob.Post(path+"/playback", map[string]any{"action": "pause"}), http.StatusForbidden)
+	apitest.Expect(t, alice.Post(path+"/playback", map[string]any{"action": "pause"}), http.StatusOK)
+
+	apitest.Expect(t, bob.Post(path+"/leave", nil), http.StatusOK)
+	apitest.Expect(t, bob.Get(path), http.StatusForbidden)
+}
+
+func TestRoomValidation(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	song := bob.UploadSong("One", "Bob")
+	code := createRoom(t, bob)
+	path := "/api/v1/rooms/" + code
+
+	apitest.Expect(t, bob.Post("/api/v1/rooms/join", map[string]string{"code": "NOPE0000"}), http.StatusNotFound)
+	apitest.Expect(t, bob.Post("/api/v1/rooms/join", map[string]string{}), http.StatusBadRequest)
+	apitest.Expect(t, bob.Get("/api/v1/rooms/NOPE0000"), http.StatusNotFound)
+	apitest.Expect(t, bob.Post(path+"/playback", map[string]any{"action": "dance"}), http.StatusBadRequest)
+	apitest.Expect(t, bob.Post(path+"/queue", map[string]any{"song_ids": []uint{9999}}), http.StatusNotFound)
+	apitest.Expect(t, bob.Post(path+"/queue", map[string]any{"song_ids": []uint{}}), http.StatusBadRequest)
+	apitest.Expect(t, bob.Delete(path+"/queue/abc", nil), http.StatusBadRequest)
+	apitest.Expect(t, bob.Delete(path+"/queue/5", nil), http.StatusBadRequest)
+	apitest.Expect(t, bob.Post(path+"/host", map[string]uint{"user_id": 9999}), http.StatusBadRequest)
+
+	apitest.Expect(t, bob.Post(path+"/queue", map[string]any{"song_ids": []uint{song.ID}}), http.StatusOK)
+	apitest.Expect(t, bob.Post(path+"/playback", map[string]any{"action": "track", "index": 3}), http.StatusBadRequest)
+}
+
+func TestRoomCrossUserAccess(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	alice := h.Register("alice")
+	song := bob.UploadSong("One", "Bob")
+	code := createRoom(t, bob)
+	path := "/api/v1/rooms/" + code
+
+	// Only members see or change a room
+	apitest.Expect(t, alice.Get(path), http.StatusForbidden)
+	apitest.Expect(t, alice.Post(path+"/queue", map[string]any{"song_ids": []uint{song.ID}}), http.StatusForbidden)
+	apitest.Expect(t, alice.Post(path+"/playback", map[string]any{"action": "play"}), http.StatusForbidden)
+	apitest.Expect(t, alice.Post(path+"/host", map[string]uint{"user_id": alice.ID}), http.StatusForbidden)
+	apitest.Expect(t, alice.Post(path+"/leave", nil), http.StatusForbidden)
+
+	// Members who are not the host cannot take over
+	apitest.Expect(t, alice.Post("/api/v1/rooms/join", map[string]string{"code": code}), http.StatusOK)
+	apitest.Expect(t, alice.Post(path+"/playback", map[string]any{"action": "play"}), http.StatusForbidden)
+	apitest.Expect(t, alice.Post(path+"/host", map[string]uint{"user_id": alice.ID}), http.StatusForbidden)
+}
+
+func TestRoomTransferHost(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	alice := h.Register("alice")
+	carol := h.Register("carol")
+	outsider := h.Register("dave")
+	song := bob.UploadSong("One", "Bob")
+	code := createRoom(t, bob)
+	path := "/api/v1/rooms/" + code
+	for _, c := range []*apitest.Client{alice, carol} {
+		apitest.Expect(t, c.Post("/api/v1/rooms/join", map[string]string{"code": code}), http.StatusOK)
+	}
+	apitest.Expect(t, bob.Post(path+"/queue", map[string]any{"song_ids": []uint{song.ID}}), http.StatusOK)
+
+	rec := bob.Post(path+"/host", map[string]uint{"user_id": alice.ID})
+	apitest.Expect(t, rec, http.StatusOK)
+	var r roomResponse
+	apitest.Decode(t, rec, &r)
+	if r.Room.HostID != alice.ID {
+		t.Fatalf("host = %d, want %d", r.Room.HostID, alice.ID)
+	}
+	// Control goes with the room
+	apitest.Expect(t, alice.Post(path+"/playback", map[string]any{"action": "play"}), http.StatusOK)
+	apitest.Expect(t, bob.Post(path+"/playback", map[string]any{"action": "pause"}), http.StatusForbidden)
+
+	// Only the host hands the room on
+	rec = bob.Post(path+"/host", map[string]uint{"user_id": carol.ID})
+	apitest.Expect(t, rec, http.StatusForbidden)
+	if got := apitest.ProblemOf(t, rec).Code; got != "not_host" {
+		t.Errorf("transfer by a former host: code %q", got)
+	}
+	rec = carol.Post(path+"/host", map[string]uint{"user_id": carol.ID})
+	apitest.Expect(t, rec, http.StatusForbidden)
+	if got := apitest.ProblemOf(t, rec).Code; got != "not_host" {
+		t.Errorf("transfer by a member: code %q", got)
+	}
+
+	// and only to a member
+	apitest.Expect(t, alice.Post(path+"/host", map[string]uint{"user_id": outsider.ID}), http.StatusBadRequest)
+	apitest.Decode(t, alice.Get(path), &r)
+	if r.Room.HostID != alice.ID {
+		t.Errorf("host = %d after refused transfers, want %d", r.Room.HostID, alice.ID)
+	}
+}
+
+func TestRoomCodeCase(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	alice := h.Register("alice")
+	song := bob.UploadSong("One", "Bob")
+	code := createRoom(t, bob)
+
+	// Codes are read aloud and typed by hand, so every route takes them in lower case
+	path := "/api/v1/rooms/" + strings.ToLower(code)
+	apitest.Expect(t, alice.Post("/api/v1/rooms/join", map[string]string{"code": " " + strings.ToLower(code) + " "}), http.StatusOK)
+	apitest.Expect(t, alice.Get(path), http.StatusOK)
+	apitest.Expect(t, bob.Post(path+"/queue", map[string]any{"song_ids": []uint{song.ID}}), http.StatusOK)
+	apitest.Expect(t, bob.Post(path+"/playback", map[string]any{"action": "play"}), http.StatusOK)
+	apitest.Expect(t, bob.Delete(path+"/queue/0", nil), http.StatusOK)
+	apitest.Expect(t, bob.Post(path+"/host", map[string]uint{"user_id": alice.ID}), http.StatusOK)
+	apitest.Expect(t, bob.Post(path+"/leave", nil), http.StatusOK)
+	apitest.Expect(t, bob.Get("/api/v1/rooms/"+code), http.StatusForbidden)
+}
+
+func TestRoomConcurrentQueueAdds(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	song := bob.UploadSong("One", "Bob")
+	code := createRoom(t, bob)
+
+	// Adds racing each other must each get their own queue positions
+	const callers = 8
+	var wg sync.WaitGroup
+	codes := make([]int, callers)
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = bob.Post("/api/v1/rooms/"+code+"/queue", map[string]any{"song_ids": []uint{song.ID, song.ID}}).Code
+		}()
+	}
+	wg.Wait()
+	for i, status := range codes {
+		if status != http.StatusOK {
+			t.Errorf("call %d: status = %d", i, status)
+		}
+	}
+
+	var r roomResponse
+	apitest.Decode(t, bob.Get("/api/v1/rooms/"+code), &r)
+	if len(r.Room.Items) != callers*2 {
+		t.Fatalf("queue has %d items, want %d", len(r.Room.Items), callers*2)
+	}
+	for i, item := range r.Room.Items {
+		if item.Position != i {
+			t.Fatalf("queue positions = %+v", r.Room.Items)
+		}
+	}
+	if r.Room.Version != 1+callers {
+		t.Errorf("version = %d after %d changes", r.Room.Version, callers)
+	}
+}
+
+func TestRoomClosesWhenEveryoneLeaves(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	code := createRoom(t, bob)
+
+	apitest.Expect(t, bob.Post(fmt.Sprintf("/api/v1/rooms/%s/leave", code), nil), http.StatusOK)
+	apitest.Expect(t, bob.Post("/api/v1/rooms/join", map[string]string{"code": code}), http.StatusNotFound)
+}
@@ -1,50 +1,319 @@
 package routes
 
 import (
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 
 	"music-player-gin/internal/api/handlers"
 	"music-player-gin/internal/api/middleware"
+	"music-player-gin/internal/events"
+	"music-player-gin/internal/jwtauth"
+	"music-player-gin/internal/mail"
+	"music-player-gin/internal/metrics"
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/oidc"
+	"music-player-gin/internal/party"
+	"music-player-gin/internal/processing"
+	"music-player-gin/internal/radio"
+	"music-player-gin/internal/ratelimit"
+	"music-player-gin/internal/recommend"
+	"music-player-gin/internal/repository"
+	"music-player-gin/internal/service"
 )
 
-func SetupRoutes(router *gin.Engine, db *gorm.DB) {
+// RateLimits are the limits applied to authentication and email endpoints
+type RateLimits struct {
+	Store         ratelimit.Store
+	LoginIP       ratelimit.Limit
+	LoginUsername ratelimit.Limit
+	RegisterIP    ratelimit.Limit
+	ResetIP       ratelimit.Limit
+	ResetAddress  ratelimit.Limit
+	VerifyResend  ratelimit.Limit
+}
+
+// Dependencies are the shared services handlers are built from
+type Dependencies struct {
+	DB             *gorm.DB
+	Processor      *processing.Processor
+	Recommender    *recommend.Engine
+	Hub            *events.Hub
+	Keys           *jwtauth.KeyRing
+	Mailer         mail.Mailer
+	AllowedOrigins []string
+	RateLimits     RateLimits
+
+	// OIDC is the identity provider users can sign in with, or nil if there is none
+	OIDC          *oidc.Client
+	OIDCProvision bool
+
+	// Logger is the base for per-request loggers, or nil for the default logger
+	Logger *slog.Logger
+
+	// Metrics are recorded by the routes and served at /metrics, behind
+	// MetricsToken if it is set. Nil metrics are replaced by new ones.
+	Metrics      *metrics.Metrics
+	MetricsToken string
+
+	// TracerProvider records a trace of each request, or nil to trace nothing
+	TracerProvider trace.TracerProvider
+}
+
+// The unversioned paths served the API before /api/v1. They answer like v1,
+// marked as deprecated, until they are removed at legacySunset.
+var (
+	legacyDeprecated = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	legacySunset     = time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// V1Prefix is where version 1 of the API is mounted
+const V1Prefix = "/api/v1"
+
+func SetupRoutes(router *gin.Engine, deps Dependencies) {
+	if deps.Metrics == nil {
+		deps.Metrics = metrics.New()
+	}
+
 	// Middleware
-	router.Use(middleware.LoggerMiddleware())
+	router.Use(
+		middleware.RequestID(),
+		middleware.Tracing(deps.TracerProvider),
+		middleware.SpanDetails(),
+		middleware.LoggerMiddleware(deps.Logger),
+		middleware.Metrics(deps.Metrics),
+		middleware.Recover(),
+		middleware.Errors(),
+	)
+	router.NoRoute(middleware.NoRoute)
+
+	h := newHandlerSet(deps)
 
-	// Initialize handlers
-	songHandler := handlers.NewSongHandler(db)
-	playlistHandler := handlers.NewPlaylistHandler(db)
-	authHandler := handlers.NewAuthHandler(db)
+	// Public keys for verifying the tokens the server issues
+	router.GET("/.well-known/jwks.json", handlers.NewJWKSHandler(deps.Keys).GetJWKS)
+
+	// API description and documentation
+	docsHandler := handlers.NewDocsHandler()
+	router.GET("/openapi.json", docsHandler.GetSpec)
+	router.GET("/docs", docsHandler.GetDocs)
+
+	// Metrics for Prometheus to scrape
+	router.GET("/metrics", handlers.NewMetricsHandler(deps.Metrics, deps.MetricsToken).GetMetrics)
+
+	registerV1(router.Group(V1Prefix), h)
+	registerV1(router.Group("/", middleware.Deprecated(legacyDeprecated, legacySunset, V1Prefix)), h)
+}
+
+// handlerSet holds the handlers and middleware every API version is built
+// from. Versions share them so limits, hubs and caches are not duplicated; a
+// new version registers its own routes and swaps in only what changed.
+type handlerSet struct {
+	auth         gin.HandlerFunc
+	songs        *handlers.SongHandler
+	jobs         *handlers.JobHandler
+	recommend    *handlers.RecommendationHandler
+	radio        *handlers.RadioHandler
+	queue        *handlers.QueueHandler
+	playlists    *handlers.PlaylistHandler
+	authFlows    *handlers.AuthHandler
+	events       *handlers.EventHandler
+	rooms        *handlers.RoomHandler
+	profile      *handlers.ProfileHandler
+	users        *handlers.UserHandler
+	feed         *handlers.FeedHandler
+	accessTokens *handlers.AccessTokenHandler
+	oidc         *handlers.OIDCHandler // nil when OIDC login is off
+
+	registerLimit  gin.HandlerFunc
+	loginIPLimit   gin.HandlerFunc
+	loginUserLimit gin.HandlerFunc
+	resetLimit     gin.HandlerFunc
+}
+
+func newHandlerSet(deps Dependencies) *handlerSet {
+	db := deps.DB
+	limits := deps.RateLimits
+
+	// Services for songs, playlists and users
+	songRepo := repository.NewSongRepository(db, deps.Processor)
+	userRepo := repository.NewUserRepository(db)
+	songs := service.NewSongService(songRepo, userRepo)
+	playlists := service.NewPlaylistService(repository.NewPlaylistRepository(db), songRepo, deps.Hub)
+	users := service.NewUserService(userRepo)
+	parties := party.NewService(db, deps.Hub)
+
+	h := &handlerSet{
+		auth:         middleware.AuthMiddleware(db, deps.Keys),
+		songs:        handlers.NewSongHandler(songs, deps.Metrics),
+		jobs:         handlers.NewJobHandler(db),
+		recommend:    handlers.NewRecommendationHandler(deps.Recommender),
+		radio:        handlers.NewRadioHandler(radio.NewService(db, deps.Recommender)),
+		queue:        handlers.NewQueueHandler(db, deps.Hub),
+		playlists:    handlers.NewPlaylistHandler(playlists),
+		authFlows:    handlers.NewAuthHandler(db, deps.Keys, deps.Mailer, ratelimit.New(limits.Store, "reset_address", limits.ResetAddress)),
+		events:       handlers.NewEventHandler(deps.Hub, deps.AllowedOrigins),
+		rooms:        handlers.NewRoomHandler(parties, deps.Hub, deps.AllowedOrigins),
+		profile:      handlers.NewProfileHandler(db, deps.Mailer, parties, ratelimit.New(limits.Store, "verify_resend", limits.VerifyResend)),
+		users:        handlers.NewUserHandler(users, playlists),
+		feed:         handlers.NewFeedHandler(db),
+		accessTokens: handlers.NewAccessTokenHandler(db),
+
+		// Logins are limited per IP and per username so neither guessing one
+		// password across many accounts nor many passwords for one account is cheap
+		registerLimit:  middleware.RateLimit(ratelimit.New(limits.Store, "register_ip", limits.RegisterIP), middleware.ByIP),
+		loginIPLimit:   middleware.RateLimit(ratelimit.New(limits.Store, "login_ip", limits.LoginIP), middleware.ByIP),
+		loginUserLimit: middleware.RateLimit(ratelimit.New(limits.Store, "login_username", limits.LoginUsername), middleware.ByJSONField("username")),
+
+		// Limit reset requests per IP on top of the per-address limit in the handler
+		resetLimit: middleware.RateLimit(ratelimit.New(limits.Store, "reset_ip", limits.ResetIP), middleware.ByIP),
+	}
+	if deps.OIDC != nil {
+		h.oidc = handlers.NewOIDCHandler(db, deps.Keys, deps.OIDC, deps.OIDCProvision)
+	}
+	return h
+}
 
+// v1TokenWrites are the writes personal access tokens may make in v1 and the
+// scope each needs. Every other write is reserved for logged-in sessions.
+var v1TokenWrites = map[string]string{
+	"POST /songs":                   models.ScopeUpload,
+	"POST /playlists":               models.ScopePlaylistsWrite,
+	"POST /playlists/add-song":      models.ScopePlaylistsWrite,
+	"PATCH /playlists/:playlist_id": models.ScopePlaylistsWrite,
+}
+
+// registerV1 adds the version 1 routes to r
+func registerV1(r *gin.RouterGroup, h *handlerSet) {
 	// Auth routes
-	authRoutes := router.Group("/auth")
+	authRoutes := r.Group("/auth")
+	{
+		authRoutes.POST("/register", h.registerLimit, h.authFlows.Register)
+		authRoutes.POST("/login", h.loginIPLimit, h.loginUserLimit, h.authFlows.Login)
+		authRoutes.POST("/confirm-email", h.profile.ConfirmEmail)
+		authRoutes.POST("/verify-email", h.authFlows.VerifyEmail)
+		authRoutes.POST("/password-reset", h.resetLimit, h.authFlows.RequestPasswordReset)
+		authRoutes.POST("/password-reset/confirm", h.resetLimit, h.authFlows.ConfirmPasswordReset)
+
+		if h.oidc != nil {
+			authRoutes.GET("/oidc/login", h.oidc.Login)
+			authRoutes.GET("/oidc/callback", h.oidc.Callback)
+		}
+	}
+
+	// Event streams accept the token as a query parameter since browsers cannot set headers on them
+	eventRoutes := r.Group("/events")
+	eventRoutes.Use(middleware.TokenFromQuery(), h.auth, middleware.RequireScopes(nil))
 	{
-		authRoutes.POST("/register", authHandler.Register)
-		authRoutes.POST("/login", authHandler.Login)
+		eventRoutes.GET("", h.events.Stream)
+		eventRoutes.GET("/ws", h.events.WebSocket)
+		eventRoutes.GET("/rooms/:code", h.rooms.Connect)
 	}
 
-	
+	// Avatars are used as image sources, which cannot carry a token
+	r.GET("/users/:id/avatar", h.profile.GetAvatar)
+
 	// Protected routes
-	protected := router.Group("/")
-	protected.Use(middleware.AuthMiddleware())
+	protected := r.Group("/")
+	protected.Use(h.auth, middleware.RequireScopes(prefixRoutes(r.BasePath(), v1TokenWrites)))
 	{
 		// Playlist routes
 		playlistRoutes := protected.Group("/playlists")
 		{
-			playlistRoutes.GET("", playlistHandler.GetAllPlaylists)
-			playlistRoutes.POST("", playlistHandler.CreatePlaylist)
-			playlistRoutes.POST("/add-song", playlistHandler.AddSongToPlaylist)
-			playlistRoutes.GET("/:playlist_id/songs", playlistHandler.GetSongsFromPlaylist)
+			playlistRoutes.GET("", h.playlists.GetAllPlaylists)
+			playlistRoutes.POST("", h.playlists.CreatePlaylist)
+			playlistRoutes.POST("/add-song", h.playlists.AddSongToPlaylist)
+			playlistRoutes.PATCH("/:playlist_id", h.playlists.UpdatePlaylist)
+			playlistRoutes.GET("/:playlist_id/songs", h.playlists.GetSongsFromPlaylist)
 		}
 
 		// Song routes
 		songRoutes := protected.Group("/songs")
 		{
-			songRoutes.GET("", songHandler.GetAllSongs)
-			songRoutes.GET("/:id", songHandler.GetSongByID)
-			songRoutes.POST("", songHandler.UploadSong)
-			songRoutes.GET("/:id/play", songHandler.PlaySong)
+			songRoutes.GET("", h.songs.GetAllSongs)
+			songRoutes.GET("/:id", h.songs.GetSongByID)
+			songRoutes.POST("", h.songs.UploadSong)
+			songRoutes.GET("/:id/play", h.songs.PlaySong)
+			songRoutes.GET("/:id/waveform", h.songs.GetWaveform)
+			songRoutes.GET("/:id/jobs", h.jobs.GetSongJobs)
+			songRoutes.POST("/:id/favorite", h.songs.AddToFavourites)
+			songRoutes.GET("/:id/similar", h.recommend.GetSimilarSongs)
+		}
+
+		protected.GET("/recommendations", h.recommend.GetRecommendations)
+
+		// Radio routes
+		radioRoutes := protected.Group("/radio")
+		{
+			radioRoutes.POST("", h.radio.StartRadio)
+			radioRoutes.GET("/:session_id/next", h.radio.NextRadioSongs)
+			radioRoutes.DELETE("/:session_id", h.radio.StopRadio)
+		}
+
+		// Listening party routes
+		roomRoutes := protected.Group("/rooms")
+		{
+			roomRoutes.POST("", h.rooms.CreateRoom)
+			roomRoutes.POST("/join", h.rooms.JoinRoom)
+			roomRoutes.GET("/:code", h.rooms.GetRoom)
+			roomRoutes.POST("/:code/leave", h.rooms.LeaveRoom)
+			roomRoutes.POST("/:code/playback", h.rooms.ControlPlayback)
+			roomRoutes.POST("/:code/queue", h.rooms.AddRoomSongs)
+			roomRoutes.DELETE("/:code/queue/:position", h.rooms.RemoveRoomSong)
+			roomRoutes.POST("/:code/host", h.rooms.TransferHost)
+		}
+
+		// Current user routes
+		meRoutes := protected.Group("/me")
+		{
+			meRoutes.GET("", h.profile.GetProfile)
+			meRoutes.PATCH("", h.profile.UpdateProfile)
+			meRoutes.DELETE("", h.profile.DeleteAccount)
+			meRoutes.PUT("/avatar", h.profile.UploadAvatar)
+			meRoutes.DELETE("/avatar", h.profile.DeleteAvatar)
+			meRoutes.POST("/password", h.profile.ChangePassword)
+			meRoutes.POST("/email", h.profile.ChangeEmail)
+			meRoutes.POST("/email/verify", h.profile.ResendVerification)
+			meRoutes.GET("/tokens", h.accessTokens.ListAccessTokens)
+			meRoutes.POST("/tokens", h.accessTokens.CreateAccessToken)
+			meRoutes.DELETE("/tokens/:id", h.accessTokens.RevokeAccessToken)
+			meRoutes.GET("/queue", h.queue.GetQueue)
+			meRoutes.PUT("/queue", h.queue.ReplaceQueue)
+			meRoutes.POST("/queue/items", h.queue.AppendToQueue)
+			meRoutes.POST("/queue/move", h.queue.MoveQueueItem)
+			meRoutes.DELETE("/queue/items/:position", h.queue.RemoveQueueItem)
+			meRoutes.PATCH("/queue/playback", h.queue.UpdatePlayback)
+		}
+
+		// User routes
+		userRoutes := protected.Group("/users")
+		{
+			userRoutes.GET("/:id", h.users.GetUser)
+			userRoutes.GET("/:id/playlists", h.users.GetUserPlaylists)
+			userRoutes.GET("/:id/followers", h.users.GetFollowers)
+			userRoutes.GET("/:id/following", h.users.GetFollowing)
+			userRoutes.POST("/:id/follow", h.users.Follow)
+			userRoutes.DELETE("/:id/follow", h.users.Unfollow)
 		}
+
+		protected.GET("/feed", h.feed.GetFeed)
+
+		// Background job routes
+		protected.GET("/jobs/:id", h.jobs.GetJob)
+	}
+}
+
+// prefixRoutes rewrites "METHOD /path" keys to the paths the routes have
+// under prefix, which is what RequireScopes sees
+func prefixRoutes(prefix string, routes map[string]string) map[string]string {
+	result := make(map[string]string, len(routes))
+	for route, value := range routes {
+		method, routePath, _ := strings.Cut(route, " ")
+		result[method+" "+path.Join(prefix, routePath)] = value
 	}
-}
\ No newline at end of file
+	return result
+}
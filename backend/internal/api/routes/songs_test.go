@@ -0,0 +1,450 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"music-player-gin/internal/api/apitest"
+	"music-player-gin/internal/dto"
+	"music-player-gin/internal/models"
+)
+
+func TestUploadSong(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+
+	data := apitest.FixtureMP3(2)
+	rec := bob.Upload(map[string]string{"title": "Intro", "artist": "The Fixtures", "duration": "2"}, "intro.mp3", data)
+	apitest.Expect(t, rec, http.StatusCreated)
+
+	var body struct {
+		Song dto.Song  `json:"song"`
+		Jobs []dto.Job `json:"jobs"`
+	}
+	apitest.Decode(t, rec, &body)
+	song := body.Song
+	if song.ID == 0 || song.Title != "Intro" || song.Artist != "The Fixtures" || song.Duration != 2 {
+		t.Errorf("song = %+v", song)
+	}
+	if song.UserID != bob.ID {
+		t.Errorf("uploader = %d, want %d", song.UserID, bob.ID)
+	}
+	if song.FileSize != int64(len(data)) {
+		t.Errorf("file size = %d, want %d", song.FileSize, len(data))
+	}
+	if song.ProcessingStatus != models.ProcessingInProgress || len(body.Jobs) == 0 {
+		t.Errorf("status %q with %d jobs, want processing jobs", song.ProcessingStatus, len(body.Jobs))
+	}
+
+	// The file is stored under the upload directory, which is not revealed
+	if strings.Contains(rec.Body.String(), "file_path") {
+		t.Errorf("response reveals the file path: %s", rec.Body.String())
+	}
+	var stored models.Song
+	h.DB.First(&stored, song.ID)
+	if !strings.HasPrefix(stored.FilePath, filepath.Join(h.UploadDir, "songs")) {
+		t.Errorf("file path %q is outside %s", stored.FilePath, h.UploadDir)
+	}
+	content, err := os.ReadFile(stored.FilePath)
+	if err != nil || len(content) != len(data) {
+		t.Errorf("stored file has %d bytes, err %v", len(content), err)
+	}
+}
+
+func TestUploadSameNameTwice(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+
+	var first, second models.Song
+	h.DB.First(&first, bob.UploadSong("One", "A").ID)
+	h.DB.First(&second, bob.UploadSong("Two", "A").ID)
+	if first.FilePath == second.FilePath {
+		t.Fatalf("both uploads were stored at %s", first.FilePath)
+	}
+	if _, err := os.Stat(first.FilePath); err != nil {
+		t.Errorf("first upload: %v", err)
+	}
+}
+
+func TestUploadMalformed(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	mp3 := apitest.FixtureMP3(1)
+
+	t.Run("no file", func(t *testing.T) {
+		rec := bob.Multipart(http.MethodPost, "/api/v1/songs", map[string]string{"title": "Nothing"})
+		apitest.Expect(t, rec, http.StatusBadRequest)
+	})
+	t.Run("wrong field", func(t *testing.T) {
+		rec := bob.Multipart(http.MethodPost, "/api/v1/songs", nil, apitest.File{Field: "audio", Name: "song.mp3", Data: mp3})
+		apitest.Expect(t, rec, http.StatusBadRequest)
+	})
+	t.Run("not an mp3 name", func(t *testing.T) {
+		rec := bob.Upload(map[string]string{"title": "Notes"}, "notes.txt", []byte("hello"))
+		apitest.Expect(t, rec, http.StatusBadRequest)
+		if msg := apitest.ErrorOf(t, rec); msg != "Only MP3 files are allowed" {
+			t.Errorf("error = %q", msg)
+		}
+	})
+	t.Run("invalid duration", func(t *testing.T) {
+		rec := bob.Upload(map[string]string{"title": "Long", "duration": "forever"}, "long.mp3", mp3)
+		apitest.Expect(t, rec, http.StatusBadRequest)
+	})
+	t.Run("json body", func(t *testing.T) {
+		apitest.Expect(t, bob.Post("/api/v1/songs", map[string]string{"title": "JSON"}), http.StatusBadRequest)
+	})
+	t.Run("truncated multipart", func(t *testing.T) {
+		req := newRequest(http.MethodPost, "/api/v1/songs")
+		req.Body = http.NoBody
+		req.Header.Set("Content-Type", "multipart/form-data; boundary=missing")
+		apitest.Expect(t, bob.Do(req), http.StatusBadRequest)
+	})
+	t.Run("path in file name", func(t *testing.T) {
+		rec := bob.Upload(map[string]string{"title": "Escape"}, "../../escape.mp3", mp3)
+		apitest.Expect(t, rec, http.StatusCreated)
+		var body struct {
+			Song dto.Song `json:"song"`
+		}
+		apitest.Decode(t, rec, &body)
+		var stored models.Song
+		h.DB.First(&stored, body.Song.ID)
+		if filepath.Dir(stored.FilePath) != filepath.Join(h.UploadDir, "songs") {
+			t.Errorf("file stored at %s", stored.FilePath)
+		}
+	})
+
+	// None of the refused uploads left a song behind
+	var count int64
+	h.DB.Model(&models.Song{}).Where("title <> ?", "Escape").Count(&count)
+	if count != 0 {
+		t.Errorf("%d songs were created by refused uploads", count)
+	}
+}
+
+func TestUploadUndecodableAudioFailsProcessing(t *testing.T) {
+	h := apitest.New(t)
+	h.StartJobs()
+	bob := h.Register("bob")
+
+	rec := bob.Upload(map[string]string{"title": "Noise"}, "noise.mp3", []byte("this is not audio at all"))
+	apitest.Expect(t, rec, http.StatusCreated)
+	var body struct {
+		Song dto.Song `json:"song"`
+	}
+	apitest.Decode(t, rec, &body)
+
+	status := waitForProcessing(t, bob, body.Song.ID)
+	if status != models.ProcessingFailed {
+		t.Errorf("processing status = %q, want failed", status)
+	}
+	rec = bob.Get(fmt.Sprintf("/api/v1/songs/%d/waveform", body.Song.ID))
+	apitest.Expect(t, rec, http.StatusNotFound)
+}
+
+func TestUploadRequiresScope(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+
+	readOnly := h.As(createAccessToken(t, bob, "reader", "read"))
+	rec := readOnly.Upload(map[string]string{"title": "Nope"}, "nope.mp3", apitest.FixtureMP3(1))
+	apitest.Expect(t, rec, http.StatusForbidden)
+
+	uploader := h.As(createAccessToken(t, bob, "uploader", "upload"))
+	rec = uploader.Upload(map[string]string{"title": "Yes"}, "yes.mp3", apitest.FixtureMP3(1))
+	apitest.Expect(t, rec, http.StatusCreated)
+}
+
+func TestGetSongs(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	alice := h.Register("alice")
+
+	var songs []dto.Song
+	apitest.Decode(t, bob.Get("/api/v1/songs"), &songs)
+	if len(songs) != 0 {
+		t.Fatalf("empty library lists %d songs", len(songs))
+	}
+
+	first := bob.UploadSong("First", "Bob")
+	alice.UploadSong("Second", "Alice")
+
+	// The library is shared, so everyone sees every song
+	apitest.Decode(t, alice.Get("/api/v1/songs"), &songs)
+	if len(songs) != 2 {
+		t.Fatalf("library lists %d songs, want 2", len(songs))
+	}
+
+	rec := alice.Get(fmt.Sprintf("/api/v1/songs/%d", first.ID))
+	apitest.Expect(t, rec, http.StatusOK)
+	var song dto.Song
+	apitest.Decode(t, rec, &song)
+	if song.Title != "First" {
+		t.Errorf("title = %q", song.Title)
+	}
+
+	apitest.Expect(t, bob.Get("/api/v1/songs/9999"), http.StatusNotFound)
+	apitest.Expect(t, bob.Get("/api/v1/songs/abc"), http.StatusNotFound)
+	apitest.Expect(t, h.Anonymous().Get(fmt.Sprintf("/api/v1/songs/%d", first.ID)), http.StatusUnauthorized)
+}
+
+func TestPlaySong(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	song := bob.UploadSong("Stream", "Bob")
+	size := len(apitest.FixtureMP3(3))
+	path := fmt.Sprintf("/api/v1/songs/%d/play", song.ID)
+
+	rec := bob.Get(path)
+	apitest.Expect(t, rec, http.StatusOK)
+	if rec.Body.Len() != size {
+		t.Errorf("body has %d bytes, want %d", rec.Body.Len(), size)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "audio/mpeg" {
+		t.Errorf("content type = %q", ct)
+	}
+	if rec.Header().Get("Accept-Ranges") != "bytes" {
+		t.Errorf("accept ranges = %q", rec.Header().Get("Accept-Ranges"))
+	}
+
+	// Without a transcoded stream low quality falls back to the original
+	apitest.Expect(t, bob.Get(path+"?quality=low"), http.StatusOK)
+
+	apitest.Expect(t, bob.Get("/api/v1/songs/9999/play"), http.StatusNotFound)
+	apitest.Expect(t, h.Anonymous().Get(path), http.StatusUnauthorized)
+
+	// A song whose file has gone is reported as such
+	var stored models.Song
+	h.DB.First(&stored, song.ID)
+	os.Remove(stored.FilePath)
+	rec = bob.Get(path)
+	apitest.Expect(t, rec, http.StatusNotFound)
+	if p := apitest.ProblemOf(t, rec); p.Code != "file_not_found" || p.Detail != "File not found" {
+		t.Errorf("problem = %+v", p)
+	}
+}
+
+func TestPlaySongRange(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	song := bob.UploadSong("Seek", "Bob")
+	data := apitest.FixtureMP3(3)
+	path := fmt.Sprintf("/api/v1/songs/%d/play", song.ID)
+
+	get := func(rangeHeader string) *httptest.ResponseRecorder {
+		req := newRequest(http.MethodGet, path)
+		req.Header.Set("Range", rangeHeader)
+		return bob.Do(req)
+	}
+
+	rec := get("bytes=0-99")
+	apitest.Expect(t, rec, http.StatusPartialContent)
+	if rec.Body.Len() != 100 || rec.Body.String() != string(data[:100]) {
+		t.Errorf("first range has %d bytes", rec.Body.Len())
+	}
+	if want := fmt.Sprintf("bytes 0-99/%d", len(data)); rec.Header().Get("Content-Range") != want {
+		t.Errorf("content range = %q, want %q", rec.Header().Get("Content-Range"), want)
+	}
+
+	rec = get("bytes=1000-")
+	apitest.Expect(t, rec, http.StatusPartialContent)
+	if rec.Body.String() != string(data[1000:]) {
+		t.Errorf("open range has %d bytes, want %d", rec.Body.Len(), len(data)-1000)
+	}
+
+	rec = get("bytes=-10")
+	apitest.Expect(t, rec, http.StatusPartialContent)
+	if rec.Body.String() != string(data[len(data)-10:]) {
+		t.Errorf("suffix range = %q", rec.Body.String())
+	}
+
+	rec = get(fmt.Sprintf("bytes=%d-", len(data)+10))
+	apitest.Expect(t, rec, http.StatusRequestedRangeNotSatisfiable)
+
+	// Only requests from the start of the file count as plays
+	var plays int64
+	h.DB.Model(&models.PlayHistory{}).Where("user_id = ? AND song_id = ?", bob.ID, song.ID).Count(&plays)
+	if plays != 1 {
+		t.Errorf("recorded %d plays, want 1", plays)
+	}
+}
+
+func TestFavouriteSong(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	song := bob.UploadSong("Loved", "Bob")
+	path := fmt.Sprintf("/api/v1/songs/%d/favorite", song.ID)
+
+	rec := bob.Post(path, nil)
+	apitest.Expect(t, rec, http.StatusOK)
+	if fav := apitest.JSON(t, rec)["isFavourited"]; fav != true {
+		t.Errorf("isFavourited = %v after adding", fav)
+	}
+	rec = bob.Post(path, nil)
+	apitest.Expect(t, rec, http.StatusOK)
+	if fav := apitest.JSON(t, rec)["isFavourited"]; fav != false {
+		t.Errorf("isFavourited = %v after toggling again", fav)
+	}
+
+	apitest.Expect(t, bob.Post("/api/v1/songs/9999/favorite", nil), http.StatusNotFound)
+	apitest.Expect(t, bob.Post("/api/v1/songs/0/favorite", nil), http.StatusBadRequest)
+	apitest.Expect(t, bob.Post("/api/v1/songs/abc/favorite", nil), http.StatusBadRequest)
+
+	// Access tokens cannot change favourites
+	reader := h.As(createAccessToken(t, bob, "everything", "read", "upload", "playlists:write"))
+	apitest.Expect(t, reader.Post(path, nil), http.StatusForbidden)
+}
+
+func TestSongJobs(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	song := bob.UploadSong("Queued", "Bob")
+
+	rec := bob.Get(fmt.Sprintf("/api/v1/songs/%d/jobs", song.ID))
+	apitest.Expect(t, rec, http.StatusOK)
+	var body struct {
+		SongID           uint      `json:"song_id"`
+		ProcessingStatus string    `json:"processing_status"`
+		Jobs             []dto.Job `json:"jobs"`
+	}
+	apitest.Decode(t, rec, &body)
+	if body.SongID != song.ID || body.ProcessingStatus != models.ProcessingInProgress || len(body.Jobs) == 0 {
+		t.Fatalf("song jobs = %+v", body)
+	}
+
+	job := body.Jobs[0]
+	rec = bob.Get("/api/v1/jobs/" + strconv.FormatUint(uint64(job.ID), 10))
+	apitest.Expect(t, rec, http.StatusOK)
+	var got dto.Job
+	apitest.Decode(t, rec, &got)
+	if got.ID != job.ID || got.Type != job.Type {
+		t.Errorf("job = %+v, want %+v", got, job)
+	}
+	// Job arguments are internal, as are the model's storage fields
+	for _, field := range []string{"payload", "DeletedAt", "ID"} {
+		if _, ok := apitest.JSON(t, rec)[field]; ok {
+			t.Errorf("job response has %s", field)
+		}
+	}
+
+	apitest.Expect(t, bob.Get("/api/v1/jobs/9999"), http.StatusNotFound)
+	apitest.Expect(t, bob.Get("/api/v1/songs/9999/jobs"), http.StatusNotFound)
+
+	// Other users cannot see the jobs working on bob's uploads
+	alice := h.Register("alice")
+	apitest.Expect(t, alice.Get("/api/v1/jobs/"+strconv.FormatUint(uint64(job.ID), 10)), http.StatusNotFound)
+	apitest.Expect(t, alice.Get(fmt.Sprintf("/api/v1/songs/%d/jobs", song.ID)), http.StatusNotFound)
+
+	// An ID that is not a number is not passed on as SQL
+	apitest.Expect(t, alice.Get("/api/v1/jobs/1)%20OR%20(1=1"), http.StatusNotFound)
+	apitest.Expect(t, alice.Get("/api/v1/songs/1)%20OR%20(1=1/jobs"), http.StatusNotFound)
+}
+
+func TestWaveform(t *testing.T) {
+	h := apitest.New(t)
+	h.StartJobs()
+	bob := h.Register("bob")
+	song := bob.UploadSong("Peaks", "Bob")
+	path := fmt.Sprintf("/api/v1/songs/%d/waveform", song.ID)
+
+	if status := waitForProcessing(t, bob, song.ID); status != models.ProcessingReady {
+		t.Fatalf("processing status = %q, want ready", status)
+	}
+
+	rec := bob.Get(path)
+	apitest.Expect(t, rec, http.StatusOK)
+	body := apitest.JSON(t, rec)
+	if body["bits"] != float64(16) || len(body["data"].([]any)) == 0 {
+		t.Errorf("waveform bits = %v with %d values", body["bits"], len(body["data"].([]any)))
+	}
+
+	apitest.Expect(t, bob.Get(path+"?bits=8"), http.StatusOK)
+	apitest.Expect(t, bob.Get(path+"?bits=12"), http.StatusBadRequest)
+	apitest.Expect(t, bob.Get(path+"?pixels_per_second=0"), http.StatusBadRequest)
+	apitest.Expect(t, bob.Get(path+"?pixels_per_second=100000"), http.StatusBadRequest)
+	apitest.Expect(t, bob.Get(path+"?pixels_per_second=20"), http.StatusOK)
+
+	rec = bob.Get(path + "?format=dat")
+	apitest.Expect(t, rec, http.StatusOK)
+	if ct := rec.Header().Get("Content-Type"); ct != "application/octet-stream" {
+		t.Errorf("content type = %q", ct)
+	}
+
+	apitest.Expect(t, bob.Get("/api/v1/songs/9999/waveform"), http.StatusNotFound)
+}
+
+func TestWaveformWhileProcessing(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	song := bob.UploadSong("Pending", "Bob")
+
+	rec := bob.Get(fmt.Sprintf("/api/v1/songs/%d/waveform", song.ID))
+	apitest.Expect(t, rec, http.StatusNotFound)
+	if status := apitest.JSON(t, rec)["processing_status"]; status != models.ProcessingInProgress {
+		t.Errorf("processing_status = %v", status)
+	}
+}
+
+func TestSimilarSongsAndRecommendations(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	song := bob.UploadSong("Seed", "Bob")
+	bob.UploadSong("Other", "Bob")
+
+	rec := bob.Get(fmt.Sprintf("/api/v1/songs/%d/similar", song.ID))
+	apitest.Expect(t, rec, http.StatusOK)
+	var songs []dto.Song
+	apitest.Decode(t, rec, &songs)
+
+	if len(songs) != 1 || songs[0].Title != "Other" {
+		t.Fatalf("similar songs = %+v", songs)
+	}
+
+	// Songs the caller played recently are left out, for them only
+	apitest.Expect(t, bob.Get(fmt.Sprintf("/api/v1/songs/%d/play", songs[0].ID)), http.StatusOK)
+	apitest.Decode(t, bob.Get(fmt.Sprintf("/api/v1/songs/%d/similar", song.ID)), &songs)
+	if len(songs) != 0 {
+		t.Errorf("similar songs after playing = %+v", songs)
+	}
+	alice := h.Register("alice")
+	apitest.Decode(t, alice.Get(fmt.Sprintf("/api/v1/songs/%d/similar", song.ID)), &songs)
+	if len(songs) != 1 {
+		t.Errorf("similar songs for another user = %+v", songs)
+	}
+
+	apitest.Expect(t, bob.Get(fmt.Sprintf("/api/v1/songs/%d/similar?limit=0", song.ID)), http.StatusBadRequest)
+	apitest.Expect(t, bob.Get("/api/v1/songs/abc/similar"), http.StatusBadRequest)
+	apitest.Expect(t, bob.Get("/api/v1/songs/9999/similar"), http.StatusNotFound)
+
+	apitest.Expect(t, bob.Get("/api/v1/recommendations"), http.StatusOK)
+	apitest.Expect(t, bob.Get("/api/v1/recommendations?limit=500"), http.StatusBadRequest)
+	apitest.Expect(t, h.Anonymous().Get("/api/v1/recommendations"), http.StatusUnauthorized)
+}
+
+// waitForProcessing polls a song's jobs until processing has finished and
+// returns the final status
+func waitForProcessing(t *testing.T, c *apitest.Client, songID uint) string {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		var body struct {
+			ProcessingStatus string `json:"processing_status"`
+		}
+		rec := c.Get(fmt.Sprintf("/api/v1/songs/%d/jobs", songID))
+		apitest.Expect(t, rec, http.StatusOK)
+		apitest.Decode(t, rec, &body)
+		if body.ProcessingStatus == models.ProcessingReady || body.ProcessingStatus == models.ProcessingFailed {
+			return body.ProcessingStatus
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("song %d is still %s", songID, body.ProcessingStatus)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
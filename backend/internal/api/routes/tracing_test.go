@@ -0,0 +1,130 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"music-player-gin/internal/api/apitest"
+	"music-player-gin/internal/models"
+)
+
+func TestTracingContinuesIncomingTrace(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	song := bob.UploadSong("Song", "Artist")
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const parentID = "00f067aa0ba902b7"
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/songs/%d/play", song.ID), nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentID+"-01")
+	apitest.Expect(t, bob.Do(req), http.StatusOK)
+
+	servers := h.Spans.Named("GET /api/v1/songs/:id/play")
+	if len(servers) != 1 {
+		t.Fatalf("%d server spans", len(servers))
+	}
+	server := servers[0]
+	if server.SpanContext().TraceID().String() != traceID || server.Parent().SpanID().String() != parentID {
+		t.Errorf("server span is in trace %s under %s", server.SpanContext().TraceID(), server.Parent().SpanID())
+	}
+	if server.SpanKind() != trace.SpanKindServer {
+		t.Errorf("kind = %s", server.SpanKind())
+	}
+	want := map[string]any{
+		"http.route":       "/api/v1/songs/:id/play",
+		"http.status_code": int64(http.StatusOK),
+		"enduser.id":       int64(bob.ID),
+	}
+	for key, value := range want {
+		if got := apitest.Attribute(server, key); got != value {
+			t.Errorf("%s = %v, want %v", key, got, value)
+		}
+	}
+
+	// File reads and queries are children of the request's span
+	reads := h.Spans.Named("storage.read")
+	if len(reads) != 1 || reads[0].Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Fatalf("storage.read spans = %+v", reads)
+	}
+	if sent, _ := apitest.Attribute(reads[0], "file.bytes_sent").(int64); sent == 0 {
+		t.Error("storage.read records no bytes")
+	}
+	var queries int
+	for _, span := range h.Spans.Ended() {
+		if span.SpanKind() == trace.SpanKindClient && span.Parent().SpanID() == server.SpanContext().SpanID() {
+			queries++
+			if text, _ := apitest.Attribute(span, "db.statement").(string); text == "" {
+				t.Errorf("query span %s has no query text", span.Name())
+			}
+		}
+	}
+	if queries == 0 {
+		t.Error("no query spans under the request")
+	}
+
+	// The request's log lines carry the trace
+	var logged bool
+	for _, entry := range h.Logs.Entries("request") {
+		logged = logged || entry["trace_id"] == traceID
+	}
+	if !logged {
+		t.Error("request log has no trace_id")
+	}
+}
+
+func TestTracingSpans(t *testing.T) {
+	h := apitest.New(t)
+	h.StartJobs()
+	bob := h.Register("bob")
+	song := bob.UploadSong("Song", "Artist")
+
+	uploads := h.Spans.Named("POST /api/v1/songs")
+	writes := h.Spans.Named("storage.write")
+	if len(uploads) != 1 || len(writes) != 1 {
+		t.Fatalf("%d upload spans, %d storage.write spans", len(uploads), len(writes))
+	}
+	if writes[0].Parent().SpanID() != uploads[0].SpanContext().SpanID() {
+		t.Error("storage.write is not a child of the upload")
+	}
+	// A new trace is started when none is sent
+	if uploads[0].Parent().IsValid() {
+		t.Error("upload span has a parent")
+	}
+
+	if status := waitForProcessing(t, bob, song.ID); status != models.ProcessingReady {
+		t.Fatalf("processing status = %q, want ready", status)
+	}
+	var jobs int
+	for _, span := range h.Spans.Ended() {
+		if strings.HasPrefix(span.Name(), "job ") {
+			jobs++
+			if apitest.Attribute(span, "job.id") == nil {
+				t.Errorf("%s has no job.id", span.Name())
+			}
+		}
+	}
+	if jobs == 0 {
+		t.Error("no job spans")
+	}
+
+	// Query arguments, such as the username registered, stay out of spans
+	for _, span := range h.Spans.Ended() {
+		if text, _ := apitest.Attribute(span, "db.statement").(string); strings.Contains(text, "bob") {
+			t.Errorf("%s holds a query argument: %s", span.Name(), text)
+		}
+	}
+
+	// Failed requests are marked as errors only when the server is at fault
+	apitest.Expect(t, bob.Get("/api/v1/songs/9999"), http.StatusNotFound)
+	for _, span := range h.Spans.Named("GET /api/v1/songs/:id") {
+		if span.Status().Code == codes.Error {
+			t.Error("404 marked as an error")
+		}
+	}
+}
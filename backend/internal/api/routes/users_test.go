@@ -0,0 +1,210 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+
+	"music-player-gin/internal/api/apitest"
+	"music-player-gin/internal/dto"
+)
+
+// followPage is a page of followers or following
+type followPage struct {
+	Users []struct {
+		ID       uint   `json:"id"`
+		Username string `json:"username"`
+	} `json:"users"`
+	NextCursor string `json:"next_cursor"`
+}
+
+func TestGetUser(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	alice := h.Register("alice")
+
+	rec := alice.Get(fmt.Sprintf("/api/v1/users/%d", bob.ID))
+	apitest.Expect(t, rec, http.StatusOK)
+	body := apitest.JSON(t, rec)
+	if body["username"] != "bob" || body["is_following"] != false || body["followers"] != float64(0) {
+		t.Errorf("profile = %v", body)
+	}
+	// Other users never see the email address
+	if _, ok := body["email"]; ok {
+		t.Error("public profile includes the email address")
+	}
+
+	apitest.Expect(t, alice.Get("/api/v1/users/9999"), http.StatusNotFound)
+	apitest.Expect(t, alice.Get("/api/v1/users/abc"), http.StatusBadRequest)
+	apitest.Expect(t, alice.Get("/api/v1/users/9999/playlists"), http.StatusNotFound)
+	apitest.Expect(t, h.Anonymous().Get(fmt.Sprintf("/api/v1/users/%d", bob.ID)), http.StatusUnauthorized)
+}
+
+func TestFollow(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	alice := h.Register("alice")
+	path := fmt.Sprintf("/api/v1/users/%d/follow", bob.ID)
+
+	apitest.Expect(t, alice.Post(path, nil), http.StatusOK)
+	// Following twice is harmless
+	apitest.Expect(t, alice.Post(path, nil), http.StatusOK)
+
+	body := apitest.JSON(t, alice.Get(fmt.Sprintf("/api/v1/users/%d", bob.ID)))
+	if body["is_following"] != true || body["followers"] != float64(1) {
+		t.Errorf("after following: %v", body)
+	}
+	body = apitest.JSON(t, bob.Get(fmt.Sprintf("/api/v1/users/%d", alice.ID)))
+	if body["following"] != float64(1) || body["is_following"] != false {
+		t.Errorf("follower's profile: %v", body)
+	}
+
+	var page followPage
+	apitest.Decode(t, bob.Get(fmt.Sprintf("/api/v1/users/%d/followers", bob.ID)), &page)
+	if len(page.Users) != 1 || page.Users[0].ID != alice.ID {
+		t.Errorf("followers = %+v", page)
+	}
+	apitest.Decode(t, bob.Get(fmt.Sprintf("/api/v1/users/%d/following", alice.ID)), &page)
+	if len(page.Users) != 1 || page.Users[0].ID != bob.ID {
+		t.Errorf("following = %+v", page)
+	}
+
+	apitest.Expect(t, alice.Delete(path, nil), http.StatusOK)
+	body = apitest.JSON(t, alice.Get(fmt.Sprintf("/api/v1/users/%d", bob.ID)))
+	if body["is_following"] != false || body["followers"] != float64(0) {
+		t.Errorf("after unfollowing: %v", body)
+	}
+
+	apitest.Expect(t, alice.Post(fmt.Sprintf("/api/v1/users/%d/follow", alice.ID), nil), http.StatusBadRequest)
+	apitest.Expect(t, alice.Post("/api/v1/users/9999/follow", nil), http.StatusNotFound)
+	apitest.Expect(t, alice.Delete("/api/v1/users/9999/follow", nil), http.StatusNotFound)
+
+	// Following is only for logged-in sessions
+	reader := h.As(createAccessToken(t, alice, "reader", "read"))
+	apitest.Expect(t, reader.Post(path, nil), http.StatusForbidden)
+}
+
+func TestFollowersPagination(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	for _, name := range []string{"alice", "carol", "dave", "erin", "frank"} {
+		apitest.Expect(t, h.Register(name).Post(fmt.Sprintf("/api/v1/users/%d/follow", bob.ID), nil), http.StatusOK)
+	}
+
+	seen := map[uint]bool{}
+	path := fmt.Sprintf("/api/v1/users/%d/followers?limit=2", bob.ID)
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("pagination did not end")
+		}
+		var page followPage
+		rec := bob.Get(path)
+		apitest.Expect(t, rec, http.StatusOK)
+		apitest.Decode(t, rec, &page)
+		for _, u := range page.Users {
+			if seen[u.ID] {
+				t.Errorf("user %d appears on two pages", u.ID)
+			}
+			seen[u.ID] = true
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		path = fmt.Sprintf("/api/v1/users/%d/followers?limit=2&cursor=%s", bob.ID, url.QueryEscape(page.NextCursor))
+	}
+	if len(seen) != 5 {
+		t.Errorf("paged through %d followers, want 5", len(seen))
+	}
+
+	apitest.Expect(t, bob.Get(fmt.Sprintf("/api/v1/users/%d/followers?cursor=!!", bob.ID)), http.StatusBadRequest)
+	apitest.Expect(t, bob.Get(fmt.Sprintf("/api/v1/users/%d/followers?limit=0", bob.ID)), http.StatusBadRequest)
+	apitest.Expect(t, bob.Get("/api/v1/users/9999/followers"), http.StatusNotFound)
+}
+
+func TestFeed(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	alice := h.Register("alice")
+
+	var feed struct {
+		Items []struct {
+			Type  string `json:"type"`
+			Actor struct {
+				ID uint `json:"id"`
+			} `json:"actor"`
+		} `json:"items"`
+		NextCursor string `json:"next_cursor"`
+	}
+	apitest.Decode(t, alice.Get("/api/v1/feed"), &feed)
+	if len(feed.Items) != 0 {
+		t.Fatalf("empty feed has %d items", len(feed.Items))
+	}
+
+	song := bob.UploadSong("New", "Bob")
+	shared := createPlaylist(t, bob, "Shared", true)
+	createPlaylist(t, bob, "Secret", false)
+	apitest.Expect(t, bob.Post(fmt.Sprintf("/api/v1/songs/%d/favorite", song.ID), nil), http.StatusOK)
+
+	// Nothing shows up until alice follows bob
+	apitest.Decode(t, alice.Get("/api/v1/feed"), &feed)
+	if len(feed.Items) != 0 {
+		t.Fatalf("feed before following has %d items", len(feed.Items))
+	}
+
+	apitest.Expect(t, alice.Post(fmt.Sprintf("/api/v1/users/%d/follow", bob.ID), nil), http.StatusOK)
+	apitest.Decode(t, alice.Get("/api/v1/feed"), &feed)
+	kinds := map[string]int{}
+	for _, item := range feed.Items {
+		kinds[item.Type]++
+		if item.Actor.ID != bob.ID {
+			t.Errorf("item by %d, want %d", item.Actor.ID, bob.ID)
+		}
+	}
+	// The private playlist stays out of the feed
+	if kinds["upload"] != 1 || kinds["playlist"] != 1 || kinds["favourite"] != 1 {
+		t.Errorf("feed item kinds = %v", kinds)
+	}
+
+	// Walking the feed a page at a time returns each item once, in order
+	var walked []string
+	path := "/api/v1/feed?limit=1"
+	for page := 1; ; page++ {
+		var body struct {
+			Items      []dto.FeedItem `json:"items"`
+			NextCursor string         `json:"next_cursor"`
+		}
+		apitest.Decode(t, alice.Get(path), &body)
+		if len(body.Items) != 1 {
+			t.Fatalf("page %d has %d items", page, len(body.Items))
+		}
+		item := body.Items[0]
+		key := item.Type
+		switch {
+		case item.Song != nil:
+			key += fmt.Sprintf(" song %d", item.Song.ID)
+		case item.Playlist != nil:
+			key += fmt.Sprintf(" playlist %d", item.Playlist.ID)
+		}
+		walked = append(walked, key)
+		if body.NextCursor == "" {
+			break
+		}
+		if page == len(feed.Items) {
+			t.Fatalf("cursor %q after the last item", body.NextCursor)
+		}
+		path = "/api/v1/feed?limit=1&cursor=" + url.QueryEscape(body.NextCursor)
+	}
+	want := []string{
+		fmt.Sprintf("favourite song %d", song.ID),
+		fmt.Sprintf("playlist playlist %d", shared.ID),
+		fmt.Sprintf("upload song %d", song.ID),
+	}
+	if !slices.Equal(walked, want) {
+		t.Errorf("walked %v, want %v", walked, want)
+	}
+
+	apitest.Expect(t, alice.Get("/api/v1/feed?cursor=garbage"), http.StatusBadRequest)
+	apitest.Expect(t, alice.Get("/api/v1/feed?limit=1000"), http.StatusBadRequest)
+}
@@ -0,0 +1,70 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"music-player-gin/internal/api/apitest"
+	"music-player-gin/internal/models"
+)
+
+func TestLegacyAliases(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	song := bob.UploadSong("Old", "Bob")
+
+	v1 := bob.Get(fmt.Sprintf("/api/v1/songs/%d", song.ID))
+	apitest.Expect(t, v1, http.StatusOK)
+	if v1.Header().Get("Deprecation") != "" || v1.Header().Get("Sunset") != "" {
+		t.Errorf("v1 response is marked deprecated: %v", v1.Header())
+	}
+
+	legacy := bob.Get(fmt.Sprintf("/songs/%d", song.ID))
+	apitest.Expect(t, legacy, http.StatusOK)
+	if legacy.Body.String() != v1.Body.String() {
+		t.Errorf("alias body = %s, want %s", legacy.Body.String(), v1.Body.String())
+	}
+	if d := legacy.Header().Get("Deprecation"); !strings.HasPrefix(d, "@") {
+		t.Errorf("Deprecation = %q", d)
+	}
+	if sunset, err := time.Parse(http.TimeFormat, legacy.Header().Get("Sunset")); err != nil || !sunset.After(time.Now()) {
+		t.Errorf("Sunset = %q, err %v", legacy.Header().Get("Sunset"), err)
+	}
+	want := fmt.Sprintf(`</api/v1/songs/%d>; rel="successor-version"`, song.ID)
+	if link := legacy.Header().Get("Link"); link != want {
+		t.Errorf("Link = %q, want %q", link, want)
+	}
+
+	// Errors and logins work the same on both
+	rec := bob.Get("/songs/9999")
+	apitest.Expect(t, rec, http.StatusNotFound)
+	if p := apitest.ProblemOf(t, rec); p.Code != "song_not_found" {
+		t.Errorf("code = %q", p.Code)
+	}
+	apitest.Expect(t, h.Anonymous().Post("/auth/login", map[string]string{"username": "bob", "password": apitest.DefaultPassword}), http.StatusOK)
+
+	// Documents and keys are not versioned
+	for _, path := range []string{"/openapi.json", "/docs", "/.well-known/jwks.json"} {
+		if rec := h.Anonymous().Get(path); rec.Header().Get("Deprecation") != "" {
+			t.Errorf("%s is marked deprecated", path)
+		}
+	}
+}
+
+func TestLegacyAliasesKeepTokenScopes(t *testing.T) {
+	h := apitest.New(t)
+	bob := h.Register("bob")
+	token := h.As(createAccessToken(t, bob, "sync", models.ScopeRead, models.ScopePlaylistsWrite))
+
+	for _, prefix := range []string{"/api/v1", ""} {
+		rec := token.Post(prefix+"/playlists", map[string]any{"name": "Synced" + prefix})
+		apitest.Expect(t, rec, http.StatusCreated)
+
+		// Writes outside the token's scopes stay refused
+		rec = token.Patch(prefix+"/me", map[string]string{"bio": "changed"})
+		apitest.Expect(t, rec, http.StatusForbidden)
+	}
+}
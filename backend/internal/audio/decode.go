@@ -0,0 +1,122 @@
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/hajimehoshi/go-mp3"
+)
+
+// Channels is the number of channels produced by the decoder. MP3 streams are
+// always decoded to interleaved stereo, mono sources are duplicated.
+const Channels = 2
+
+// Stream is a decoded PCM stream of interleaved 16-bit stereo samples
+type Stream struct {
+	file     *os.File
+	dec      *mp3.Decoder
+	buf      []byte
+	channels int
+}
+
+// OpenMP3 opens an MP3 file for decoding
+func OpenMP3(path string) (*Stream, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	channels := sourceChannels(f)
+	dec, err := mp3.NewDecoder(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &Stream{file: f, dec: dec, channels: channels}, nil
+}
+
+// SourceChannels returns how many channels the file was encoded with: 1 for
+// mono, whose samples appear on both decoded channels, or 2
+func (s *Stream) SourceChannels() int {
+	return s.channels
+}
+
+// SampleRate returns the number of sample frames per second
+func (s *Stream) SampleRate() int {
+	return s.dec.SampleRate()
+}
+
+// Frames returns the total number of sample frames in the stream
+func (s *Stream) Frames() int64 {
+	return s.dec.Length() / (2 * Channels)
+}
+
+// ReadFrames decodes up to len(dst)/Channels frames into dst as interleaved
+// samples and returns the number of frames read. It returns io.EOF at the end
+// of the stream.
+func (s *Stream) ReadFrames(dst []int16) (int, error) {
+	frames := len(dst) / Channels
+	want := frames * Channels * 2
+	if cap(s.buf) < want {
+		s.buf = make([]byte, want)
+	}
+	buf := s.buf[:want]
+
+	n, err := io.ReadFull(s.dec, buf)
+	if err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	if n == 0 && err == nil {
+		err = io.EOF
+	}
+
+	read := n / (2 * Channels)
+	for i := 0; i < read*Channels; i++ {
+		dst[i] = int16(binary.LittleEndian.Uint16(buf[i*2:]))
+	}
+	return read, err
+}
+
+// Close releases the underlying file
+func (s *Stream) Close() error {
+	return s.file.Close()
+}
+
+// sourceChannels reads the channel mode from the first frame header after any
+// ID3v2 tag, assuming stereo when no header is found
+func sourceChannels(r io.ReaderAt) int {
+	head := make([]byte, 64<<10)
+	n, _ := r.ReadAt(head, 0)
+	head = head[:n]
+
+	start := 0
+	if len(head) >= 10 && string(head[:3]) == "ID3" {
+		// The tag size is stored as four 7-bit bytes and leaves out the header and footer
+		size := int(head[6])<<21 | int(head[7])<<14 | int(head[8])<<7 | int(head[9])
+		start = 10 + size
+		if head[5]&0x10 != 0 {
+			start += 10
+		}
+		if start >= len(head) {
+			head = make([]byte, 4<<10)
+			n, _ := r.ReadAt(head, int64(start))
+			head, start = head[:n], 0
+		}
+	}
+
+	for i := start; i+4 <= len(head); i++ {
+		if head[i] != 0xFF || head[i+1]&0xE0 != 0xE0 {
+			continue
+		}
+		version, layer := head[i+1]>>3&3, head[i+1]>>1&3
+		bitrate, rate := head[i+2]>>4, head[i+2]>>2&3
+		if version == 1 || layer == 0 || bitrate == 15 || rate == 3 {
+			continue
+		}
+		if head[i+3]>>6 == 3 {
+			return 1
+		}
+		return 2
+	}
+	return 2
+}
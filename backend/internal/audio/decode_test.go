@@ -0,0 +1,37 @@
+package audio
+
+import (
+	"bytes"
+	"testing"
+)
+
+// frame returns an MPEG-1 Layer III frame header at 128 kbps and 44.1 kHz in
+// the given channel mode, padded to the frame's length
+func frame(mode byte) []byte {
+	f := make([]byte, 417)
+	copy(f, []byte{0xFF, 0xFB, 0x90, mode<<6 | 0x04})
+	return f
+}
+
+func TestSourceChannels(t *testing.T) {
+	// ID3v2 tag of 20 bytes whose body happens to contain a sync word
+	tag := append([]byte("ID3\x04\x00\x00\x00\x00\x00\x14"), bytes.Repeat([]byte{0xFF, 0xFB}, 10)...)
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"mono", frame(3), 1},
+		{"stereo", frame(0), 2},
+		{"joint stereo", frame(1), 2},
+		{"mono after a tag", append(append([]byte{}, tag...), frame(3)...), 1},
+		{"junk before the frame", append([]byte{0x00, 0xFF, 0x00}, frame(3)...), 1},
+		{"no frame", []byte("not an mp3"), 2},
+	}
+	for _, tt := range tests {
+		if got := sourceChannels(bytes.NewReader(tt.data)); got != tt.want {
+			t.Errorf("%s: %d channels, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Package database opens the database the server stores everything in.
+// SQLite is the default; PostgreSQL and MySQL are supported for deployments
+// that run several instances or want a managed database.
+package database
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"music-player-gin/internal/logging"
+)
+
+// Dialect names a supported database. The values match gorm's dialector names.
+type Dialect string
+
+const (
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+	MySQL    Dialect = "mysql"
+)
+
+// DefaultDSN is the SQLite file used when DB_DSN is unset. The busy timeout
+// lets background workers and requests wait on each other's writes.
+const DefaultDSN = "albums.db?_pragma=busy_timeout(5000)"
+
+// Config chooses the database and how connections to it are pooled.
+// Zero pool settings keep the database/sql defaults.
+type Config struct {
+	Dialect         Dialect
+	DSN             string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// ConfigFromEnv reads the DB_* environment variables
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Dialect: Dialect(os.Getenv("DB_DRIVER")),
+		DSN:     os.Getenv("DB_DSN"),
+	}
+	switch cfg.Dialect {
+	case "":
+		cfg.Dialect = SQLite
+	case SQLite, Postgres, MySQL:
+	default:
+		return Config{}, fmt.Errorf("unsupported DB_DRIVER %q, use sqlite, postgres or mysql", cfg.Dialect)
+	}
+	if cfg.DSN == "" {
+		if cfg.Dialect != SQLite {
+			return Config{}, fmt.Errorf("DB_DSN is required for %s", cfg.Dialect)
+		}
+		cfg.DSN = DefaultDSN
+	}
+
+	for key, target := range map[string]*int{
+		"DB_MAX_OPEN_CONNS": &cfg.MaxOpenConns,
+		"DB_MAX_IDLE_CONNS": &cfg.MaxIdleConns,
+	} {
+		if value := os.Getenv(key); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return Config{}, fmt.Errorf("%s must be a number", key)
+			}
+			*target = n
+		}
+	}
+	for key, target := range map[string]*time.Duration{
+		"DB_CONN_MAX_LIFETIME":  &cfg.ConnMaxLifetime,
+		"DB_CONN_MAX_IDLE_TIME": &cfg.ConnMaxIdleTime,
+	} {
+		if value := os.Getenv(key); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return Config{}, fmt.Errorf("%s must be a duration", key)
+			}
+			*target = d
+		}
+	}
+	return cfg, nil
+}
+
+// Open connects to the database and applies the pool settings
+func Open(cfg Config) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch cfg.Dialect {
+	case SQLite:
+		dialector = sqlite.Open(cfg.DSN)
+	case Postgres:
+		dialector = postgres.Open(cfg.DSN)
+	case MySQL:
+		dsn, err := mysqldriver.ParseDSN(cfg.DSN)
+		if err != nil {
+			return nil, err
+		}
+		// Times are stored as DATETIME and must scan into time.Time
+		dsn.ParseTime = true
+		dialector = mysqlDialector{mysql.New(mysql.Config{DSNConfig: dsn}).(*mysql.Dialector)}
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER %q, use sqlite, postgres or mysql", cfg.Dialect)
+	}
+
+	// Errors are translated so unique violations read as gorm.ErrDuplicatedKey on every driver
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logging.Gorm{}, TranslateError: true})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+	return db, nil
+}
+
+// mysqlDialector gives strings in a unique index a length when their model
+// does not. gorm makes such strings longtext, which MySQL cannot index, and
+// the baseline migration's models predate MySQL support.
+type mysqlDialector struct {
+	*mysql.Dialector
+}
+
+func (d mysqlDialector) DataTypeOf(field *schema.Field) string {
+	if field.DataType == schema.String && field.Size == 0 && field.TagSettings["UNIQUEINDEX"] != "" {
+		return "varchar(255)"
+	}
+	return d.Dialector.DataTypeOf(field)
+}
+
+// Migrator creates tables through DataTypeOf above
+func (d mysqlDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	m := d.Dialector.Migrator(db).(mysql.Migrator)
+	m.Migrator.Config.Dialector = d
+	return m
+}
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+func TestMySQLSizesIndexedStrings(t *testing.T) {
+	// Nothing is sent to the server, so the address does not need to exist
+	dialector := mysqlDialector{mysql.New(mysql.Config{DSN: "user@tcp(127.0.0.1:1)/music", SkipInitializeWithVersion: true}).(*mysql.Dialector)}
+	db, err := gorm.Open(dialector, &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type User struct {
+		ID       uint
+		Username string `gorm:"uniqueIndex"`
+		Sized    string `gorm:"uniqueIndex;size:64"`
+		Bio      string
+	}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(&User{}); err != nil {
+		t.Fatal(err)
+	}
+	m := db.Migrator().(interface {
+		FullDataTypeOf(*schema.Field) clause.Expr
+	})
+	for field, want := range map[string]string{"Username": "varchar(255)", "Sized": "varchar(64)", "Bio": "longtext"} {
+		if got := m.FullDataTypeOf(stmt.Schema.LookUpField(field)).SQL; !strings.HasPrefix(got, want) {
+			t.Errorf("%s is %q, want %s", field, got, want)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package database
+
+import "gorm.io/gorm"
+
+// DialectOf returns the dialect db talks to
+func DialectOf(db *gorm.DB) Dialect {
+	return Dialect(db.Dialector.Name())
+}
+
+// Random is an ORDER BY expression that shuffles rows
+func (d Dialect) Random() string {
+	if d == MySQL {
+		return "RAND()"
+	}
+	return "RANDOM()"
+}
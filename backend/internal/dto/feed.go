@@ -0,0 +1,33 @@
+package dto
+
+import (
+	"time"
+
+	"music-player-gin/internal/feed"
+)
+
+// FeedItem is one entry in the feed. Playlist is set for playlist items and
+// Song for uploads and favourites.
+type FeedItem struct {
+	Type      string     `json:"type"`
+	CreatedAt time.Time  `json:"created_at"`
+	Actor     feed.Actor `json:"actor"`
+	Playlist  *Playlist  `json:"playlist,omitempty"`
+	Song      *Song      `json:"song,omitempty"`
+}
+
+func NewFeedItems(items []feed.Item) []FeedItem {
+	result := make([]FeedItem, len(items))
+	for i, item := range items {
+		result[i] = FeedItem{Type: item.Type, CreatedAt: item.CreatedAt, Actor: item.Actor}
+		if item.Playlist != nil {
+			playlist := NewPlaylist(*item.Playlist)
+			result[i].Playlist = &playlist
+		}
+		if item.Song != nil {
+			song := NewSong(*item.Song)
+			result[i].Song = &song
+		}
+	}
+	return result
+}
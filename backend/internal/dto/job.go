@@ -0,0 +1,48 @@
+package dto
+
+import (
+	"time"
+
+	"music-player-gin/internal/models"
+)
+
+// Job is a background job working on a song
+type Job struct {
+	ID          uint       `json:"id"`
+	Type        string     `json:"type"`
+	SongID      *uint      `json:"song_id,omitempty"`
+	Status      string     `json:"status"`
+	Attempts    int        `json:"attempts"`
+	MaxAttempts int        `json:"max_attempts"`
+	RunAt       time.Time  `json:"run_at"` // Earliest time the job may be picked up
+	LastError   string     `json:"last_error,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func NewJob(job models.Job) Job {
+	return Job{
+		ID:          job.ID,
+		Type:        job.Type,
+		SongID:      job.SongID,
+		Status:      job.Status,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		RunAt:       job.RunAt,
+		LastError:   job.LastError,
+		StartedAt:   job.StartedAt,
+		FinishedAt:  job.FinishedAt,
+		CreatedAt:   job.CreatedAt,
+		UpdatedAt:   job.UpdatedAt,
+	}
+}
+
+func NewJobs(jobs []models.Job) []Job {
+	result := make([]Job, len(jobs))
+	for i, job := range jobs {
+		result[i] = NewJob(job)
+	}
+	return result
+}
@@ -0,0 +1,44 @@
+package dto
+
+import (
+	"time"
+
+	"music-player-gin/internal/models"
+)
+
+// Playlist is a user's playlist. Songs is only filled in by responses that
+// include the songs, and left out when there are none.
+type Playlist struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	UserID      uint      `json:"user_id"`
+	IsPublic    bool      `json:"is_public"`
+	Songs       []Song    `json:"songs,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func NewPlaylist(playlist models.Playlist) Playlist {
+	result := Playlist{
+		ID:          playlist.ID,
+		Name:        playlist.Name,
+		Description: playlist.Description,
+		UserID:      playlist.UserID,
+		IsPublic:    playlist.IsPublic,
+		CreatedAt:   playlist.CreatedAt,
+		UpdatedAt:   playlist.UpdatedAt,
+	}
+	if playlist.Songs != nil {
+		result.Songs = NewSongs(playlist.Songs)
+	}
+	return result
+}
+
+func NewPlaylists(playlists []models.Playlist) []Playlist {
+	result := make([]Playlist, len(playlists))
+	for i, playlist := range playlists {
+		result[i] = NewPlaylist(playlist)
+	}
+	return result
+}
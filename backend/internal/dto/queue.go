@@ -0,0 +1,43 @@
+package dto
+
+import (
+	"time"
+
+	"music-player-gin/internal/models"
+)
+
+// PlayQueue is a user's play queue and playback state
+type PlayQueue struct {
+	UserID          uint            `json:"user_id"`
+	CurrentIndex    int             `json:"current_index"`
+	PositionSeconds float64         `json:"position_seconds"`
+	Shuffle         bool            `json:"shuffle"`
+	RepeatMode      string          `json:"repeat_mode"`
+	Version         int             `json:"version"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	Items           []PlayQueueItem `json:"items"`
+}
+
+// PlayQueueItem is one entry in a play queue
+type PlayQueueItem struct {
+	Position int  `json:"position"`
+	SongID   uint `json:"song_id"`
+	Song     Song `json:"song"`
+}
+
+func NewPlayQueue(queue models.PlayQueue) PlayQueue {
+	items := make([]PlayQueueItem, len(queue.Items))
+	for i, item := range queue.Items {
+		items[i] = PlayQueueItem{Position: item.Position, SongID: item.SongID, Song: NewSong(item.Song)}
+	}
+	return PlayQueue{
+		UserID:          queue.UserID,
+		CurrentIndex:    queue.CurrentIndex,
+		PositionSeconds: queue.PositionSeconds,
+		Shuffle:         queue.Shuffle,
+		RepeatMode:      queue.RepeatMode,
+		Version:         queue.Version,
+		UpdatedAt:       queue.UpdatedAt,
+		Items:           items,
+	}
+}
@@ -0,0 +1,32 @@
+package dto
+
+import (
+	"time"
+
+	"music-player-gin/internal/models"
+)
+
+// RadioSession is a radio queue generated from a seed
+type RadioSession struct {
+	ID          string    `json:"id"`
+	UserID      uint      `json:"user_id"`
+	SeedType    string    `json:"seed_type"`
+	SeedValue   string    `json:"seed_value"`  // Song or playlist ID, artist or genre name
+	Familiarity float64   `json:"familiarity"` // Share of songs the user already knows, from 0 to 1
+	Served      int       `json:"served"`      // Number of songs handed out so far
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func NewRadioSession(session models.RadioSession) RadioSession {
+	return RadioSession{
+		ID:          session.ID,
+		UserID:      session.UserID,
+		SeedType:    session.SeedType,
+		SeedValue:   session.SeedValue,
+		Familiarity: session.Familiarity,
+		Served:      session.Served,
+		CreatedAt:   session.CreatedAt,
+		UpdatedAt:   session.UpdatedAt,
+	}
+}
@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"time"
+
+	"music-player-gin/internal/models"
+)
+
+// Room is a listening party with its shared queue, members and playback state
+type Room struct {
+	Code            string          `json:"code"`
+	Name            string          `json:"name"`
+	HostID          uint            `json:"host_id"`
+	CurrentIndex    int             `json:"current_index"`
+	PositionSeconds float64         `json:"position_seconds"` // Position at StateAt
+	Playing         bool            `json:"playing"`
+	StateAt         time.Time       `json:"state_at"` // Server time the playback state was recorded
+	Version         int             `json:"version"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	Items           []RoomQueueItem `json:"items"`
+	Members         []RoomMember    `json:"members"`
+}
+
+// RoomQueueItem is one entry in a room's shared queue
+type RoomQueueItem struct {
+	Position int  `json:"position"`
+	SongID   uint `json:"song_id"`
+	Song     Song `json:"song"`
+}
+
+// RoomMember is a user who has joined a room
+type RoomMember struct {
+	UserID   uint      `json:"user_id"`
+	Username string    `json:"username"`
+	Online   bool      `json:"online"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+func NewRoom(room models.Room) Room {
+	items := make([]RoomQueueItem, len(room.Items))
+	for i, item := range room.Items {
+		items[i] = RoomQueueItem{Position: item.Position, SongID: item.SongID, Song: NewSong(item.Song)}
+	}
+	members := make([]RoomMember, len(room.Members))
+	for i, m := range room.Members {
+		members[i] = RoomMember{UserID: m.UserID, Username: m.Username, Online: m.Online, JoinedAt: m.JoinedAt}
+	}
+	return Room{
+		Code:            room.Code,
+		Name:            room.Name,
+		HostID:          room.HostID,
+		CurrentIndex:    room.CurrentIndex,
+		PositionSeconds: room.PositionSeconds,
+		Playing:         room.Playing,
+		StateAt:         room.StateAt,
+		Version:         room.Version,
+		CreatedAt:       room.CreatedAt,
+		UpdatedAt:       room.UpdatedAt,
+		Items:           items,
+		Members:         members,
+	}
+}
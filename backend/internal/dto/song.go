@@ -0,0 +1,84 @@
+// Package dto holds the shapes songs, playlists and users take in API
+// responses and events. Models are never serialised directly, so storage
+// details such as file paths and soft deletion stay private and the models
+// can change without changing the API.
+package dto
+
+import (
+	"time"
+
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/recommend"
+)
+
+// Song is a song in the library
+type Song struct {
+	ID       uint   `json:"id"`
+	Title    string `json:"title"`
+	Artist   string `json:"artist"`
+	Album    string `json:"album"`
+	Genre    string `json:"genre"`
+	Duration int    `json:"duration"`  // Length in seconds
+	FileSize int64  `json:"file_size"` // Size of the uploaded file in bytes
+	UserID   uint   `json:"user_id"`   // Uploader, or 0 if their account was deleted
+
+	// HasLowQuality is true once a low bitrate stream is available with quality=low
+	HasLowQuality bool `json:"has_low_quality"`
+
+	Loudness  *float64 `json:"loudness,omitempty"`   // Integrated loudness in LUFS
+	TruePeak  *float64 `json:"true_peak,omitempty"`  // True peak in dBTP
+	TrackGain *float64 `json:"track_gain,omitempty"` // ReplayGain track gain in dB
+	TrackPeak *float64 `json:"track_peak,omitempty"` // Track true peak as a linear amplitude
+	AlbumGain *float64 `json:"album_gain,omitempty"` // ReplayGain album gain in dB
+	AlbumPeak *float64 `json:"album_peak,omitempty"` // Album true peak as a linear amplitude
+
+	ProcessingStatus string    `json:"processing_status"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+func NewSong(song models.Song) Song {
+	return Song{
+		ID:               song.ID,
+		Title:            song.Title,
+		Artist:           song.Artist,
+		Album:            song.Album,
+		Genre:            song.Genre,
+		Duration:         song.Duration,
+		FileSize:         song.FileSize,
+		UserID:           song.UserID,
+		HasLowQuality:    song.TranscodedPath != "",
+		Loudness:         song.Loudness,
+		TruePeak:         song.TruePeak,
+		TrackGain:        song.TrackGain,
+		TrackPeak:        song.TrackPeak,
+		AlbumGain:        song.AlbumGain,
+		AlbumPeak:        song.AlbumPeak,
+		ProcessingStatus: song.ProcessingStatus,
+		CreatedAt:        song.CreatedAt,
+		UpdatedAt:        song.UpdatedAt,
+	}
+}
+
+// NewSongs converts a list of songs. The result is never nil so it encodes as [].
+func NewSongs(songs []models.Song) []Song {
+	result := make([]Song, len(songs))
+	for i, song := range songs {
+		result[i] = NewSong(song)
+	}
+	return result
+}
+
+// ScoredSong is a recommended song with its relevance score
+type ScoredSong struct {
+	Song
+	Score float64 `json:"score"`
+}
+
+func NewScoredSongs(songs []recommend.ScoredSong) []ScoredSong {
+	result := make([]ScoredSong, len(songs))
+	for i, song := range songs {
+		result[i] = ScoredSong{Song: NewSong(song.Song), Score: song.Score}
+	}
+	return result
+}
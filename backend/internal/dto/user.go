@@ -0,0 +1,59 @@
+package dto
+
+import (
+	"time"
+
+	"music-player-gin/internal/models"
+)
+
+// User is what other users see of an account
+type User struct {
+	ID          uint      `json:"id"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   *string   `json:"avatar_url"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func NewUser(user models.User) User {
+	return User{
+		ID:          user.ID,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarURL:   user.AvatarURL(),
+		CreatedAt:   user.CreatedAt,
+	}
+}
+
+// Profile is the current user's own view of their account
+type Profile struct {
+	User
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	PendingEmail  string `json:"pending_email"` // New address waiting to be confirmed
+}
+
+func NewProfile(user models.User) Profile {
+	return Profile{
+		User:          NewUser(user),
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		PendingEmail:  user.PendingEmail,
+	}
+}
+
+// UserProfile is a user with their follower counts, as seen by the current user
+type UserProfile struct {
+	User
+	Followers   int64 `json:"followers"`
+	Following   int64 `json:"following"`
+	IsFollowing bool  `json:"is_following"` // Whether the current user follows them
+}
+
+// Follow is a user in a list of followers or followed users
+type Follow struct {
+	User
+	FollowedAt time.Time `json:"followed_at"`
+}
@@ -0,0 +1,181 @@
+// Package events is an in-process publish/subscribe hub used to push
+// changes to connected clients over Server-Sent Events or WebSocket.
+package events
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Event types published by the API
+const (
+	PlaylistCreated = "playlist.created"
+	PlaylistUpdated = "playlist.updated"
+	SongProcessed   = "song.processed"
+	QueueUpdated    = "queue.updated"
+
+	// Listening party events, published on the room's topic with the full room
+	RoomPlayback = "room.playback"
+	RoomQueue    = "room.queue"
+	RoomMembers  = "room.members"
+	RoomHost     = "room.host"
+	RoomClosed   = "room.closed"
+
+	// Resync tells a reconnecting client that events were missed and it
+	// should refetch whatever state it keeps
+	Resync = "resync"
+)
+
+const (
+	// historySize is how many recent events are kept for Last-Event-ID replay
+	historySize = 1024
+
+	// subscriberBuffer is how far a subscriber may fall behind before it is dropped
+	subscriberBuffer = 64
+)
+
+// Event is a single message delivered to subscribers of its topic
+type Event struct {
+	ID    uint64    `json:"id"`
+	Type  string    `json:"type"`
+	Topic string    `json:"-"`
+	Data  any       `json:"data,omitempty"`
+	Time  time.Time `json:"time"`
+}
+
+// UserTopic returns the topic events for a single user are published on
+func UserTopic(userID uint) string {
+	return "user:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// RoomTopic returns the topic a listening party's events are published on
+func RoomTopic(code string) string {
+	return "room:" + code
+}
+
+// Subscription receives events for a set of topics until it is closed.
+// C is closed when the subscriber falls too far behind or is closed.
+type Subscription struct {
+	C <-chan Event
+
+	hub    *Hub
+	ch     chan Event
+	topics []string
+	once   sync.Once
+}
+
+// Close unsubscribes and releases the subscription
+func (s *Subscription) Close() {
+	s.hub.unsubscribe(s)
+}
+
+// Hub fans published events out to subscribers and keeps a short history
+type Hub struct {
+	mu      sync.Mutex
+	lastID  uint64
+	subs    map[string]map[*Subscription]struct{}
+	history []Event
+	next    int
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subs:    make(map[string]map[*Subscription]struct{}),
+		history: make([]Event, 0, historySize),
+	}
+}
+
+// Publish delivers an event to every subscriber of the topic
+func (h *Hub) Publish(topic, eventType string, data any) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	e := Event{ID: h.lastID, Type: eventType, Topic: topic, Data: data, Time: time.Now()}
+
+	if len(h.history) < historySize {
+		h.history = append(h.history, e)
+	} else {
+		h.history[h.next] = e
+		h.next = (h.next + 1) % historySize
+	}
+
+	for sub := range h.subs[topic] {
+		select {
+		case sub.ch <- e:
+		default:
+			// The client is not keeping up; dropping it makes it reconnect and replay
+			h.remove(sub)
+		}
+	}
+	return e
+}
+
+// Subscribe starts receiving events for the topics. When lastEventID is
+// non-zero the events published after it are returned for replay, or a single
+// Resync event if they are no longer in the history.
+func (h *Hub) Subscribe(topics []string, lastEventID uint64) (*Subscription, []Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan Event, subscriberBuffer)
+	sub := &Subscription{C: ch, hub: h, ch: ch, topics: topics}
+	for _, topic := range topics {
+		if h.subs[topic] == nil {
+			h.subs[topic] = make(map[*Subscription]struct{})
+		}
+		h.subs[topic][sub] = struct{}{}
+	}
+
+	if lastEventID == 0 || lastEventID == h.lastID {
+		return sub, nil
+	}
+
+	// An ID from the future means the server restarted and the history is gone
+	ordered := h.ordered()
+	if lastEventID > h.lastID || len(ordered) == 0 || ordered[0].ID > lastEventID+1 {
+		return sub, []Event{{ID: h.lastID, Type: Resync, Time: time.Now()}}
+	}
+
+	wanted := make(map[string]bool, len(topics))
+	for _, topic := range topics {
+		wanted[topic] = true
+	}
+	var replay []Event
+	for _, e := range ordered {
+		if e.ID > lastEventID && wanted[e.Topic] {
+			replay = append(replay, e)
+		}
+	}
+	return sub, replay
+}
+
+// ordered returns the history oldest first. The caller must hold the lock.
+func (h *Hub) ordered() []Event {
+	if len(h.history) < historySize {
+		return h.history
+	}
+	out := make([]Event, 0, historySize)
+	out = append(out, h.history[h.next:]...)
+	return append(out, h.history[:h.next]...)
+}
+
+func (h *Hub) unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(sub)
+}
+
+// remove detaches a subscriber and closes its channel. The caller must hold the lock.
+func (h *Hub) remove(sub *Subscription) {
+	sub.once.Do(func() {
+		for _, topic := range sub.topics {
+			delete(h.subs[topic], sub)
+			if len(h.subs[topic]) == 0 {
+				delete(h.subs, topic)
+			}
+		}
+		close(sub.ch)
+	})
+}
@@ -0,0 +1,203 @@
+// Package feed builds a user's activity feed from the public playlists,
+// uploads and favourites of the people they follow. Nothing is written at
+// activity time; the feed is assembled from the source tables when read.
+package feed
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"music-player-gin/internal/models"
+)
+
+// Kinds of feed item
+const (
+	KindPlaylist  = "playlist"
+	KindUpload    = "upload"
+	KindFavourite = "favourite"
+)
+
+// ErrInvalidCursor means a cursor could not be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Actor is the followed user behind a feed item
+type Actor struct {
+	ID          uint    `json:"id"`
+	Username    string  `json:"username"`
+	DisplayName string  `json:"display_name"`
+	AvatarURL   *string `json:"avatar_url"`
+}
+
+// Item is one entry in the feed. Playlist is set for playlist items and
+// Song for uploads and favourites.
+type Item struct {
+	Type      string           `json:"type"`
+	CreatedAt time.Time        `json:"created_at"`
+	Actor     Actor            `json:"actor"`
+	Playlist  *models.Playlist `json:"playlist,omitempty"`
+	Song      *models.Song     `json:"song,omitempty"`
+}
+
+// Cursor marks the position after the last item of a page. Items are ordered
+// by time and then by kind, item and actor so the order is total.
+type Cursor struct {
+	At      time.Time `json:"t"`
+	Kind    string    `json:"k"`
+	ItemID  uint      `json:"i"`
+	ActorID uint      `json:"a"`
+}
+
+// Encode renders the cursor as an opaque string for clients
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor returned by Encode
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Kind == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// activity is a feed entry before its playlist, song and actor are loaded
+type activity struct {
+	Kind    string
+	ItemID  uint
+	ActorID uint
+	At      time.Time
+}
+
+// activitySQL unions every kind of activity by the users the viewer follows
+const activitySQL = `
+SELECT 'playlist' AS kind, playlists.id AS item_id, playlists.user_id AS actor_id, playlists.created_at AS at
+FROM playlists
+WHERE playlists.user_id IN (@followees) AND playlists.is_public = @public AND playlists.deleted_at IS NULL
+UNION ALL
+SELECT 'upload', songs.id, songs.user_id, songs.created_at
+FROM songs
+WHERE songs.user_id IN (@followees) AND songs.deleted_at IS NULL
+UNION ALL
+SELECT 'favourite', user_favorite_songs.song_id, user_favorite_songs.user_id, user_favorite_songs.created_at
+FROM user_favorite_songs
+JOIN songs ON songs.id = user_favorite_songs.song_id AND songs.deleted_at IS NULL
+WHERE user_favorite_songs.user_id IN (@followees)`
+
+// Page returns up to limit items, newest first, starting after the cursor,
+// and the cursor for the next page, which is empty on the last page
+func Page(ctx context.Context, db *gorm.DB, userID uint, after *Cursor, limit int) ([]Item, string, error) {
+	db = db.WithContext(ctx)
+
+	followees := db.Model(&models.Follow{}).Select("followee_id").Where("follower_id = ?", userID)
+	query := db.Table("(?) AS activity", db.Raw(activitySQL, map[string]any{
+		"followees": followees,
+		"public":    true,
+	}))
+	if after != nil {
+		query = query.Where(
+			"at < ? OR (at = ? AND (kind < ? OR (kind = ? AND (item_id < ? OR (item_id = ? AND actor_id < ?)))))",
+			after.At, after.At, after.Kind, after.Kind, after.ItemID, after.ItemID, after.ActorID,
+		)
+	}
+
+	// One extra row tells whether there is another page
+	var rows []activity
+	err := query.Order("at DESC, kind DESC, item_id DESC, actor_id DESC").Limit(limit + 1).Scan(&rows).Error
+	if err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		next = Cursor{At: last.At, Kind: last.Kind, ItemID: last.ItemID, ActorID: last.ActorID}.Encode()
+	}
+
+	items, err := hydrate(db, rows)
+	if err != nil {
+		return nil, "", err
+	}
+	return items, next, nil
+}
+
+// hydrate loads the playlists, songs and users the activity refers to
+func hydrate(db *gorm.DB, rows []activity) ([]Item, error) {
+	var playlistIDs, songIDs, userIDs []uint
+	for _, r := range rows {
+		if r.Kind == KindPlaylist {
+			playlistIDs = append(playlistIDs, r.ItemID)
+		} else {
+			songIDs = append(songIDs, r.ItemID)
+		}
+		userIDs = append(userIDs, r.ActorID)
+	}
+
+	playlists := make(map[uint]*models.Playlist)
+	if len(playlistIDs) > 0 {
+		var found []models.Playlist
+		if err := db.Where("id IN ?", playlistIDs).Find(&found).Error; err != nil {
+			return nil, err
+		}
+		for i := range found {
+			playlists[found[i].ID] = &found[i]
+		}
+	}
+
+	songs := make(map[uint]*models.Song)
+	if len(songIDs) > 0 {
+		var found []models.Song
+		if err := db.Where("id IN ?", songIDs).Find(&found).Error; err != nil {
+			return nil, err
+		}
+		for i := range found {
+			songs[found[i].ID] = &found[i]
+		}
+	}
+
+	actors := make(map[uint]Actor)
+	if len(userIDs) > 0 {
+		var found []models.User
+		err := db.Select("id", "username", "display_name", "avatar_path", "updated_at").Where("id IN ?", userIDs).Find(&found).Error
+		if err != nil {
+			return nil, err
+		}
+		for i := range found {
+			u := &found[i]
+			actors[u.ID] = Actor{ID: u.ID, Username: u.Username, DisplayName: u.DisplayName, AvatarURL: u.AvatarURL()}
+		}
+	}
+
+	items := make([]Item, 0, len(rows))
+	for _, r := range rows {
+		actor, ok := actors[r.ActorID]
+		if !ok {
+			continue
+		}
+		item := Item{Type: r.Kind, CreatedAt: r.At, Actor: actor}
+		if r.Kind == KindPlaylist {
+			item.Playlist = playlists[r.ItemID]
+			if item.Playlist == nil {
+				continue
+			}
+		} else {
+			item.Song = songs[r.ItemID]
+			if item.Song == nil {
+				continue
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
@@ -0,0 +1,420 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+
+	"music-player-gin/internal/logging"
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/tracing"
+)
+
+const (
+	defaultMaxAttempts  = 5
+	defaultPollInterval = 2 * time.Second
+	baseBackoff         = 5 * time.Second
+	maxBackoff          = 10 * time.Minute
+)
+
+// Handler processes a single job. Returning an error schedules a retry
+// unless the error was wrapped with Permanent or the job is out of attempts.
+type Handler func(ctx context.Context, job *models.Job) error
+
+// permanentError marks a failure that retrying will not fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the queue fails the job without retrying it
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Queue is an in-process job queue backed by the jobs table
+type Queue struct {
+	db       *gorm.DB
+	workers  int
+	handlers map[string]Handler
+
+	wake chan struct{}
+	wg   sync.WaitGroup
+	stop context.CancelFunc
+
+	onSongStatus func(songID uint, status string)
+}
+
+func NewQueue(db *gorm.DB, workers int) *Queue {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Queue{
+		db:       db,
+		workers:  workers,
+		handlers: make(map[string]Handler),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Register sets the handler for a job type. It must be called before Start.
+func (q *Queue) Register(jobType string, h Handler) {
+	q.handlers[jobType] = h
+}
+
+// OnSongStatus sets a callback run whenever a song's processing status
+// changes as a result of one of its jobs finishing
+func (q *Queue) OnSongStatus(fn func(songID uint, status string)) {
+	q.onSongStatus = fn
+}
+
+// Handles reports whether a handler is registered for the job type
+func (q *Queue) Handles(jobType string) bool {
+	_, ok := q.handlers[jobType]
+	return ok
+}
+
+// Enqueue persists a new job and wakes the dispatcher
+func (q *Queue) Enqueue(jobType string, songID *uint, payload any) (*models.Job, error) {
+	job, err := q.enqueue(q.db, jobType, songID, payload)
+	if err != nil {
+		return nil, err
+	}
+	q.Notify()
+	return job, nil
+}
+
+// EnqueueTx persists a new job as part of an existing transaction. The
+// dispatcher cannot see the job until the transaction commits, so call Notify
+// afterwards rather than waiting for the next poll.
+func (q *Queue) EnqueueTx(tx *gorm.DB, jobType string, songID *uint, payload any) (*models.Job, error) {
+	return q.enqueue(tx, jobType, songID, payload)
+}
+
+// EnqueueOnce is Enqueue unless the same job is already waiting to run, in
+// which case that job is returned instead. Handlers use it for follow-up
+// work so that a retried job does not queue the work twice.
+func (q *Queue) EnqueueOnce(jobType string, songID *uint, payload any) (*models.Job, error) {
+	job, err := q.newJob(jobType, songID, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	created := false
+	err = q.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("type = ? AND status = ? AND payload = ?", job.Type, models.JobPending, job.Payload)
+		if songID != nil {
+			query = query.Where("song_id = ?", *songID)
+		} else {
+			query = query.Where("song_id IS NULL")
+		}
+		err := query.First(job).Error
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		created = true
+		return tx.Create(job).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		q.Notify()
+	}
+	return job, nil
+}
+
+func (q *Queue) enqueue(db *gorm.DB, jobType string, songID *uint, payload any) (*models.Job, error) {
+	job, err := q.newJob(jobType, songID, payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Create(job).Error; err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (q *Queue) newJob(jobType string, songID *uint, payload any) (*models.Job, error) {
+	if !q.Handles(jobType) {
+		return nil, fmt.Errorf("no handler registered for job type %q", jobType)
+	}
+
+	job := &models.Job{
+		Type:        jobType,
+		SongID:      songID,
+		Status:      models.JobPending,
+		MaxAttempts: defaultMaxAttempts,
+		RunAt:       time.Now(),
+	}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		job.Payload = string(data)
+	}
+	return job, nil
+}
+
+// Notify wakes the dispatcher to look for due jobs
+func (q *Queue) Notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start recovers jobs interrupted by a previous shutdown and launches the workers
+func (q *Queue) Start(ctx context.Context) error {
+	// Anything still marked running was cut off mid-flight, so hand it back to the queue
+	err := q.db.Model(&models.Job{}).
+		Where("status = ?", models.JobRunning).
+		Updates(map[string]any{"status": models.JobPending, "run_at": time.Now()}).Error
+	if err != nil {
+		return err
+	}
+
+	ctx, q.stop = context.WithCancel(ctx)
+	jobs := make(chan *models.Job)
+
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			for job := range jobs {
+				q.run(ctx, job)
+			}
+		}()
+	}
+
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		defer close(jobs)
+		q.dispatch(ctx, jobs)
+	}()
+
+	return nil
+}
+
+// Stop signals the workers to finish and waits for in-flight jobs
+func (q *Queue) Stop() {
+	if q.stop != nil {
+		q.stop()
+	}
+	q.wg.Wait()
+}
+
+// dispatch claims due jobs one at a time so only a single goroutine writes claims
+func (q *Queue) dispatch(ctx context.Context, jobs chan<- *models.Job) {
+	ticker := time.NewTicker(defaultPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			job, err := q.claim()
+			if err != nil {
+				slog.Error("failed to claim job", "component", "jobs", "err", err)
+				break
+			}
+			if job == nil {
+				break
+			}
+			select {
+			case jobs <- job:
+			case <-ctx.Done():
+				// Release the claim, and the attempt it took, so the job is picked up on next start
+				q.db.Model(job).Updates(map[string]any{"status": models.JobPending, "attempts": gorm.Expr("attempts - 1")})
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+func (q *Queue) claim() (*models.Job, error) {
+	var job models.Job
+	err := q.db.Where("status = ? AND run_at <= ?", models.JobPending, time.Now()).
+		Order("run_at, id").
+		First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	res := q.db.Model(&models.Job{}).
+		Where("id = ? AND status = ?", job.ID, models.JobPending).
+		Updates(map[string]any{
+			"status":     models.JobRunning,
+			"attempts":   gorm.Expr("attempts + 1"),
+			"started_at": now,
+		})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		// Someone else got there first; the next loop iteration will look again
+		return q.claim()
+	}
+
+	job.Status = models.JobRunning
+	job.Attempts++
+	job.StartedAt = &now
+	return &job, nil
+}
+
+func (q *Queue) run(ctx context.Context, job *models.Job) {
+	handler := q.handlers[job.Type]
+	ctx, span := tracing.Start(ctx, "job "+job.Type, trace.WithAttributes(
+		attribute.Int64("job.id", int64(job.ID)),
+		attribute.String("job.type", job.Type),
+		attribute.Int("job.attempt", job.Attempts),
+	))
+	defer span.End()
+	ctx = logging.With(ctx, "component", "jobs", "job_id", job.ID, "job_type", job.Type)
+	if sc := span.SpanContext(); sc.IsValid() {
+		ctx = logging.With(ctx, "trace_id", sc.TraceID().String())
+	}
+	logger := logging.FromContext(ctx)
+
+	var err error
+	if handler == nil {
+		err = Permanent(fmt.Errorf("no handler registered for job type %q", job.Type))
+	} else {
+		err = safeCall(ctx, handler, job)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	now := time.Now()
+	updates := map[string]any{"finished_at": now}
+
+	var permanent *permanentError
+	switch {
+	case err != nil && ctx.Err() != nil:
+		// Interrupted by shutdown, which should not count against the job
+		updates["status"] = models.JobPending
+		updates["attempts"] = gorm.Expr("attempts - 1")
+		updates["run_at"] = now
+	case err == nil:
+		updates["status"] = models.JobSucceeded
+		updates["last_error"] = ""
+	case errors.As(err, &permanent) || job.Attempts >= job.MaxAttempts:
+		logger.Error("job failed", "attempt", job.Attempts, "err", err)
+		updates["status"] = models.JobFailed
+		updates["last_error"] = err.Error()
+	default:
+		delay := backoff(job.Attempts)
+		logger.Warn("job attempt failed, retrying", "attempt", job.Attempts, "retry_in", delay.String(), "err", err)
+		updates["status"] = models.JobPending
+		updates["last_error"] = err.Error()
+		updates["run_at"] = now.Add(delay)
+	}
+
+	if err := q.db.Model(job).Updates(updates).Error; err != nil {
+		logger.Error("failed to record job result", "err", err)
+		return
+	}
+
+	if job.SongID != nil {
+		status, changed, err := UpdateSongStatus(q.db, *job.SongID)
+		if err != nil {
+			logger.Error("failed to update song processing status", "song_id", *job.SongID, "err", err)
+		} else if changed && q.onSongStatus != nil {
+			q.onSongStatus(*job.SongID, status)
+		}
+	}
+}
+
+// safeCall runs the handler, turning a panic into a permanent failure
+func safeCall(ctx context.Context, h Handler, job *models.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = Permanent(fmt.Errorf("panic: %v", r))
+		}
+	}()
+	return h(ctx, job)
+}
+
+// backoff returns an exponential delay with jitter for the given attempt number
+func backoff(attempt int) time.Duration {
+	d := baseBackoff << (attempt - 1)
+	if d <= 0 || d > maxBackoff {
+		d = maxBackoff
+	}
+	jitter := time.Duration(rand.Int63n(int64(d) / 4))
+	return d - d/8 + jitter
+}
+
+// UpdateSongStatus derives a song's processing status from the state of its
+// jobs and reports whether it differs from the status stored before
+func UpdateSongStatus(db *gorm.DB, songID uint) (string, bool, error) {
+	var counts []struct {
+		Status string
+		Count  int64
+	}
+	err := db.Model(&models.Job{}).
+		Select("status, count(*) as count").
+		Where("song_id = ?", songID).
+		Group("status").
+		Scan(&counts).Error
+	if err != nil {
+		return "", false, err
+	}
+
+	byStatus := make(map[string]int64)
+	for _, c := range counts {
+		byStatus[c.Status] = c.Count
+	}
+
+	status := models.ProcessingReady
+	switch {
+	case byStatus[models.JobPending] > 0 || byStatus[models.JobRunning] > 0:
+		status = models.ProcessingInProgress
+	case byStatus[models.JobFailed] > 0:
+		status = models.ProcessingFailed
+	}
+
+	res := db.Model(&models.Song{}).
+		Where("id = ? AND processing_status <> ?", songID, status).
+		Update("processing_status", status)
+	if res.Error != nil {
+		return "", false, res.Error
+	}
+	return status, res.RowsAffected > 0, nil
+}
+
+// DecodePayload unmarshals a job's JSON payload into v
+func DecodePayload(job *models.Job, v any) error {
+	if job.Payload == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(job.Payload), v); err != nil {
+		return Permanent(fmt.Errorf("invalid payload: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"music-player-gin/internal/models"
+)
+
+func newTestQueue(t *testing.T) *Queue {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared&_pragma=busy_timeout(5000)"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Job{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	q := NewQueue(db, 1)
+	q.Register("test", func(context.Context, *models.Job) error { return nil })
+	return q
+}
+
+func TestEnqueueOnce(t *testing.T) {
+	q := newTestQueue(t)
+	songID := uint(7)
+
+	first, err := q.EnqueueOnce("test", &songID, map[string]uint{"song_id": songID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := q.EnqueueOnce("test", &songID, map[string]uint{"song_id": songID})
+	if err != nil || again.ID != first.ID {
+		t.Errorf("second enqueue = %+v, %v, want job %d", again, err, first.ID)
+	}
+
+	// Different arguments and jobs that already ran do not count
+	other, _ := q.EnqueueOnce("test", nil, map[string]uint{"song_id": songID})
+	if other.ID == first.ID {
+		t.Error("job without a song matched one with a song")
+	}
+	q.db.Model(first).Update("status", models.JobSucceeded)
+	if rerun, _ := q.EnqueueOnce("test", &songID, map[string]uint{"song_id": songID}); rerun.ID == first.ID {
+		t.Error("finished job reused")
+	}
+
+	var count int64
+	q.db.Model(&models.Job{}).Count(&count)
+	if count != 3 {
+		t.Errorf("%d jobs stored, want 3", count)
+	}
+}
+
+func TestEnqueueTxWaitsForCommit(t *testing.T) {
+	q := newTestQueue(t)
+
+	err := q.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := q.EnqueueTx(tx, "test", nil, nil); err != nil {
+			return err
+		}
+		// Woken now, the dispatcher would look before the job is visible
+		select {
+		case <-q.wake:
+			t.Error("dispatcher woken inside the transaction")
+		default:
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestShutdownDoesNotCountAsAttempt(t *testing.T) {
+	q := newTestQueue(t)
+	started := make(chan struct{})
+	q.Register("slow", func(ctx context.Context, job *models.Job) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	job, err := q.Enqueue("slow", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job did not start")
+	}
+	q.Stop()
+
+	var stored models.Job
+	q.db.First(&stored, job.ID)
+	if stored.Status != models.JobPending || stored.Attempts != 0 {
+		t.Errorf("interrupted job is %s after %d attempts", stored.Status, stored.Attempts)
+	}
+}
@@ -0,0 +1,206 @@
+// Package jwtauth signs and verifies the server's JWTs with asymmetric keys.
+// Keys live in the database so every server instance shares them. They are
+// rotated on a schedule, and each is published at /.well-known/jwks.json
+// before first use and for as long as tokens signed with it can be valid.
+// That lets other services verify tokens without sharing a secret. The
+// private keys are stored encrypted with a key kept outside the database.
+package jwtauth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	// TokenTTL is how long user tokens stay valid
+	TokenTTL = 24 * time.Hour
+
+	// clockSkew is the leeway allowed when checking token times
+	clockSkew = 30 * time.Second
+)
+
+// Signing algorithms
+const (
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+// ErrInvalidToken wraps every reason a token is refused
+var ErrInvalidToken = errors.New("invalid token")
+
+// Config chooses how tokens are signed and what they are checked for
+type Config struct {
+	Algorithm string        // RS256 or EdDSA
+	Issuer    string        // iss of every token
+	Audience  string        // aud of user tokens
+	Rotation  time.Duration // How long each key signs before the next takes over
+
+	// EncryptionKey is the 32 byte AES-256 key private keys are stored under
+	EncryptionKey []byte
+}
+
+// ConfigFromEnv reads the JWT_* environment variables
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Algorithm: os.Getenv("JWT_ALGORITHM"),
+		Issuer:    os.Getenv("JWT_ISSUER"),
+		Audience:  os.Getenv("JWT_AUDIENCE"),
+		Rotation:  30 * 24 * time.Hour,
+	}
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = RS256
+	}
+	if cfg.Algorithm != RS256 && cfg.Algorithm != EdDSA {
+		return Config{}, fmt.Errorf("JWT_ALGORITHM must be %s or %s", RS256, EdDSA)
+	}
+	if cfg.Issuer == "" {
+		cfg.Issuer = "gomusic"
+	}
+	if cfg.Audience == "" {
+		cfg.Audience = "gomusic-api"
+	}
+	if value := os.Getenv("JWT_KEY_ROTATION"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 10*time.Minute {
+			return Config{}, errors.New("JWT_KEY_ROTATION must be a duration of at least 10m")
+		}
+		cfg.Rotation = d
+	}
+
+	encoded := os.Getenv("JWT_KEY_ENCRYPTION_KEY")
+	if encoded == "" {
+		return Config{}, errors.New("JWT_KEY_ENCRYPTION_KEY is required, generate one with: openssl rand -base64 32")
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		return Config{}, errors.New("JWT_KEY_ENCRYPTION_KEY must be 32 bytes, base64 encoded")
+	}
+	cfg.EncryptionKey = key
+	return cfg, nil
+}
+
+// Claims are the claims of a user token. Subject repeats UserID as a string
+// for services that only look at standard claims.
+type Claims struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	jwt.RegisteredClaims
+}
+
+// KeyRing holds the signing keys and issues and checks tokens with them
+type KeyRing struct {
+	db   *gorm.DB
+	cfg  Config
+	aead cipher.AEAD // Seals the private keys in the database
+	keys keyCache
+	now  func() time.Time
+}
+
+// NewKeyRing loads the keys, creating the first one if there is none
+func NewKeyRing(db *gorm.DB, cfg Config) (*KeyRing, error) {
+	return newKeyRing(db, cfg, time.Now)
+}
+
+// newKeyRing is NewKeyRing with a clock tests can move
+func newKeyRing(db *gorm.DB, cfg Config, now func() time.Time) (*KeyRing, error) {
+	if len(cfg.EncryptionKey) != 32 {
+		return nil, errors.New("jwtauth: the encryption key must be 32 bytes")
+	}
+	block, err := aes.NewCipher(cfg.EncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("jwtauth: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("jwtauth: %w", err)
+	}
+
+	r := &KeyRing{db: db, cfg: cfg, aead: aead, now: now}
+	if err := r.sealStored(); err != nil {
+		return nil, fmt.Errorf("jwtauth: %w", err)
+	}
+	if err := r.rotate(now()); err != nil {
+		return nil, fmt.Errorf("jwtauth: %w", err)
+	}
+	return r, nil
+}
+
+// Registered returns the standard claims for a token issued now for the
+// audience and valid for ttl, which must not be longer than TokenTTL
+func (r *KeyRing) Registered(audience string, ttl time.Duration) jwt.RegisteredClaims {
+	now := r.now()
+	return jwt.RegisteredClaims{
+		Issuer:    r.cfg.Issuer,
+		Audience:  jwt.ClaimStrings{audience},
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		NotBefore: jwt.NewNumericDate(now),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}
+}
+
+// IssueUserToken signs a token for the user
+func (r *KeyRing) IssueUserToken(userID uint, username string) (string, error) {
+	claims := Claims{UserID: userID, Username: username, RegisteredClaims: r.Registered(r.cfg.Audience, TokenTTL)}
+	claims.Subject = strconv.FormatUint(uint64(userID), 10)
+	return r.Sign(claims)
+}
+
+// ParseUserToken verifies a user token
+func (r *KeyRing) ParseUserToken(raw string) (*Claims, error) {
+	var claims Claims
+	if err := r.Parse(raw, &claims, r.cfg.Audience); err != nil {
+		return nil, err
+	}
+	if claims.UserID == 0 {
+		return nil, fmt.Errorf("%w: no user", ErrInvalidToken)
+	}
+	return &claims, nil
+}
+
+// Sign signs claims with the current key. Build their standard claims with
+// Registered so old keys stay published for as long as the token is valid.
+func (r *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	k := r.keys.signing(r.now())
+	if k == nil {
+		return "", errors.New("jwtauth: no signing key")
+	}
+	token := jwt.NewWithClaims(k.method, claims)
+	token.Header["kid"] = k.id
+	return token.SignedString(k.private)
+}
+
+// Parse verifies a token's signature, issuer, audience and times into claims
+func (r *KeyRing) Parse(raw string, claims jwt.Claims, audience string) error {
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		k := r.verificationKey(kid)
+		if k == nil {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		if t.Method.Alg() != k.method.Alg() {
+			return nil, errors.New("algorithm does not match key")
+		}
+		return k.public, nil
+	},
+		jwt.WithValidMethods([]string{RS256, EdDSA}),
+		jwt.WithIssuer(r.cfg.Issuer),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+		jwt.WithTimeFunc(r.now),
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return nil
+}
@@ -0,0 +1,317 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+
+	"music-player-gin/internal/jwk"
+	"music-player-gin/internal/models"
+)
+
+// keyReloadInterval is the least time between reloads caused by tokens
+// naming an unknown key, which may have been created by another instance
+const keyReloadInterval = 10 * time.Second
+
+// key is a signing key ready for use
+type key struct {
+	id          string
+	method      jwt.SigningMethod
+	private     crypto.Signer
+	public      crypto.PublicKey
+	activatesAt time.Time
+	expiresAt   *time.Time
+}
+
+func (k *key) validAt(now time.Time) bool {
+	return k.expiresAt == nil || now.Before(*k.expiresAt)
+}
+
+// keyCache is the in-memory copy of the keys table
+type keyCache struct {
+	mu         sync.RWMutex
+	keys       []*key // Ordered by activation time
+	reloadedAt time.Time
+}
+
+// signing returns the most recently activated key
+func (c *keyCache) signing(now time.Time) *key {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for i := len(c.keys) - 1; i >= 0; i-- {
+		if k := c.keys[i]; !k.activatesAt.After(now) && k.validAt(now) {
+			return k
+		}
+	}
+	return nil
+}
+
+func (c *keyCache) find(kid string, now time.Time) *key {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, k := range c.keys {
+		if k.id == kid && k.validAt(now) {
+			return k
+		}
+	}
+	return nil
+}
+
+// verificationKey finds the key a token was signed with, reloading the keys
+// if it is not known yet
+func (r *KeyRing) verificationKey(kid string) *key {
+	now := r.now()
+	if k := r.keys.find(kid, now); k != nil {
+		return k
+	}
+	r.keys.mu.RLock()
+	stale := now.Sub(r.keys.reloadedAt) > keyReloadInterval
+	r.keys.mu.RUnlock()
+	if !stale {
+		return nil
+	}
+	if err := r.reload(now); err != nil {
+		slog.Error("failed to reload signing keys", "component", "jwtauth", "err", err)
+		return nil
+	}
+	return r.keys.find(kid, now)
+}
+
+// JWKS returns the public half of every key that is or will be in use
+func (r *KeyRing) JWKS() jwk.Set {
+	now := r.now()
+	r.keys.mu.RLock()
+	defer r.keys.mu.RUnlock()
+	set := jwk.Set{Keys: []jwk.Key{}}
+	for _, k := range r.keys.keys {
+		if !k.validAt(now) {
+			continue
+		}
+		if pub, err := jwk.FromPublicKey(k.id, k.method.Alg(), k.public); err == nil {
+			set.Keys = append(set.Keys, pub)
+		}
+	}
+	return set
+}
+
+// Schedule rotates keys in the background until ctx is done
+func (r *KeyRing) Schedule(ctx context.Context) {
+	interval := min(10*time.Minute, r.prepublish()/2)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.rotate(r.now()); err != nil {
+					slog.Error("signing key rotation failed", "component", "jwtauth", "err", err)
+				}
+			}
+		}
+	}()
+}
+
+// prepublish is how long a new key is published before it starts signing,
+// giving services that cache the key set time to pick it up
+func (r *KeyRing) prepublish() time.Duration {
+	return min(time.Hour, r.cfg.Rotation/4)
+}
+
+// rotate creates the next key once the current one has signed for nearly
+// its rotation period, and drops keys no valid token can use any more
+func (r *KeyRing) rotate(now time.Time) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at <= ?", now).Delete(&models.SigningKey{}).Error; err != nil {
+			return err
+		}
+		var rows []models.SigningKey
+		if err := tx.Order("activates_at").Find(&rows).Error; err != nil {
+			return err
+		}
+
+		// The current key is the one activated last; a pending one is published but not yet signing
+		var current *models.SigningKey
+		pending := false
+		for i := range rows {
+			if rows[i].ActivatesAt.After(now) {
+				pending = true
+			} else {
+				current = &rows[i]
+			}
+		}
+
+		switch {
+		case current == nil || current.Algorithm != r.cfg.Algorithm:
+			// Nothing to sign with, or the algorithm changed: start a key right away.
+			// Pending keys never signed anything and would bring the old algorithm back.
+			if err := tx.Where("activates_at > ?", now).Delete(&models.SigningKey{}).Error; err != nil {
+				return err
+			}
+			if err := r.createKey(tx, now); err != nil {
+				return err
+			}
+			if current != nil {
+				if err := supersede(tx, current, now); err != nil {
+					return err
+				}
+			}
+		case !pending && !now.Before(current.ActivatesAt.Add(r.cfg.Rotation-r.prepublish())):
+			activatesAt := current.ActivatesAt.Add(r.cfg.Rotation)
+			if earliest := now.Add(r.prepublish()); activatesAt.Before(earliest) {
+				activatesAt = earliest
+			}
+			if err := r.createKey(tx, activatesAt); err != nil {
+				return err
+			}
+			if err := supersede(tx, current, activatesAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return r.reload(now)
+}
+
+// supersede lets a key verify tokens for as long as the last one it signs
+// before at can be valid
+func supersede(tx *gorm.DB, k *models.SigningKey, at time.Time) error {
+	return tx.Model(k).Update("expires_at", at.Add(TokenTTL+clockSkew)).Error
+}
+
+// createKey generates a key that starts signing at activatesAt
+func (r *KeyRing) createKey(tx *gorm.DB, activatesAt time.Time) error {
+	var private crypto.Signer
+	var err error
+	switch r.cfg.Algorithm {
+	case RS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case EdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		err = fmt.Errorf("unsupported algorithm %q", r.cfg.Algorithm)
+	}
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return err
+	}
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	kid := base64.RawURLEncoding.EncodeToString(id)
+	sealed, err := r.seal(kid, der)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&models.SigningKey{
+		ID:          kid,
+		Algorithm:   r.cfg.Algorithm,
+		PrivateKey:  sealed,
+		Sealed:      true,
+		ActivatesAt: activatesAt,
+	}).Error
+}
+
+// seal encrypts a private key for storage. The key ID is authenticated with
+// it, so a sealed key cannot be passed off as another row's.
+func (r *KeyRing) seal(kid string, der []byte) ([]byte, error) {
+	nonce := make([]byte, r.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return r.aead.Seal(nonce, nonce, der, []byte(kid)), nil
+}
+
+// open decrypts a private key sealed by seal
+func (r *KeyRing) open(kid string, sealed []byte) ([]byte, error) {
+	if len(sealed) < r.aead.NonceSize() {
+		return nil, errors.New("sealed key is too short")
+	}
+	nonce, ciphertext := sealed[:r.aead.NonceSize()], sealed[r.aead.NonceSize():]
+	der, err := r.aead.Open(nil, nonce, ciphertext, []byte(kid))
+	if err != nil {
+		return nil, errors.New("cannot decrypt, JWT_KEY_ENCRYPTION_KEY may have changed")
+	}
+	return der, nil
+}
+
+// sealStored encrypts keys stored before keys were encrypted
+func (r *KeyRing) sealStored() error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var rows []models.SigningKey
+		if err := tx.Where("sealed = ?", false).Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			sealed, err := r.seal(row.ID, row.PrivateKey)
+			if err != nil {
+				return err
+			}
+			err = tx.Model(&row).Updates(map[string]any{"private_key": sealed, "sealed": true}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// reload replaces the cached keys with those in the database
+func (r *KeyRing) reload(now time.Time) error {
+	var rows []models.SigningKey
+	err := r.db.Where("expires_at IS NULL OR expires_at > ?", now).Order("activates_at").Find(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	keys := make([]*key, 0, len(rows))
+	for _, row := range rows {
+		der, err := r.open(row.ID, row.PrivateKey)
+		if err != nil {
+			return fmt.Errorf("key %s: %w", row.ID, err)
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return fmt.Errorf("key %s: %w", row.ID, err)
+		}
+		private, ok := parsed.(crypto.Signer)
+		method := jwt.GetSigningMethod(row.Algorithm)
+		if !ok || method == nil {
+			return fmt.Errorf("key %s: unsupported algorithm %q", row.ID, row.Algorithm)
+		}
+		keys = append(keys, &key{
+			id:          row.ID,
+			method:      method,
+			private:     private,
+			public:      private.Public(),
+			activatesAt: row.ActivatesAt,
+			expiresAt:   row.ExpiresAt,
+		})
+	}
+
+	r.keys.mu.Lock()
+	r.keys.keys = keys
+	r.keys.reloadedAt = r.now()
+	r.keys.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,236 @@
+package jwtauth
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"music-player-gin/internal/models"
+)
+
+// clock is a time source tests move by hand
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time          { return c.now }
+func (c *clock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func testConfig(algorithm string) Config {
+	return Config{
+		Algorithm:     algorithm,
+		Issuer:        "gomusic",
+		Audience:      "gomusic-api",
+		Rotation:      48 * time.Hour,
+		EncryptionKey: bytes.Repeat([]byte{1}, 32),
+	}
+}
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.SigningKey{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func newTestRing(t *testing.T, db *gorm.DB, cfg Config, c *clock) *KeyRing {
+	t.Helper()
+	r, err := newKeyRing(db, cfg, c.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// kidOf returns the key a token names in its header
+func kidOf(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+func published(r *KeyRing) []string {
+	var kids []string
+	for _, k := range r.JWKS().Keys {
+		kids = append(kids, k.Kid)
+	}
+	return kids
+}
+
+func issue(t *testing.T, r *KeyRing) string {
+	t.Helper()
+	token, err := r.IssueUserToken(1, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestRotation(t *testing.T) {
+	c := &clock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cfg := testConfig(EdDSA)
+	r := newTestRing(t, openTestDB(t), cfg, c)
+	first := kidOf(t, issue(t, r))
+	if kids := published(r); len(kids) != 1 || kids[0] != first {
+		t.Fatalf("published %v, want only the signing key %s", kids, first)
+	}
+
+	// Nothing happens until the next key is due to be published
+	c.Advance(cfg.Rotation - r.prepublish() - time.Minute)
+	if err := r.rotate(c.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if kids := published(r); len(kids) != 1 {
+		t.Errorf("published %v before the next key is due", kids)
+	}
+
+	// The next key is published ahead of signing anything
+	c.Advance(time.Minute)
+	for i := 0; i < 2; i++ {
+		if err := r.rotate(c.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	kids := published(r)
+	if len(kids) != 2 {
+		t.Fatalf("published %v, want the current key and one pending", kids)
+	}
+	next := kids[1]
+	old := issue(t, r)
+	if kidOf(t, old) != first {
+		t.Error("the pending key signed before its time")
+	}
+
+	// Once it takes over, tokens from the old key still verify
+	c.Advance(r.prepublish())
+	if got := kidOf(t, issue(t, r)); got != next {
+		t.Errorf("signed with %s after the switch, want %s", got, next)
+	}
+	if _, err := r.ParseUserToken(old); err != nil {
+		t.Errorf("token from the previous key refused: %v", err)
+	}
+
+	// and the old key goes once no token it signed can still be valid
+	c.Advance(TokenTTL + clockSkew + time.Second)
+	if err := r.rotate(c.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if kids := published(r); len(kids) != 1 || kids[0] != next {
+		t.Errorf("published %v after the old key expired, want only %s", kids, next)
+	}
+	var count int64
+	r.db.Model(&models.SigningKey{}).Where("id = ?", first).Count(&count)
+	if count != 0 {
+		t.Error("expired key left in the database")
+	}
+}
+
+func TestAlgorithmChangeSupersedes(t *testing.T) {
+	c := &clock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	db := openTestDB(t)
+	ed := newTestRing(t, db, testConfig(EdDSA), c)
+	c.Advance(ed.cfg.Rotation - ed.prepublish())
+	if err := ed.rotate(c.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if kids := published(ed); len(kids) != 2 {
+		t.Fatalf("published %v, want a pending key", kids)
+	}
+	old := issue(t, ed)
+
+	// The new algorithm signs at once; the pending key never signed anything
+	// and goes, while the current one stays for the tokens it signed
+	rs := newTestRing(t, db, testConfig(RS256), c)
+	token := issue(t, rs)
+	if parsed, _, _ := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{}); parsed.Method.Alg() != RS256 {
+		t.Errorf("signed with %s after switching to RS256", parsed.Method.Alg())
+	}
+	kids := published(rs)
+	if len(kids) != 2 || kids[0] != kidOf(t, old) || kids[1] != kidOf(t, token) {
+		t.Errorf("published %v, want the superseded key then the new one", kids)
+	}
+	if _, err := rs.ParseUserToken(old); err != nil {
+		t.Errorf("token from the superseded key refused: %v", err)
+	}
+
+	var superseded models.SigningKey
+	db.First(&superseded, "id = ?", kidOf(t, old))
+	if want := c.Now().Add(TokenTTL + clockSkew); superseded.ExpiresAt == nil || !superseded.ExpiresAt.Equal(want) {
+		t.Errorf("superseded key expires at %v, want %v", superseded.ExpiresAt, want)
+	}
+}
+
+func TestKeysEncryptedAtRest(t *testing.T) {
+	c := &clock{now: time.Now()}
+	db := openTestDB(t)
+	r := newTestRing(t, db, testConfig(EdDSA), c)
+
+	var row models.SigningKey
+	db.First(&row)
+	if !row.Sealed {
+		t.Error("new key is not marked sealed")
+	}
+	if _, err := x509.ParsePKCS8PrivateKey(row.PrivateKey); err == nil {
+		t.Error("private key is stored in the clear")
+	}
+
+	// Without the right encryption key the keys cannot be loaded
+	wrong := testConfig(EdDSA)
+	wrong.EncryptionKey = bytes.Repeat([]byte{2}, 32)
+	if _, err := newKeyRing(db, wrong, c.Now); err == nil {
+		t.Error("keys loaded with the wrong encryption key")
+	}
+
+	// A sealed key only opens under its own ID
+	if _, err := r.open("another-id", row.PrivateKey); err == nil {
+		t.Error("sealed key opened under another key ID")
+	}
+}
+
+func TestPlainKeysAreSealed(t *testing.T) {
+	c := &clock{now: time.Now()}
+	db := openTestDB(t)
+
+	// A key stored before keys were encrypted
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&models.SigningKey{ID: "legacy", Algorithm: EdDSA, PrivateKey: der, ActivatesAt: c.Now().Add(-time.Hour)})
+
+	r := newTestRing(t, db, testConfig(EdDSA), c)
+	var row models.SigningKey
+	db.First(&row, "id = ?", "legacy")
+	if !row.Sealed || bytes.Equal(row.PrivateKey, der) {
+		t.Error("stored key was not sealed")
+	}
+	if got := kidOf(t, issue(t, r)); got != "legacy" {
+		t.Errorf("signed with %s, want the existing key", got)
+	}
+}
@@ -0,0 +1,86 @@
+// Package logging sets up the server's structured logger and carries
+// per-request loggers through contexts, so everything logged while handling a
+// request can be tied back to it by its request ID.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Config chooses how much is logged and in which format
+type Config struct {
+	Level  slog.Level
+	Format string // json or text
+}
+
+// ConfigFromEnv reads LOG_LEVEL (debug, info, warn or error; info by default)
+// and LOG_FORMAT (json or text; json by default)
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{Format: "json"}
+	if value := os.Getenv("LOG_LEVEL"); value != "" {
+		if err := cfg.Level.UnmarshalText([]byte(value)); err != nil {
+			return Config{}, fmt.Errorf("invalid LOG_LEVEL %q, use debug, info, warn or error", value)
+		}
+	}
+	if value := strings.ToLower(os.Getenv("LOG_FORMAT")); value != "" {
+		if value != "json" && value != "text" {
+			return Config{}, fmt.Errorf("invalid LOG_FORMAT %q, use json or text", value)
+		}
+		cfg.Format = value
+	}
+	return cfg, nil
+}
+
+// New returns a logger writing to w. Attributes named like credentials are
+// redacted wherever they appear.
+func New(w io.Writer, cfg Config) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: cfg.Level, ReplaceAttr: redact}
+	if cfg.Format == "text" {
+		return slog.New(slog.NewTextHandler(w, opts))
+	}
+	return slog.New(slog.NewJSONHandler(w, opts))
+}
+
+// secretKeys are attribute keys whose values are never written
+var secretKeys = map[string]bool{
+	"password":      true,
+	"token":         true,
+	"access_token":  true,
+	"authorization": true,
+	"secret":        true,
+	"client_secret": true,
+	"cookie":        true,
+}
+
+func redact(_ []string, a slog.Attr) slog.Attr {
+	if secretKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, "[REDACTED]")
+	}
+	return a
+}
+
+type contextKey struct{}
+
+// WithLogger returns a context carrying logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger stored in ctx, or the default logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// With returns a context whose logger adds the given attributes, such as the
+// user a request was authenticated as
+func With(ctx context.Context, args ...any) context.Context {
+	return WithLogger(ctx, FromContext(ctx).With(args...))
+}
@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "")
+	t.Setenv("LOG_FORMAT", "")
+	cfg, err := ConfigFromEnv()
+	if err != nil || cfg.Level != slog.LevelInfo || cfg.Format != "json" {
+		t.Errorf("defaults = %+v, %v", cfg, err)
+	}
+
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("LOG_FORMAT", "TEXT")
+	cfg, err = ConfigFromEnv()
+	if err != nil || cfg.Level != slog.LevelDebug || cfg.Format != "text" {
+		t.Errorf("config = %+v, %v", cfg, err)
+	}
+
+	t.Setenv("LOG_LEVEL", "loud")
+	if _, err := ConfigFromEnv(); err == nil {
+		t.Error("invalid LOG_LEVEL accepted")
+	}
+	t.Setenv("LOG_LEVEL", "")
+	t.Setenv("LOG_FORMAT", "xml")
+	if _, err := ConfigFromEnv(); err == nil {
+		t.Error("invalid LOG_FORMAT accepted")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Config{})
+	logger.Info("login", "user", "bob", "password", "hunter2", slog.Group("headers", "Authorization", "Bearer abc"))
+
+	out := buf.String()
+	for _, secret := range []string{"hunter2", "Bearer abc"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("%q logged in %s", secret, out)
+		}
+	}
+	if !strings.Contains(out, `"user":"bob"`) {
+		t.Errorf("other attributes missing from %s", out)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("empty context does not fall back to the default logger")
+	}
+
+	var buf bytes.Buffer
+	ctx := WithLogger(context.Background(), New(&buf, Config{}))
+	ctx = With(ctx, "request_id", "abc")
+	FromContext(ctx).Info("hello")
+	if !strings.Contains(buf.String(), `"request_id":"abc"`) {
+		t.Errorf("log = %s", buf.String())
+	}
+}
@@ -0,0 +1,200 @@
+// Package loudness measures programme loudness and true peak as described in
+// ITU-R BS.1770-4 / EBU R128, and derives ReplayGain 2.0 style gain values.
+package loudness
+
+import (
+	"math"
+)
+
+const (
+	// ReferenceLUFS is the ReplayGain 2.0 target loudness gains are computed against
+	ReferenceLUFS = -18.0
+
+	absoluteGate = -70.0
+	relativeGate = -10.0
+
+	// Gating blocks are 400ms long and overlap by 75%, so a new block starts every 100ms
+	subBlocksPerBlock = 4
+)
+
+// biquad is a second order IIR filter in direct form I
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
+
+// kWeighting returns the two stage K-weighting filter for the sample rate,
+// using the analog prototype from BS.1770 so rates other than 48kHz work too
+func kWeighting(rate float64) (biquad, biquad) {
+	// High shelf modelling the acoustic effect of the head
+	f0 := 1681.974450955533
+	g := 3.999843853973347
+	q := 0.7071752369554196
+	k := math.Tan(math.Pi * f0 / rate)
+	vh := math.Pow(10, g/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelf := biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	// RLB high pass
+	f0 = 38.13547087602444
+	q = 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / rate)
+	a0 = 1 + k/q + k*k
+	highPass := biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	return shelf, highPass
+}
+
+// Meter accumulates interleaved samples and measures their loudness
+type Meter struct {
+	channels int
+	filters  [][2]biquad
+	peaks    []*truePeak
+
+	subBlockLen int
+	subCount    int
+	subSum      float64
+	recentSubs  []float64
+
+	blocks []float64
+}
+
+// NewMeter returns a meter for audio with the given layout. Every channel is
+// weighted equally, which is correct for stereo material; mono material must
+// be measured as a single channel rather than duplicated.
+func NewMeter(sampleRate, channels int) *Meter {
+	m := &Meter{
+		channels:    channels,
+		filters:     make([][2]biquad, channels),
+		peaks:       make([]*truePeak, channels),
+		subBlockLen: sampleRate / 10,
+	}
+	for ch := range m.filters {
+		shelf, highPass := kWeighting(float64(sampleRate))
+		m.filters[ch] = [2]biquad{shelf, highPass}
+		m.peaks[ch] = newTruePeak()
+	}
+	return m
+}
+
+// Write adds interleaved 16-bit samples to the measurement
+func (m *Meter) Write(samples []int16) {
+	for i := 0; i+m.channels <= len(samples); i += m.channels {
+		for ch := 0; ch < m.channels; ch++ {
+			x := float64(samples[i+ch]) / 32768
+			m.peaks[ch].add(x)
+
+			f := &m.filters[ch]
+			y := f[1].process(f[0].process(x))
+			m.subSum += y * y
+		}
+
+		m.subCount++
+		if m.subCount == m.subBlockLen {
+			m.finishSubBlock()
+		}
+	}
+}
+
+func (m *Meter) finishSubBlock() {
+	m.recentSubs = append(m.recentSubs, m.subSum/float64(m.subBlockLen))
+	m.subCount, m.subSum = 0, 0
+
+	if len(m.recentSubs) < subBlocksPerBlock {
+		return
+	}
+	m.recentSubs = m.recentSubs[len(m.recentSubs)-subBlocksPerBlock:]
+
+	var energy float64
+	for _, e := range m.recentSubs {
+		energy += e
+	}
+	m.blocks = append(m.blocks, energy/subBlocksPerBlock)
+}
+
+// Blocks returns the mean square energy of every 400ms gating block measured so far
+func (m *Meter) Blocks() []float64 {
+	return m.blocks
+}
+
+// Integrated returns the gated integrated loudness in LUFS
+func (m *Meter) Integrated() float64 {
+	return Integrated(m.blocks)
+}
+
+// TruePeak returns the highest inter-sample peak across channels as a linear amplitude
+func (m *Meter) TruePeak() float64 {
+	var peak float64
+	for _, p := range m.peaks {
+		peak = max(peak, p.max)
+	}
+	return peak
+}
+
+// Integrated applies the absolute and relative gates to block energies and
+// returns the loudness of what remains. Block energies from several tracks can
+// be concatenated to measure an album as a whole.
+func Integrated(blocks []float64) float64 {
+	var sum float64
+	var n int
+	for _, e := range blocks {
+		if blockLoudness(e) > absoluteGate {
+			sum += e
+			n++
+		}
+	}
+	if n == 0 {
+		return math.Inf(-1)
+	}
+
+	gate := blockLoudness(sum/float64(n)) + relativeGate
+	sum, n = 0, 0
+	for _, e := range blocks {
+		if l := blockLoudness(e); l > absoluteGate && l > gate {
+			sum += e
+			n++
+		}
+	}
+	if n == 0 {
+		return math.Inf(-1)
+	}
+	return blockLoudness(sum / float64(n))
+}
+
+func blockLoudness(energy float64) float64 {
+	return -0.691 + 10*math.Log10(energy)
+}
+
+// Gain returns the ReplayGain adjustment in dB that brings audio measured at
+// the given loudness to the reference level
+func Gain(lufs float64) float64 {
+	if math.IsInf(lufs, -1) {
+		return 0
+	}
+	return ReferenceLUFS - lufs
+}
+
+// ToDB converts a linear amplitude to decibels
+func ToDB(amplitude float64) float64 {
+	return 20 * math.Log10(amplitude)
+}
@@ -0,0 +1,102 @@
+package loudness
+
+import (
+	"math"
+	"testing"
+)
+
+// segment is a stretch of 1 kHz sine at a peak level in dBFS on every channel
+type segment struct {
+	dbfs    float64
+	seconds float64
+}
+
+// measure runs a sine test signal through a meter, as in EBU Tech 3341
+func measure(rate, channels int, segments ...segment) *Meter {
+	m := NewMeter(rate, channels)
+	var n int
+	for _, seg := range segments {
+		amplitude := math.Pow(10, seg.dbfs/20)
+		frames := int(seg.seconds * float64(rate))
+		buf := make([]int16, 0, frames*channels)
+		for i := 0; i < frames; i++ {
+			x := int16(math.Round(32767 * amplitude * math.Sin(2*math.Pi*1000*float64(n)/float64(rate))))
+			n++
+			for ch := 0; ch < channels; ch++ {
+				buf = append(buf, x)
+			}
+		}
+		m.Write(buf)
+	}
+	return m
+}
+
+func TestIntegratedEBUReference(t *testing.T) {
+	// EBU Tech 3341 minimum requirements, test cases 1 to 5
+	tests := []struct {
+		name     string
+		segments []segment
+		want     float64
+	}{
+		{"case 1", []segment{{-23, 20}}, -23},
+		{"case 2", []segment{{-33, 20}}, -33},
+		{"case 3", []segment{{-36, 10}, {-23, 60}, {-36, 10}}, -23},
+		{"case 4", []segment{{-72, 10}, {-36, 10}, {-23, 60}, {-36, 10}, {-72, 10}}, -23},
+		{"case 5", []segment{{-26, 20}, {-20, 20.1}, {-26, 20}}, -23},
+	}
+	for _, rate := range []int{44100, 48000} {
+		for _, tt := range tests {
+			got := measure(rate, 2, tt.segments...).Integrated()
+			if math.Abs(got-tt.want) > 0.1 {
+				t.Errorf("%s at %d Hz = %.2f LUFS, want %.1f", tt.name, rate, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestIntegratedMono(t *testing.T) {
+	// A single channel carries half the energy of the same signal on two
+	if got := measure(48000, 1, segment{-23, 20}).Integrated(); math.Abs(got+26) > 0.1 {
+		t.Errorf("mono = %.2f LUFS, want -26", got)
+	}
+}
+
+func TestIntegratedSilence(t *testing.T) {
+	m := measure(48000, 2, segment{-120, 5})
+	if got := m.Integrated(); !math.IsInf(got, -1) {
+		t.Errorf("silence = %v LUFS", got)
+	}
+	if got := Gain(m.Integrated()); got != 0 {
+		t.Errorf("gain for silence = %v", got)
+	}
+}
+
+func TestAlbumFromBlocks(t *testing.T) {
+	// Two tracks 6 LU apart measured as one programme, like case 5 split in two
+	quiet := measure(48000, 2, segment{-26, 20}).Blocks()
+	loud := measure(48000, 2, segment{-20, 20}).Blocks()
+	album := Integrated(append(append([]float64{}, quiet...), loud...))
+	if math.Abs(album-(-22.04)) > 0.1 {
+		t.Errorf("album = %.2f LUFS", album)
+	}
+	if gain := Gain(album); math.Abs(gain-(ReferenceLUFS-album)) > 1e-9 {
+		t.Errorf("gain = %v", gain)
+	}
+}
+
+func TestTruePeak(t *testing.T) {
+	// A quarter of the sample rate shifted by 45 degrees has every sample at
+	// 0.707 of the true peak
+	const rate = 48000
+	m := NewMeter(rate, 1)
+	amplitude := math.Pow(10, -6.0/20)
+	buf := make([]int16, rate)
+	for i := range buf {
+		buf[i] = int16(math.Round(32767 * amplitude * math.Sin(math.Pi/2*float64(i)+math.Pi/4)))
+	}
+	m.Write(buf)
+
+	if got := ToDB(m.TruePeak()); math.Abs(got-(-6)) > 0.2 {
+		t.Errorf("true peak = %.2f dBTP, want -6", got)
+	}
+}
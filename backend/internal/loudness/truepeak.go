@@ -0,0 +1,63 @@
+package loudness
+
+import "math"
+
+const (
+	oversample    = 4
+	tapsPerPhase  = 12
+	interpolation = oversample * tapsPerPhase
+)
+
+// phases holds the polyphase decomposition of a windowed sinc interpolation filter
+var phases = func() [oversample][tapsPerPhase]float64 {
+	var p [oversample][tapsPerPhase]float64
+	center := float64(interpolation-1) / 2
+	for n := 0; n < interpolation; n++ {
+		t := (float64(n) - center) / oversample
+		sinc := 1.0
+		if t != 0 {
+			sinc = math.Sin(math.Pi*t) / (math.Pi * t)
+		}
+		// Blackman window
+		w := 0.42 - 0.5*math.Cos(2*math.Pi*float64(n)/(interpolation-1)) + 0.08*math.Cos(4*math.Pi*float64(n)/(interpolation-1))
+		p[n%oversample][n/oversample] = sinc * w
+	}
+
+	// Normalise each phase to unity gain at DC
+	for i := range p {
+		var sum float64
+		for _, c := range p[i] {
+			sum += c
+		}
+		for j := range p[i] {
+			p[i][j] /= sum
+		}
+	}
+	return p
+}()
+
+// truePeak estimates inter-sample peaks by 4x oversampling, as BS.1770 annex 2 recommends
+type truePeak struct {
+	history [tapsPerPhase]float64
+	pos     int
+	max     float64
+}
+
+func newTruePeak() *truePeak {
+	return &truePeak{}
+}
+
+func (t *truePeak) add(x float64) {
+	t.history[t.pos] = x
+	t.pos = (t.pos + 1) % tapsPerPhase
+
+	t.max = max(t.max, math.Abs(x))
+	for _, phase := range phases {
+		var y float64
+		for k, c := range phase {
+			// k counts backwards from the newest sample
+			y += c * t.history[(t.pos-1-k+2*tapsPerPhase)%tapsPerPhase]
+		}
+		t.max = max(t.max, math.Abs(y))
+	}
+}
@@ -0,0 +1,89 @@
+// Package metrics counts what the server does and exposes the figures in the
+// Prometheus text format, for scraping at /metrics.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Upper bounds of the query duration buckets, in seconds
+var queryBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1}
+
+// Upper bounds of the upload size buckets, in bytes: 1 MiB to 256 MiB
+var sizeBuckets = prometheus.ExponentialBuckets(1<<20, 2, 9)
+
+// Metrics are the server's metrics. A single value is shared by everything
+// that records them. Each has its own registry rather than the global one,
+// so several servers can run in one process, as they do in tests.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	// Requests and RequestDuration are labelled by method, route and status
+	Requests        *prometheus.CounterVec
+	RequestDuration *prometheus.HistogramVec
+
+	// StreamedBytes is labelled by the quality asked for, low or original
+	StreamedBytes *prometheus.CounterVec
+	ActiveStreams prometheus.Gauge
+
+	Uploads     prometheus.Counter
+	UploadBytes prometheus.Histogram
+
+	// QueryDuration is labelled by operation (query, create, update, delete,
+	// row or raw) and table
+	QueryDuration *prometheus.HistogramVec
+}
+
+// New returns metrics with nothing recorded yet, including the Go runtime's
+// and the process's
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		Requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests handled, by method, route and status",
+		}, []string{"method", "route", "status"}),
+		RequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time taken to handle HTTP requests",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		StreamedBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "songs_streamed_bytes_total",
+			Help: "Bytes of audio sent to players, by the quality asked for",
+		}, []string{"quality"}),
+		ActiveStreams: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "songs_active_streams",
+			Help: "Songs being streamed right now",
+		}),
+		Uploads: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "song_uploads_total",
+			Help: "Songs uploaded",
+		}),
+		UploadBytes: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "song_upload_size_bytes",
+			Help:    "Size of uploaded song files",
+			Buckets: sizeBuckets,
+		}),
+		QueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Time taken by database queries, by operation and table",
+			Buckets: queryBuckets,
+		}, []string{"operation", "table"}),
+	}
+	m.registry.MustRegister(
+		m.Requests, m.RequestDuration, m.StreamedBytes, m.ActiveStreams, m.Uploads, m.UploadBytes, m.QueryDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler serves every metric in the format the scraper asks for
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+
+	"music-player-gin/internal/migrations/baseline"
+)
+
+// The baseline creates the schema AutoMigrate used to maintain. On a
+// database created before migrations existed the tables are already there,
+// and it only fills in whatever an older build had not added yet.
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "baseline",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.SetupJoinTable(&baseline.User{}, "FavoriteSongs", &baseline.UserFavoriteSong{}); err != nil {
+				return err
+			}
+			return tx.AutoMigrate(baseline.Models()...)
+		},
+		Down: func(tx *gorm.DB) error {
+			// Tables that reference others go first
+			return tx.Migrator().DropTable("user_favorite_songs", "playlist_songs",
+				"play_queue_items", "room_queue_items", "room_members", "radio_session_songs",
+				"play_queues", "rooms", "radio_sessions", "song_similarities", "play_histories",
+				"jobs", "user_tokens", "follows", "access_tokens", "user_identities", "signing_keys",
+				"playlists", "songs", "users")
+		},
+	})
+}
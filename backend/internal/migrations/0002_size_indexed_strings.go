@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Usernames, emails and identity subjects get a length so that every
+// database indexes them the same way. SQLite ignores column lengths and
+// MySQL databases were created with them, so only PostgreSQL changes.
+func init() {
+	columns := []struct{ table, column string }{
+		{"users", "username"},
+		{"users", "email"},
+		{"user_identities", "issuer"},
+		{"user_identities", "subject"},
+	}
+	alter := func(tx *gorm.DB, dataType string) error {
+		if tx.Dialector.Name() != "postgres" {
+			return nil
+		}
+		for _, c := range columns {
+			if err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s", c.table, c.column, dataType)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	register(Migration{
+		Version: 2,
+		Name:    "size_indexed_strings",
+		Up: func(tx *gorm.DB) error {
+			return alter(tx, "varchar(255)")
+		},
+		Down: func(tx *gorm.DB) error {
+			return alter(tx, "text")
+		},
+	})
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Signing keys are encrypted with JWT_KEY_ENCRYPTION_KEY from now on. The
+// server seals the keys already stored the next time it starts, since the
+// encryption key is not available to migrations.
+func init() {
+	type SigningKey struct {
+		Sealed bool `gorm:"not null;default:false"`
+	}
+
+	register(Migration{
+		Version: 3,
+		Name:    "seal_signing_keys",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&SigningKey{}, "Sealed")
+		},
+		Down: func(tx *gorm.DB) error {
+			// Older builds cannot read sealed keys, so they start over with new ones
+			if err := tx.Where("sealed = ?", true).Delete(&SigningKey{}).Error; err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&SigningKey{}, "Sealed")
+		},
+	})
+}
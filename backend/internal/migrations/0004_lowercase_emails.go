@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Emails are stored in lower case from now on, so looking one up is a plain
+// comparison the unique index can answer. Accounts whose addresses differ
+// only in case have to be merged by hand first, since either could be the
+// one a lookup should find.
+func init() {
+	type user struct {
+		ID    uint
+		Email string
+	}
+
+	register(Migration{
+		Version: 4,
+		Name:    "lowercase_emails",
+		Up: func(tx *gorm.DB) error {
+			var users []user
+			if err := tx.Table("users").Select("id", "email").Order("id").Find(&users).Error; err != nil {
+				return err
+			}
+
+			owners := make(map[string][]uint, len(users))
+			for _, u := range users {
+				lower := strings.ToLower(u.Email)
+				owners[lower] = append(owners[lower], u.ID)
+			}
+			var clashes []string
+			for email, ids := range owners {
+				if len(ids) > 1 {
+					clashes = append(clashes, fmt.Sprintf("%s (users %v)", email, ids))
+				}
+			}
+			if len(clashes) > 0 {
+				sort.Strings(clashes)
+				return fmt.Errorf("emails differing only in case belong to several users, merge them first: %s", strings.Join(clashes, ", "))
+			}
+
+			for _, u := range users {
+				if lower := strings.ToLower(u.Email); lower != u.Email {
+					if err := tx.Table("users").Where("id = ?", u.ID).Update("email", lower).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			// The original capitalisation is gone, and lower case still works
+			return nil
+		},
+	})
+}
@@ -0,0 +1,214 @@
+// Package baseline freezes the models as they were when versioned
+// migrations were introduced. The type names match the originals because
+// gorm derives join table columns and constraint names from them.
+package baseline
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Song struct {
+	gorm.Model
+	Title              string
+	Artist             string
+	Album              string
+	Genre              string
+	Duration           int
+	FilePath           string
+	FileSize           int64
+	UserID             uint `gorm:"index"`
+	TranscodedPath     string
+	WaveformPath       string
+	LoudnessBlocksPath string
+	Loudness           *float64
+	TruePeak           *float64
+	TrackGain          *float64
+	TrackPeak          *float64
+	AlbumGain          *float64
+	AlbumPeak          *float64
+	ProcessingStatus   string     `gorm:"default:pending"`
+	Playlists          []Playlist `gorm:"many2many:playlist_songs;"`
+}
+
+type Playlist struct {
+	gorm.Model
+	Name        string
+	Description string
+	UserID      uint
+	IsPublic    bool   `gorm:"not null;default:false"`
+	Songs       []Song `gorm:"many2many:playlist_songs;"`
+}
+
+type User struct {
+	gorm.Model
+	Username        string `gorm:"uniqueIndex;not null"`
+	Email           string `gorm:"uniqueIndex;not null"`
+	PasswordHash    string `gorm:"not null"`
+	EmailVerifiedAt *time.Time
+	PendingEmail    string
+	DisplayName     string
+	Bio             string
+	AvatarPath      string
+	FailedLogins    int `gorm:"not null;default:0"`
+	LockedUntil     *time.Time
+	Playlists       []Playlist `gorm:"foreignKey:UserID"`
+	FavoriteSongs   []Song     `gorm:"many2many:user_favorite_songs;"`
+}
+
+type UserFavoriteSong struct {
+	UserID    uint `gorm:"primaryKey"`
+	SongID    uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+}
+
+type Job struct {
+	gorm.Model
+	Type        string `gorm:"index;not null"`
+	Payload     string
+	SongID      *uint  `gorm:"index"`
+	Status      string `gorm:"index;not null;default:pending"`
+	Attempts    int
+	MaxAttempts int
+	RunAt       time.Time `gorm:"index"`
+	LastError   string
+	StartedAt   *time.Time
+	FinishedAt  *time.Time
+}
+
+type PlayHistory struct {
+	ID       uint      `gorm:"primarykey"`
+	UserID   uint      `gorm:"index:idx_play_history_user_played,priority:1;not null"`
+	SongID   uint      `gorm:"index;not null"`
+	PlayedAt time.Time `gorm:"index:idx_play_history_user_played,priority:2;not null"`
+}
+
+type SongSimilarity struct {
+	SongID        uint    `gorm:"primaryKey"`
+	SimilarSongID uint    `gorm:"primaryKey"`
+	Score         float64 `gorm:"index"`
+}
+
+type RadioSession struct {
+	ID          string `gorm:"primaryKey;size:32"`
+	UserID      uint   `gorm:"index;not null"`
+	SeedType    string `gorm:"not null"`
+	SeedValue   string `gorm:"not null"`
+	Familiarity float64
+	Served      int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type RadioSessionSong struct {
+	SessionID string `gorm:"primaryKey;size:32"`
+	Position  int    `gorm:"primaryKey"`
+	SongID    uint   `gorm:"index;not null"`
+}
+
+type PlayQueue struct {
+	ID              uint `gorm:"primarykey"`
+	UserID          uint `gorm:"uniqueIndex;not null"`
+	CurrentIndex    int
+	PositionSeconds float64
+	Shuffle         bool
+	RepeatMode      string `gorm:"not null;default:off"`
+	Version         int    `gorm:"not null"`
+	UpdatedAt       time.Time
+	Items           []PlayQueueItem `gorm:"foreignKey:QueueID"`
+}
+
+type PlayQueueItem struct {
+	ID       uint `gorm:"primarykey"`
+	QueueID  uint `gorm:"index;not null"`
+	Position int  `gorm:"not null"`
+	SongID   uint `gorm:"not null"`
+	Song     Song `gorm:"foreignKey:SongID"`
+}
+
+type Room struct {
+	ID              uint   `gorm:"primarykey"`
+	Code            string `gorm:"uniqueIndex;size:8;not null"`
+	Name            string
+	HostID          uint `gorm:"index;not null"`
+	CurrentIndex    int
+	PositionSeconds float64
+	Playing         bool
+	StateAt         time.Time
+	Version         int `gorm:"not null"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Items           []RoomQueueItem `gorm:"foreignKey:RoomID"`
+	Members         []RoomMember    `gorm:"foreignKey:RoomID"`
+}
+
+type RoomMember struct {
+	RoomID   uint `gorm:"primaryKey"`
+	UserID   uint `gorm:"primaryKey"`
+	JoinedAt time.Time
+}
+
+type RoomQueueItem struct {
+	ID       uint `gorm:"primarykey"`
+	RoomID   uint `gorm:"index;not null"`
+	Position int  `gorm:"not null"`
+	SongID   uint `gorm:"not null"`
+	Song     Song `gorm:"foreignKey:SongID"`
+}
+
+type UserToken struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index;not null"`
+	Purpose   string `gorm:"index;not null"`
+	TokenHash string `gorm:"uniqueIndex;size:64;not null"`
+	Data      string
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type Follow struct {
+	FollowerID uint      `gorm:"primaryKey"`
+	FolloweeID uint      `gorm:"primaryKey;index"`
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+type AccessToken struct {
+	ID         uint   `gorm:"primarykey"`
+	UserID     uint   `gorm:"index;not null"`
+	Name       string `gorm:"not null"`
+	Prefix     string `gorm:"not null"`
+	TokenHash  string `gorm:"uniqueIndex;size:64;not null"`
+	Scopes     string `gorm:"not null"`
+	LastUsedAt *time.Time
+	ExpiresAt  *time.Time
+	CreatedAt  time.Time
+}
+
+type UserIdentity struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index;not null"`
+	Issuer    string `gorm:"uniqueIndex:idx_identity_subject;not null"`
+	Subject   string `gorm:"uniqueIndex:idx_identity_subject;not null"`
+	Email     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type SigningKey struct {
+	ID          string    `gorm:"primarykey;size:32"`
+	Algorithm   string    `gorm:"not null"`
+	PrivateKey  []byte    `gorm:"not null"`
+	ActivatesAt time.Time `gorm:"not null"`
+	ExpiresAt   *time.Time
+	CreatedAt   time.Time
+}
+
+// Models lists the models in the order they were migrated
+func Models() []any {
+	return []any{&Song{}, &Playlist{}, &User{}, &Job{}, &PlayHistory{}, &SongSimilarity{},
+		&RadioSession{}, &RadioSessionSong{}, &PlayQueue{}, &PlayQueueItem{},
+		&Room{}, &RoomMember{}, &RoomQueueItem{}, &UserToken{}, &Follow{},
+		&AccessToken{}, &UserIdentity{}, &SigningKey{}}
+}
@@ -0,0 +1,284 @@
+// Package migrations versions the database schema. Each migration is a Go
+// file named NNNN_name.go that registers an Up and a Down step in init.
+// Applied versions are recorded in the schema_migrations table.
+//
+// Migrations must not use the current models, which keep changing; they
+// declare the shape of the tables they touch as it was when they were written.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is one schema change. Each runs in a transaction together with
+// recording it, so a failed migration leaves no trace on databases with
+// transactional DDL.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// State is a migration and when it was applied, if it was
+type State struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// schemaMigration is a row of the schema_migrations table
+type schemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+var registry = map[int]Migration{}
+
+// register adds a migration. It is called from the init function of each migration file.
+func register(m Migration) {
+	if _, ok := registry[m.Version]; ok {
+		panic(fmt.Sprintf("migrations: version %d registered twice", m.Version))
+	}
+	registry[m.Version] = m
+}
+
+// All returns every migration in version order
+func All() []Migration {
+	all := make([]Migration, 0, len(registry))
+	for _, m := range registry {
+		all = append(all, m)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	return all
+}
+
+// applied returns the applied versions and when they were applied
+func applied(db *gorm.DB) (map[int]time.Time, error) {
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return nil, err
+	}
+	var rows []schemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	done := make(map[int]time.Time, len(rows))
+	for _, row := range rows {
+		done[row.Version] = row.AppliedAt
+	}
+	return done, nil
+}
+
+// Status lists every migration with when it was applied
+func Status(db *gorm.DB) ([]State, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	var states []State
+	for _, m := range All() {
+		s := State{Migration: m}
+		if at, ok := done[m.Version]; ok {
+			s.AppliedAt = &at
+		}
+		states = append(states, s)
+	}
+	return states, nil
+}
+
+// Pending returns the migrations not applied yet
+func Pending(db *gorm.DB) ([]Migration, error) {
+	states, err := Status(db)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, s := range states {
+		if s.AppliedAt == nil {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// The lock held while migrating. PostgreSQL advisory locks are
+// keyed by a number and are per database already; MySQL named locks are per
+// server, so the name includes the database.
+const (
+	pgLockKey    = 4721983450128
+	mysqlLockKey = "CONCAT(DATABASE(), '.schema_migrations')"
+)
+
+// lock keeps other processes from migrating the same database until the
+// returned function is called, so that instances starting together do not
+// all apply the same migrations. SQLite needs none, as it only lets one
+// transaction write at a time and a second run finds the versions recorded.
+func lock(db *gorm.DB) (func(), error) {
+	var acquire, release string
+	switch db.Dialector.Name() {
+	case "postgres":
+		acquire = fmt.Sprintf("SELECT 1 FROM pg_advisory_lock(%d)", pgLockKey)
+		release = fmt.Sprintf("SELECT pg_advisory_unlock(%d)", pgLockKey)
+	case "mysql":
+		acquire = fmt.Sprintf("SELECT GET_LOCK(%s, -1)", mysqlLockKey)
+		release = fmt.Sprintf("SELECT RELEASE_LOCK(%s)", mysqlLockKey)
+	default:
+		return func() {}, nil
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	// Both locks belong to the session, so they are taken and released on
+	// one connection kept out of the pool meanwhile
+	ctx := context.Background()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, acquire).Scan(&acquired); err != nil || acquired.Int64 != 1 {
+		conn.Close()
+		if err == nil {
+			err = fmt.Errorf("%s returned %v", acquire, acquired)
+		}
+		return nil, fmt.Errorf("taking the migration lock: %w", err)
+	}
+	return func() {
+		conn.ExecContext(ctx, release)
+		conn.Close()
+	}, nil
+}
+
+// Up applies pending migrations in order, up to and including target, or
+// all of them when target is 0
+func Up(db *gorm.DB, target int) ([]Migration, error) {
+	unlock, err := lock(db)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	pending, err := Pending(db)
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, m := range pending {
+		if target > 0 && m.Version > target {
+			break
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// Down rolls back the latest steps applied migrations, newest first
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	unlock, err := lock(db)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	states, err := Status(db)
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for i := len(states) - 1; i >= 0 && len(done) < steps; i-- {
+		m := states[i].Migration
+		if states[i].AppliedAt == nil {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("rolling back %04d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+var (
+	fileName   = regexp.MustCompile(`^(\d{4,})_[a-z0-9_]+\.go$`)
+	nameFilter = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// Create writes an empty migration to dir, numbered after the existing
+// ones, and returns its path
+func Create(dir, name string) (string, error) {
+	name = strings.Trim(nameFilter.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", fmt.Errorf("migration name must contain letters or digits")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	version := 0
+	for _, e := range entries {
+		if match := fileName.FindStringSubmatch(e.Name()); match != nil {
+			if v, _ := strconv.Atoi(match[1]); v > version {
+				version = v
+			}
+		}
+	}
+	version++
+
+	path := filepath.Join(dir, fmt.Sprintf("%04d_%s.go", version, name))
+	source := fmt.Sprintf(template, version, name)
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+const template = `package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: %d,
+		Name:    %q,
+		Up: func(tx *gorm.DB) error {
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
+`
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Scopes a personal access token can be granted
+const (
+	ScopeRead           = "read"
+	ScopeUpload         = "upload"
+	ScopePlaylistsWrite = "playlists:write"
+)
+
+// AccessScopes lists every valid scope
+var AccessScopes = []string{ScopeRead, ScopeUpload, ScopePlaylistsWrite}
+
+// AccessToken is a long-lived personal access token for scripts. Only a hash
+// of the token is stored; Prefix keeps enough of it for users to tell tokens apart.
+type AccessToken struct {
+	ID         uint       `json:"id" gorm:"primarykey"`
+	UserID     uint       `json:"-" gorm:"index;not null"`
+	Name       string     `json:"name" gorm:"not null"`
+	Prefix     string     `json:"prefix" gorm:"not null"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;size:64;not null"`
+	Scopes     string     `json:"-" gorm:"not null"` // Space separated
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ScopeList returns the token's scopes
+func (t *AccessToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
+
+// HasScope reports whether the token was granted scope
+func (t *AccessToken) HasScope(scope string) bool {
+	for _, s := range t.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
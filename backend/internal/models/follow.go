@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// Follow records that one user follows another
+type Follow struct {
+	FollowerID uint      `json:"follower_id" gorm:"primaryKey"`
+	FolloweeID uint      `json:"followee_id" gorm:"primaryKey;index"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// PlayHistory records each time a user starts playing a song
+type PlayHistory struct {
+	ID       uint      `json:"id" gorm:"primarykey"`
+	UserID   uint      `json:"user_id" gorm:"index:idx_play_history_user_played,priority:1;not null"`
+	SongID   uint      `json:"song_id" gorm:"index;not null"`
+	PlayedAt time.Time `json:"played_at" gorm:"index:idx_play_history_user_played,priority:2;not null"`
+}
+
+// UserFavoriteSong is the join table behind User.FavoriteSongs, with a
+// timestamp so favourites can be ordered and weighted by age
+type UserFavoriteSong struct {
+	UserID    uint      `gorm:"primaryKey"`
+	SongID    uint      `gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SongSimilarity is a precomputed item-item similarity score between two songs
+type SongSimilarity struct {
+	SongID        uint    `json:"song_id" gorm:"primaryKey"`
+	SimilarSongID uint    `json:"similar_song_id" gorm:"primaryKey"`
+	Score         float64 `json:"score" gorm:"index"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// UserIdentity links a user to an account at an external identity provider.
+// Issuer and Subject together identify the account for good, unlike its email.
+type UserIdentity struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	UserID    uint      `json:"-" gorm:"index;not null"`
+	Issuer    string    `json:"issuer" gorm:"uniqueIndex:idx_identity_subject;size:255;not null"`
+	Subject   string    `json:"subject" gorm:"uniqueIndex:idx_identity_subject;size:255;not null"`
+	Email     string    `json:"email"` // Address the provider reported at the last sign-in
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
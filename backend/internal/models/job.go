@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Job states
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// Job is a unit of background work persisted so it survives restarts
+type Job struct {
+	gorm.Model
+	Type        string     `json:"type" gorm:"index;not null"`
+	Payload     string     `json:"payload,omitempty"`              // JSON encoded job arguments
+	SongID      *uint      `json:"song_id,omitempty" gorm:"index"` // Song the job belongs to, if any
+	Status      string     `json:"status" gorm:"index;not null;default:pending"`
+	Attempts    int        `json:"attempts"`
+	MaxAttempts int        `json:"max_attempts"`
+	RunAt       time.Time  `json:"run_at" gorm:"index"` // Earliest time the job may be picked up
+	LastError   string     `json:"last_error,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
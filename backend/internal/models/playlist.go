@@ -4,6 +4,14 @@ import (
 	"gorm.io/gorm"
 )
 
+// Processing states of a song while its post-upload jobs run
+const (
+	ProcessingPending    = "pending"
+	ProcessingInProgress = "processing"
+	ProcessingReady      = "ready"
+	ProcessingFailed     = "failed"
+)
+
 type Song struct {
 	gorm.Model
 	Title       string `json:"title"`
@@ -13,6 +21,17 @@ type Song struct {
 	Duration    int    `json:"duration"` 
 	FilePath    string    `json:"file_path"`     // Path to the stored MP3 file
     FileSize    int64     `json:"file_size"`     // Size of the file in bytes
+	UserID      uint      `json:"user_id" gorm:"index"` // User who uploaded the song
+	TranscodedPath   string `json:"transcoded_path,omitempty"`           // Path to the low bitrate stream, if one was produced
+	WaveformPath     string `json:"-"`                                   // Path to the generated peak data, served by /songs/:id/waveform
+	LoudnessBlocksPath string `json:"-"`                                 // Path to the gating block energies used to measure the album
+	Loudness    *float64 `json:"loudness,omitempty"`   // Integrated loudness in LUFS
+	TruePeak    *float64 `json:"true_peak,omitempty"`  // True peak in dBTP
+	TrackGain   *float64 `json:"track_gain,omitempty"` // ReplayGain track gain in dB, relative to -18 LUFS
+	TrackPeak   *float64 `json:"track_peak,omitempty"` // Track true peak as a linear amplitude
+	AlbumGain   *float64 `json:"album_gain,omitempty"` // ReplayGain album gain in dB
+	AlbumPeak   *float64 `json:"album_peak,omitempty"` // Album true peak as a linear amplitude
+	ProcessingStatus string `json:"processing_status" gorm:"default:pending"` // pending, processing, ready or failed
 	Playlists   []Playlist `json:"playlists" gorm:"many2many:playlist_songs;"` // Many-to-many relationship with playlists
 }
 
@@ -21,5 +40,6 @@ type Playlist struct {
     Name        string `json:"name"`
     Description string `json:"description,omitempty"`
 	UserID      uint   `json:"user_id"`
+	IsPublic    bool   `json:"is_public" gorm:"not null;default:false"` // Public playlists appear in followers' feeds
     Songs       []Song `json:"songs" gorm:"many2many:playlist_songs;"` // Many-to-many relationship with songs
 }
\ No newline at end of file
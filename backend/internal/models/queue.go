@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Repeat modes of a play queue
+const (
+	RepeatOff = "off"
+	RepeatOne = "one"
+	RepeatAll = "all"
+)
+
+// PlayQueue is a user's server-side play queue and playback state, shared by
+// all of their devices. Version increases on every change and must be sent
+// back with updates so concurrent edits from two devices are detected.
+type PlayQueue struct {
+	ID              uint            `json:"-" gorm:"primarykey"`
+	UserID          uint            `json:"user_id" gorm:"uniqueIndex;not null"`
+	CurrentIndex    int             `json:"current_index"`
+	PositionSeconds float64         `json:"position_seconds"`
+	Shuffle         bool            `json:"shuffle"`
+	RepeatMode      string          `json:"repeat_mode" gorm:"not null;default:off"`
+	Version         int             `json:"version" gorm:"not null"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	Items           []PlayQueueItem `json:"items" gorm:"foreignKey:QueueID"`
+}
+
+// PlayQueueItem is one entry in a play queue. The same song may appear more than once.
+type PlayQueueItem struct {
+	ID       uint `json:"-" gorm:"primarykey"`
+	QueueID  uint `json:"-" gorm:"index;not null"`
+	Position int  `json:"position" gorm:"not null"`
+	SongID   uint `json:"song_id" gorm:"not null"`
+	Song     Song `json:"song" gorm:"foreignKey:SongID"`
+}
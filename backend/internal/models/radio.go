@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Radio seed types
+const (
+	RadioSeedSong     = "song"
+	RadioSeedArtist   = "artist"
+	RadioSeedGenre    = "genre"
+	RadioSeedPlaylist = "playlist"
+)
+
+// RadioSession is an endless queue generated from a seed
+type RadioSession struct {
+	ID          string    `json:"id" gorm:"primaryKey;size:32"`
+	UserID      uint      `json:"user_id" gorm:"index;not null"`
+	SeedType    string    `json:"seed_type" gorm:"not null"`
+	SeedValue   string    `json:"seed_value" gorm:"not null"` // Song or playlist ID, artist or genre name
+	Familiarity float64   `json:"familiarity"`                // Share of songs the user already knows, from 0 to 1
+	Served      int       `json:"served"`                     // Number of songs handed out so far
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// RadioSessionSong is a song handed out by a radio session, kept so the
+// session does not repeat itself
+type RadioSessionSong struct {
+	SessionID string `gorm:"primaryKey;size:32"`
+	Position  int    `gorm:"primaryKey"`
+	SongID    uint   `gorm:"index;not null"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Room is a listening party. The host controls a shared queue and playback
+// state that every member's player follows.
+type Room struct {
+	ID              uint            `json:"-" gorm:"primarykey"`
+	Code            string          `json:"code" gorm:"uniqueIndex;size:8;not null"`
+	Name            string          `json:"name"`
+	HostID          uint            `json:"host_id" gorm:"index;not null"`
+	CurrentIndex    int             `json:"current_index"`
+	PositionSeconds float64         `json:"position_seconds"` // Position at StateAt
+	Playing         bool            `json:"playing"`
+	StateAt         time.Time       `json:"state_at"` // Server time the playback state was recorded
+	Version         int             `json:"version" gorm:"not null"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	Items           []RoomQueueItem `json:"items" gorm:"foreignKey:RoomID"`
+	Members         []RoomMember    `json:"members" gorm:"foreignKey:RoomID"`
+}
+
+// RoomMember is a user who has joined a room
+type RoomMember struct {
+	RoomID   uint      `json:"-" gorm:"primaryKey"`
+	UserID   uint      `json:"user_id" gorm:"primaryKey"`
+	Username string    `json:"username" gorm:"-"`
+	Online   bool      `json:"online" gorm:"-"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// RoomQueueItem is one entry in a room's shared queue
+type RoomQueueItem struct {
+	ID       uint `json:"-" gorm:"primarykey"`
+	RoomID   uint `json:"-" gorm:"index;not null"`
+	Position int  `json:"position" gorm:"not null"`
+	SongID   uint `json:"song_id" gorm:"not null"`
+	Song     Song `json:"song" gorm:"foreignKey:SongID"`
+}
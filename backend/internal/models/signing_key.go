@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// SigningKey is a key the server signs its tokens with. A key is published
+// before it is first used and keeps verifying tokens until ExpiresAt, after
+// a newer key has taken over signing.
+type SigningKey struct {
+	ID          string     `gorm:"primarykey;size:32"` // The kid in token headers
+	Algorithm   string     `gorm:"not null"`
+	PrivateKey  []byte     `gorm:"not null"`               // PKCS #8, DER encoded, then sealed with AES-GCM when Sealed
+	Sealed      bool       `gorm:"not null;default:false"` // False only for keys stored before keys were encrypted
+	ActivatesAt time.Time  `gorm:"not null"`               // Signing starts then
+	ExpiresAt   *time.Time // Set once a newer key signs; verification stops then
+	CreatedAt   time.Time
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Purposes of a user token
+const (
+	TokenEmailChange   = "email_change"
+	TokenEmailVerify   = "email_verify"
+	TokenPasswordReset = "password_reset"
+)
+
+// UserToken is a single-use secret sent to a user, for example in a
+// confirmation link. Only a hash of the token is stored.
+type UserToken struct {
+	ID        uint      `gorm:"primarykey"`
+	UserID    uint      `gorm:"index;not null"`
+	Purpose   string    `gorm:"index;not null"`
+	TokenHash string    `gorm:"uniqueIndex;size:64;not null"`
+	Data      string    // Purpose-specific value, such as the new email address
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
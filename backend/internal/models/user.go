@@ -1,15 +1,25 @@
 package models
 
 import (
+	"fmt"
+	"time"
+
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 type User struct {
     gorm.Model
-    Username     string `json:"username" gorm:"uniqueIndex;not null"`
-    Email        string `json:"email" gorm:"uniqueIndex;not null"`
+    Username     string `json:"username" gorm:"uniqueIndex;size:255;not null"`
+    Email        string `json:"email" gorm:"uniqueIndex;size:255;not null"`
     PasswordHash string `json:"-" gorm:"not null"` // "-" means don't show in JSON responses
+    EmailVerifiedAt *time.Time `json:"email_verified_at"` // When the user proved they own Email
+    PendingEmail string `json:"pending_email,omitempty"` // New address waiting to be confirmed
+    DisplayName  string `json:"display_name"`
+    Bio          string `json:"bio"`
+    AvatarPath   string `json:"-"` // Path to the stored avatar image, served by /api/v1/users/:id/avatar
+    FailedLogins int        `json:"-" gorm:"not null;default:0"` // Wrong passwords since the last successful login
+    LockedUntil  *time.Time `json:"-"`                           // Logins are refused until then
     Playlists    []Playlist `json:"playlists" gorm:"foreignKey:UserID"` 
     FavoriteSongs []Song `json:"favoriteSongs" gorm:"many2many:user_favorite_songs;"`
 }
@@ -25,4 +35,14 @@ func (u *User) HashPassword(password string) error {
 
 func (u *User) CheckPassword(password string) error {
     return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) 
-}
\ No newline at end of file
+}
+
+// AvatarURL is where the user's avatar is served, or nil without one. The
+// version parameter changes with every update so caches pick up a new image.
+func (u *User) AvatarURL() *string {
+    if u.AvatarPath == "" {
+        return nil
+    }
+    url := fmt.Sprintf("/api/v1/users/%d/avatar?v=%d", u.ID, u.UpdatedAt.Unix())
+    return &url
+}
@@ -0,0 +1,57 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"music-player-gin/internal/jwk"
+)
+
+// keyRefreshInterval is the least time between fetches of the provider's
+// keys, so tokens with unknown key IDs cannot make us hammer the provider
+const keyRefreshInterval = time.Minute
+
+// keyCache holds the provider's signing keys. They are fetched again when a
+// token names a key that is not known yet, which is how rotation shows up.
+type keyCache struct {
+	client *Client
+	uri    string
+
+	mu        sync.Mutex
+	set       *jwk.Set
+	fetchedAt time.Time
+}
+
+func newKeyCache(client *Client, uri string) *keyCache {
+	return &keyCache{client: client, uri: uri}
+}
+
+func (k *keyCache) lookup(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.set != nil {
+		if key, err := k.set.Lookup(kid); err == nil {
+			return key, nil
+		}
+	}
+	if time.Since(k.fetchedAt) < keyRefreshInterval {
+		return nil, fmt.Errorf("oidc: unknown signing key %q", kid)
+	}
+
+	var raw json.RawMessage
+	k.fetchedAt = time.Now()
+	if err := k.client.getJSON(ctx, k.uri, &raw); err != nil {
+		return nil, fmt.Errorf("oidc: fetching keys: %w", err)
+	}
+	set, err := jwk.ParseSet(raw)
+	if err != nil {
+		return nil, err
+	}
+	k.set = set
+	return set.Lookup(kid)
+}
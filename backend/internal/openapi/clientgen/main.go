@@ -0,0 +1,29 @@
+// Command clientgen generates the Go API client from the OpenAPI document.
+// It runs through go generate in the client package.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"music-player-gin/internal/openapi"
+)
+
+func main() {
+	out := flag.String("o", "client_gen.go", "file to write")
+	pkg := flag.String("package", "client", "package name of the generated file")
+	flag.Parse()
+
+	doc, err := openapi.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, err := openapi.GenerateClient(doc, *pkg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
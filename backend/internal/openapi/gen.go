@@ -0,0 +1,507 @@
+package openapi
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// initialisms are name parts written in upper case in Go identifiers
+var initialisms = map[string]string{
+	"id":   "ID",
+	"ids":  "IDs",
+	"url":  "URL",
+	"jwk":  "JWK",
+	"jwks": "JWKS",
+	"oidc": "OIDC",
+}
+
+// GenerateClient renders a Client method for every operation and a Go type
+// for every component schema the methods send or return, as a formatted file
+// of package pkg. Error responses are left to the hand-written client. The
+// package provides Client, File, RequestOption and the send, decode,
+// jsonBody and multipartBody helpers the methods use. Operations that only
+// upgrade to a WebSocket are left out.
+func GenerateClient(doc *Document, pkg string) ([]byte, error) {
+	g := &generator{doc: doc, forms: map[string]bool{}, imports: map[string]bool{"context": true}}
+
+	// Schemas sent as multipart forms become structs of form fields
+	for _, route := range doc.Operations() {
+		if body := route.Operation.RequestBody; body != nil {
+			if media, ok := body.Content["multipart/form-data"]; ok && media.Schema != nil {
+				g.forms[refName(media.Schema.Ref)] = true
+			}
+		}
+	}
+
+	used, err := g.usedSchemas()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(used))
+	for name := range used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := g.schemaType(name, doc.Components.Schemas[name]); err != nil {
+			return nil, err
+		}
+	}
+	for _, route := range doc.Operations() {
+		if err := g.operation(route); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by clientgen from the OpenAPI document. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\nimport (\n", pkg)
+	imports := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		imports = append(imports, path)
+	}
+	sort.Strings(imports)
+	for _, path := range imports {
+		fmt.Fprintf(&out, "%q\n", path)
+	}
+	out.WriteString(")\n")
+	out.Write(g.buf.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("openapi: format client: %w", err)
+	}
+	return src, nil
+}
+
+type generator struct {
+	doc     *Document
+	buf     bytes.Buffer
+	forms   map[string]bool // Schemas used as multipart forms
+	imports map[string]bool
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) comment(text string) {
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		g.printf("// %s\n", line)
+	}
+}
+
+// usedSchemas finds the component schemas reachable from request bodies and
+// successful responses
+func (g *generator) usedSchemas() (map[string]bool, error) {
+	used := map[string]bool{}
+	var walk func(s *Schema) error
+	walk = func(s *Schema) error {
+		if s == nil {
+			return nil
+		}
+		if s.Ref != "" {
+			name := refName(s.Ref)
+			if used[name] {
+				return nil
+			}
+			used[name] = true
+			resolved, err := g.doc.Schema(s)
+			if err != nil {
+				return err
+			}
+			s = resolved
+		}
+		for _, part := range s.AllOf {
+			if err := walk(part); err != nil {
+				return err
+			}
+		}
+		for _, prop := range s.Properties {
+			if err := walk(prop); err != nil {
+				return err
+			}
+		}
+		return walk(s.Items)
+	}
+
+	for _, route := range g.doc.Operations() {
+		op := route.Operation
+		if op.RequestBody != nil {
+			for _, media := range op.RequestBody.Content {
+				if err := walk(media.Schema); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for status, resp := range op.Responses {
+			if code, err := strconv.Atoi(status); err != nil || code < 200 || code >= 300 {
+				continue
+			}
+			resp, err := g.doc.Response(resp)
+			if err != nil {
+				return nil, err
+			}
+			if media, ok := resp.Content["application/json"]; ok {
+				if err := walk(media.Schema); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return used, nil
+}
+
+// schemaType declares the Go type for a component schema
+func (g *generator) schemaType(name string, s *Schema) error {
+	g.printf("\n")
+	if s.Description != "" {
+		g.comment(name + ": " + s.Description)
+	}
+
+	switch {
+	case g.forms[name]:
+		g.printf("type %s struct {\n", name)
+		for _, prop := range s.PropertyOrder {
+			schema := s.Properties[prop]
+			if schema.Description != "" {
+				g.comment(schema.Description)
+			}
+			typ := "string"
+			if schema.Format == "binary" {
+				typ = "*File"
+			}
+			g.printf("%s %s\n", goName(prop), typ)
+		}
+		g.printf("}\n")
+	case len(s.AllOf) > 0:
+		g.printf("type %s struct {\n", name)
+		for _, part := range s.AllOf {
+			if part.Ref != "" {
+				g.printf("%s\n", refName(part.Ref))
+				continue
+			}
+			if err := g.fields(part); err != nil {
+				return fmt.Errorf("openapi: schema %s: %w", name, err)
+			}
+		}
+		g.printf("}\n")
+	case s.Type == "object" && len(s.Properties) > 0:
+		g.printf("type %s struct {\n", name)
+		if err := g.fields(s); err != nil {
+			return fmt.Errorf("openapi: schema %s: %w", name, err)
+		}
+		g.printf("}\n")
+	default:
+		g.printf("type %s %s\n", name, g.goType(s, true))
+	}
+	return nil
+}
+
+// fields declares a struct field for each property of an object schema.
+// Optional properties are omitted when empty.
+func (g *generator) fields(s *Schema) error {
+	required := map[string]bool{}
+	for _, name := range s.Required {
+		required[name] = true
+	}
+	for _, prop := range s.PropertyOrder {
+		schema := s.Properties[prop]
+		if schema.Type == "object" && len(schema.Properties) > 0 {
+			return fmt.Errorf("property %s: nested objects must be component schemas", prop)
+		}
+		if schema.Description != "" {
+			g.comment(schema.Description)
+		}
+		tag := prop
+		if !required[prop] {
+			tag += ",omitempty"
+		}
+		g.printf("%s %s `json:%q`\n", goName(prop), g.goType(schema, required[prop]), tag)
+	}
+	return nil
+}
+
+// goType is the Go type for values of a schema. Optional and nullable values
+// become pointers so they can be told apart from zero values.
+func (g *generator) goType(s *Schema, required bool) string {
+	optional := !required || s.Nullable
+	ptr := func(typ string) string {
+		if optional {
+			return "*" + typ
+		}
+		return typ
+	}
+
+	if s.Ref != "" {
+		return ptr(refName(s.Ref))
+	}
+	if len(s.AllOf) == 1 && s.AllOf[0].Ref != "" {
+		return ptr(refName(s.AllOf[0].Ref))
+	}
+	switch s.Type {
+	case "array":
+		return "[]" + g.goType(s.Items, true)
+	case "object":
+		return "map[string]any"
+	case "string":
+		switch s.Format {
+		case "date-time":
+			g.imports["time"] = true
+			return ptr("time.Time")
+		case "binary":
+			return "*File"
+		}
+		return ptr("string")
+	case "integer":
+		if s.Format == "int64" {
+			return ptr("int64")
+		}
+		return ptr("int")
+	case "number":
+		return ptr("float64")
+	case "boolean":
+		return ptr("bool")
+	}
+	return "any"
+}
+
+// param is a resolved operation parameter with its Go names
+type param struct {
+	*Parameter
+	field string // Exported name, for query parameter struct fields
+	arg   string // Argument name, for path parameters
+}
+
+// operation declares the Client method for an operation
+func (g *generator) operation(route Route) error {
+	op := route.Operation
+	name := methodName(op.OperationID)
+
+	// The method decodes the first successful JSON response, or hands back
+	// the raw response when the operation has none
+	var result *Schema
+	returns := false
+	statuses := make([]string, 0, len(op.Responses))
+	for status := range op.Responses {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	for _, status := range statuses {
+		code, err := strconv.Atoi(status)
+		if err != nil || code < 200 || code >= 400 {
+			continue
+		}
+		returns = true
+		resp, err := g.doc.Response(op.Responses[status])
+		if err != nil {
+			return err
+		}
+		if media, ok := resp.Content["application/json"]; ok && media.Schema != nil && code < 300 && result == nil {
+			result = media.Schema
+		}
+	}
+	if !returns {
+		return nil
+	}
+
+	var pathParams, queryParams []param
+	for _, p := range op.Parameters {
+		p, err := g.doc.Parameter(p)
+		if err != nil {
+			return err
+		}
+		field := goName(p.Name)
+		switch p.In {
+		case "path":
+			pathParams = append(pathParams, param{Parameter: p, field: field, arg: unexport(field)})
+		case "query":
+			queryParams = append(queryParams, param{Parameter: p, field: field})
+		}
+	}
+
+	// Optional query parameters are gathered in a struct
+	paramsType := name + "Params"
+	if len(queryParams) > 0 {
+		g.printf("\n// %s holds the query parameters of %s\n", paramsType, name)
+		g.printf("type %s struct {\n", paramsType)
+		for _, p := range queryParams {
+			if p.Description != "" {
+				g.comment(p.Description)
+			}
+			g.printf("%s %s\n", p.field, g.goType(p.Schema, false))
+		}
+		g.printf("}\n")
+	}
+
+	args := []string{"ctx context.Context"}
+	for _, p := range pathParams {
+		args = append(args, p.arg+" "+g.goType(p.Schema, true))
+	}
+	if len(queryParams) > 0 {
+		args = append(args, "params *"+paramsType)
+	}
+	var bodyExpr = "nil"
+	var form *Schema
+	if op.RequestBody != nil {
+		if media, ok := op.RequestBody.Content["application/json"]; ok {
+			args = append(args, "body "+refName(media.Schema.Ref))
+			bodyExpr = "jsonBody{body}"
+		} else if media, ok := op.RequestBody.Content["multipart/form-data"]; ok {
+			args = append(args, "form "+refName(media.Schema.Ref))
+			bodyExpr = "multipartBody{fields: fields, files: files}"
+			var err error
+			if form, err = g.doc.Schema(media.Schema); err != nil {
+				return err
+			}
+		}
+	}
+	args = append(args, "opts ...RequestOption")
+
+	ret := "*http.Response"
+	g.imports["net/http"] = true
+	if result != nil {
+		ret = g.goType(result, true)
+		if !strings.HasPrefix(ret, "[]") && !strings.HasPrefix(ret, "map[") {
+			ret = "*" + ret
+		}
+	}
+
+	g.printf("\n")
+	// Lower case the summary to follow the method name, unless it starts with an acronym
+	summary := op.Summary
+	if len(summary) > 1 && !unicode.IsUpper(rune(summary[1])) {
+		summary = strings.ToLower(summary[:1]) + summary[1:]
+	}
+	g.comment(fmt.Sprintf("%s sends %s %s: %s.", name, route.Method, route.Path, summary))
+	if op.Description != "" {
+		g.printf("//\n")
+		g.comment(op.Description)
+	}
+	if result == nil {
+		g.printf("// The caller must close the response body.\n")
+	}
+	g.printf("func (c *Client) %s(%s) (%s, error) {\n", name, strings.Join(args, ", "), ret)
+
+	// Build the path from its literal parts and escaped parameters
+	path := route.Path
+	expr := []string{}
+	for path != "" {
+		start := strings.Index(path, "{")
+		if start < 0 {
+			expr = append(expr, strconv.Quote(path))
+			break
+		}
+		end := strings.Index(path, "}")
+		if start > 0 {
+			expr = append(expr, strconv.Quote(path[:start]))
+		}
+		paramName := path[start+1 : end]
+		for _, p := range pathParams {
+			if p.Name == paramName {
+				g.imports["fmt"] = true
+				g.imports["net/url"] = true
+				expr = append(expr, "url.PathEscape(fmt.Sprint("+p.arg+"))")
+			}
+		}
+		path = path[end+1:]
+	}
+	g.printf("path := %s\n", strings.Join(expr, " + "))
+
+	queryExpr := "nil"
+	if len(queryParams) > 0 {
+		g.imports["fmt"] = true
+		g.imports["net/url"] = true
+		queryExpr = "query"
+		g.printf("query := url.Values{}\nif params != nil {\n")
+		for _, p := range queryParams {
+			g.printf("if params.%s != nil {\nquery.Set(%q, fmt.Sprint(*params.%s))\n}\n", p.field, p.Name, p.field)
+		}
+		g.printf("}\n")
+	}
+
+	if form != nil {
+		g.printf("fields := map[string]string{}\nfiles := map[string]*File{}\n")
+		for _, prop := range form.PropertyOrder {
+			field := goName(prop)
+			if form.Properties[prop].Format == "binary" {
+				g.printf("if form.%s != nil {\nfiles[%q] = form.%s\n}\n", field, prop, field)
+			} else {
+				g.printf("if form.%s != \"\" {\nfields[%q] = form.%s\n}\n", field, prop, field)
+			}
+		}
+	}
+
+	method := "http.Method" + strings.ToUpper(route.Method[:1]) + strings.ToLower(route.Method[1:])
+	send := fmt.Sprintf("c.send(ctx, %s, path, %s, %s, opts)", method, queryExpr, bodyExpr)
+	if result == nil {
+		g.printf("return %s\n}\n", send)
+		return nil
+	}
+
+	g.printf("resp, err := %s\nif err != nil {\nreturn nil, err\n}\n", send)
+	g.printf("var out %s\n", strings.TrimPrefix(ret, "*"))
+	g.printf("if err := decode(resp, &out); err != nil {\nreturn nil, err\n}\n")
+	if strings.HasPrefix(ret, "*") {
+		g.printf("return &out, nil\n}\n")
+	} else {
+		g.printf("return out, nil\n}\n")
+	}
+	return nil
+}
+
+// refName is the component name a reference points to
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// goName turns a JSON property or parameter name into an exported Go name
+func goName(s string) string {
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '_' || r == '-' || r == '.' })
+	for i, part := range parts {
+		if upper, ok := initialisms[strings.ToLower(part)]; ok {
+			parts[i] = upper
+		} else {
+			parts[i] = exportName(part)
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// methodName turns a camel case operation ID into a method name, such as
+// oidcLogin into OIDCLogin
+func methodName(id string) string {
+	var parts []string
+	start := 0
+	for i, r := range id {
+		if i > 0 && unicode.IsUpper(r) {
+			parts = append(parts, id[start:i])
+			start = i
+		}
+	}
+	parts = append(parts, id[start:])
+	return goName(strings.Join(parts, "_"))
+}
+
+func exportName(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// unexport turns an exported name into an argument name, such as ID into id
+// and PlaylistID into playlistID
+func unexport(s string) string {
+	for _, upper := range initialisms {
+		if s == upper {
+			return strings.ToLower(s)
+		}
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
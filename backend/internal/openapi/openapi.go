@@ -0,0 +1,261 @@
+// Package openapi holds the OpenAPI description of the HTTP API. The document
+// in openapi.json is served as is, checked against real responses in tests
+// and used to generate the Go client in music-player-gin/client.
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Spec is the raw OpenAPI document
+//
+//go:embed openapi.json
+var Spec []byte
+
+// Methods lists the operations a path item can hold, in the order they are generated
+var Methods = []string{"get", "put", "post", "delete", "patch"}
+
+// Document is the subset of an OpenAPI 3.0 document the server and its tools use
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+// PathItem maps lower case HTTP methods to operations
+type PathItem map[string]*Operation
+
+type Components struct {
+	Schemas    map[string]*Schema    `json:"schemas"`
+	Responses  map[string]*Response  `json:"responses"`
+	Parameters map[string]*Parameter `json:"parameters"`
+}
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Description string               `json:"description"`
+	Tags        []string             `json:"tags"`
+	Parameters  []*Parameter         `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Ref         string  `json:"$ref"`
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required"`
+	Description string  `json:"description"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Ref         string               `json:"$ref"`
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of JSON Schema the document uses
+type Schema struct {
+	Ref         string             `json:"$ref"`
+	Description string             `json:"description"`
+	Type        string             `json:"type"`
+	Format      string             `json:"format"`
+	Nullable    bool               `json:"nullable"`
+	Enum        []any              `json:"enum"`
+	Properties  map[string]*Schema `json:"properties"`
+	Required    []string           `json:"required"`
+	Items       *Schema            `json:"items"`
+	AllOf       []*Schema          `json:"allOf"`
+	MinItems    *int               `json:"minItems"`
+
+	// AdditionalProperties is true when an object may hold properties it does not list
+	AdditionalProperties bool `json:"additionalProperties"`
+
+	// PropertyOrder lists the properties in the order the document gives them
+	PropertyOrder []string `json:"-"`
+}
+
+// UnmarshalJSON decodes a schema, keeping the order of its properties
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	type plain Schema
+	if err := json.Unmarshal(data, (*plain)(s)); err != nil {
+		return err
+	}
+	var raw struct {
+		Properties json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil || raw.Properties == nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw.Properties))
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return err
+		}
+		s.PropertyOrder = append(s.PropertyOrder, key.(string))
+	}
+	return nil
+}
+
+// Load parses the embedded document. The result is shared and must not be modified.
+var Load = sync.OnceValues(func() (*Document, error) {
+	return Parse(Spec)
+})
+
+// Parse decodes an OpenAPI document
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+	return &doc, nil
+}
+
+// Schema resolves a reference to a component schema, or returns s itself
+func (d *Document) Schema(s *Schema) (*Schema, error) {
+	for s != nil && s.Ref != "" {
+		name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/")
+		if !ok || d.Components.Schemas[name] == nil {
+			return nil, fmt.Errorf("openapi: unknown schema %s", s.Ref)
+		}
+		s = d.Components.Schemas[name]
+	}
+	return s, nil
+}
+
+// Response resolves a reference to a component response
+func (d *Document) Response(r *Response) (*Response, error) {
+	if r.Ref == "" {
+		return r, nil
+	}
+	name, ok := strings.CutPrefix(r.Ref, "#/components/responses/")
+	if !ok || d.Components.Responses[name] == nil {
+		return nil, fmt.Errorf("openapi: unknown response %s", r.Ref)
+	}
+	return d.Components.Responses[name], nil
+}
+
+// Parameter resolves a reference to a component parameter
+func (d *Document) Parameter(p *Parameter) (*Parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	name, ok := strings.CutPrefix(p.Ref, "#/components/parameters/")
+	if !ok || d.Components.Parameters[name] == nil {
+		return nil, fmt.Errorf("openapi: unknown parameter %s", p.Ref)
+	}
+	return d.Components.Parameters[name], nil
+}
+
+// Find returns the operation that handles a request and the path template it
+// is listed under. Literal path segments win over parameters, as in the router.
+func (d *Document) Find(method, path string) (*Operation, string) {
+	method = strings.ToLower(method)
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	var best string
+	bestScore := -1
+	for template, item := range d.Paths {
+		if item[method] == nil {
+			continue
+		}
+		if score, ok := matchPath(strings.Split(strings.Trim(template, "/"), "/"), segments); ok && score > bestScore {
+			best, bestScore = template, score
+		}
+	}
+	if bestScore < 0 {
+		return nil, ""
+	}
+	return d.Paths[best][method], best
+}
+
+// matchPath reports whether the segments fit a template, scoring matches by
+// how many literal segments they share
+func matchPath(template, segments []string) (int, bool) {
+	if len(template) != len(segments) {
+		return 0, false
+	}
+	score := 0
+	for i, part := range template {
+		switch {
+		case strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}"):
+			if segments[i] == "" {
+				return 0, false
+			}
+		case part == segments[i]:
+			score++
+		default:
+			return 0, false
+		}
+	}
+	return score, true
+}
+
+// Operations lists every operation with its method and path, sorted by path
+// and then in the order of Methods
+func (d *Document) Operations() []Route {
+	var routes []Route
+	for path, item := range d.Paths {
+		for _, method := range Methods {
+			if op := item[method]; op != nil {
+				routes = append(routes, Route{Method: strings.ToUpper(method), Path: path, Operation: op})
+			}
+		}
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return methodIndex(routes[i].Method) < methodIndex(routes[j].Method)
+	})
+	return routes
+}
+
+func methodIndex(method string) int {
+	for i, m := range Methods {
+		if strings.EqualFold(m, method) {
+			return i
+		}
+	}
+	return len(Methods)
+}
+
+// Route is an operation with the method and path it is listed under
+type Route struct {
+	Method    string
+	Path      string
+	Operation *Operation
+}
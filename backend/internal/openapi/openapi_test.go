@@ -0,0 +1,181 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func load(t *testing.T) *Document {
+	t.Helper()
+	doc, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestDocumentReferences(t *testing.T) {
+	doc := load(t)
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Fatalf("openapi = %q", doc.OpenAPI)
+	}
+
+	// Every reference must resolve and every operation needs a unique ID
+	var check func(s *Schema)
+	check = func(s *Schema) {
+		if s == nil {
+			return
+		}
+		if s.Ref != "" {
+			if _, err := doc.Schema(s); err != nil {
+				t.Error(err)
+			}
+			return
+		}
+		for _, p := range s.Properties {
+			check(p)
+		}
+		for _, p := range s.AllOf {
+			check(p)
+		}
+		check(s.Items)
+	}
+	for _, s := range doc.Components.Schemas {
+		check(s)
+	}
+
+	ids := map[string]string{}
+	for _, route := range doc.Operations() {
+		op := route.Operation
+		if op.OperationID == "" {
+			t.Errorf("%s %s has no operationId", route.Method, route.Path)
+		} else if other, ok := ids[op.OperationID]; ok {
+			t.Errorf("%s %s and %s share operationId %s", route.Method, route.Path, other, op.OperationID)
+		}
+		ids[op.OperationID] = route.Method + " " + route.Path
+
+		for _, p := range op.Parameters {
+			if _, err := doc.Parameter(p); err != nil {
+				t.Error(err)
+			}
+		}
+		if op.RequestBody != nil {
+			for _, media := range op.RequestBody.Content {
+				check(media.Schema)
+			}
+		}
+		for status, resp := range op.Responses {
+			resp, err := doc.Response(resp)
+			if err != nil {
+				t.Errorf("%s: %v", op.OperationID, err)
+				continue
+			}
+			if resp.Description == "" {
+				t.Errorf("%s: response %s has no description", op.OperationID, status)
+			}
+			for _, media := range resp.Content {
+				check(media.Schema)
+			}
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	doc := load(t)
+	tests := []struct {
+		method, path, template string
+	}{
+		{http.MethodGet, "/api/v1/songs/12", "/api/v1/songs/{id}"},
+		{http.MethodGet, "/api/v1/songs/12/play", "/api/v1/songs/{id}/play"},
+		{http.MethodPost, "/api/v1/rooms/join", "/api/v1/rooms/join"},
+		{http.MethodGet, "/api/v1/rooms/ABCD1234", "/api/v1/rooms/{code}"},
+		{http.MethodDelete, "/api/v1/me/queue/items/3", "/api/v1/me/queue/items/{position}"},
+		{http.MethodGet, "/api/v1/songs/12/nothing", ""},
+		{http.MethodDelete, "/api/v1/songs/12", ""},
+	}
+	for _, tt := range tests {
+		op, template := doc.Find(tt.method, tt.path)
+		if template != tt.template || (op == nil) != (tt.template == "") {
+			t.Errorf("Find(%s %s) = %q, want %q", tt.method, tt.path, template, tt.template)
+		}
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	doc := load(t)
+	getSong, _ := doc.Find(http.MethodGet, "/api/v1/songs/1")
+	song := `{"id": 1, "created_at": "2024-05-01T10:00:00Z", "updated_at": "2024-05-01T10:00:00.123+02:00",
+		"title": "T", "artist": "A", "album": "", "genre": "", "duration": 0, "file_size": 10,
+		"user_id": 1, "has_low_quality": false, "processing_status": "ready"%s}`
+	valid := strings.Replace(song, "%s", "", 1)
+	problem := `{"type": "about:blank", "title": "Not Found", "status": 404, "detail": "Song not found", "code": "song_not_found"}`
+
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		wantErr     string
+	}{
+		{"valid", 200, "application/json; charset=utf-8", valid, ""},
+		{"optional property", 200, "application/json", strings.Replace(song, "%s", `, "loudness": -14.2`, 1), ""},
+		{"undocumented property", 200, "application/json", strings.Replace(song, "%s", `, "secret": 1`, 1), "undocumented property secret"},
+		{"wrong type", 200, "application/json", strings.Replace(valid, `"duration": 0`, `"duration": "0"`, 1), "$.duration"},
+		{"fraction for integer", 200, "application/json", strings.Replace(valid, `"duration": 0`, `"duration": 1.5`, 1), "not an integer"},
+		{"missing property", 200, "application/json", strings.Replace(valid, `"title": "T", `, "", 1), "missing property title"},
+		{"bad enum", 200, "application/json", strings.Replace(valid, `"ready"`, `"done"`, 1), "not one of"},
+		{"bad date", 200, "application/json", strings.Replace(valid, `"2024-05-01T10:00:00Z"`, `"yesterday"`, 1), "not a date-time"},
+		{"stored path", 200, "application/json", strings.Replace(song, "%s", `, "file_path": "x.mp3"`, 1), "undocumented property file_path"},
+		{"null for non-nullable", 200, "application/json", strings.Replace(valid, `"title": "T"`, `"title": null`, 1), "must not be null"},
+		{"problem", 404, "application/problem+json", problem, ""},
+		{"problem with extension", 404, "application/problem+json", strings.Replace(problem, `}`, `, "processing_status": "pending"}`, 1), ""},
+		{"problem without code", 404, "application/problem+json", strings.Replace(problem, `, "code": "song_not_found"`, "", 1), "missing property code"},
+		{"problem as plain JSON", 404, "application/json", problem, "content type application/json"},
+		{"undocumented status", 418, "application/json", `{}`, "not documented"},
+		{"undocumented content type", 200, "text/plain", "hi", "content type text/plain"},
+		{"invalid JSON", 200, "application/json", "{", "invalid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := doc.ValidateResponse(getSong, tt.status, tt.contentType, []byte(tt.body))
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateAllOf(t *testing.T) {
+	doc := load(t)
+	profile := `{"id": 2, "username": "bob", "display_name": "", "bio": "", "avatar_url": null,
+		"created_at": "2024-05-01T10:00:00Z", "followers": 1, "following": 0, "is_following": true%s}`
+	valid := strings.Replace(profile, "%s", "", 1)
+
+	schema := &Schema{Ref: "#/components/schemas/UserProfile"}
+	if err := doc.Validate(schema, decode(t, valid)); err != nil {
+		t.Errorf("valid profile: %v", err)
+	}
+	if err := doc.Validate(schema, decode(t, strings.Replace(profile, "%s", `, "email": "bob@example.com"`, 1))); err == nil {
+		t.Error("a private field passed as part of a public profile")
+	}
+	if err := doc.Validate(schema, decode(t, strings.Replace(valid, `"followers": 1, `, "", 1))); err == nil {
+		t.Error("a profile without followers passed")
+	}
+}
+
+// decode parses JSON the way ValidateResponse does
+func decode(t *testing.T, s string) any {
+	t.Helper()
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
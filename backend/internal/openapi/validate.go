@@ -0,0 +1,205 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidateResponse checks a response against the operation: its status must
+// be listed, and a JSON body, including problem+json, must match the schema. Objects may not hold
+// properties their schema leaves out unless it allows them, so the document
+// cannot quietly fall behind the handlers.
+func (d *Document) ValidateResponse(op *Operation, status int, contentType string, body []byte) error {
+	resp := op.Responses[strconv.Itoa(status)]
+	if resp == nil {
+		resp = op.Responses["default"]
+	}
+	if resp == nil {
+		return fmt.Errorf("status %d is not documented", status)
+	}
+	resp, err := d.Response(resp)
+	if err != nil {
+		return err
+	}
+	if len(resp.Content) == 0 {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("status %d: invalid content type %q", status, contentType)
+	}
+	media, ok := findMediaType(resp.Content, mediaType)
+	if !ok {
+		return fmt.Errorf("status %d: content type %s is not documented", status, mediaType)
+	}
+	if !isJSON(mediaType) || media.Schema == nil {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return fmt.Errorf("status %d: invalid JSON: %w", status, err)
+	}
+	if err := d.Validate(media.Schema, value); err != nil {
+		return fmt.Errorf("status %d: %w", status, err)
+	}
+	return nil
+}
+
+// isJSON reports whether a media type is JSON, such as application/json or
+// application/problem+json
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// findMediaType looks up a media type, falling back to wildcards such as image/*
+func findMediaType(content map[string]MediaType, mediaType string) (MediaType, bool) {
+	if media, ok := content[mediaType]; ok {
+		return media, true
+	}
+	if major, _, ok := strings.Cut(mediaType, "/"); ok {
+		if media, ok := content[major+"/*"]; ok {
+			return media, true
+		}
+	}
+	media, ok := content["*/*"]
+	return media, ok
+}
+
+// Validate checks a value decoded with json.Decoder.UseNumber against a schema
+func (d *Document) Validate(schema *Schema, value any) error {
+	return d.validate(schema, value, "$")
+}
+
+func (d *Document) validate(schema *Schema, value any, path string) error {
+	schema, err := d.Schema(schema)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		if schema.Nullable {
+			return nil
+		}
+		return fmt.Errorf("%s: must not be null", path)
+	}
+	if len(schema.AllOf) > 0 {
+		merged, err := d.flatten(schema)
+		if err != nil {
+			return err
+		}
+		return d.validateObject(merged, value, path)
+	}
+	if len(schema.Enum) > 0 && !slices.ContainsFunc(schema.Enum, func(e any) bool { return fmt.Sprint(e) == fmt.Sprint(value) }) {
+		return fmt.Errorf("%s: %v is not one of %v", path, value, schema.Enum)
+	}
+
+	switch schema.Type {
+	case "object":
+		return d.validateObject(schema, value, path)
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: %T is not an array", path, value)
+		}
+		if schema.MinItems != nil && len(items) < *schema.MinItems {
+			return fmt.Errorf("%s: has %d items, want at least %d", path, len(items), *schema.MinItems)
+		}
+		for i, item := range items {
+			if err := d.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: %T is not a string", path, value)
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+				return fmt.Errorf("%s: %q is not a date-time", path, s)
+			}
+		}
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s: %T is not an integer", path, value)
+		}
+		if _, err := n.Int64(); err != nil {
+			return fmt.Errorf("%s: %s is not an integer", path, n)
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return fmt.Errorf("%s: %T is not a number", path, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: %T is not a boolean", path, value)
+		}
+	}
+	return nil
+}
+
+func (d *Document) validateObject(schema *Schema, value any, path string) error {
+	fields, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s: %T is not an object", path, value)
+	}
+	for _, name := range schema.Required {
+		if _, ok := fields[name]; !ok {
+			return fmt.Errorf("%s: missing property %s", path, name)
+		}
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prop := schema.Properties[name]
+		if prop == nil {
+			// An object that lists no properties may hold any
+			if schema.AdditionalProperties || schema.Properties == nil {
+				continue
+			}
+			return fmt.Errorf("%s: undocumented property %s", path, name)
+		}
+		if err := d.validate(prop, fields[name], path+"."+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flatten merges the object schemas of an allOf into one
+func (d *Document) flatten(schema *Schema) (*Schema, error) {
+	merged := &Schema{Type: "object", Nullable: schema.Nullable, Properties: map[string]*Schema{}}
+	parts := append([]*Schema{schema}, schema.AllOf...)
+	for i, part := range parts {
+		part, err := d.Schema(part)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 && len(part.AllOf) > 0 {
+			if part, err = d.flatten(part); err != nil {
+				return nil, err
+			}
+		}
+		for name, prop := range part.Properties {
+			merged.Properties[name] = prop
+		}
+		merged.Required = append(merged.Required, part.Required...)
+		merged.AdditionalProperties = merged.AdditionalProperties || part.AdditionalProperties
+	}
+	return merged, nil
+}
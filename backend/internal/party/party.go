@@ -0,0 +1,658 @@
+// Package party runs listening parties: rooms where a host controls a shared
+// queue and playback state that every member's player follows.
+package party
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"music-player-gin/internal/dto"
+	"music-player-gin/internal/events"
+	"music-player-gin/internal/models"
+)
+
+// HostGrace is how long a disconnected host has to come back before another
+// connected member takes over
+const HostGrace = 30 * time.Second
+
+// codeAlphabet leaves out characters that are easy to misread when a code is read aloud
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const codeLength = 6
+
+// Playback actions a host can send
+const (
+	ActionPlay  = "play"
+	ActionPause = "pause"
+	ActionSeek  = "seek"
+	ActionTrack = "track"
+	ActionNext  = "next"
+)
+
+var (
+	ErrRoomNotFound = errors.New("room not found")
+	ErrNotMember    = errors.New("you are not a member of this room")
+	ErrNotHost      = errors.New("only the host can do this")
+	ErrInvalidInput = errors.New("invalid room command")
+	ErrSongNotFound = errors.New("one or more songs not found")
+)
+
+// inputError explains why a command was rejected. It matches ErrInvalidInput.
+type inputError struct {
+	message string
+}
+
+func (e *inputError) Error() string        { return e.message }
+func (e *inputError) Is(target error) bool { return target == ErrInvalidInput }
+
+// Command changes a room's playback state
+type Command struct {
+	Action          string
+	PositionSeconds *float64
+	Index           *int
+}
+
+// Service manages rooms and tracks which members are connected
+type Service struct {
+	db  *gorm.DB
+	hub *events.Hub
+
+	// mu guards the connection state below. Changes to a room are serialised
+	// by the database instead, see lock, so mu is never held during a query.
+	mu      sync.Mutex
+	online  map[uint]map[uint]int // room ID -> user ID -> open connections
+	handoff map[uint]*time.Timer  // room ID -> pending host hand-off
+}
+
+func NewService(db *gorm.DB, hub *events.Hub) *Service {
+	return &Service{
+		db:      db,
+		hub:     hub,
+		online:  make(map[uint]map[uint]int),
+		handoff: make(map[uint]*time.Timer),
+	}
+}
+
+// Create opens a new room hosted by the user
+func (s *Service) Create(ctx context.Context, hostID uint, name string) (*models.Room, error) {
+	db := s.db.WithContext(ctx)
+	now := time.Now()
+
+	var room models.Room
+	err := db.Transaction(func(tx *gorm.DB) error {
+		code, err := s.newCode(tx)
+		if err != nil {
+			return err
+		}
+		room = models.Room{Code: code, Name: name, HostID: hostID, StateAt: now, Version: 1}
+		if err := tx.Create(&room).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.RoomMember{RoomID: room.ID, UserID: hostID, JoinedAt: now}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.load(db, room.Code)
+}
+
+// Join adds the user to the room with the given code. Joining twice is harmless.
+func (s *Service) Join(ctx context.Context, userID uint, code string) (*models.Room, error) {
+	code = NormalizeCode(code)
+	db := s.db.WithContext(ctx)
+
+	joined := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		room, err := s.lock(tx, code)
+		if err != nil {
+			return err
+		}
+		member := models.RoomMember{RoomID: room.ID, UserID: userID, JoinedAt: time.Now()}
+		res := tx.Where(models.RoomMember{RoomID: room.ID, UserID: userID}).FirstOrCreate(&member)
+		joined = res.RowsAffected > 0
+		return res.Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	room, err := s.load(db, code)
+	if err != nil {
+		return nil, err
+	}
+	if joined {
+		s.publish(events.RoomMembers, room)
+	}
+	return room, nil
+}
+
+// Leave removes the user from the room. A leaving host hands the room to the
+// next member and the room is closed once nobody is left.
+func (s *Service) Leave(ctx context.Context, userID uint, code string) error {
+	code = NormalizeCode(code)
+	db := s.db.WithContext(ctx)
+
+	var room *models.Room
+	var remaining []models.RoomMember
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if room, err = s.lock(tx, code); err != nil {
+			return err
+		}
+		if err := s.checkMember(tx, room.ID, userID); err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.RoomMember{}, "room_id = ? AND user_id = ?", room.ID, userID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("room_id = ?", room.ID).Order("joined_at, user_id").Find(&remaining).Error; err != nil {
+			return err
+		}
+
+		if len(remaining) == 0 {
+			if err := tx.Where("room_id = ?", room.ID).Delete(&models.RoomQueueItem{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(room).Error
+		}
+
+		if room.HostID == userID {
+			room.HostID = s.successor(room.ID, remaining, userID)
+			room.Version++
+			return tx.Model(room).Updates(map[string]any{"host_id": room.HostID, "version": room.Version}).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.online[room.ID], userID)
+	if len(remaining) == 0 {
+		s.forget(room.ID)
+	}
+	s.mu.Unlock()
+	if len(remaining) == 0 {
+		s.hub.Publish(events.RoomTopic(code), events.RoomClosed, map[string]any{"code": code})
+		return nil
+	}
+
+	room, err = s.load(db, code)
+	if err != nil {
+		return err
+	}
+	s.publish(events.RoomMembers, room)
+	return nil
+}
+
+// LeaveAll removes the user from every room they belong to
+func (s *Service) LeaveAll(ctx context.Context, userID uint) error {
+	var codes []string
+	err := s.db.WithContext(ctx).Model(&models.Room{}).
+		Joins("JOIN room_members ON room_members.room_id = rooms.id").
+		Where("room_members.user_id = ?", userID).
+		Pluck("rooms.code", &codes).Error
+	if err != nil {
+		return err
+	}
+	for _, code := range codes {
+		if err := s.Leave(ctx, userID, code); err != nil && !errors.Is(err, ErrRoomNotFound) && !errors.Is(err, ErrNotMember) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get returns the room if the user is one of its members
+func (s *Service) Get(ctx context.Context, userID uint, code string) (*models.Room, error) {
+	code = NormalizeCode(code)
+	db := s.db.WithContext(ctx)
+	if _, err := s.member(db, userID, code); err != nil {
+		return nil, err
+	}
+	return s.load(db, code)
+}
+
+// Control applies a playback command from the host
+func (s *Service) Control(ctx context.Context, userID uint, code string, cmd Command) (*models.Room, error) {
+	return s.update(ctx, userID, code, events.RoomPlayback, func(tx *gorm.DB, room *models.Room, length int) error {
+		now := time.Now()
+		position := Position(room, now)
+		if cmd.PositionSeconds != nil {
+			if *cmd.PositionSeconds < 0 {
+				return &inputError{"position_seconds must not be negative"}
+			}
+			position = *cmd.PositionSeconds
+		}
+
+		switch cmd.Action {
+		case ActionPlay:
+			if length == 0 {
+				return &inputError{"the queue is empty"}
+			}
+			room.Playing = true
+		case ActionPause:
+			room.Playing = false
+		case ActionSeek:
+			if cmd.PositionSeconds == nil {
+				return &inputError{"seek needs position_seconds"}
+			}
+		case ActionTrack, ActionNext:
+			index := room.CurrentIndex + 1
+			if cmd.Action == ActionTrack {
+				if cmd.Index == nil {
+					return &inputError{"track needs index"}
+				}
+				index = *cmd.Index
+			}
+			if index < 0 || index >= length {
+				return &inputError{"index is out of range"}
+			}
+			room.CurrentIndex = index
+			if cmd.PositionSeconds == nil {
+				position = 0
+			}
+		default:
+			return &inputError{"unknown action"}
+		}
+
+		room.PositionSeconds = position
+		room.StateAt = now
+		return nil
+	})
+}
+
+// AddSongs appends songs to the room's queue
+func (s *Service) AddSongs(ctx context.Context, userID uint, code string, songIDs []uint) (*models.Room, error) {
+	return s.update(ctx, userID, code, events.RoomQueue, func(tx *gorm.DB, room *models.Room, length int) error {
+		var found int64
+		if err := tx.Model(&models.Song{}).Where("id IN ?", songIDs).Count(&found).Error; err != nil {
+			return err
+		}
+		if int(found) != len(uniq(songIDs)) {
+			return ErrSongNotFound
+		}
+
+		items := make([]models.RoomQueueItem, len(songIDs))
+		for i, id := range songIDs {
+			items[i] = models.RoomQueueItem{RoomID: room.ID, Position: length + i, SongID: id}
+		}
+		return tx.Create(&items).Error
+	})
+}
+
+// RemoveItem removes the queue entry at position
+func (s *Service) RemoveItem(ctx context.Context, userID uint, code string, position int) (*models.Room, error) {
+	return s.update(ctx, userID, code, events.RoomQueue, func(tx *gorm.DB, room *models.Room, length int) error {
+		if position < 0 || position >= length {
+			return &inputError{"position is out of range"}
+		}
+		if err := tx.Where("room_id = ? AND position = ?", room.ID, position).Delete(&models.RoomQueueItem{}).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&models.RoomQueueItem{}).
+			Where("room_id = ? AND position > ?", room.ID, position).
+			Update("position", gorm.Expr("position - 1")).Error
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case position < room.CurrentIndex:
+			room.CurrentIndex--
+		case position == room.CurrentIndex:
+			// The next song moves into place, so start it from the top
+			room.PositionSeconds = 0
+			room.StateAt = time.Now()
+			if room.CurrentIndex >= length-1 {
+				room.CurrentIndex = max(length-2, 0)
+				room.Playing = false
+			}
+		}
+		return nil
+	})
+}
+
+// TransferHost hands control of the room to another member
+func (s *Service) TransferHost(ctx context.Context, userID uint, code string, newHostID uint) (*models.Room, error) {
+	return s.update(ctx, userID, code, events.RoomHost, func(tx *gorm.DB, room *models.Room, length int) error {
+		var count int64
+		if err := tx.Model(&models.RoomMember{}).Where("room_id = ? AND user_id = ?", room.ID, newHostID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return &inputError{"the new host must be a member of the room"}
+		}
+		room.HostID = newHostID
+		return nil
+	})
+}
+
+// Connect records an open connection from a member. When the host is away,
+// a connecting member starts the countdown to taking over.
+func (s *Service) Connect(room *models.Room, userID uint) {
+	s.mu.Lock()
+	if s.online[room.ID] == nil {
+		s.online[room.ID] = make(map[uint]int)
+	}
+	s.online[room.ID][userID]++
+	first := s.online[room.ID][userID] == 1
+	s.mu.Unlock()
+
+	// The host may have changed since the caller loaded the room
+	var current models.Room
+	if err := s.db.Select("id", "host_id").First(&current, room.ID).Error; err != nil {
+		return
+	}
+	s.mu.Lock()
+	if userID == current.HostID {
+		if t := s.handoff[room.ID]; t != nil {
+			t.Stop()
+			delete(s.handoff, room.ID)
+		}
+	} else if s.online[room.ID][current.HostID] == 0 {
+		s.scheduleHandoff(room.ID)
+	}
+	s.mu.Unlock()
+
+	if first {
+		s.publishPresence(room.Code)
+	}
+}
+
+// Disconnect records a closed connection. A host whose last connection closes
+// loses the room after HostGrace unless they reconnect.
+func (s *Service) Disconnect(room *models.Room, userID uint) {
+	s.mu.Lock()
+	conns := s.online[room.ID]
+	if conns == nil || conns[userID] == 0 {
+		s.mu.Unlock()
+		return
+	}
+	conns[userID]--
+	last := conns[userID] == 0
+	if last {
+		delete(conns, userID)
+	}
+	s.mu.Unlock()
+	if !last {
+		return
+	}
+
+	var current models.Room
+	if err := s.db.Select("id", "host_id").First(&current, room.ID).Error; err != nil {
+		return
+	}
+	if current.HostID == userID {
+		s.mu.Lock()
+		// They may have reconnected while the host was looked up
+		if s.online[room.ID][userID] == 0 {
+			s.scheduleHandoff(room.ID)
+		}
+		s.mu.Unlock()
+	}
+	s.publishPresence(room.Code)
+}
+
+// Position is where playback of the current song is at the given time
+func Position(room *models.Room, now time.Time) float64 {
+	if !room.Playing {
+		return room.PositionSeconds
+	}
+	return room.PositionSeconds + now.Sub(room.StateAt).Seconds()
+}
+
+// NormalizeCode returns a room code the way it is stored. Codes are often
+// typed in by hand, so surrounding space and lower case are accepted.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// update runs fn against a room the user hosts, bumps the version and
+// broadcasts the result
+func (s *Service) update(ctx context.Context, userID uint, code, eventType string, fn func(tx *gorm.DB, room *models.Room, length int) error) (*models.Room, error) {
+	code = NormalizeCode(code)
+	db := s.db.WithContext(ctx)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		room, err := s.lock(tx, code)
+		if err != nil {
+			return err
+		}
+		if err := s.checkMember(tx, room.ID, userID); err != nil {
+			return err
+		}
+		if room.HostID != userID {
+			return ErrNotHost
+		}
+
+		var length int64
+		if err := tx.Model(&models.RoomQueueItem{}).Where("room_id = ?", room.ID).Count(&length).Error; err != nil {
+			return err
+		}
+		if err := fn(tx, room, int(length)); err != nil {
+			return err
+		}
+
+		room.Version++
+		return tx.Model(room).Select("host_id", "current_index", "position_seconds", "playing", "state_at", "version").Updates(room).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	room, err := s.load(db, code)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(eventType, room)
+	return room, nil
+}
+
+// scheduleHandoff starts the host grace period unless one is already running.
+// The caller must hold the lock.
+func (s *Service) scheduleHandoff(roomID uint) {
+	if s.handoff[roomID] != nil {
+		return
+	}
+	s.handoff[roomID] = time.AfterFunc(HostGrace, func() {
+		s.mu.Lock()
+		delete(s.handoff, roomID)
+		s.mu.Unlock()
+		s.handOff(roomID)
+	})
+}
+
+// handOff gives the room to the longest-standing connected member if the
+// host is still away
+func (s *Service) handOff(roomID uint) {
+	var room models.Room
+	if err := s.db.First(&room, roomID).Error; err != nil {
+		return
+	}
+	var members []models.RoomMember
+	if err := s.db.Where("room_id = ?", roomID).Order("joined_at, user_id").Find(&members).Error; err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	host := room.HostID
+	if s.online[roomID][room.HostID] == 0 {
+		for _, m := range members {
+			if m.UserID != room.HostID && s.online[roomID][m.UserID] > 0 {
+				host = m.UserID
+				break
+			}
+		}
+	}
+	s.mu.Unlock()
+	if host == room.HostID {
+		// The host is back, or nobody is connected and the next member to
+		// connect restarts the countdown
+		return
+	}
+
+	// The host may have been handed on since the room was read
+	res := s.db.Model(&models.Room{}).
+		Where("id = ? AND host_id = ?", roomID, room.HostID).
+		Updates(map[string]any{"host_id": host, "version": gorm.Expr("version + 1")})
+	if res.Error != nil || res.RowsAffected == 0 {
+		return
+	}
+	if loaded, err := s.load(s.db, room.Code); err == nil {
+		s.publish(events.RoomHost, loaded)
+	}
+}
+
+// successor picks who takes over from a leaving host: the longest-standing
+// connected member, or the longest-standing member if nobody is connected
+func (s *Service) successor(roomID uint, members []models.RoomMember, leaving uint) uint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range members {
+		if m.UserID != leaving && s.online[roomID][m.UserID] > 0 {
+			return m.UserID
+		}
+	}
+	return members[0].UserID
+}
+
+// forget drops the connection state of a closed room. The caller must hold the lock.
+func (s *Service) forget(roomID uint) {
+	if t := s.handoff[roomID]; t != nil {
+		t.Stop()
+	}
+	delete(s.handoff, roomID)
+	delete(s.online, roomID)
+}
+
+// publishPresence broadcasts the member list after someone came or went
+func (s *Service) publishPresence(code string) {
+	if room, err := s.load(s.db, code); err == nil {
+		s.publish(events.RoomMembers, room)
+	}
+}
+
+func (s *Service) publish(eventType string, room *models.Room) {
+	s.hub.Publish(events.RoomTopic(room.Code), eventType, dto.NewRoom(*room))
+}
+
+func (s *Service) find(db *gorm.DB, code string) (*models.Room, error) {
+	var room models.Room
+	err := db.Where("code = ?", code).First(&room).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRoomNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
+
+// lock finds the room and holds its row until the transaction ends, so
+// changes to the same room wait for each other. The row is written rather
+// than selected FOR UPDATE since that works on every supported database.
+func (s *Service) lock(tx *gorm.DB, code string) (*models.Room, error) {
+	err := tx.Model(&models.Room{}).Where("code = ?", code).UpdateColumn("version", gorm.Expr("version")).Error
+	if err != nil {
+		return nil, err
+	}
+	return s.find(tx, code)
+}
+
+// member returns the room if the user belongs to it
+func (s *Service) member(db *gorm.DB, userID uint, code string) (*models.Room, error) {
+	room, err := s.find(db, code)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkMember(db, room.ID, userID); err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+func (s *Service) checkMember(db *gorm.DB, roomID, userID uint) error {
+	var count int64
+	if err := db.Model(&models.RoomMember{}).Where("room_id = ? AND user_id = ?", roomID, userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotMember
+	}
+	return nil
+}
+
+// load fetches a room with its queue and members
+func (s *Service) load(db *gorm.DB, code string) (*models.Room, error) {
+	var room models.Room
+	err := db.Where("code = ?", code).
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Items.Song").
+		Preload("Members", func(db *gorm.DB) *gorm.DB { return db.Order("joined_at, user_id") }).
+		First(&room).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRoomNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(room.Members))
+	for i, m := range room.Members {
+		ids[i] = m.UserID
+	}
+	var users []models.User
+	if len(ids) > 0 {
+		if err := db.Select("id", "username").Where("id IN ?", ids).Find(&users).Error; err != nil {
+			return nil, err
+		}
+	}
+	names := make(map[uint]string, len(users))
+	for _, u := range users {
+		names[u.ID] = u.Username
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range room.Members {
+		room.Members[i].Username = names[room.Members[i].UserID]
+		room.Members[i].Online = s.online[room.ID][room.Members[i].UserID] > 0
+	}
+	return &room, nil
+}
+
+// newCode generates a join code that is not in use yet
+func (s *Service) newCode(db *gorm.DB) (string, error) {
+	for {
+		b := make([]byte, codeLength)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		for i := range b {
+			b[i] = codeAlphabet[int(b[i])%len(codeAlphabet)]
+		}
+		code := string(b)
+
+		var count int64
+		if err := db.Model(&models.Room{}).Where("code = ?", code).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return code, nil
+		}
+	}
+}
+
+func uniq(ids []uint) map[uint]bool {
+	set := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
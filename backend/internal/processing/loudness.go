@@ -0,0 +1,217 @@
+package processing
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+
+	"music-player-gin/internal/audio"
+	"music-player-gin/internal/jobs"
+	"music-player-gin/internal/loudness"
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/storage"
+)
+
+// analyzeLoudness measures a track's integrated loudness and true peak and
+// keeps its gating blocks so the album can be measured without decoding again
+func (p *Processor) analyzeLoudness(ctx context.Context, job *models.Job) error {
+	song, err := p.loadSong(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	stream, err := audio.OpenMP3(song.FilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return jobs.Permanent(err)
+		}
+		return jobs.Permanent(fmt.Errorf("failed to decode audio: %w", err))
+	}
+	defer stream.Close()
+
+	// Mono sources are decoded to two identical channels but measured as one,
+	// as measuring both would read 3 LU too loud
+	channels := stream.SourceChannels()
+	meter := loudness.NewMeter(stream.SampleRate(), channels)
+	buf := make([]int16, 4096*audio.Channels)
+	for {
+		n, err := stream.ReadFrames(buf)
+		if channels == 1 {
+			for i := 0; i < n; i++ {
+				buf[i] = buf[i*audio.Channels]
+			}
+		}
+		meter.Write(buf[:n*channels])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return jobs.Permanent(fmt.Errorf("failed to decode audio: %w", err))
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	dir, err := storage.Dir(storage.LoudnessDir)
+	if err != nil {
+		return err
+	}
+	blocksPath := filepath.Join(dir, fmt.Sprintf("%d.blocks", song.ID))
+	if err := writeBlocks(blocksPath, meter.Blocks()); err != nil {
+		return err
+	}
+
+	lufs := meter.Integrated()
+	gain := loudness.Gain(lufs)
+	peak := meter.TruePeak()
+	updates := map[string]any{
+		"loudness_blocks_path": blocksPath,
+		"track_gain":           gain,
+		"track_peak":           peak,
+	}
+	// Silent tracks have no measurable loudness
+	if !math.IsInf(lufs, -1) {
+		updates["loudness"] = lufs
+	}
+	if peak > 0 {
+		updates["true_peak"] = loudness.ToDB(peak)
+	}
+	if err := p.db.WithContext(ctx).Model(song).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	song.LoudnessBlocksPath = blocksPath
+	if err := p.enqueueAlbumLoudness(song); err != nil {
+		return err
+	}
+
+	// With gain applied during transcoding the transcode has to wait for the
+	// measurement. Album gain needs the album measured too, so that job queues
+	// the transcodes of the whole album instead.
+	if p.applyGain == "track" || (p.applyGain == "album" && song.Album == "") {
+		return p.enqueueTranscode(song.ID)
+	}
+	return nil
+}
+
+// enqueueAlbumLoudness schedules a measurement of the song's album once the
+// song has both album metadata and a loudness measurement. The job covers the
+// whole album, so it only holds up the song's processing status when the
+// song's transcode waits for it.
+func (p *Processor) enqueueAlbumLoudness(song *models.Song) error {
+	if song.Album == "" || song.LoudnessBlocksPath == "" {
+		return nil
+	}
+	var songID *uint
+	if p.applyGain == "album" {
+		songID = &song.ID
+	}
+	_, err := p.queue.EnqueueOnce(TypeAlbumLoudness, songID, songPayload{SongID: song.ID})
+	return err
+}
+
+// enqueueTranscode schedules a transcode of the song unless one is already waiting
+func (p *Processor) enqueueTranscode(songID uint) error {
+	if !p.queue.Handles(TypeTranscode) {
+		return nil
+	}
+	_, err := p.queue.EnqueueOnce(TypeTranscode, &songID, songPayload{SongID: songID})
+	return err
+}
+
+// analyzeAlbumLoudness measures every analysed track on the song's album as
+// one programme and stores the album gain on each of them. The album is
+// looked up when the job runs so metadata changes made in the meantime count.
+func (p *Processor) analyzeAlbumLoudness(ctx context.Context, job *models.Job) error {
+	song, err := p.loadSong(ctx, job)
+	if err != nil {
+		return err
+	}
+	if song.Album == "" {
+		// The album was cleared since the job was queued, so the track's own gain is used
+		if p.applyGain == "album" {
+			return p.enqueueTranscode(song.ID)
+		}
+		return nil
+	}
+
+	var songs []models.Song
+	err = p.db.WithContext(ctx).
+		Where("artist = ? AND album = ? AND loudness_blocks_path <> ''", song.Artist, song.Album).
+		Find(&songs).Error
+	if err != nil {
+		return err
+	}
+
+	var blocks []float64
+	var peak float64
+	ids := make([]uint, 0, len(songs))
+	for _, song := range songs {
+		trackBlocks, err := readBlocks(song.LoudnessBlocksPath)
+		if err != nil {
+			// The track will queue another album measurement when it is re-analysed
+			continue
+		}
+		blocks = append(blocks, trackBlocks...)
+		if song.TrackPeak != nil {
+			peak = max(peak, *song.TrackPeak)
+		}
+		ids = append(ids, song.ID)
+	}
+	if len(ids) == 0 {
+		if p.applyGain == "album" {
+			return p.enqueueTranscode(song.ID)
+		}
+		return nil
+	}
+
+	err = p.db.WithContext(ctx).Model(&models.Song{}).
+		Where("id IN ?", ids).
+		Updates(map[string]any{
+			"album_gain": loudness.Gain(loudness.Integrated(blocks)),
+			"album_peak": peak,
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	// Every track's stream is made again with the album's new gain
+	if p.applyGain == "album" {
+		for _, id := range ids {
+			if err := p.enqueueTranscode(id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeBlocks stores gating block energies as little-endian float64s
+func writeBlocks(path string, blocks []float64) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := binary.Write(f, binary.LittleEndian, blocks); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func readBlocks(path string) ([]float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	blocks := make([]float64, len(data)/8)
+	for i := range blocks {
+		blocks[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[i*8:]))
+	}
+	return blocks, nil
+}
@@ -0,0 +1,123 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"music-player-gin/internal/events"
+	"music-player-gin/internal/jobs"
+	"music-player-gin/internal/models"
+)
+
+// Job types run after a song is uploaded
+const (
+	TypeExtractTags = "extract_tags"
+	TypeTranscode   = "transcode"
+	TypeWaveform    = "waveform"
+	TypeLoudness    = "loudness"
+
+	// TypeAlbumLoudness measures a whole album and is queued by the per-track jobs
+	TypeAlbumLoudness = "album_loudness"
+)
+
+// songPayload is the payload shared by all per-song jobs
+type songPayload struct {
+	SongID uint `json:"song_id"`
+}
+
+// Processor runs the post-upload pipeline for songs
+type Processor struct {
+	db        *gorm.DB
+	queue     *jobs.Queue
+	hub       *events.Hub
+	ffmpeg    string
+	applyGain string
+}
+
+// NewProcessor registers the pipeline's job handlers with the queue and
+// notifies uploaders through the hub when their songs finish processing
+func NewProcessor(db *gorm.DB, queue *jobs.Queue, hub *events.Hub) *Processor {
+	p := &Processor{db: db, queue: queue, hub: hub, ffmpeg: lookupFFmpeg(), applyGain: transcodeGainMode()}
+	queue.OnSongStatus(p.songStatusChanged)
+
+	queue.Register(TypeExtractTags, p.extractTags)
+	queue.Register(TypeWaveform, p.generateWaveform)
+	queue.Register(TypeLoudness, p.analyzeLoudness)
+	queue.Register(TypeAlbumLoudness, p.analyzeAlbumLoudness)
+	// Transcoding is skipped entirely on hosts without ffmpeg
+	if p.ffmpeg != "" {
+		queue.Register(TypeTranscode, p.transcode)
+	}
+	return p
+}
+
+// EnqueueSong schedules every post-upload job for a freshly created song as
+// part of tx. Call Notify once tx has committed.
+func (p *Processor) EnqueueSong(tx *gorm.DB, song *models.Song) ([]models.Job, error) {
+	var created []models.Job
+	for _, jobType := range []string{TypeExtractTags, TypeWaveform, TypeLoudness, TypeTranscode} {
+		if !p.queue.Handles(jobType) {
+			continue
+		}
+		// When gain is applied the loudness job queues the transcode once it has a measurement
+		if jobType == TypeTranscode && p.applyGain != "" {
+			continue
+		}
+		job, err := p.queue.EnqueueTx(tx, jobType, &song.ID, songPayload{SongID: song.ID})
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, *job)
+	}
+
+	status := models.ProcessingInProgress
+	if len(created) == 0 {
+		status = models.ProcessingReady
+	}
+	if err := tx.Model(song).Update("processing_status", status).Error; err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// Notify starts the jobs queued by EnqueueSong
+func (p *Processor) Notify() {
+	p.queue.Notify()
+}
+
+// songStatusChanged tells the uploader that processing finished
+func (p *Processor) songStatusChanged(songID uint, status string) {
+	if status != models.ProcessingReady && status != models.ProcessingFailed {
+		return
+	}
+
+	var song models.Song
+	if err := p.db.First(&song, songID).Error; err != nil || song.UserID == 0 {
+		return
+	}
+	p.hub.Publish(events.UserTopic(song.UserID), events.SongProcessed, map[string]any{
+		"song_id":           song.ID,
+		"processing_status": status,
+	})
+}
+
+// loadSong fetches the song a job refers to
+func (p *Processor) loadSong(ctx context.Context, job *models.Job) (*models.Song, error) {
+	var payload songPayload
+	if err := jobs.DecodePayload(job, &payload); err != nil {
+		return nil, err
+	}
+
+	var song models.Song
+	err := p.db.WithContext(ctx).First(&song, payload.SongID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, jobs.Permanent(fmt.Errorf("song %d no longer exists", payload.SongID))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &song, nil
+}
@@ -0,0 +1,90 @@
+package processing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/dhowden/tag"
+	"github.com/hajimehoshi/go-mp3"
+
+	"music-player-gin/internal/jobs"
+	"music-player-gin/internal/models"
+)
+
+// extractTags fills in metadata the uploader left blank from the file's ID3 tags
+// and measures the duration when it was not supplied
+func (p *Processor) extractTags(ctx context.Context, job *models.Job) error {
+	song, err := p.loadSong(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(song.FilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+	defer f.Close()
+
+	updates := map[string]any{}
+
+	// Files without tags are fine, we just have nothing to add
+	if meta, err := tag.ReadFrom(f); err == nil {
+		setIfEmpty(updates, "title", song.Title, meta.Title())
+		setIfEmpty(updates, "artist", song.Artist, meta.Artist())
+		setIfEmpty(updates, "album", song.Album, meta.Album())
+		setIfEmpty(updates, "genre", song.Genre, meta.Genre())
+	}
+
+	if song.Duration == 0 {
+		if _, err := f.Seek(0, 0); err != nil {
+			return err
+		}
+		seconds, err := mp3Duration(f)
+		if err != nil {
+			return jobs.Permanent(fmt.Errorf("failed to decode audio: %w", err))
+		}
+		updates["duration"] = seconds
+	}
+
+	if len(updates) == 0 {
+		return nil
+	}
+	if err := p.db.WithContext(ctx).Model(song).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	// If loudness analysis finished first it measured the album under the old metadata
+	_, albumChanged := updates["album"]
+	_, artistChanged := updates["artist"]
+	if albumChanged || artistChanged {
+		if err := p.db.WithContext(ctx).First(song, song.ID).Error; err != nil {
+			return err
+		}
+		return p.enqueueAlbumLoudness(song)
+	}
+	return nil
+}
+
+func setIfEmpty(updates map[string]any, column, current, fromTag string) {
+	if current == "" && fromTag != "" {
+		updates[column] = fromTag
+	}
+}
+
+// mp3Duration returns the length of an MP3 stream in whole seconds
+func mp3Duration(f *os.File) (int, error) {
+	dec, err := mp3.NewDecoder(f)
+	if err != nil {
+		return 0, err
+	}
+	// The decoder always produces 16-bit stereo, so 4 bytes per sample frame
+	samples := dec.Length() / 4
+	if samples <= 0 || dec.SampleRate() == 0 {
+		return 0, fmt.Errorf("could not determine stream length")
+	}
+	return int(samples / int64(dec.SampleRate())), nil
+}
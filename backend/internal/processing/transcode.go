@@ -0,0 +1,95 @@
+package processing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"music-player-gin/internal/jobs"
+	"music-player-gin/internal/loudness"
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/storage"
+)
+
+// lookupFFmpeg returns the ffmpeg binary to use, or "" when transcoding is unavailable.
+// FFMPEG_PATH overrides the lookup in PATH.
+func lookupFFmpeg() string {
+	if path := os.Getenv("FFMPEG_PATH"); path != "" {
+		return path
+	}
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// transcodeBitrate is the bitrate of the low quality stream, configurable with TRANSCODE_BITRATE
+func transcodeBitrate() string {
+	if bitrate := os.Getenv("TRANSCODE_BITRATE"); bitrate != "" {
+		return bitrate
+	}
+	return "128k"
+}
+
+// transcodeGainMode returns which gain, if any, is baked into transcoded
+// streams: "track", "album" or "" (TRANSCODE_APPLY_GAIN)
+func transcodeGainMode() string {
+	switch mode := os.Getenv("TRANSCODE_APPLY_GAIN"); mode {
+	case "track", "album":
+		return mode
+	default:
+		return ""
+	}
+}
+
+// transcode produces a lower bitrate MP3 for streaming over slow connections
+func (p *Processor) transcode(ctx context.Context, job *models.Job) error {
+	song, err := p.loadSong(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	dir, err := storage.Dir(storage.TranscodedDir)
+	if err != nil {
+		return err
+	}
+	bitrate := transcodeBitrate()
+	outPath := filepath.Join(dir, fmt.Sprintf("%d_%s.mp3", song.ID, bitrate))
+
+	args := []string{"-y", "-loglevel", "error", "-i", song.FilePath, "-vn"}
+	if gain, ok := p.transcodeGain(song); ok {
+		args = append(args, "-af", fmt.Sprintf("volume=%.2fdB", gain))
+	}
+	args = append(args, "-codec:a", "libmp3lame", "-b:a", bitrate, outPath)
+	out, err := exec.CommandContext(ctx, p.ffmpeg, args...).CombinedOutput()
+	if err != nil {
+		os.Remove(outPath)
+		if _, statErr := os.Stat(song.FilePath); os.IsNotExist(statErr) {
+			return jobs.Permanent(statErr)
+		}
+		return fmt.Errorf("ffmpeg: %v: %s", err, out)
+	}
+
+	return p.db.WithContext(ctx).Model(song).Update("transcoded_path", outPath).Error
+}
+
+// transcodeGain picks the gain to apply for the configured mode, falling back
+// to the track gain when the album has not been measured. The gain is capped
+// so that the true peak does not exceed full scale.
+func (p *Processor) transcodeGain(song *models.Song) (float64, bool) {
+	if p.applyGain == "" || song.TrackGain == nil {
+		return 0, false
+	}
+
+	gain, peak := *song.TrackGain, song.TrackPeak
+	if p.applyGain == "album" && song.AlbumGain != nil {
+		gain, peak = *song.AlbumGain, song.AlbumPeak
+	}
+	if peak != nil && *peak > 0 {
+		gain = min(gain, -loudness.ToDB(*peak))
+	}
+	return gain, true
+}
@@ -0,0 +1,65 @@
+package processing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"music-player-gin/internal/audio"
+	"music-player-gin/internal/jobs"
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/storage"
+	"music-player-gin/internal/waveform"
+)
+
+// WaveformSamplesPerPixel is the resolution peak data is stored at. Requests
+// for coarser zoom levels are served by resampling the stored data.
+const WaveformSamplesPerPixel = 256
+
+// generateWaveform decodes the song and stores its peak data next to the upload
+func (p *Processor) generateWaveform(ctx context.Context, job *models.Job) error {
+	song, err := p.loadSong(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	stream, err := audio.OpenMP3(song.FilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return jobs.Permanent(err)
+		}
+		return jobs.Permanent(fmt.Errorf("failed to decode audio: %w", err))
+	}
+	defer stream.Close()
+
+	wf, err := waveform.Generate(stream, WaveformSamplesPerPixel)
+	if err != nil {
+		return jobs.Permanent(fmt.Errorf("failed to generate waveform: %w", err))
+	}
+
+	dir, err := storage.Dir(storage.WaveformsDir)
+	if err != nil {
+		return err
+	}
+	outPath := filepath.Join(dir, fmt.Sprintf("%d.dat", song.ID))
+
+	// Write to a temporary file first so readers never see a partial waveform
+	tmp, err := os.CreateTemp(dir, "waveform-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := wf.WriteBinary(tmp, 16); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), outPath); err != nil {
+		return err
+	}
+
+	return p.db.WithContext(ctx).Model(song).Update("waveform_path", outPath).Error
+}
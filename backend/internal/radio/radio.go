@@ -0,0 +1,357 @@
+// Package radio generates endless queues that start from a seed song,
+// artist, genre or playlist and drift towards related music.
+package radio
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	mathrand "math/rand"
+	"sort"
+	"strconv"
+
+	"gorm.io/gorm"
+
+	"music-player-gin/internal/database"
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/recommend"
+)
+
+const (
+	// DefaultFamiliarity is the share of songs the listener already knows
+	DefaultFamiliarity = 0.3
+
+	// recentWindow is how many of the latest songs drive what plays next
+	recentWindow = 5
+
+	// seedWeight keeps the seed relevant as the session drifts
+	seedWeight = 2.0
+)
+
+var (
+	ErrInvalidSeed        = errors.New("invalid radio seed")
+	ErrInvalidFamiliarity = errors.New("familiarity must be between 0 and 1")
+	ErrSeedNotFound       = errors.New("radio seed not found")
+	ErrEmptyLibrary       = errors.New("no songs available")
+	ErrSessionNotFound    = errors.New("radio session not found")
+)
+
+// Service creates radio sessions and hands out their songs
+type Service struct {
+	db     *gorm.DB
+	engine *recommend.Engine
+}
+
+func NewService(db *gorm.DB, engine *recommend.Engine) *Service {
+	return &Service{db: db, engine: engine}
+}
+
+// Start creates a session for the user after checking that the seed exists
+func (s *Service) Start(ctx context.Context, userID uint, seedType, seedValue string, familiarity float64) (*models.RadioSession, error) {
+	if familiarity < 0 || familiarity > 1 {
+		return nil, ErrInvalidFamiliarity
+	}
+
+	if _, err := s.seedSongs(s.db.WithContext(ctx), userID, seedType, seedValue); err != nil {
+		return nil, err
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+	session := models.RadioSession{
+		ID:          id,
+		UserID:      userID,
+		SeedType:    seedType,
+		SeedValue:   seedValue,
+		Familiarity: familiarity,
+	}
+	if err := s.db.WithContext(ctx).Create(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Get returns the user's session with the given ID
+func (s *Service) Get(ctx context.Context, userID uint, id string) (*models.RadioSession, error) {
+	var session models.RadioSession
+	err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Delete ends a session and forgets the songs it played
+func (s *Service) Delete(ctx context.Context, session *models.RadioSession) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("session_id = ?", session.ID).Delete(&models.RadioSessionSong{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(session).Error
+	})
+}
+
+// Next picks the next n songs of the session. Songs already handed out are
+// skipped until the whole library has been played, after which only the
+// most recent half of the session is avoided so the queue never runs dry.
+func (s *Service) Next(ctx context.Context, session *models.RadioSession, n int) ([]models.Song, error) {
+	db := s.db.WithContext(ctx)
+
+	var library int64
+	if err := db.Model(&models.Song{}).Count(&library).Error; err != nil {
+		return nil, err
+	}
+	if library == 0 {
+		return nil, ErrEmptyLibrary
+	}
+
+	// Scoring only ranks songs, so it can follow a history that a concurrent
+	// call is about to extend. What has been played is read again below.
+	var recent []models.RadioSessionSong
+	if err := db.Where("session_id = ?", session.ID).Order("position DESC").Limit(recentWindow).Find(&recent).Error; err != nil {
+		return nil, err
+	}
+	scores, err := s.candidates(ctx, session, recent)
+	if err != nil {
+		return nil, err
+	}
+
+	known, err := s.engine.Familiar(ctx, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ordered []models.Song
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Reserving the positions first locks the session's row, so
+		// concurrent calls read the history and pick one after the other
+		err := tx.Model(&models.RadioSession{}).Where("id = ?", session.ID).
+			Update("served", gorm.Expr("served + ?", n)).Error
+		if err != nil {
+			return err
+		}
+		var served int
+		if err := tx.Model(&models.RadioSession{}).Where("id = ?", session.ID).Pluck("served", &served).Error; err != nil {
+			return err
+		}
+		start := served - n
+
+		var history []models.RadioSessionSong
+		if err := tx.Where("session_id = ?", session.ID).Order("position").Find(&history).Error; err != nil {
+			return err
+		}
+		played := make(map[uint]bool, len(history))
+		avoid := history
+		if int64(len(history)) >= library {
+			avoid = history[len(history)-len(history)/2:]
+		}
+		for _, h := range avoid {
+			played[h.SongID] = true
+		}
+
+		picked := s.pick(scores, played, known, session.Familiarity, n)
+		if len(picked) < n {
+			// Related music has run out, widen to the rest of the library
+			more, err := s.fill(tx, played, picked, n-len(picked))
+			if err != nil {
+				return err
+			}
+			picked = append(picked, more...)
+		}
+
+		var songs []models.Song
+		if len(picked) > 0 {
+			if err := tx.Where("id IN ?", picked).Find(&songs).Error; err != nil {
+				return err
+			}
+		}
+		byID := make(map[uint]models.Song, len(songs))
+		for _, song := range songs {
+			byID[song.ID] = song
+		}
+
+		rows := make([]models.RadioSessionSong, 0, len(picked))
+		for _, id := range picked {
+			song, ok := byID[id]
+			if !ok {
+				continue
+			}
+			ordered = append(ordered, song)
+			rows = append(rows, models.RadioSessionSong{SessionID: session.ID, Position: start + len(rows), SongID: id})
+		}
+		if len(rows) > 0 {
+			if err := tx.Create(&rows).Error; err != nil {
+				return err
+			}
+		}
+		// Give back the positions that found no song
+		if len(rows) < n {
+			served = start + len(rows)
+			if err := tx.Model(&models.RadioSession{}).Where("id = ?", session.ID).Update("served", served).Error; err != nil {
+				return err
+			}
+		}
+		session.Served = served
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ordered, nil
+}
+
+// candidates scores songs by how well they follow the seed and the songs
+// played most recently in the session, given newest first
+func (s *Service) candidates(ctx context.Context, session *models.RadioSession, recent []models.RadioSessionSong) (map[uint]float64, error) {
+	seed, err := s.seedSongs(s.db.WithContext(ctx), session.UserID, session.SeedType, session.SeedValue)
+	if err != nil && !errors.Is(err, ErrSeedNotFound) {
+		return nil, err
+	}
+
+	scores := make(map[uint]float64)
+	for _, id := range seed {
+		scores[id] += seedWeight
+	}
+
+	related, err := s.engine.Related(ctx, seed)
+	if err != nil {
+		return nil, err
+	}
+	for id, score := range related {
+		scores[id] += seedWeight * score
+	}
+
+	ids := make([]uint, len(recent))
+	for i, r := range recent {
+		ids[i] = r.SongID
+	}
+	related, err = s.engine.Related(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for id, score := range related {
+		scores[id] += score
+	}
+	return scores, nil
+}
+
+// pick chooses n songs, drawing from the familiar pool with the given
+// probability. Scores are jittered so repeated sessions from the same seed differ.
+func (s *Service) pick(scores map[uint]float64, played, known map[uint]bool, familiarity float64, n int) []uint {
+	var familiar, fresh []weighted
+	for id, score := range scores {
+		if played[id] {
+			continue
+		}
+		w := weighted{id: id, key: score * (0.5 + mathrand.Float64())}
+		if known[id] {
+			familiar = append(familiar, w)
+		} else {
+			fresh = append(fresh, w)
+		}
+	}
+	sortWeighted(familiar)
+	sortWeighted(fresh)
+
+	picked := make([]uint, 0, n)
+	for len(picked) < n && (len(familiar) > 0 || len(fresh) > 0) {
+		useFamiliar := len(fresh) == 0 || (len(familiar) > 0 && mathrand.Float64() < familiarity)
+		if useFamiliar {
+			picked = append(picked, familiar[0].id)
+			familiar = familiar[1:]
+		} else {
+			picked = append(picked, fresh[0].id)
+			fresh = fresh[1:]
+		}
+	}
+	return picked
+}
+
+// fill returns random songs that have not been played in the session
+func (s *Service) fill(db *gorm.DB, played map[uint]bool, picked []uint, n int) ([]uint, error) {
+	exclude := make([]uint, 0, len(played)+len(picked))
+	for id := range played {
+		exclude = append(exclude, id)
+	}
+	exclude = append(exclude, picked...)
+
+	query := db.Model(&models.Song{}).Order(database.DialectOf(db).Random()).Limit(n)
+	if len(exclude) > 0 {
+		query = query.Where("id NOT IN ?", exclude)
+	}
+	var ids []uint
+	if err := query.Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// seedSongs resolves a seed to the songs it stands for. A playlist seed must
+// be one of the user's own playlists or a public one.
+func (s *Service) seedSongs(db *gorm.DB, userID uint, seedType, seedValue string) ([]uint, error) {
+	if seedValue == "" {
+		return nil, ErrInvalidSeed
+	}
+
+	var ids []uint
+	var err error
+	switch seedType {
+	case models.RadioSeedSong:
+		id, convErr := strconv.ParseUint(seedValue, 10, 0)
+		if convErr != nil {
+			return nil, ErrInvalidSeed
+		}
+		err = db.Model(&models.Song{}).Where("id = ?", id).Pluck("id", &ids).Error
+	case models.RadioSeedArtist:
+		err = db.Model(&models.Song{}).Where("artist = ?", seedValue).Pluck("id", &ids).Error
+	case models.RadioSeedGenre:
+		err = db.Model(&models.Song{}).Where("genre = ?", seedValue).Pluck("id", &ids).Error
+	case models.RadioSeedPlaylist:
+		id, convErr := strconv.ParseUint(seedValue, 10, 0)
+		if convErr != nil {
+			return nil, ErrInvalidSeed
+		}
+		err = db.Table("playlist_songs").
+			Joins("JOIN songs ON songs.id = playlist_songs.song_id AND songs.deleted_at IS NULL").
+			Joins("JOIN playlists ON playlists.id = playlist_songs.playlist_id AND playlists.deleted_at IS NULL").
+			Where("playlist_songs.playlist_id = ? AND (playlists.user_id = ? OR playlists.is_public = ?)", id, userID, true).
+			Pluck("playlist_songs.song_id", &ids).Error
+	default:
+		return nil, ErrInvalidSeed
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, ErrSeedNotFound
+	}
+	return ids, nil
+}
+
+type weighted struct {
+	id  uint
+	key float64
+}
+
+func sortWeighted(ws []weighted) {
+	sort.Slice(ws, func(i, j int) bool {
+		if ws[i].key != ws[j].key {
+			return ws[i].key > ws[j].key
+		}
+		return ws[i].id < ws[j].id
+	})
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
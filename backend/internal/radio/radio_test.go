@@ -0,0 +1,124 @@
+package radio
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"music-player-gin/internal/jobs"
+	"music-player-gin/internal/migrations"
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/recommend"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_pragma=busy_timeout(5000)", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrations.Up(db, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// holdHistoryReads holds each caller that has read a session's history, up
+// to a limit, until the others have read it too, so they all see the same
+// history unless something keeps them apart
+func holdHistoryReads(t *testing.T, db *gorm.DB, callers int32) {
+	t.Helper()
+	var read atomic.Int32
+	err := db.Callback().Query().After("gorm:query").Register("test:hold_history", func(tx *gorm.DB) {
+		if tx.Statement.Table != "radio_session_songs" {
+			return
+		}
+		read.Add(1)
+		deadline := time.Now().Add(200 * time.Millisecond)
+		for read.Load() < callers && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNextConcurrentCallsDoNotRepeat(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	service := NewService(db, recommend.NewEngine(db, jobs.NewQueue(db, 1)))
+
+	user := models.User{Username: "bob", Email: "bob@example.com", PasswordHash: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	songs := []models.Song{{Title: "Seed"}, {Title: "Close"}, {Title: "Distant"}}
+	if err := db.Create(&songs).Error; err != nil {
+		t.Fatal(err)
+	}
+	seed, near, distant := songs[0].ID, songs[1].ID, songs[2].ID
+	// One song follows the seed far better than the other, so every caller
+	// that has not seen it played picks it
+	similar := []models.SongSimilarity{
+		{SongID: seed, SimilarSongID: near, Score: 1},
+		{SongID: seed, SimilarSongID: distant, Score: 0.1},
+	}
+	if err := db.Create(&similar).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := service.Start(ctx, user.ID, models.RadioSeedSong, fmt.Sprint(seed), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	served, err := service.Next(ctx, session, 1)
+	if err != nil || len(served) != 1 {
+		t.Fatalf("first song: %v, %v", served, err)
+	}
+
+	const callers = 2
+	holdHistoryReads(t, db, callers)
+	var wg sync.WaitGroup
+	errs := make([]error, callers)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			own := *session
+			_, errs[i] = service.Next(ctx, &own, 1)
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+
+	var rows []models.RadioSessionSong
+	db.Where("session_id = ?", session.ID).Order("position").Find(&rows)
+	seen := make(map[uint]bool, len(rows))
+	for i, row := range rows {
+		if row.Position != i || seen[row.SongID] {
+			t.Fatalf("served %+v", rows)
+		}
+		seen[row.SongID] = true
+	}
+	if len(rows) != 1+callers {
+		t.Errorf("served %d songs, want %d", len(rows), 1+callers)
+	}
+}
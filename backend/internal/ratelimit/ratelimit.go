@@ -0,0 +1,125 @@
+// Package ratelimit limits how often a key, such as a client IP or a
+// username, may perform an action using token buckets kept in a Store.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Requests actions per Period. Buckets refill continuously, so
+// a client that used up its allowance gets a new token every Period/Requests.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseLimit reads a limit written as "requests/period", for example "5/1m"
+func ParseLimit(s string) (Limit, error) {
+	count, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("rate limit %q must look like 5/1m", s)
+	}
+	requests, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || requests < 1 {
+		return Limit{}, fmt.Errorf("rate limit %q has an invalid request count", s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q has an invalid period", s)
+	}
+	return Limit{Requests: requests, Period: d}, nil
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// Result describes the state of a bucket after taking from it
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration // Until the next token, when not allowed
+	Reset      time.Duration // Until the bucket is full again
+}
+
+// Store keeps token buckets. Implementations must be safe for concurrent use.
+type Store interface {
+	// Take removes a token from the key's bucket if one is available
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Limiter applies one limit to many keys
+type Limiter struct {
+	store Store
+	name  string
+	limit Limit
+}
+
+// New creates a limiter. The name keeps its buckets apart from those of
+// other limiters sharing the store.
+func New(store Store, name string, limit Limit) *Limiter {
+	return &Limiter{store: store, name: name, limit: limit}
+}
+
+// Allow takes a token for the key
+func (l *Limiter) Allow(ctx context.Context, key string) (Result, error) {
+	return l.store.Take(ctx, l.name+":"+key, l.limit)
+}
+
+// Limit returns the limit the limiter applies
+func (l *Limiter) Limit() Limit {
+	return l.limit
+}
+
+// bucket is the state of one key. Tokens are refilled lazily on each take.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket for the time passed and removes a token if possible
+func (b *bucket) take(limit Limit, now time.Time) Result {
+	rate := float64(limit.Requests) / limit.Period.Seconds()
+	burst := float64(limit.Requests)
+
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	result := Result{Limit: limit.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((burst - b.tokens) / rate)
+	return result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// WriteHeaders sets the RateLimit-* headers from the IETF draft, and
+// Retry-After when the request was refused
+func WriteHeaders(h http.Header, limit Limit, r Result) {
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, ceilSeconds(limit.Period)))
+	h.Set("RateLimit-Limit", strconv.Itoa(r.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(r.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(r.Reset)))
+	if !r.Allowed {
+		h.Set("Retry-After", strconv.Itoa(ceilSeconds(r.RetryAfter)))
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
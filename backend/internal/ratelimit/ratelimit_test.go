@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Limit
+		ok   bool
+	}{
+		{"5/1m", Limit{Requests: 5, Period: time.Minute}, true},
+		{" 10 / 30s ", Limit{Requests: 10, Period: 30 * time.Second}, true},
+		{"5", Limit{}, false},
+		{"0/1m", Limit{}, false},
+		{"five/1m", Limit{}, false},
+		{"5/soon", Limit{}, false},
+		{"5/-1m", Limit{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseLimit(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestBucket(t *testing.T) {
+	limit := Limit{Requests: 2, Period: 10 * time.Second}
+	start := time.Now()
+	b := bucket{tokens: 2, last: start}
+
+	for i := 0; i < 2; i++ {
+		if r := b.take(limit, start); !r.Allowed || r.Remaining != 1-i {
+			t.Fatalf("take %d = %+v", i, r)
+		}
+	}
+	r := b.take(limit, start)
+	if r.Allowed || r.RetryAfter != 5*time.Second || r.Reset != 10*time.Second {
+		t.Errorf("take from an empty bucket = %+v", r)
+	}
+
+	// A token comes back every Period/Requests
+	if r := b.take(limit, start.Add(4*time.Second)); r.Allowed {
+		t.Errorf("take before the refill = %+v", r)
+	}
+	if r := b.take(limit, start.Add(5*time.Second)); !r.Allowed {
+		t.Errorf("take after the refill = %+v", r)
+	}
+	// and the bucket never holds more than Requests
+	b.take(limit, start.Add(time.Hour))
+	if b.tokens != 1 {
+		t.Errorf("bucket holds %v tokens after a long wait and a take, want 1", b.tokens)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	a := New(store, "login", Limit{Requests: 1, Period: time.Hour})
+	b := New(store, "register", Limit{Requests: 1, Period: time.Hour})
+
+	if r, _ := a.Allow(ctx, "bob"); !r.Allowed {
+		t.Fatal("first request refused")
+	}
+	if r, _ := a.Allow(ctx, "bob"); r.Allowed {
+		t.Error("second request allowed")
+	}
+	// Keys and limiters have buckets of their own
+	if r, _ := a.Allow(ctx, "alice"); !r.Allowed {
+		t.Error("another key was refused")
+	}
+	if r, _ := b.Allow(ctx, "bob"); !r.Allowed {
+		t.Error("the same key was refused by another limiter")
+	}
+
+	// Sweeping forgets full buckets only
+	full := New(store, "full", Limit{Requests: 1, Period: time.Nanosecond})
+	full.Allow(ctx, "bob")
+	store.mu.Lock()
+	store.sweep(time.Now().Add(time.Second))
+	_, kept := store.buckets["login:bob"]
+	_, swept := store.buckets["full:bob"]
+	store.mu.Unlock()
+	if !kept || swept {
+		t.Errorf("after a sweep login:bob kept = %v, full:bob kept = %v", kept, swept)
+	}
+}
+
+func TestWriteHeaders(t *testing.T) {
+	limit := Limit{Requests: 5, Period: time.Minute}
+	h := http.Header{}
+	WriteHeaders(h, limit, Result{Allowed: true, Limit: 5, Remaining: 4, Reset: 11500 * time.Millisecond})
+	want := map[string]string{"RateLimit-Policy": "5;w=60", "RateLimit-Limit": "5", "RateLimit-Remaining": "4", "RateLimit-Reset": "12"}
+	for key, value := range want {
+		if got := h.Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+	if h.Get("Retry-After") != "" {
+		t.Error("allowed request has Retry-After")
+	}
+
+	WriteHeaders(h, limit, Result{Limit: 5, RetryAfter: 200 * time.Millisecond})
+	if got := h.Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+}
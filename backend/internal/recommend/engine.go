@@ -0,0 +1,370 @@
+// Package recommend builds an item-item similarity model from playlists,
+// favourites and play history and uses it to suggest songs.
+package recommend
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"music-player-gin/internal/jobs"
+	"music-player-gin/internal/models"
+)
+
+// TypeRecompute is the job type that rebuilds the similarity model
+const TypeRecompute = "recompute_similarity"
+
+const (
+	// RecentlyPlayed is how long a played song is kept out of recommendations
+	RecentlyPlayed = 7 * 24 * time.Hour
+
+	// historyHalfLife controls how quickly old plays stop influencing recommendations
+	historyHalfLife = 14 * 24 * time.Hour
+
+	favouriteWeight = 1.0
+	playlistWeight  = 0.5
+)
+
+// Engine computes and serves song recommendations
+type Engine struct {
+	db     *gorm.DB
+	queue  *jobs.Queue
+	metric Metric
+}
+
+// NewEngine registers the recompute job with the queue. The scoring metric is
+// cosine unless RECOMMEND_METRIC is set to jaccard.
+func NewEngine(db *gorm.DB, queue *jobs.Queue) *Engine {
+	metric := Cosine
+	if Metric(os.Getenv("RECOMMEND_METRIC")) == Jaccard {
+		metric = Jaccard
+	}
+
+	e := &Engine{db: db, queue: queue, metric: metric}
+	queue.Register(TypeRecompute, func(ctx context.Context, job *models.Job) error {
+		return e.Recompute(ctx)
+	})
+	return e
+}
+
+// Schedule enqueues a recompute straight away and then once per interval
+// until ctx is cancelled. A recompute already waiting in the queue is not duplicated.
+func (e *Engine) Schedule(ctx context.Context, interval time.Duration) {
+	enqueue := func() {
+		var pending int64
+		e.db.Model(&models.Job{}).
+			Where("type = ? AND status IN ?", TypeRecompute, []string{models.JobPending, models.JobRunning}).
+			Count(&pending)
+		if pending > 0 {
+			return
+		}
+		if _, err := e.queue.Enqueue(TypeRecompute, nil, nil); err != nil {
+			slog.Error("failed to schedule recompute", "component", "recommend", "err", err)
+		}
+	}
+
+	go func() {
+		enqueue()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				enqueue()
+			}
+		}
+	}()
+}
+
+// ScoredSong is a recommended song with its relevance score
+type ScoredSong struct {
+	models.Song
+	Score float64 `json:"score"`
+}
+
+// Similar returns the songs most similar to songID, leaving out what the user
+// played within RecentlyPlayed. When the model has too few neighbours, for
+// instance for a new upload, songs from the same artist and genre fill the
+// remaining slots.
+func (e *Engine) Similar(ctx context.Context, userID, songID uint, limit int) ([]ScoredSong, error) {
+	db := e.db.WithContext(ctx)
+
+	var seed models.Song
+	if err := db.First(&seed, songID).Error; err != nil {
+		return nil, err
+	}
+
+	var recent []uint
+	err := db.Model(&models.PlayHistory{}).
+		Where("user_id = ? AND played_at > ? AND song_id <> ?", userID, time.Now().Add(-RecentlyPlayed), songID).
+		Distinct().Pluck("song_id", &recent).Error
+	if err != nil {
+		return nil, err
+	}
+	exclude := make(map[uint]bool, len(recent))
+	for _, id := range recent {
+		exclude[id] = true
+	}
+
+	var neighbours []models.SongSimilarity
+	err = db.Where("song_id = ?", songID).Order("score DESC").Limit(limit + len(exclude)).Find(&neighbours).Error
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[uint]float64, len(neighbours))
+	for _, n := range neighbours {
+		if !exclude[n.SimilarSongID] {
+			scores[n.SimilarSongID] = n.Score
+		}
+	}
+
+	if len(scores) < limit {
+		skip := append(append(keys(scores), recent...), songID)
+		var fill []models.Song
+		query := db.Select("id", "artist", "genre").Where("id NOT IN ?", skip)
+		switch {
+		case seed.Artist != "" && seed.Genre != "":
+			query = query.Where("artist = ? OR genre = ?", seed.Artist, seed.Genre)
+		case seed.Artist != "":
+			query = query.Where("artist = ?", seed.Artist)
+		case seed.Genre != "":
+			query = query.Where("genre = ?", seed.Genre)
+		default:
+			query = nil
+		}
+		if query != nil {
+			if err := query.Limit(limit - len(scores)).Find(&fill).Error; err != nil {
+				return nil, err
+			}
+		}
+		for _, s := range fill {
+			scores[s.ID] = contentScore(seed, s)
+		}
+	}
+
+	return e.load(db, scores, exclude, limit)
+}
+
+// Related sums the similarity of every song to the given songs, for building
+// queues that follow on from several songs at once
+func (e *Engine) Related(ctx context.Context, songIDs []uint) (map[uint]float64, error) {
+	scores := make(map[uint]float64)
+	if len(songIDs) == 0 {
+		return scores, nil
+	}
+
+	var neighbours []models.SongSimilarity
+	if err := e.db.WithContext(ctx).Where("song_id IN ?", songIDs).Find(&neighbours).Error; err != nil {
+		return nil, err
+	}
+	for _, n := range neighbours {
+		scores[n.SimilarSongID] += n.Score
+	}
+	return scores, nil
+}
+
+// Familiar returns the songs a user already knows from favourites or play history
+func (e *Engine) Familiar(ctx context.Context, userID uint) (map[uint]bool, error) {
+	db := e.db.WithContext(ctx)
+	known := make(map[uint]bool)
+
+	var ids []uint
+	if err := db.Model(&models.UserFavoriteSong{}).Where("user_id = ?", userID).Pluck("song_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		known[id] = true
+	}
+
+	ids = nil
+	if err := db.Model(&models.PlayHistory{}).Where("user_id = ?", userID).Distinct().Pluck("song_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		known[id] = true
+	}
+	return known, nil
+}
+
+// ForUser recommends songs based on the user's favourites, playlists and
+// listening history, skipping favourites and anything heard within
+// RecentlyPlayed. Users without any activity get the most played songs.
+func (e *Engine) ForUser(ctx context.Context, userID uint, limit int) ([]ScoredSong, error) {
+	db := e.db.WithContext(ctx)
+
+	seeds, exclude, err := e.userSeeds(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[uint]float64)
+	if len(seeds) > 0 {
+		var neighbours []models.SongSimilarity
+		if err := db.Where("song_id IN ?", keys(seeds)).Find(&neighbours).Error; err != nil {
+			return nil, err
+		}
+		for _, n := range neighbours {
+			if !exclude[n.SimilarSongID] {
+				scores[n.SimilarSongID] += seeds[n.SongID] * n.Score
+			}
+		}
+	}
+
+	if len(scores) < limit {
+		popular, err := e.popular(db, exclude, scores, limit-len(scores))
+		if err != nil {
+			return nil, err
+		}
+		for id, score := range popular {
+			scores[id] = score
+		}
+	}
+
+	return e.load(db, scores, exclude, limit)
+}
+
+// userSeeds returns the songs that describe a user's taste, weighted by how
+// strong a signal they are, and the songs that should not be recommended
+func (e *Engine) userSeeds(db *gorm.DB, userID uint) (map[uint]float64, map[uint]bool, error) {
+	seeds := make(map[uint]float64)
+	exclude := make(map[uint]bool)
+
+	var favourites []models.UserFavoriteSong
+	if err := db.Where("user_id = ?", userID).Find(&favourites).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, f := range favourites {
+		seeds[f.SongID] += favouriteWeight
+		exclude[f.SongID] = true
+	}
+
+	var playlistSongs []uint
+	err := db.Table("playlist_songs").
+		Joins("JOIN playlists ON playlists.id = playlist_songs.playlist_id AND playlists.deleted_at IS NULL").
+		Where("playlists.user_id = ?", userID).
+		Pluck("playlist_songs.song_id", &playlistSongs).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, id := range playlistSongs {
+		seeds[id] += playlistWeight
+	}
+
+	var plays []models.PlayHistory
+	err = db.Where("user_id = ? AND played_at > ?", userID, time.Now().Add(-historyWindow)).Find(&plays).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	for _, p := range plays {
+		age := now.Sub(p.PlayedAt)
+		seeds[p.SongID] += math.Pow(0.5, float64(age)/float64(historyHalfLife))
+		if age < RecentlyPlayed {
+			exclude[p.SongID] = true
+		}
+	}
+
+	return seeds, exclude, nil
+}
+
+// popular returns the most played songs of the last month that are neither
+// excluded nor already scored, with small scores so they rank below real matches
+func (e *Engine) popular(db *gorm.DB, exclude map[uint]bool, scored map[uint]float64, limit int) (map[uint]float64, error) {
+	var rows []struct {
+		SongID uint
+		Plays  int64
+	}
+	err := db.Model(&models.PlayHistory{}).
+		Select("song_id, COUNT(*) AS plays").
+		Where("played_at > ?", time.Now().Add(-30*24*time.Hour)).
+		Group("song_id").
+		Order("plays DESC").
+		Limit(limit + len(exclude) + len(scored)).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint]float64)
+	for _, r := range rows {
+		if len(result) == limit {
+			break
+		}
+		if exclude[r.SongID] {
+			continue
+		}
+		if _, ok := scored[r.SongID]; ok {
+			continue
+		}
+		result[r.SongID] = 0.01 * math.Log1p(float64(r.Plays))
+	}
+	return result, nil
+}
+
+// load fetches the scored songs, best first
+func (e *Engine) load(db *gorm.DB, scores map[uint]float64, exclude map[uint]bool, limit int) ([]ScoredSong, error) {
+	ids := keys(scores)
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+
+	filtered := ids[:0]
+	for _, id := range ids {
+		if !exclude[id] {
+			filtered = append(filtered, id)
+		}
+	}
+	if len(filtered) > limit {
+		filtered = filtered[:limit]
+	}
+	if len(filtered) == 0 {
+		return []ScoredSong{}, nil
+	}
+
+	var songs []models.Song
+	if err := db.Where("id IN ?", filtered).Find(&songs).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Song, len(songs))
+	for _, s := range songs {
+		byID[s.ID] = s
+	}
+
+	result := make([]ScoredSong, 0, len(filtered))
+	for _, id := range filtered {
+		if s, ok := byID[id]; ok {
+			result = append(result, ScoredSong{Song: s, Score: scores[id]})
+		}
+	}
+	return result, nil
+}
+
+func contentScore(a, b models.Song) float64 {
+	var score float64
+	if a.Artist != "" && a.Artist == b.Artist {
+		score += sameArtistBoost
+	}
+	if a.Genre != "" && a.Genre == b.Genre {
+		score += sameGenreBoost
+	}
+	return score
+}
+
+func keys[V any](m map[uint]V) []uint {
+	ids := make([]uint, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	return ids
+}
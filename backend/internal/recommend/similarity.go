@@ -0,0 +1,225 @@
+package recommend
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"music-player-gin/internal/models"
+)
+
+const (
+	// neighboursPerSong caps how many similar songs are stored for each song
+	neighboursPerSong = 50
+
+	// maxBasketSize keeps heavy listeners from dominating the co-occurrence counts
+	maxBasketSize = 200
+
+	// historyWindow is how far back play history counts towards co-occurrence
+	historyWindow = 90 * 24 * time.Hour
+
+	// Content boosts added on top of the co-occurrence score
+	sameArtistBoost = 0.2
+	sameGenreBoost  = 0.1
+)
+
+// Metric scores how strongly two songs co-occur
+type Metric string
+
+const (
+	Cosine  Metric = "cosine"
+	Jaccard Metric = "jaccard"
+)
+
+// score returns the similarity of two songs that appear together in
+// together baskets and individually in a and b baskets
+func (m Metric) score(together, a, b int) float64 {
+	if together == 0 {
+		return 0
+	}
+	if m == Jaccard {
+		return float64(together) / float64(a+b-together)
+	}
+	return float64(together) / math.Sqrt(float64(a)*float64(b))
+}
+
+type pair struct {
+	a, b uint
+}
+
+// Recompute rebuilds the song_similarities table from playlists, favourites
+// and recent play history. Each playlist is a basket, as is each user's
+// combined favourites and history; songs sharing baskets are similar.
+func (e *Engine) Recompute(ctx context.Context) error {
+	db := e.db.WithContext(ctx)
+
+	var songs []models.Song
+	if err := db.Select("id", "artist", "genre").Find(&songs).Error; err != nil {
+		return err
+	}
+	byID := make(map[uint]models.Song, len(songs))
+	for _, s := range songs {
+		byID[s.ID] = s
+	}
+
+	baskets, err := e.baskets(db)
+	if err != nil {
+		return err
+	}
+
+	occurrences := make(map[uint]int)
+	together := make(map[pair]int)
+	for _, basket := range baskets {
+		// Songs deleted since they were added to a basket are ignored
+		live := basket[:0]
+		for _, id := range basket {
+			if _, ok := byID[id]; ok {
+				live = append(live, id)
+			}
+		}
+		for i, a := range live {
+			occurrences[a]++
+			for _, b := range live[i+1:] {
+				together[pair{min(a, b), max(a, b)}]++
+			}
+		}
+	}
+
+	scores := make(map[pair]float64, len(together))
+	for p, n := range together {
+		scores[p] = e.metric.score(n, occurrences[p.a], occurrences[p.b])
+	}
+
+	// Songs by the same artist are related even if nobody has listened to them together yet
+	byArtist := make(map[string][]uint)
+	for _, s := range songs {
+		if s.Artist != "" {
+			byArtist[s.Artist] = append(byArtist[s.Artist], s.ID)
+		}
+	}
+	for _, ids := range byArtist {
+		for i, a := range ids {
+			for _, b := range ids[i+1:] {
+				p := pair{min(a, b), max(a, b)}
+				if _, ok := scores[p]; !ok {
+					scores[p] = 0
+				}
+			}
+		}
+	}
+
+	neighbours := make(map[uint][]models.SongSimilarity)
+	for p, score := range scores {
+		a, b := byID[p.a], byID[p.b]
+		if a.Artist != "" && a.Artist == b.Artist {
+			score += sameArtistBoost
+		}
+		if a.Genre != "" && a.Genre == b.Genre {
+			score += sameGenreBoost
+		}
+		if score <= 0 {
+			continue
+		}
+		neighbours[p.a] = append(neighbours[p.a], models.SongSimilarity{SongID: p.a, SimilarSongID: p.b, Score: score})
+		neighbours[p.b] = append(neighbours[p.b], models.SongSimilarity{SongID: p.b, SimilarSongID: p.a, Score: score})
+	}
+
+	var rows []models.SongSimilarity
+	for _, list := range neighbours {
+		sort.Slice(list, func(i, j int) bool { return list[i].Score > list[j].Score })
+		if len(list) > neighboursPerSong {
+			list = list[:neighboursPerSong]
+		}
+		rows = append(rows, list...)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.SongSimilarity{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(rows, 500).Error
+	})
+}
+
+// baskets loads the groups of songs used for co-occurrence counting
+func (e *Engine) baskets(db *gorm.DB) ([][]uint, error) {
+	var playlistRows []struct {
+		PlaylistID uint
+		SongID     uint
+	}
+	err := db.Table("playlist_songs").
+		Joins("JOIN playlists ON playlists.id = playlist_songs.playlist_id AND playlists.deleted_at IS NULL").
+		Select("playlist_songs.playlist_id, playlist_songs.song_id").
+		Scan(&playlistRows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var favouriteRows []struct {
+		UserID uint
+		SongID uint
+	}
+	err = db.Model(&models.UserFavoriteSong{}).
+		Select("user_id, song_id").
+		Order("created_at DESC").
+		Scan(&favouriteRows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var historyRows []struct {
+		UserID uint
+		SongID uint
+	}
+	err = db.Model(&models.PlayHistory{}).
+		Select("user_id, song_id, MAX(played_at) AS last_played").
+		Where("played_at > ?", time.Now().Add(-historyWindow)).
+		Group("user_id, song_id").
+		Order("last_played DESC").
+		Scan(&historyRows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	playlists := make(map[uint][]uint)
+	for _, r := range playlistRows {
+		playlists[r.PlaylistID] = append(playlists[r.PlaylistID], r.SongID)
+	}
+
+	// A user's favourites and listening history together describe their taste
+	users := make(map[uint][]uint)
+	seen := make(map[pair]bool)
+	addToUser := func(userID, songID uint) {
+		p := pair{userID, songID}
+		if seen[p] || len(users[userID]) >= maxBasketSize {
+			return
+		}
+		seen[p] = true
+		users[userID] = append(users[userID], songID)
+	}
+	for _, r := range favouriteRows {
+		addToUser(r.UserID, r.SongID)
+	}
+	for _, r := range historyRows {
+		addToUser(r.UserID, r.SongID)
+	}
+
+	baskets := make([][]uint, 0, len(playlists)+len(users))
+	for _, songs := range playlists {
+		if len(songs) > maxBasketSize {
+			songs = songs[:maxBasketSize]
+		}
+		baskets = append(baskets, songs)
+	}
+	for _, songs := range users {
+		baskets = append(baskets, songs)
+	}
+	return baskets, nil
+}
@@ -0,0 +1,97 @@
+// Package memory implements the repositories with maps, so services and
+// handlers can be tested without a database. A Store holds the data the
+// repositories share, such as the songs playlists refer to.
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/repository"
+)
+
+// pair is a user and the song or user they relate to
+type pair struct{ a, b uint }
+
+// Store holds songs, playlists and users in memory
+type Store struct {
+	mu     sync.Mutex
+	nextID uint
+
+	songs         map[uint]models.Song
+	playlists     map[uint]models.Playlist
+	playlistSongs map[uint][]uint // Playlist ID -> song IDs in the order they were added
+	users         map[uint]models.User
+	favourites    map[pair]time.Time
+	follows       map[pair]time.Time
+	plays         []models.PlayHistory
+}
+
+// New returns an empty store
+func New() *Store {
+	return &Store{
+		songs:         make(map[uint]models.Song),
+		playlists:     make(map[uint]models.Playlist),
+		playlistSongs: make(map[uint][]uint),
+		users:         make(map[uint]models.User),
+		favourites:    make(map[pair]time.Time),
+		follows:       make(map[pair]time.Time),
+	}
+}
+
+// Songs returns the store's song repository
+func (s *Store) Songs() repository.SongRepository { return songRepository{s} }
+
+// Playlists returns the store's playlist repository
+func (s *Store) Playlists() repository.PlaylistRepository { return playlistRepository{s} }
+
+// Users returns the store's user repository
+func (s *Store) Users() repository.UserRepository { return userRepository{s} }
+
+// AddUser stores a user, giving it an ID if it has none, and returns it
+func (s *Store) AddUser(user models.User) models.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if user.ID == 0 {
+		user.ID = s.id()
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+		user.UpdatedAt = user.CreatedAt
+	}
+	s.users[user.ID] = user
+	return user
+}
+
+// AddSong stores a song, giving it an ID if it has none, and returns it
+func (s *Store) AddSong(song models.Song) models.Song {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.putSong(&song)
+	return song
+}
+
+// Plays returns the plays recorded so far, oldest first
+func (s *Store) Plays() []models.PlayHistory {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.PlayHistory(nil), s.plays...)
+}
+
+// id hands out IDs; one sequence for every table keeps them easy to tell apart
+func (s *Store) id() uint {
+	s.nextID++
+	return s.nextID
+}
+
+func (s *Store) putSong(song *models.Song) {
+	if song.ID == 0 {
+		song.ID = s.id()
+	}
+	if song.CreatedAt.IsZero() {
+		song.CreatedAt = time.Now()
+		song.UpdatedAt = song.CreatedAt
+	}
+	s.songs[song.ID] = *song
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/repository"
+)
+
+type playlistRepository struct {
+	s *Store
+}
+
+func (r playlistRepository) ListByUser(ctx context.Context, userID uint, publicOnly bool) ([]models.Playlist, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	playlists := []models.Playlist{}
+	for _, p := range r.s.playlists {
+		if p.UserID == userID && (p.IsPublic || !publicOnly) {
+			playlists = append(playlists, p)
+		}
+	}
+	sort.Slice(playlists, func(i, j int) bool {
+		if !playlists[i].CreatedAt.Equal(playlists[j].CreatedAt) {
+			return playlists[i].CreatedAt.After(playlists[j].CreatedAt)
+		}
+		return playlists[i].ID > playlists[j].ID
+	})
+	return playlists, nil
+}
+
+func (r playlistRepository) Get(ctx context.Context, id uint, withSongs bool) (*models.Playlist, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	playlist, ok := r.s.playlists[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	if withSongs {
+		playlist.Songs = []models.Song{}
+		for _, songID := range r.s.playlistSongs[id] {
+			if song, ok := r.s.songs[songID]; ok {
+				playlist.Songs = append(playlist.Songs, song)
+			}
+		}
+	}
+	return &playlist, nil
+}
+
+func (r playlistRepository) Create(ctx context.Context, playlist *models.Playlist) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	playlist.ID = r.s.id()
+	playlist.CreatedAt = time.Now()
+	playlist.UpdatedAt = playlist.CreatedAt
+	stored := *playlist
+	stored.Songs = nil
+	r.s.playlists[playlist.ID] = stored
+	return nil
+}
+
+func (r playlistRepository) Update(ctx context.Context, playlist *models.Playlist, changes repository.PlaylistChanges) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stored, ok := r.s.playlists[playlist.ID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	changes.Apply(&stored)
+	stored.UpdatedAt = time.Now()
+	r.s.playlists[playlist.ID] = stored
+	changes.Apply(playlist)
+	playlist.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+func (r playlistRepository) AddSong(ctx context.Context, playlistID, songID uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if !slices.Contains(r.s.playlistSongs[playlistID], songID) {
+		r.s.playlistSongs[playlistID] = append(r.s.playlistSongs[playlistID], songID)
+	}
+	return nil
+}
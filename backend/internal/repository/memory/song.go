@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/repository"
+)
+
+type songRepository struct {
+	s *Store
+}
+
+func (r songRepository) List(ctx context.Context) ([]models.Song, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	songs := make([]models.Song, 0, len(r.s.songs))
+	for _, song := range r.s.songs {
+		songs = append(songs, song)
+	}
+	sort.Slice(songs, func(i, j int) bool { return songs[i].ID < songs[j].ID })
+	return songs, nil
+}
+
+func (r songRepository) Get(ctx context.Context, id uint) (*models.Song, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	song, ok := r.s.songs[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &song, nil
+}
+
+// Create stores the song; no processing jobs are queued in memory
+func (r songRepository) Create(ctx context.Context, song *models.Song) ([]models.Job, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.putSong(song)
+	return []models.Job{}, nil
+}
+
+func (r songRepository) RecordPlay(ctx context.Context, play *models.PlayHistory) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	play.ID = r.s.id()
+	r.s.plays = append(r.s.plays, *play)
+	return nil
+}
+
+func (r songRepository) IsFavourite(ctx context.Context, userID, songID uint) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	_, ok := r.s.favourites[pair{userID, songID}]
+	return ok, nil
+}
+
+func (r songRepository) AddFavourite(ctx context.Context, userID, songID uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.favourites[pair{userID, songID}] = time.Now()
+	return nil
+}
+
+func (r songRepository) RemoveFavourite(ctx context.Context, userID, songID uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	delete(r.s.favourites, pair{userID, songID})
+	return nil
+}
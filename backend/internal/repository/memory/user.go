@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/repository"
+)
+
+type userRepository struct {
+	s *Store
+}
+
+func (r userRepository) Get(ctx context.Context, id uint) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	user, ok := r.s.users[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r userRepository) GetMany(ctx context.Context, ids []uint) ([]models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var users []models.User
+	for _, id := range ids {
+		if user, ok := r.s.users[id]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (r userRepository) CountFollowers(ctx context.Context, userID uint) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var count int64
+	for f := range r.s.follows {
+		if f.b == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r userRepository) CountFollowing(ctx context.Context, userID uint) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var count int64
+	for f := range r.s.follows {
+		if f.a == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r userRepository) IsFollowing(ctx context.Context, followerID, followeeID uint) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	_, ok := r.s.follows[pair{followerID, followeeID}]
+	return ok, nil
+}
+
+func (r userRepository) Follow(ctx context.Context, followerID, followeeID uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.follows[pair{followerID, followeeID}]; !ok {
+		r.s.follows[pair{followerID, followeeID}] = time.Now()
+	}
+	return nil
+}
+
+func (r userRepository) Unfollow(ctx context.Context, followerID, followeeID uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	delete(r.s.follows, pair{followerID, followeeID})
+	return nil
+}
+
+func (r userRepository) Follows(ctx context.Context, query repository.FollowQuery) ([]models.Follow, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	// other is the user on the far side of a follow; it breaks ties between follows made at the same time
+	other := func(f models.Follow) uint {
+		if query.Followers {
+			return f.FollowerID
+		}
+		return f.FolloweeID
+	}
+
+	var follows []models.Follow
+	for p, at := range r.s.follows {
+		f := models.Follow{FollowerID: p.a, FolloweeID: p.b, CreatedAt: at}
+		if (query.Followers && p.b != query.UserID) || (!query.Followers && p.a != query.UserID) {
+			continue
+		}
+		if after := query.After; after != nil && !(at.Before(after.At) || (at.Equal(after.At) && other(f) < after.UserID)) {
+			continue
+		}
+		follows = append(follows, f)
+	}
+	sort.Slice(follows, func(i, j int) bool {
+		if !follows[i].CreatedAt.Equal(follows[j].CreatedAt) {
+			return follows[i].CreatedAt.After(follows[j].CreatedAt)
+		}
+		return other(follows[i]) > other(follows[j])
+	})
+	if query.Limit > 0 && len(follows) > query.Limit {
+		follows = follows[:query.Limit]
+	}
+	return follows, nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"music-player-gin/internal/models"
+)
+
+type playlistRepository struct {
+	db *gorm.DB
+}
+
+// NewPlaylistRepository stores playlists with GORM
+func NewPlaylistRepository(db *gorm.DB) PlaylistRepository {
+	return &playlistRepository{db: db}
+}
+
+func (r *playlistRepository) ListByUser(ctx context.Context, userID uint, publicOnly bool) ([]models.Playlist, error) {
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if publicOnly {
+		query = query.Where("is_public = ?", true)
+	}
+	var playlists []models.Playlist
+	if err := query.Order("created_at DESC").Find(&playlists).Error; err != nil {
+		return nil, err
+	}
+	return playlists, nil
+}
+
+func (r *playlistRepository) Get(ctx context.Context, id uint, withSongs bool) (*models.Playlist, error) {
+	query := r.db.WithContext(ctx)
+	if withSongs {
+		query = query.Preload("Songs")
+	}
+	var playlist models.Playlist
+	if err := query.First(&playlist, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &playlist, nil
+}
+
+func (r *playlistRepository) Create(ctx context.Context, playlist *models.Playlist) error {
+	return r.db.WithContext(ctx).Create(playlist).Error
+}
+
+func (r *playlistRepository) Update(ctx context.Context, playlist *models.Playlist, changes PlaylistChanges) error {
+	updates := map[string]any{}
+	if changes.Name != nil {
+		updates["name"] = *changes.Name
+	}
+	if changes.Description != nil {
+		updates["description"] = *changes.Description
+	}
+	if changes.IsPublic != nil {
+		updates["is_public"] = *changes.IsPublic
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	if err := r.db.WithContext(ctx).Model(playlist).Updates(updates).Error; err != nil {
+		return err
+	}
+	changes.Apply(playlist)
+	return nil
+}
+
+func (r *playlistRepository) AddSong(ctx context.Context, playlistID, songID uint) error {
+	// Adding a song that is already in the playlist is harmless
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Table("playlist_songs").
+		Create(map[string]any{"playlist_id": playlistID, "song_id": songID}).Error
+}
@@ -0,0 +1,88 @@
+// Package repository loads and stores songs, playlists and users. Services
+// depend on the interfaces here: the GORM implementations back the server,
+// and the in-memory ones in package memory let tests run without a database.
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"music-player-gin/internal/models"
+)
+
+// ErrNotFound is returned when a record does not exist
+var ErrNotFound = errors.New("record not found")
+
+// SongRepository stores songs, plays and favourites
+type SongRepository interface {
+	List(ctx context.Context) ([]models.Song, error)
+	Get(ctx context.Context, id uint) (*models.Song, error)
+	// Create stores a song together with the jobs that process it, so a song
+	// is never left without its pipeline
+	Create(ctx context.Context, song *models.Song) ([]models.Job, error)
+	RecordPlay(ctx context.Context, play *models.PlayHistory) error
+	IsFavourite(ctx context.Context, userID, songID uint) (bool, error)
+	AddFavourite(ctx context.Context, userID, songID uint) error
+	RemoveFavourite(ctx context.Context, userID, songID uint) error
+}
+
+// PlaylistChanges are the playlist fields to update; nil fields are left alone
+type PlaylistChanges struct {
+	Name        *string
+	Description *string
+	IsPublic    *bool
+}
+
+// Apply copies the changes onto playlist
+func (c PlaylistChanges) Apply(playlist *models.Playlist) {
+	if c.Name != nil {
+		playlist.Name = *c.Name
+	}
+	if c.Description != nil {
+		playlist.Description = *c.Description
+	}
+	if c.IsPublic != nil {
+		playlist.IsPublic = *c.IsPublic
+	}
+}
+
+// PlaylistRepository stores playlists and the songs in them
+type PlaylistRepository interface {
+	// ListByUser returns a user's playlists, newest first
+	ListByUser(ctx context.Context, userID uint, publicOnly bool) ([]models.Playlist, error)
+	Get(ctx context.Context, id uint, withSongs bool) (*models.Playlist, error)
+	Create(ctx context.Context, playlist *models.Playlist) error
+	// Update saves the changes and applies them to playlist
+	Update(ctx context.Context, playlist *models.Playlist, changes PlaylistChanges) error
+	AddSong(ctx context.Context, playlistID, songID uint) error
+}
+
+// FollowPosition is where a page of follows ends
+type FollowPosition struct {
+	At     time.Time
+	UserID uint
+}
+
+// FollowQuery selects a page of a user's followers or of the users they follow
+type FollowQuery struct {
+	UserID    uint
+	Followers bool            // Followers of UserID rather than users UserID follows
+	After     *FollowPosition // Start after this follow
+	Limit     int
+}
+
+// UserRepository stores users and who follows whom
+type UserRepository interface {
+	Get(ctx context.Context, id uint) (*models.User, error)
+	// GetMany returns the users that exist among ids, in no particular order
+	GetMany(ctx context.Context, ids []uint) ([]models.User, error)
+	CountFollowers(ctx context.Context, userID uint) (int64, error)
+	CountFollowing(ctx context.Context, userID uint) (int64, error)
+	IsFollowing(ctx context.Context, followerID, followeeID uint) (bool, error)
+	// Follow records a follow; following twice is harmless
+	Follow(ctx context.Context, followerID, followeeID uint) error
+	Unfollow(ctx context.Context, followerID, followeeID uint) error
+	// Follows returns follows matching the query, newest first
+	Follows(ctx context.Context, query FollowQuery) ([]models.Follow, error)
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/processing"
+)
+
+type songRepository struct {
+	db        *gorm.DB
+	processor *processing.Processor
+}
+
+// NewSongRepository stores songs with GORM and queues their processing jobs with processor
+func NewSongRepository(db *gorm.DB, processor *processing.Processor) SongRepository {
+	return &songRepository{db: db, processor: processor}
+}
+
+func (r *songRepository) List(ctx context.Context) ([]models.Song, error) {
+	var songs []models.Song
+	if err := r.db.WithContext(ctx).Find(&songs).Error; err != nil {
+		return nil, err
+	}
+	return songs, nil
+}
+
+func (r *songRepository) Get(ctx context.Context, id uint) (*models.Song, error) {
+	var song models.Song
+	if err := r.db.WithContext(ctx).First(&song, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &song, nil
+}
+
+func (r *songRepository) Create(ctx context.Context, song *models.Song) ([]models.Job, error) {
+	var queued []models.Job
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(song).Error; err != nil {
+			return err
+		}
+		var err error
+		queued, err = r.processor.EnqueueSong(tx, song)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Workers only see the jobs once the transaction has committed
+	r.processor.Notify()
+	return queued, nil
+}
+
+func (r *songRepository) RecordPlay(ctx context.Context, play *models.PlayHistory) error {
+	return r.db.WithContext(ctx).Create(play).Error
+}
+
+func (r *songRepository) IsFavourite(ctx context.Context, userID, songID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.UserFavoriteSong{}).
+		Where("user_id = ? AND song_id = ?", userID, songID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *songRepository) AddFavourite(ctx context.Context, userID, songID uint) error {
+	return r.db.WithContext(ctx).Create(&models.UserFavoriteSong{UserID: userID, SongID: songID}).Error
+}
+
+func (r *songRepository) RemoveFavourite(ctx context.Context, userID, songID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ? AND song_id = ?", userID, songID).Delete(&models.UserFavoriteSong{}).Error
+}
+
+// notFound turns GORM's missing record error into ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"music-player-gin/internal/models"
+)
+
+type userRepository struct {
+	db *gorm.DB
+}
+
+// NewUserRepository stores users and follows with GORM
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return &userRepository{db: db}
+}
+
+func (r *userRepository) Get(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (r *userRepository) GetMany(ctx context.Context, ids []uint) ([]models.User, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var users []models.User
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *userRepository) CountFollowers(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Follow{}).Where("followee_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *userRepository) CountFollowing(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Follow{}).Where("follower_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *userRepository) IsFollowing(ctx context.Context, followerID, followeeID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Follow{}).
+		Where("follower_id = ? AND followee_id = ?", followerID, followeeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *userRepository) Follow(ctx context.Context, followerID, followeeID uint) error {
+	follow := models.Follow{FollowerID: followerID, FolloweeID: followeeID}
+	return r.db.WithContext(ctx).Where(follow).FirstOrCreate(&follow).Error
+}
+
+func (r *userRepository) Unfollow(ctx context.Context, followerID, followeeID uint) error {
+	return r.db.WithContext(ctx).Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Delete(&models.Follow{}).Error
+}
+
+func (r *userRepository) Follows(ctx context.Context, query FollowQuery) ([]models.Follow, error) {
+	column, other := "follower_id", "followee_id"
+	if query.Followers {
+		column, other = other, column
+	}
+
+	db := r.db.WithContext(ctx).Where(column+" = ?", query.UserID)
+	if after := query.After; after != nil {
+		db = db.Where("created_at < ? OR (created_at = ? AND "+other+" < ?)", after.At, after.At, after.UserID)
+	}
+	var follows []models.Follow
+	if err := db.Order("created_at DESC, " + other + " DESC").Limit(query.Limit).Find(&follows).Error; err != nil {
+		return nil, err
+	}
+	return follows, nil
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+
+	"music-player-gin/internal/dto"
+	"music-player-gin/internal/events"
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/repository"
+)
+
+// PlaylistService manages playlists and tells their owner's devices about changes
+type PlaylistService interface {
+	// List returns all of the user's own playlists
+	List(ctx context.Context, userID uint) ([]models.Playlist, error)
+	// ListVisible returns the owner's playlists the viewer may see: all of
+	// them for the owner, the public ones for anyone else
+	ListVisible(ctx context.Context, viewerID, ownerID uint) ([]models.Playlist, error)
+	Create(ctx context.Context, playlist *models.Playlist) error
+	// Songs returns the songs of a playlist the viewer owns or that is public
+	Songs(ctx context.Context, viewerID, playlistID uint) ([]models.Song, error)
+	// AddSong adds a song to a playlist the user owns and returns the playlist with its songs
+	AddSong(ctx context.Context, userID, playlistID, songID uint) (*models.Playlist, error)
+	// Update changes a playlist the user owns
+	Update(ctx context.Context, userID, playlistID uint, changes repository.PlaylistChanges) (*models.Playlist, error)
+}
+
+type playlistService struct {
+	playlists repository.PlaylistRepository
+	songs     repository.SongRepository
+	hub       *events.Hub
+}
+
+func NewPlaylistService(playlists repository.PlaylistRepository, songs repository.SongRepository, hub *events.Hub) PlaylistService {
+	return &playlistService{playlists: playlists, songs: songs, hub: hub}
+}
+
+func (s *playlistService) List(ctx context.Context, userID uint) ([]models.Playlist, error) {
+	return s.playlists.ListByUser(ctx, userID, false)
+}
+
+func (s *playlistService) ListVisible(ctx context.Context, viewerID, ownerID uint) ([]models.Playlist, error) {
+	return s.playlists.ListByUser(ctx, ownerID, viewerID != ownerID)
+}
+
+func (s *playlistService) Create(ctx context.Context, playlist *models.Playlist) error {
+	if err := s.playlists.Create(ctx, playlist); err != nil {
+		return err
+	}
+	s.hub.Publish(events.UserTopic(playlist.UserID), events.PlaylistCreated, dto.NewPlaylist(*playlist))
+	return nil
+}
+
+func (s *playlistService) Songs(ctx context.Context, viewerID, playlistID uint) ([]models.Song, error) {
+	playlist, err := s.playlists.Get(ctx, playlistID, true)
+	if err != nil {
+		return nil, translate(err, ErrPlaylistNotFound)
+	}
+	if playlist.UserID != viewerID && !playlist.IsPublic {
+		return nil, ErrPlaylistNotFound
+	}
+	return playlist.Songs, nil
+}
+
+func (s *playlistService) AddSong(ctx context.Context, userID, playlistID, songID uint) (*models.Playlist, error) {
+	playlist, err := s.playlists.Get(ctx, playlistID, false)
+	if err != nil {
+		return nil, translate(err, ErrPlaylistNotFound)
+	}
+	if playlist.UserID != userID {
+		return nil, ErrPlaylistNotFound
+	}
+	if _, err := s.songs.Get(ctx, songID); err != nil {
+		return nil, translate(err, ErrSongNotFound)
+	}
+	if err := s.playlists.AddSong(ctx, playlistID, songID); err != nil {
+		return nil, err
+	}
+	if playlist, err = s.playlists.Get(ctx, playlistID, true); err != nil {
+		return nil, err
+	}
+
+	s.hub.Publish(events.UserTopic(playlist.UserID), events.PlaylistUpdated, map[string]any{
+		"playlist_id": playlist.ID,
+		"song_id":     songID,
+		"action":      "song_added",
+	})
+	return playlist, nil
+}
+
+func (s *playlistService) Update(ctx context.Context, userID, playlistID uint, changes repository.PlaylistChanges) (*models.Playlist, error) {
+	playlist, err := s.playlists.Get(ctx, playlistID, false)
+	if err != nil {
+		return nil, translate(err, ErrPlaylistNotFound)
+	}
+	// Someone else's playlist is reported as missing rather than revealed
+	if playlist.UserID != userID {
+		return nil, ErrPlaylistNotFound
+	}
+	if err := s.playlists.Update(ctx, playlist, changes); err != nil {
+		return nil, err
+	}
+
+	s.hub.Publish(events.UserTopic(playlist.UserID), events.PlaylistUpdated, map[string]any{
+		"playlist_id": playlist.ID,
+		"action":      "details_changed",
+	})
+	return playlist, nil
+}
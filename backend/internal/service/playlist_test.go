@@ -0,0 +1,141 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"music-player-gin/internal/events"
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/repository"
+	"music-player-gin/internal/repository/memory"
+	"music-player-gin/internal/service"
+)
+
+func newPlaylistService(store *memory.Store) (service.PlaylistService, *events.Hub) {
+	hub := events.NewHub()
+	return service.NewPlaylistService(store.Playlists(), store.Songs(), hub), hub
+}
+
+func TestPlaylistCreateAndList(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	playlists, hub := newPlaylistService(store)
+	bob := store.AddUser(models.User{Username: "bob"})
+
+	sub, _ := hub.Subscribe([]string{events.UserTopic(bob.ID)}, 0)
+	defer sub.Close()
+
+	private := models.Playlist{Name: "Private", UserID: bob.ID}
+	public := models.Playlist{Name: "Public", UserID: bob.ID, IsPublic: true}
+	for _, p := range []*models.Playlist{&private, &public} {
+		if err := playlists.Create(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if e := <-sub.C; e.Type != events.PlaylistCreated {
+		t.Errorf("published %q", e.Type)
+	}
+
+	own, err := playlists.List(ctx, bob.ID)
+	if err != nil || len(own) != 2 || own[0].ID != public.ID {
+		t.Errorf("own playlists = %+v, %v", own, err)
+	}
+	visible, err := playlists.ListVisible(ctx, bob.ID+100, bob.ID)
+	if err != nil || len(visible) != 1 || visible[0].ID != public.ID {
+		t.Errorf("visible to others = %+v, %v", visible, err)
+	}
+}
+
+func TestPlaylistUpdate(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	playlists, _ := newPlaylistService(store)
+	bob := store.AddUser(models.User{Username: "bob"})
+	alice := store.AddUser(models.User{Username: "alice"})
+
+	playlist := models.Playlist{Name: "Mix", UserID: bob.ID}
+	if err := playlists.Create(ctx, &playlist); err != nil {
+		t.Fatal(err)
+	}
+
+	name, public := "Renamed", true
+	updated, err := playlists.Update(ctx, bob.ID, playlist.ID, repository.PlaylistChanges{Name: &name, IsPublic: &public})
+	if err != nil || updated.Name != name || !updated.IsPublic || updated.Description != "" {
+		t.Errorf("updated = %+v, %v", updated, err)
+	}
+
+	// Someone else's playlist looks like it does not exist
+	if _, err := playlists.Update(ctx, alice.ID, playlist.ID, repository.PlaylistChanges{Name: &name}); !errors.Is(err, service.ErrPlaylistNotFound) {
+		t.Errorf("update by another user: %v", err)
+	}
+	if _, err := playlists.Update(ctx, bob.ID, 9999, repository.PlaylistChanges{}); !errors.Is(err, service.ErrPlaylistNotFound) {
+		t.Errorf("update of a missing playlist: %v", err)
+	}
+}
+
+func TestPlaylistSongsVisibility(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	playlists, _ := newPlaylistService(store)
+	bob := store.AddUser(models.User{Username: "bob"})
+	alice := store.AddUser(models.User{Username: "alice"})
+	song := store.AddSong(models.Song{Title: "Song"})
+
+	private := models.Playlist{Name: "Private", UserID: bob.ID}
+	public := models.Playlist{Name: "Public", UserID: bob.ID, IsPublic: true}
+	for _, p := range []*models.Playlist{&private, &public} {
+		if err := playlists.Create(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := playlists.AddSong(ctx, bob.ID, p.ID, song.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if songs, err := playlists.Songs(ctx, bob.ID, private.ID); err != nil || len(songs) != 1 {
+		t.Errorf("owner's private songs = %+v, %v", songs, err)
+	}
+	if songs, err := playlists.Songs(ctx, alice.ID, public.ID); err != nil || len(songs) != 1 {
+		t.Errorf("public songs = %+v, %v", songs, err)
+	}
+	// A private playlist is reported as missing to everyone but its owner
+	if _, err := playlists.Songs(ctx, alice.ID, private.ID); !errors.Is(err, service.ErrPlaylistNotFound) {
+		t.Errorf("private songs for another user: %v", err)
+	}
+}
+
+func TestPlaylistAddSongOwnership(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	playlists, hub := newPlaylistService(store)
+	bob := store.AddUser(models.User{Username: "bob"})
+	alice := store.AddUser(models.User{Username: "alice"})
+	song := store.AddSong(models.Song{Title: "Song"})
+
+	playlist := models.Playlist{Name: "Public", UserID: bob.ID, IsPublic: true}
+	if err := playlists.Create(ctx, &playlist); err != nil {
+		t.Fatal(err)
+	}
+	sub, _ := hub.Subscribe([]string{events.UserTopic(bob.ID)}, 0)
+	defer sub.Close()
+
+	// Even a public playlist only takes songs from its owner
+	if _, err := playlists.AddSong(ctx, alice.ID, playlist.ID, song.ID); !errors.Is(err, service.ErrPlaylistNotFound) {
+		t.Errorf("add by another user: %v", err)
+	}
+	if _, err := playlists.AddSong(ctx, bob.ID, playlist.ID, 9999); !errors.Is(err, service.ErrSongNotFound) {
+		t.Errorf("add of a missing song: %v", err)
+	}
+	if songs, _ := playlists.Songs(ctx, bob.ID, playlist.ID); len(songs) != 0 {
+		t.Errorf("rejected adds changed the playlist: %+v", songs)
+	}
+
+	updated, err := playlists.AddSong(ctx, bob.ID, playlist.ID, song.ID)
+	if err != nil || len(updated.Songs) != 1 || updated.Songs[0].ID != song.ID {
+		t.Fatalf("added = %+v, %v", updated, err)
+	}
+	if e := <-sub.C; e.Type != events.PlaylistUpdated {
+		t.Errorf("published %q", e.Type)
+	}
+}
@@ -0,0 +1,26 @@
+// Package service holds the rules for songs, playlists and users. Handlers
+// depend on the interfaces here, and the implementations reach storage only
+// through the repository interfaces.
+package service
+
+import (
+	"errors"
+
+	"music-player-gin/internal/repository"
+)
+
+var (
+	ErrSongNotFound     = errors.New("song not found")
+	ErrSongFileMissing  = errors.New("song file not found")
+	ErrPlaylistNotFound = errors.New("playlist not found")
+	ErrUserNotFound     = errors.New("user not found")
+	ErrFollowSelf       = errors.New("you cannot follow yourself")
+)
+
+// translate replaces the repository's not found error with notFound
+func translate(err, notFound error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return notFound
+	}
+	return err
+}
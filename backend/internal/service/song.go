@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/repository"
+)
+
+// SongService manages the song library, plays and favourites
+type SongService interface {
+	List(ctx context.Context) ([]models.Song, error)
+	Get(ctx context.Context, id uint) (*models.Song, error)
+	// Create adds an uploaded song and starts processing it
+	Create(ctx context.Context, song *models.Song) ([]models.Job, error)
+	// Play returns the file to stream for a song. A play is recorded for the
+	// user when the stream starts at the beginning of the file.
+	Play(ctx context.Context, userID, songID uint, lowQuality, fromStart bool) (string, error)
+	// ToggleFavourite adds the song to the user's favourites, or removes it if
+	// it is there, and reports whether it is now a favourite
+	ToggleFavourite(ctx context.Context, userID, songID uint) (bool, error)
+}
+
+type songService struct {
+	songs repository.SongRepository
+	users repository.UserRepository
+}
+
+func NewSongService(songs repository.SongRepository, users repository.UserRepository) SongService {
+	return &songService{songs: songs, users: users}
+}
+
+func (s *songService) List(ctx context.Context) ([]models.Song, error) {
+	return s.songs.List(ctx)
+}
+
+func (s *songService) Get(ctx context.Context, id uint) (*models.Song, error) {
+	song, err := s.songs.Get(ctx, id)
+	if err != nil {
+		return nil, translate(err, ErrSongNotFound)
+	}
+	return song, nil
+}
+
+func (s *songService) Create(ctx context.Context, song *models.Song) ([]models.Job, error) {
+	song.ProcessingStatus = models.ProcessingPending
+	return s.songs.Create(ctx, song)
+}
+
+func (s *songService) Play(ctx context.Context, userID, songID uint, lowQuality, fromStart bool) (string, error) {
+	song, err := s.Get(ctx, songID)
+	if err != nil {
+		return "", err
+	}
+
+	// Serve the transcoded stream when the client asks for low quality and one exists
+	path := song.FilePath
+	if lowQuality && song.TranscodedPath != "" {
+		path = song.TranscodedPath
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return "", ErrSongFileMissing
+	}
+
+	// Range requests for later parts of the file are the same play continuing
+	if fromStart {
+		if err := s.songs.RecordPlay(ctx, &models.PlayHistory{UserID: userID, SongID: song.ID, PlayedAt: time.Now()}); err != nil {
+			return "", err
+		}
+	}
+	return path, nil
+}
+
+func (s *songService) ToggleFavourite(ctx context.Context, userID, songID uint) (bool, error) {
+	if _, err := s.Get(ctx, songID); err != nil {
+		return false, err
+	}
+	if _, err := s.users.Get(ctx, userID); err != nil {
+		return false, translate(err, ErrUserNotFound)
+	}
+
+	favourite, err := s.songs.IsFavourite(ctx, userID, songID)
+	if err != nil {
+		return false, err
+	}
+	if favourite {
+		return false, s.songs.RemoveFavourite(ctx, userID, songID)
+	}
+	return true, s.songs.AddFavourite(ctx, userID, songID)
+}
@@ -0,0 +1,82 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/repository/memory"
+	"music-player-gin/internal/service"
+)
+
+func TestSongPlay(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	songs := service.NewSongService(store.Songs(), store.Users())
+	bob := store.AddUser(models.User{Username: "bob"})
+
+	dir := t.TempDir()
+	original := filepath.Join(dir, "song.mp3")
+	low := filepath.Join(dir, "song.low.mp3")
+	for _, path := range []string{original, low} {
+		if err := os.WriteFile(path, []byte("audio"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	song := store.AddSong(models.Song{Title: "Song", FilePath: original, TranscodedPath: low})
+
+	path, err := songs.Play(ctx, bob.ID, song.ID, false, true)
+	if err != nil || path != original {
+		t.Fatalf("play = %q, %v", path, err)
+	}
+	if path, _ := songs.Play(ctx, bob.ID, song.ID, true, false); path != low {
+		t.Errorf("low quality play = %q", path)
+	}
+	// Only the request starting at the beginning of the file counts as a play
+	if plays := store.Plays(); len(plays) != 1 || plays[0].UserID != bob.ID || plays[0].SongID != song.ID {
+		t.Errorf("plays = %+v", plays)
+	}
+
+	missing := store.AddSong(models.Song{Title: "Gone", FilePath: filepath.Join(dir, "gone.mp3")})
+	if _, err := songs.Play(ctx, bob.ID, missing.ID, false, true); !errors.Is(err, service.ErrSongFileMissing) {
+		t.Errorf("play of a missing file: %v", err)
+	}
+	if _, err := songs.Play(ctx, bob.ID, 9999, false, true); !errors.Is(err, service.ErrSongNotFound) {
+		t.Errorf("play of a missing song: %v", err)
+	}
+}
+
+func TestSongToggleFavourite(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	songs := service.NewSongService(store.Songs(), store.Users())
+	bob := store.AddUser(models.User{Username: "bob"})
+	song := store.AddSong(models.Song{Title: "Song"})
+
+	for i, want := range []bool{true, false, true} {
+		if got, err := songs.ToggleFavourite(ctx, bob.ID, song.ID); err != nil || got != want {
+			t.Errorf("toggle %d = %v, %v, want %v", i, got, err, want)
+		}
+	}
+	if _, err := songs.ToggleFavourite(ctx, bob.ID, 9999); !errors.Is(err, service.ErrSongNotFound) {
+		t.Errorf("favourite of a missing song: %v", err)
+	}
+	if _, err := songs.ToggleFavourite(ctx, 9999, song.ID); !errors.Is(err, service.ErrUserNotFound) {
+		t.Errorf("favourite by a missing user: %v", err)
+	}
+}
+
+func TestSongCreate(t *testing.T) {
+	store := memory.New()
+	songs := service.NewSongService(store.Songs(), store.Users())
+	song := models.Song{Title: "New"}
+	if _, err := songs.Create(context.Background(), &song); err != nil {
+		t.Fatal(err)
+	}
+	if song.ID == 0 || song.ProcessingStatus != models.ProcessingPending {
+		t.Errorf("created %+v", song)
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/repository"
+)
+
+// UserProfile is a user with their follow counts as seen by a viewer
+type UserProfile struct {
+	User        models.User
+	Followers   int64
+	Following   int64
+	IsFollowing bool // Whether the viewer follows the user
+}
+
+// FollowedUser is one side of a follow and when it was made
+type FollowedUser struct {
+	User       models.User
+	FollowedAt time.Time
+}
+
+// FollowPage is a page of followers or followees, newest first. Next is
+// where the following page starts, or nil on the last page.
+type FollowPage struct {
+	Users []FollowedUser
+	Next  *repository.FollowPosition
+}
+
+// UserService looks up users and manages who follows whom
+type UserService interface {
+	Get(ctx context.Context, id uint) (*models.User, error)
+	Profile(ctx context.Context, viewerID, id uint) (*UserProfile, error)
+	Follow(ctx context.Context, followerID, followeeID uint) error
+	Unfollow(ctx context.Context, followerID, followeeID uint) error
+	// Followers pages through who follows the user
+	Followers(ctx context.Context, userID uint, after *repository.FollowPosition, limit int) (*FollowPage, error)
+	// Following pages through who the user follows
+	Following(ctx context.Context, userID uint, after *repository.FollowPosition, limit int) (*FollowPage, error)
+}
+
+type userService struct {
+	users repository.UserRepository
+}
+
+func NewUserService(users repository.UserRepository) UserService {
+	return &userService{users: users}
+}
+
+func (s *userService) Get(ctx context.Context, id uint) (*models.User, error) {
+	user, err := s.users.Get(ctx, id)
+	if err != nil {
+		return nil, translate(err, ErrUserNotFound)
+	}
+	return user, nil
+}
+
+func (s *userService) Profile(ctx context.Context, viewerID, id uint) (*UserProfile, error) {
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	profile := &UserProfile{User: *user}
+	if profile.Followers, err = s.users.CountFollowers(ctx, id); err != nil {
+		return nil, err
+	}
+	if profile.Following, err = s.users.CountFollowing(ctx, id); err != nil {
+		return nil, err
+	}
+	if profile.IsFollowing, err = s.users.IsFollowing(ctx, viewerID, id); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+func (s *userService) Follow(ctx context.Context, followerID, followeeID uint) error {
+	if _, err := s.Get(ctx, followeeID); err != nil {
+		return err
+	}
+	if followerID == followeeID {
+		return ErrFollowSelf
+	}
+	return s.users.Follow(ctx, followerID, followeeID)
+}
+
+func (s *userService) Unfollow(ctx context.Context, followerID, followeeID uint) error {
+	if _, err := s.Get(ctx, followeeID); err != nil {
+		return err
+	}
+	return s.users.Unfollow(ctx, followerID, followeeID)
+}
+
+func (s *userService) Followers(ctx context.Context, userID uint, after *repository.FollowPosition, limit int) (*FollowPage, error) {
+	return s.follows(ctx, repository.FollowQuery{UserID: userID, Followers: true, After: after, Limit: limit})
+}
+
+func (s *userService) Following(ctx context.Context, userID uint, after *repository.FollowPosition, limit int) (*FollowPage, error) {
+	return s.follows(ctx, repository.FollowQuery{UserID: userID, After: after, Limit: limit})
+}
+
+func (s *userService) follows(ctx context.Context, query repository.FollowQuery) (*FollowPage, error) {
+	if _, err := s.Get(ctx, query.UserID); err != nil {
+		return nil, err
+	}
+
+	// One extra row tells whether there is another page
+	limit := query.Limit
+	query.Limit++
+	follows, err := s.users.Follows(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	more := len(follows) > limit
+	if more {
+		follows = follows[:limit]
+	}
+
+	// other is the user on the far side of a follow
+	other := func(f models.Follow) uint {
+		if query.Followers {
+			return f.FollowerID
+		}
+		return f.FolloweeID
+	}
+
+	page := &FollowPage{Users: []FollowedUser{}}
+	if more {
+		last := follows[len(follows)-1]
+		page.Next = &repository.FollowPosition{At: last.CreatedAt, UserID: other(last)}
+	}
+
+	ids := make([]uint, len(follows))
+	for i, f := range follows {
+		ids[i] = other(f)
+	}
+	users, err := s.users.GetMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+	for _, f := range follows {
+		if u, ok := byID[other(f)]; ok {
+			page.Users = append(page.Users, FollowedUser{User: u, FollowedAt: f.CreatedAt})
+		}
+	}
+	return page, nil
+}
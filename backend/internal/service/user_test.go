@@ -0,0 +1,86 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"music-player-gin/internal/models"
+	"music-player-gin/internal/repository/memory"
+	"music-player-gin/internal/service"
+)
+
+func TestUserFollow(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	users := service.NewUserService(store.Users())
+	bob := store.AddUser(models.User{Username: "bob"})
+	alice := store.AddUser(models.User{Username: "alice"})
+
+	if err := users.Follow(ctx, bob.ID, bob.ID); !errors.Is(err, service.ErrFollowSelf) {
+		t.Errorf("self follow: %v", err)
+	}
+	if err := users.Follow(ctx, bob.ID, 9999); !errors.Is(err, service.ErrUserNotFound) {
+		t.Errorf("follow of a missing user: %v", err)
+	}
+	// Following twice is harmless
+	for range 2 {
+		if err := users.Follow(ctx, bob.ID, alice.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	profile, err := users.Profile(ctx, bob.ID, alice.ID)
+	if err != nil || profile.Followers != 1 || profile.Following != 0 || !profile.IsFollowing {
+		t.Errorf("alice's profile = %+v, %v", profile, err)
+	}
+
+	if err := users.Unfollow(ctx, bob.ID, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if profile, _ := users.Profile(ctx, bob.ID, alice.ID); profile.Followers != 0 || profile.IsFollowing {
+		t.Errorf("after unfollowing = %+v", profile)
+	}
+}
+
+func TestUserFollowersPages(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	users := service.NewUserService(store.Users())
+	star := store.AddUser(models.User{Username: "star"})
+
+	var fans []models.User
+	for _, name := range []string{"a", "b", "c"} {
+		fan := store.AddUser(models.User{Username: name})
+		fans = append(fans, fan)
+		if err := users.Follow(ctx, fan.ID, star.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Paging visits every follower once, newest first
+	var seen []uint
+	page, err := users.Followers(ctx, star.ID, nil, 2)
+	for err == nil {
+		for _, f := range page.Users {
+			seen = append(seen, f.User.ID)
+		}
+		if page.Next == nil {
+			break
+		}
+		page, err = users.Followers(ctx, star.ID, page.Next, 2)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != len(fans) || seen[0] != fans[2].ID || seen[1] != fans[1].ID || seen[2] != fans[0].ID {
+		t.Fatalf("followers = %v", seen)
+	}
+	following, err := users.Following(ctx, fans[0].ID, nil, 10)
+	if err != nil || len(following.Users) != 1 || following.Users[0].User.ID != star.ID || following.Next != nil {
+		t.Errorf("following = %+v, %v", following, err)
+	}
+	if _, err := users.Followers(ctx, 9999, nil, 10); !errors.Is(err, service.ErrUserNotFound) {
+		t.Errorf("followers of a missing user: %v", err)
+	}
+}
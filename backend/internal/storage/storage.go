@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Subdirectories of the upload root
+const (
+	SongsDir      = "songs"
+	TranscodedDir = "transcoded"
+	WaveformsDir  = "waveforms"
+	LoudnessDir   = "loudness"
+	AvatarsDir    = "avatars"
+)
+
+// Root returns the directory uploaded and generated files are stored under.
+// It defaults to ./uploads and can be overridden with UPLOAD_DIR.
+func Root() string {
+	if dir := os.Getenv("UPLOAD_DIR"); dir != "" {
+		return dir
+	}
+	return "./uploads"
+}
+
+// Dir returns the given subdirectory of the upload root, creating it if needed
+func Dir(name string) (string, error) {
+	dir := filepath.Join(Root(), name)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
@@ -0,0 +1,87 @@
+// Package tokens issues and redeems single-use, expiring tokens such as the
+// ones sent in email confirmation links, and long-lived personal access tokens.
+package tokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"music-player-gin/internal/models"
+)
+
+// ErrInvalid means the token does not exist, has expired or was already used
+var ErrInvalid = errors.New("invalid or expired token")
+
+// Issue creates a token for the user and returns the raw value to send them.
+// Earlier unused tokens for the same purpose stop working.
+func Issue(db *gorm.DB, userID uint, purpose, data string, ttl time.Duration) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	raw := base64.RawURLEncoding.EncodeToString(b)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := Revoke(tx, userID, purpose); err != nil {
+			return err
+		}
+		return tx.Create(&models.UserToken{
+			UserID:    userID,
+			Purpose:   purpose,
+			TokenHash: hash(raw),
+			Data:      data,
+			ExpiresAt: time.Now().Add(ttl),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// Revoke stops the user's unused tokens for the purpose from working
+func Revoke(db *gorm.DB, userID uint, purpose string) error {
+	return db.Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Delete(&models.UserToken{}).Error
+}
+
+// Consume redeems a token for the given purpose. A token can only be consumed once.
+func Consume(db *gorm.DB, raw, purpose string) (*models.UserToken, error) {
+	var token models.UserToken
+	err := db.Where("token_hash = ? AND purpose = ?", hash(raw), purpose).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if token.UsedAt != nil || now.After(token.ExpiresAt) {
+		return nil, ErrInvalid
+	}
+
+	// Marking the token used only if nobody else did stops two requests redeeming it at once
+	res := db.Model(&models.UserToken{}).
+		Where("id = ? AND used_at IS NULL", token.ID).
+		Update("used_at", now)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrInvalid
+	}
+	token.UsedAt = &now
+	return &token, nil
+}
+
+func hash(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
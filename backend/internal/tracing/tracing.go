@@ -0,0 +1,69 @@
+// Package tracing sets up OpenTelemetry tracing and starts the spans the
+// server records itself. Requests are traced by otelgin, queries by otelgorm
+// and outgoing HTTP by otelhttp; trace context is carried between services
+// in W3C traceparent headers.
+//
+// Spans are started with Start from a context. Within a request the context
+// carries the request's span; elsewhere, such as in background jobs, it
+// carries the provider set with WithProvider. Without either, Start records
+// nothing.
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/uptrace/opentelemetry-go-extra/otelgorm"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"gorm.io/gorm"
+)
+
+// ScopeName names the instrumentation in the spans Start records
+const ScopeName = "music-player-gin"
+
+// Propagator reads and writes traceparent, tracestate and baggage headers
+var Propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+type providerKey struct{}
+
+// WithProvider returns a context whose spans, when it carries none, start
+// new traces with provider
+func WithProvider(ctx context.Context, provider trace.TracerProvider) context.Context {
+	return context.WithValue(ctx, providerKey{}, provider)
+}
+
+// Start starts a span as a child of the one ctx carries, recorded by the
+// same provider, or else as the root of a new trace
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return tracer(ctx).Start(ctx, name, opts...)
+}
+
+func tracer(ctx context.Context) trace.Tracer {
+	if span := trace.SpanFromContext(ctx); span.IsRecording() {
+		return span.TracerProvider().Tracer(ScopeName)
+	}
+	if provider, ok := ctx.Value(providerKey{}).(trace.TracerProvider); ok && provider != nil {
+		return provider.Tracer(ScopeName)
+	}
+	return noop.NewTracerProvider().Tracer(ScopeName)
+}
+
+// Gorm returns a plugin, installed with db.Use, that gives each query made
+// with a traced context a span. Spans hold the SQL with its arguments
+// masked.
+func Gorm(provider trace.TracerProvider) gorm.Plugin {
+	return otelgorm.NewPlugin(
+		otelgorm.WithTracerProvider(provider),
+		otelgorm.WithoutQueryVariables(),
+		otelgorm.WithoutMetrics(),
+	)
+}
+
+// Transport traces outgoing requests made with a traced context and passes
+// the trace on in their headers. A nil base uses http.DefaultTransport.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base, otelhttp.WithPropagators(Propagator))
+}
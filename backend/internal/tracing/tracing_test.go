@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestFromEnv(t *testing.T) {
+	for _, exporter := range []string{"", "none"} {
+		t.Setenv("OTEL_TRACES_EXPORTER", exporter)
+		if provider, err := FromEnv(context.Background()); provider != nil || err != nil {
+			t.Errorf("exporter %q: provider %v, error %v, want tracing off", exporter, provider, err)
+		}
+	}
+
+	t.Setenv("OTEL_TRACES_EXPORTER", "console")
+	provider, err := FromEnv(context.Background())
+	if err != nil || provider == nil {
+		t.Fatalf("console exporter: provider %v, error %v", provider, err)
+	}
+	provider.Shutdown(context.Background())
+
+	t.Setenv("OTEL_TRACES_EXPORTER", "zipkin")
+	if _, err := FromEnv(context.Background()); err == nil {
+		t.Error("unknown exporter accepted")
+	}
+	t.Setenv("OTEL_TRACES_EXPORTER", "otlp")
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "http/json")
+	if _, err := FromEnv(context.Background()); err == nil {
+		t.Error("unknown protocol accepted")
+	}
+}
+
+func TestStart(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	// Without a span or provider nothing is recorded
+	_, span := Start(context.Background(), "untraced")
+	if span.IsRecording() {
+		t.Error("span recorded without a provider")
+	}
+	span.End()
+
+	// A provider in the context starts a trace, and children join it
+	ctx, root := Start(WithProvider(context.Background(), provider), "root")
+	_, child := Start(ctx, "child")
+	child.End()
+	root.End()
+
+	ended := recorder.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("%d spans recorded, want 2", len(ended))
+	}
+	if ended[0].Name() != "child" || ended[0].Parent().SpanID() != ended[1].SpanContext().SpanID() {
+		t.Errorf("child span %s is not under the root", ended[0].Name())
+	}
+	if ended[1].Parent().IsValid() {
+		t.Error("root span has a parent")
+	}
+}
@@ -0,0 +1,231 @@
+// Package waveform computes min/max peak data for audio in the format used by
+// BBC audiowaveform, so the output can be fed straight to waveform-data.js or peaks.js.
+package waveform
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"music-player-gin/internal/audio"
+)
+
+const (
+	version = 2
+
+	// flagEightBit is set in the binary header when data points are 8-bit
+	flagEightBit = 1
+)
+
+// Waveform holds interleaved min/max pairs for a single channel. Data is
+// always kept at 16-bit resolution and scaled when exported as 8-bit.
+type Waveform struct {
+	SampleRate      int
+	SamplesPerPixel int
+	Data            []int16
+}
+
+// Length returns the number of pixels (min/max pairs) in the waveform
+func (w *Waveform) Length() int {
+	return len(w.Data) / 2
+}
+
+// Source is decoded audio with audio.Channels interleaved channels, such as
+// an *audio.Stream
+type Source interface {
+	SampleRate() int
+	Frames() int64
+	ReadFrames(dst []int16) (int, error)
+}
+
+// Generate decodes the whole stream and computes one min/max pair per
+// samplesPerPixel frames, mixing the channels down to mono
+func Generate(s Source, samplesPerPixel int) (*Waveform, error) {
+	if samplesPerPixel < 1 {
+		return nil, errors.New("samples per pixel must be positive")
+	}
+
+	w := &Waveform{
+		SampleRate:      s.SampleRate(),
+		SamplesPerPixel: samplesPerPixel,
+		Data:            make([]int16, 0, 2*(s.Frames()/int64(samplesPerPixel)+1)),
+	}
+
+	buf := make([]int16, 4096*audio.Channels)
+	var (
+		count            int
+		minimum, maximum int16 = math.MaxInt16, math.MinInt16
+	)
+	for {
+		n, err := s.ReadFrames(buf)
+		for i := 0; i < n; i++ {
+			sum := 0
+			for ch := 0; ch < audio.Channels; ch++ {
+				sum += int(buf[i*audio.Channels+ch])
+			}
+			v := int16(sum / audio.Channels)
+			minimum = min(minimum, v)
+			maximum = max(maximum, v)
+
+			count++
+			if count == samplesPerPixel {
+				w.Data = append(w.Data, minimum, maximum)
+				count, minimum, maximum = 0, math.MaxInt16, math.MinInt16
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Keep the trailing partial pixel so the waveform covers the full track
+	if count > 0 {
+		w.Data = append(w.Data, minimum, maximum)
+	}
+	return w, nil
+}
+
+// Resample merges pixels to produce a coarser waveform with the given number
+// of samples per pixel. Zooming in beyond the stored resolution is not possible.
+func (w *Waveform) Resample(samplesPerPixel int) (*Waveform, error) {
+	if samplesPerPixel < w.SamplesPerPixel {
+		return nil, fmt.Errorf("samples per pixel must be at least %d", w.SamplesPerPixel)
+	}
+	if samplesPerPixel == w.SamplesPerPixel {
+		return w, nil
+	}
+
+	ratio := float64(samplesPerPixel) / float64(w.SamplesPerPixel)
+	length := int(math.Ceil(float64(w.Length()) / ratio))
+	out := &Waveform{
+		SampleRate:      w.SampleRate,
+		SamplesPerPixel: samplesPerPixel,
+		Data:            make([]int16, 0, 2*length),
+	}
+
+	for i := 0; i < length; i++ {
+		start := int(float64(i) * ratio)
+		end := min(int(float64(i+1)*ratio), w.Length())
+		if end <= start {
+			end = start + 1
+		}
+
+		minimum, maximum := int16(math.MaxInt16), int16(math.MinInt16)
+		for p := start; p < end; p++ {
+			minimum = min(minimum, w.Data[2*p])
+			maximum = max(maximum, w.Data[2*p+1])
+		}
+		out.Data = append(out.Data, minimum, maximum)
+	}
+	return out, nil
+}
+
+// header is the fixed-size preamble of the binary format
+type header struct {
+	Version         int32
+	Flags           uint32
+	SampleRate      int32
+	SamplesPerPixel int32
+	Length          uint32
+	Channels        int32
+}
+
+// WriteBinary encodes the waveform in the audiowaveform binary (.dat) format
+func (w *Waveform) WriteBinary(dst io.Writer, bits int) error {
+	h := header{
+		Version:         version,
+		SampleRate:      int32(w.SampleRate),
+		SamplesPerPixel: int32(w.SamplesPerPixel),
+		Length:          uint32(w.Length()),
+		Channels:        1,
+	}
+	if bits == 8 {
+		h.Flags = flagEightBit
+	}
+	if err := binary.Write(dst, binary.LittleEndian, h); err != nil {
+		return err
+	}
+
+	if bits == 8 {
+		return binary.Write(dst, binary.LittleEndian, to8Bit(w.Data))
+	}
+	return binary.Write(dst, binary.LittleEndian, w.Data)
+}
+
+// ReadBinary decodes a waveform written by WriteBinary
+func ReadBinary(src io.Reader) (*Waveform, error) {
+	var h header
+	if err := binary.Read(src, binary.LittleEndian, &h); err != nil {
+		return nil, err
+	}
+	if h.Version != version || h.Channels != 1 {
+		return nil, fmt.Errorf("unsupported waveform version %d with %d channels", h.Version, h.Channels)
+	}
+
+	w := &Waveform{
+		SampleRate:      int(h.SampleRate),
+		SamplesPerPixel: int(h.SamplesPerPixel),
+		Data:            make([]int16, 2*h.Length),
+	}
+	if h.Flags&flagEightBit != 0 {
+		data := make([]int8, 2*h.Length)
+		if err := binary.Read(src, binary.LittleEndian, data); err != nil {
+			return nil, err
+		}
+		for i, v := range data {
+			w.Data[i] = int16(v) << 8
+		}
+		return w, nil
+	}
+	if err := binary.Read(src, binary.LittleEndian, w.Data); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// JSON is the audiowaveform JSON representation of a waveform
+type JSON struct {
+	Version         int     `json:"version"`
+	Channels        int     `json:"channels"`
+	SampleRate      int     `json:"sample_rate"`
+	SamplesPerPixel int     `json:"samples_per_pixel"`
+	Bits            int     `json:"bits"`
+	Length          int     `json:"length"`
+	Data            []int16 `json:"data"`
+}
+
+// ToJSON converts the waveform to its JSON representation at the given bit depth
+func (w *Waveform) ToJSON(bits int) JSON {
+	data := w.Data
+	if bits == 8 {
+		data = make([]int16, len(w.Data))
+		for i, v := range to8Bit(w.Data) {
+			data[i] = int16(v)
+		}
+	} else {
+		bits = 16
+	}
+
+	return JSON{
+		Version:         version,
+		Channels:        1,
+		SampleRate:      w.SampleRate,
+		SamplesPerPixel: w.SamplesPerPixel,
+		Bits:            bits,
+		Length:          w.Length(),
+		Data:            data,
+	}
+}
+
+func to8Bit(data []int16) []int8 {
+	out := make([]int8, len(data))
+	for i, v := range data {
+		out[i] = int8(v >> 8)
+	}
+	return out
+}
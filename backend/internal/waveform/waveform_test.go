@@ -0,0 +1,187 @@
+package waveform
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"slices"
+	"testing"
+
+	"music-player-gin/internal/audio"
+)
+
+// pcm is a Source over interleaved stereo samples that hands out at most
+// chunk frames per read, so pixels straddle reads
+type pcm struct {
+	samples []int16
+	chunk   int
+	err     error // returned once the samples run out, io.EOF if nil
+}
+
+func (p *pcm) SampleRate() int { return 44100 }
+func (p *pcm) Frames() int64   { return int64(len(p.samples) / audio.Channels) }
+
+func (p *pcm) ReadFrames(dst []int16) (int, error) {
+	frames := min(len(dst)/audio.Channels, len(p.samples)/audio.Channels, p.chunk)
+	n := copy(dst, p.samples[:frames*audio.Channels])
+	p.samples = p.samples[n:]
+	if len(p.samples) == 0 {
+		if p.err != nil {
+			return frames, p.err
+		}
+		return frames, io.EOF
+	}
+	return frames, nil
+}
+
+// stereo interleaves left and right channels
+func stereo(left, right []int16) []int16 {
+	samples := make([]int16, 0, 2*len(left))
+	for i := range left {
+		samples = append(samples, left[i], right[i])
+	}
+	return samples
+}
+
+func TestGeneratePeaks(t *testing.T) {
+	left := []int16{
+		100, -200, 300, 50, // a quiet pixel
+		8000, -12000, 0, 4000, // a loud one
+		2000, 2000, 2000, 2000, // a channel alone, halved by the mix
+		math.MaxInt16, math.MinInt16, 0, 0, // full scale does not overflow
+		-7, 9, // a partial pixel at the end
+	}
+	right := slices.Clone(left)
+	for i := 8; i < 12; i++ {
+		right[i] = 0
+	}
+
+	for _, chunk := range []int{1, 3, 4, 4096} {
+		w, err := Generate(&pcm{samples: stereo(left, right), chunk: chunk}, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []int16{
+			-200, 300,
+			-12000, 8000,
+			1000, 1000,
+			math.MinInt16, math.MaxInt16,
+			-7, 9,
+		}
+		if !slices.Equal(w.Data, want) {
+			t.Errorf("%d frames per read: data = %v, want %v", chunk, w.Data, want)
+		}
+		if w.Length() != 5 || w.SampleRate != 44100 || w.SamplesPerPixel != 4 {
+			t.Errorf("%d frames per read: %d pixels at %d Hz and %d samples per pixel", chunk, w.Length(), w.SampleRate, w.SamplesPerPixel)
+		}
+	}
+}
+
+func TestGenerateSineWave(t *testing.T) {
+	// One cycle every 100 frames at 0.5 full scale, with 100 frames per
+	// pixel, puts both peaks of the sine in every pixel
+	const amplitude = 16384
+	samples := make([]int16, 0, 2*1000)
+	for i := 0; i < 1000; i++ {
+		v := int16(math.Round(amplitude * math.Sin(2*math.Pi*float64(i)/100)))
+		samples = append(samples, v, v)
+	}
+	w, err := Generate(&pcm{samples: samples, chunk: 64}, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Length() != 10 {
+		t.Fatalf("%d pixels, want 10", w.Length())
+	}
+	for i := 0; i < w.Length(); i++ {
+		if w.Data[2*i] != -amplitude || w.Data[2*i+1] != amplitude {
+			t.Errorf("pixel %d = [%d, %d], want [%d, %d]", i, w.Data[2*i], w.Data[2*i+1], -amplitude, amplitude)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	if _, err := Generate(&pcm{chunk: 1}, 0); err == nil {
+		t.Error("zero samples per pixel accepted")
+	}
+
+	broken := errors.New("corrupt frame")
+	_, err := Generate(&pcm{samples: make([]int16, 20), chunk: 4, err: broken}, 4)
+	if !errors.Is(err, broken) {
+		t.Errorf("err = %v, want the read error", err)
+	}
+
+	// An empty stream has no pixels
+	w, err := Generate(&pcm{chunk: 1}, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Length() != 0 {
+		t.Errorf("empty stream has %d pixels", w.Length())
+	}
+}
+
+func TestResample(t *testing.T) {
+	w := &Waveform{SampleRate: 44100, SamplesPerPixel: 256, Data: []int16{
+		-10, 20,
+		-30, 5,
+		-1, 1,
+		-100, 200,
+		-4, 4,
+	}}
+
+	coarser, err := w.Resample(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Pairs of pixels are merged, and the odd one out keeps its own peaks
+	want := []int16{-30, 20, -100, 200, -4, 4}
+	if !slices.Equal(coarser.Data, want) || coarser.SamplesPerPixel != 512 {
+		t.Errorf("resampled to %d: data = %v, want %v", coarser.SamplesPerPixel, coarser.Data, want)
+	}
+
+	if same, err := w.Resample(256); err != nil || same != w {
+		t.Error("resampling to the stored resolution did not return the waveform")
+	}
+	if _, err := w.Resample(128); err == nil {
+		t.Error("zooming in accepted")
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	w := &Waveform{SampleRate: 44100, SamplesPerPixel: 256, Data: []int16{-12000, 8000, math.MinInt16, math.MaxInt16, -256, 255}}
+
+	var buf bytes.Buffer
+	if err := w.WriteBinary(&buf, 16); err != nil {
+		t.Fatal(err)
+	}
+	// A 24 byte header and two bytes per value
+	if buf.Len() != 24+2*len(w.Data) {
+		t.Errorf("16-bit file is %d bytes", buf.Len())
+	}
+	got, err := ReadBinary(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got.Data, w.Data) || got.SampleRate != w.SampleRate || got.SamplesPerPixel != w.SamplesPerPixel {
+		t.Errorf("16-bit round trip = %+v, want %+v", got, w)
+	}
+
+	// 8-bit data keeps the top byte of each value
+	buf.Reset()
+	if err := w.WriteBinary(&buf, 8); err != nil {
+		t.Fatal(err)
+	}
+	got, err = ReadBinary(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int16{-12032, 7936, math.MinInt16, 32512, -256, 0}
+	if !slices.Equal(got.Data, want) {
+		t.Errorf("8-bit round trip = %v, want %v", got.Data, want)
+	}
+	if json := w.ToJSON(8); json.Bits != 8 || !slices.Equal(json.Data, []int16{-47, 31, -128, 127, -1, 0}) {
+		t.Errorf("8-bit JSON = %+v", json)
+	}
+}
@@ -0,0 +1,70 @@
+'use client';
+
+import { useEffect, useRef, useState } from 'react';
+import Link from 'next/link';
+import { useRouter } from 'next/navigation';
+import { useAuth } from '@/context/AuthContext';
+
+const API_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080';
+
+// The API sends the browser here once an OpenID Connect sign-in is done, with
+// #token=... on success or #error=...&error_description=... otherwise. The
+// fragment never reaches a server, so the token stays between API and browser.
+export default function OIDCCallback() {
+  const router = useRouter();
+  const { storeSession } = useAuth();
+  const [error, setError] = useState<string | null>(null);
+  const handled = useRef(false);
+
+  useEffect(() => {
+    // React runs effects twice in development, and the fragment is gone the second time
+    if (handled.current) {
+      return;
+    }
+    handled.current = true;
+
+    const params = new URLSearchParams(window.location.hash.slice(1));
+    // Keep the token out of the browser history
+    window.history.replaceState(null, '', window.location.pathname);
+
+    const token = params.get('token');
+    if (!token) {
+      setError(params.get('error_description') || params.get('error') || 'Sign in failed');
+      return;
+    }
+
+    fetch(`${API_URL}/api/v1/me`, { headers: { Authorization: `Bearer ${token}` } })
+      .then(async (response) => {
+        const data = await response.json();
+        if (!response.ok) {
+          throw new Error(data.detail || 'Sign in failed');
+        }
+        storeSession(token, data);
+        router.replace('/');
+      })
+      .catch((err) => {
+        setError(err instanceof Error ? err.message : 'Something went wrong during sign in');
+        console.error("OIDC sign in error:", err);
+      });
+  }, [router, storeSession]);
+
+  return (
+    <div className="relative flex min-h-screen overflow-hidden">
+      <div className="relative z-10 w-full max-w-md m-auto p-8">
+        <div className="bg-gray-900 shadow-xl rounded-lg p-8 border border-gray-800 bg-opacity-90 text-center">
+          {error ? (
+            <>
+              <h2 className="text-2xl font-bold text-white mb-6">Sign In Failed</h2>
+              <div className="bg-red-900/30 border border-red-800 text-red-300 px-4 py-3 rounded-lg mb-6">
+                {error}
+              </div>
+              <Link href="/login" className="text-pink-400 hover:text-pink-300 transition-colors">Back to sign in</Link>
+            </>
+          ) : (
+            <h2 className="text-2xl font-bold text-white">Signing you in...</h2>
+          )}
+        </div>
+      </div>
+    </div>
+  );
+}
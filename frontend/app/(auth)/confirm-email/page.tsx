@@ -0,0 +1,26 @@
+'use client';
+
+import { useCallback } from 'react';
+import EmailLink from '@/components/EmailLink';
+import { useAuth, User } from '@/context/AuthContext';
+
+// Linked from the email sent to a new address when a user changes theirs
+export default function ConfirmEmail() {
+  const { authTokens, storeSession } = useAuth();
+
+  // The API answers with the updated profile, which replaces the stored one
+  const onSuccess = useCallback((profile: unknown) => {
+    if (authTokens?.token) {
+      storeSession(authTokens.token, profile as User);
+    }
+  }, [authTokens, storeSession]);
+
+  return (
+    <EmailLink
+      endpoint="/api/v1/auth/confirm-email"
+      title="Confirm Email"
+      success="Your account now uses this email address."
+      onSuccess={onSuccess}
+    />
+  );
+}
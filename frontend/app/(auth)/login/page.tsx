@@ -87,6 +87,18 @@ export default function Login() {
               </button>
             </div>
             
+            {process.env.NEXT_PUBLIC_OIDC_ENABLED === 'true' && (
+              <div className="mb-6">
+                {/* A full page load, since the API redirects on to the identity provider */}
+                <a
+                  href={`${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/api/v1/auth/oidc/login`}
+                  className="block w-full text-center border border-pink-500 text-pink-400 hover:bg-pink-500/10 font-bold py-3 px-4 rounded-lg transition-all duration-300"
+                >
+                  Sign in with SSO
+                </a>
+              </div>
+            )}
+
             <div className="text-center text-gray-400">
               <p>Don't have an account? <Link href="/signup" className="text-pink-400 hover:text-pink-300 transition-colors">Sign up</Link></p>
             </div>
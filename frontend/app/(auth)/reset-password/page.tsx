@@ -0,0 +1,111 @@
+'use client';
+
+import { useState } from 'react';
+import Link from 'next/link';
+import { FaLock } from 'react-icons/fa';
+
+const API_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080';
+
+// Linked from the password reset email; the token is in the query string
+export default function ResetPassword() {
+  const [password, setPassword] = useState('');
+  const [confirmPassword, setConfirmPassword] = useState('');
+  const [error, setError] = useState<string | null>(null);
+  const [isLoading, setIsLoading] = useState(false);
+  const [done, setDone] = useState(false);
+
+  const resetPassword = async (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault();
+    setError(null);
+
+    if (password !== confirmPassword) {
+      setError("Passwords don't match");
+      return;
+    }
+    const token = new URLSearchParams(window.location.search).get('token');
+    if (!token) {
+      setError('This link is incomplete');
+      return;
+    }
+
+    setIsLoading(true);
+    try {
+      const response = await fetch(`${API_URL}/api/v1/auth/password-reset/confirm`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ token, password }),
+      });
+      const data = await response.json();
+      if (!response.ok) {
+        throw new Error(data.errors?.[0]?.message || data.detail || 'This link is invalid or has expired');
+      }
+      setDone(true);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Something went wrong');
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  const inputClass = "block w-full pl-10 pr-3 py-3 border border-gray-800 rounded-lg bg-gray-900 text-white placeholder-gray-500 focus:outline-none focus:ring-2 focus:ring-pink-500 focus:border-pink-500";
+
+  return (
+    <div className="relative flex min-h-screen overflow-hidden">
+      <div className="relative z-10 w-full max-w-md m-auto p-8">
+        <div className="bg-gray-900 shadow-xl rounded-lg p-8 border border-gray-800 bg-opacity-90">
+          <h2 className="text-2xl font-bold text-white mb-6 text-center">Choose a New Password</h2>
+
+          {error && (
+            <div className="bg-red-900/30 border border-red-800 text-red-300 px-4 py-3 rounded-lg mb-6">
+              {error}
+            </div>
+          )}
+
+          {done ? (
+            <div className="text-center text-gray-300">
+              <p className="mb-6">Your password has been changed.</p>
+              <Link href="/login" className="text-pink-400 hover:text-pink-300 transition-colors">Sign in</Link>
+            </div>
+          ) : (
+            <form onSubmit={resetPassword}>
+              {[
+                { id: 'password', label: 'New Password', value: password, set: setPassword },
+                { id: 'confirmPassword', label: 'Confirm Password', value: confirmPassword, set: setConfirmPassword },
+              ].map((field) => (
+                <div className="mb-6" key={field.id}>
+                  <label className="block text-gray-300 text-sm font-medium mb-2" htmlFor={field.id}>
+                    {field.label}
+                  </label>
+                  <div className="relative">
+                    <div className="absolute inset-y-0 left-0 pl-3 flex items-center pointer-events-none">
+                      <FaLock className="h-4 w-4 text-pink-500" />
+                    </div>
+                    <input
+                      id={field.id}
+                      type="password"
+                      minLength={6}
+                      value={field.value}
+                      onChange={(e) => field.set(e.target.value)}
+                      className={inputClass}
+                      required
+                    />
+                  </div>
+                </div>
+              ))}
+
+              <button
+                type="submit"
+                disabled={isLoading}
+                className="w-full bg-gradient-to-r from-pink-500 to-pink-600 hover:from-pink-600 hover:to-pink-700 text-white font-bold py-3 px-4 rounded-lg focus:outline-none focus:shadow-outline transition-all duration-300"
+              >
+                {isLoading ? 'Saving...' : 'Set Password'}
+              </button>
+            </form>
+          )}
+        </div>
+      </div>
+    </div>
+  );
+}
@@ -0,0 +1,14 @@
+'use client';
+
+import EmailLink from '@/components/EmailLink';
+
+// Linked from the email sent when an account is registered
+export default function VerifyEmail() {
+  return (
+    <EmailLink
+      endpoint="/api/v1/auth/verify-email"
+      title="Verify Email"
+      success="Your email address is verified."
+    />
+  );
+}
@@ -7,14 +7,13 @@ import { FaPlay, FaPause, FaVolumeUp, FaVolumeMute, FaHeart, FaStepBackward, FaS
 import { useAuth } from '@/context/AuthContext';
 
 interface Song {
-  ID: number;
-  Title: string;
-  Artist: string;
-  Album: string;
-  Genre: string;
-  Duration: number;
-  FilePath: string;
-  CreatedAt: string;
+  id: number;
+  title: string;
+  artist: string;
+  album: string;
+  genre: string;
+  duration: number;
+  created_at: string;
 }
 
 export default function SongPage() {
@@ -48,7 +47,7 @@ export default function SongPage() {
       setIsLoading(true);
       try {
         const response = await fetch(
-          `${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/songs/${id}`,
+          `${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/api/v1/songs/${id}`,
           {
             headers: {
               'Authorization': `Bearer ${authTokens.token}`
@@ -84,7 +83,7 @@ export default function SongPage() {
       
       try {
         const response = await fetch(
-          `${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/songs/${song.ID}/play`,
+          `${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/api/v1/songs/${song.id}/play`,
           {
             headers: {
               'Authorization': `Bearer ${authTokens.token}`
@@ -124,7 +123,7 @@ export default function SongPage() {
 
     try {
       const response = await fetch(
-        `${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/favorites/${id}`,
+        `${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/api/v1/favorites/${id}`,
         {
           headers: {
             'Authorization': `Bearer ${authTokens.token}`
@@ -148,7 +147,7 @@ export default function SongPage() {
     setIsToggling(true);
     try {
       const response = await fetch(
-        `${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/songs/${id}/favorite`,
+        `${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/api/v1/songs/${id}/favorite`,
         {
           method: 'POST',
           headers: {
@@ -354,7 +353,7 @@ export default function SongPage() {
               {/* Song Details */}
               <div className="flex-1 text-center md:text-left">
                 <div className="flex flex-col md:flex-row md:items-center md:justify-between">
-                  <h1 className="text-3xl md:text-4xl font-bold text-white mb-2">{song.Title}</h1>
+                  <h1 className="text-3xl md:text-4xl font-bold text-white mb-2">{song.title}</h1>
                   <button 
                     onClick={toggleFavorite}
                     disabled={isToggling}
@@ -368,20 +367,20 @@ export default function SongPage() {
                     )}
                   </button>
                 </div>
-                <h2 className="text-xl text-gray-300 mb-4">{song.Artist}</h2>
+                <h2 className="text-xl text-gray-300 mb-4">{song.artist}</h2>
                 
                 <div className="grid grid-cols-1 md:grid-cols-2 gap-4 text-left mb-6">
-                  {song.Album && (
+                  {song.album && (
                     <div>
                       <span className="text-gray-400 text-sm">Album</span>
-                      <p className="text-white">{song.Album}</p>
+                      <p className="text-white">{song.album}</p>
                     </div>
                   )}
                   
-                  {song.Genre && (
+                  {song.genre && (
                     <div>
                       <span className="text-gray-400 text-sm">Genre</span>
-                      <p className="text-white">{song.Genre}</p>
+                      <p className="text-white">{song.genre}</p>
                     </div>
                   )}
                   
@@ -392,7 +391,7 @@ export default function SongPage() {
                   
                   <div>
                     <span className="text-gray-400 text-sm">Added on</span>
-                    <p className="text-white">{new Date(song.CreatedAt).toLocaleDateString()}</p>
+                    <p className="text-white">{new Date(song.created_at).toLocaleDateString()}</p>
                   </div>
                 </div>
                 
@@ -413,8 +412,8 @@ export default function SongPage() {
                   </button>
                   
                   <Link 
-                    href={`${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/songs/${song.ID}/play`}
-                    download={`${song.Title} - ${song.Artist}.mp3`}
+                    href={`${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/api/v1/songs/${song.id}/play`}
+                    download={`${song.title} - ${song.artist}.mp3`}
                     className="bg-gray-800 hover:bg-gray-700 text-white font-bold py-3 px-8 rounded-lg focus:outline-none focus:shadow-outline transition-all duration-300 flex items-center justify-center"
                     target="_blank"
                     rel="noopener noreferrer"
@@ -8,14 +8,13 @@ import { motion } from 'framer-motion';
 import { useAuth } from '@/context/AuthContext';
 
 interface Song {
-  ID: number;
-  Title: string;
-  Artist: string;
-  Album: string;
-  Genre: string;
-  Duration: number;
-  FilePath: string;
-  CreatedAt: string;
+  id: number;
+  title: string;
+  artist: string;
+  album: string;
+  genre: string;
+  duration: number;
+  created_at: string;
 }
 
 export default function ExplorePage() {
@@ -46,7 +45,7 @@ export default function ExplorePage() {
       setIsLoading(true);
       try {
         const response = await fetch(
-          `${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/songs`,
+          `${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/api/v1/songs`,
           {
             headers: {
               'Authorization': `Bearer ${authTokens.token}`
@@ -82,21 +81,21 @@ export default function ExplorePage() {
     if (searchQuery) {
       const query = searchQuery.toLowerCase();
       results = results.filter(song => 
-        song.Title.toLowerCase().includes(query) || 
-        song.Artist.toLowerCase().includes(query) || 
-        song.Album.toLowerCase().includes(query) ||
-        song.Genre.toLowerCase().includes(query)
+        song.title.toLowerCase().includes(query) || 
+        song.artist.toLowerCase().includes(query) || 
+        song.album.toLowerCase().includes(query) ||
+        song.genre.toLowerCase().includes(query)
       );
     }
     
     // Apply category filter
     if (activeFilter !== 'all') {
       if (activeFilter === 'favorites') {
-        results = results.filter(song => favorites[song.ID]);
+        results = results.filter(song => favorites[song.id]);
       } else {
         // Filter by genre
         results = results.filter(song => 
-          song.Genre.toLowerCase() === activeFilter.toLowerCase()
+          song.genre.toLowerCase() === activeFilter.toLowerCase()
         );
       }
     }
@@ -121,7 +120,7 @@ export default function ExplorePage() {
     
     try {
       const response = await fetch(
-        `${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/songs/${songId}/favorite`,
+        `${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/api/v1/songs/${songId}/favorite`,
         {
           method: 'POST',
           headers: {
@@ -143,7 +142,7 @@ export default function ExplorePage() {
   };
 
   // Get unique genres for filter options
-  const genres = [...new Set(songs.filter(song => song.Genre).map(song => song.Genre))];
+  const genres = [...new Set(songs.filter(song => song.genre).map(song => song.genre))];
 
   // Generate skeleton loaders during loading state
   const renderSkeletons = () => {
@@ -288,12 +287,12 @@ export default function ExplorePage() {
             >
               {filteredSongs.map((song, index) => (
                 <motion.div
-                  key={song.ID}
+                  key={song.id}
                   initial={{ opacity: 0, y: 20 }}
                   animate={{ opacity: 1, y: 0 }}
                   transition={{ duration: 0.3, delay: index * 0.05 }}
                 >
-                  <Link href={`/song/${song.ID}`} className="block">
+                  <Link href={`/song/${song.id}`} className="block">
                     <div className="bg-gray-900 rounded-lg overflow-hidden border border-gray-800 hover:border-pink-500/50 transition-all duration-300 h-full flex flex-col">
                       <div className="p-5 flex-grow">
                         <div className="flex items-start mb-4">
@@ -301,16 +300,16 @@ export default function ExplorePage() {
                             <FaMusic className="text-pink-500 text-xl" />
                           </div>
                           <div>
-                            <h3 className="text-xl font-semibold text-white mb-1 line-clamp-1">{song.Title}</h3>
-                            <p className="text-gray-400 line-clamp-1">{song.Artist}</p>
-                            {song.Album && <p className="text-gray-500 text-sm mt-1 line-clamp-1">Album: {song.Album}</p>}
+                            <h3 className="text-xl font-semibold text-white mb-1 line-clamp-1">{song.title}</h3>
+                            <p className="text-gray-400 line-clamp-1">{song.artist}</p>
+                            {song.album && <p className="text-gray-500 text-sm mt-1 line-clamp-1">Album: {song.album}</p>}
                           </div>
                         </div>
                         
-                        {song.Genre && (
+                        {song.genre && (
                           <div className="mb-3">
                             <span className="inline-block bg-gray-800 text-gray-300 text-xs px-2 py-1 rounded">
-                              {song.Genre}
+                              {song.genre}
                             </span>
                           </div>
                         )}
@@ -318,15 +317,15 @@ export default function ExplorePage() {
 
                       <div className="bg-gray-800/50 px-5 py-3 flex justify-between items-center">
                         <span className="text-gray-400 text-sm">
-                          {formatDuration(song.Duration)}
+                          {formatDuration(song.duration)}
                         </span>
                         
                         <div className="flex space-x-3">
                           <button 
-                            onClick={(e) => toggleFavorite(song.ID, e)}
+                            onClick={(e) => toggleFavorite(song.id, e)}
                             className="text-gray-400 hover:text-pink-500 transition-colors"
                           >
-                            {favorites[song.ID] ? (
+                            {favorites[song.id] ? (
                               <FaHeart className="text-pink-500" />
                             ) : (
                               <FaRegHeart />
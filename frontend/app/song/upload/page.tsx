@@ -73,7 +73,7 @@ export default function UploadSong() {
       formData.append('file', file);
 
       const response = await fetch(
-        `${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/songs`, 
+        `${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/api/v1/songs`, 
         {
           method: 'POST',
           headers: {
@@ -85,7 +85,7 @@ export default function UploadSong() {
 
       if (!response.ok) {
         const errorData = await response.json();
-        throw new Error(errorData.error || 'Failed to upload song');
+        throw new Error(errorData.detail || 'Failed to upload song');
       }
 
       const data = await response.json();
@@ -100,7 +100,7 @@ export default function UploadSong() {
       
       // Redirect to song page after a delay
       setTimeout(() => {
-        router.push(`/song/${data.song.ID}`);
+        router.push(`/song/${data.song.id}`);
       }, 2000);
       
     } catch (err) {
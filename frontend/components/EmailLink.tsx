@@ -0,0 +1,78 @@
+'use client';
+
+import { useEffect, useRef, useState } from 'react';
+import Link from 'next/link';
+
+const API_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080';
+
+interface EmailLinkProps {
+    endpoint: string;
+    title: string;
+    success: string;
+    onSuccess?: (data: unknown) => void;
+}
+
+// EmailLink handles a link from an email: it sends the link's token to the
+// API endpoint once the page loads and shows how that went
+const EmailLink = ({ endpoint, title, success, onSuccess }: EmailLinkProps) => {
+    const [status, setStatus] = useState<'pending' | 'done' | 'failed'>('pending');
+    const [error, setError] = useState<string | null>(null);
+    const sent = useRef(false);
+
+    useEffect(() => {
+        // Tokens work once, and React runs effects twice in development
+        if (sent.current) {
+            return;
+        }
+        sent.current = true;
+
+        const token = new URLSearchParams(window.location.search).get('token');
+        if (!token) {
+            setError('This link is incomplete');
+            setStatus('failed');
+            return;
+        }
+
+        fetch(`${API_URL}${endpoint}`, {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+            body: JSON.stringify({ token }),
+        })
+            .then(async (response) => {
+                const data = await response.json();
+                if (!response.ok) {
+                    throw new Error(data.detail || 'This link is invalid or has expired');
+                }
+                onSuccess?.(data);
+                setStatus('done');
+            })
+            .catch((err) => {
+                setError(err instanceof Error ? err.message : 'Something went wrong');
+                setStatus('failed');
+            });
+    }, [endpoint, onSuccess]);
+
+    return (
+        <div className="relative flex min-h-screen overflow-hidden">
+            <div className="relative z-10 w-full max-w-md m-auto p-8">
+                <div className="bg-gray-900 shadow-xl rounded-lg p-8 border border-gray-800 bg-opacity-90 text-center">
+                    <h2 className="text-2xl font-bold text-white mb-6">{title}</h2>
+                    {status === 'pending' && <p className="text-gray-300">One moment...</p>}
+                    {status === 'done' && <p className="text-gray-300 mb-6">{success}</p>}
+                    {status === 'failed' && (
+                        <div className="bg-red-900/30 border border-red-800 text-red-300 px-4 py-3 rounded-lg mb-6">
+                            {error}
+                        </div>
+                    )}
+                    {status !== 'pending' && (
+                        <Link href="/" className="text-pink-400 hover:text-pink-300 transition-colors">Go to GoMusic</Link>
+                    )}
+                </div>
+            </div>
+        </div>
+    );
+};
+
+export default EmailLink;
@@ -7,7 +7,7 @@ interface AuthTokens {
     token: string | null;
 }
 
-interface User {
+export interface User {
     username: string;
     email: string;
     id: string;
@@ -18,6 +18,7 @@ interface AuthContextType {
     loginUser: (e: React.FormEvent<HTMLFormElement>) => Promise<void>;
     signupUser: (e: React.FormEvent<HTMLFormElement>) => Promise<void>;
     logoutUser: () => Promise<void>;
+    storeSession: (token: string, user: User) => void;
     authTokens: AuthTokens | null;
     isLoading: boolean;
     error: string | null;
@@ -48,6 +49,14 @@ export const AuthProvider = ({children}: {children: React.ReactNode}) => {
         return null;
     });
 
+    // Keeps the token and user in localStorage so the session survives a reload
+    const storeSession = (token: string, user: User) => {
+        localStorage.setItem('token', token);
+        localStorage.setItem('user', JSON.stringify(user));
+        setAuthTokens({ token });
+        setUser(user);
+    };
+
     const loginUser = async (e: React.FormEvent<HTMLFormElement>) => {
         e.preventDefault();
         setIsLoading(true);
@@ -58,7 +67,7 @@ export const AuthProvider = ({children}: {children: React.ReactNode}) => {
         const password = form.password.value;
         
         try {
-            const response = await fetch(`${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/auth/login`, {
+            const response = await fetch(`${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/api/v1/auth/login`, {
                 method: 'POST',
                 headers: {
                     'Content-Type': 'application/json',
@@ -69,16 +78,10 @@ export const AuthProvider = ({children}: {children: React.ReactNode}) => {
             const data = await response.json();
 
             if (!response.ok) {
-                throw new Error(data.error || 'Login failed');
+                throw new Error(data.detail || 'Login failed');
             }
 
-            // Store token and user info in localStorage
-            localStorage.setItem('token', data.token);
-            localStorage.setItem('user', JSON.stringify(data.user));
-            
-            // Update context state
-            setAuthTokens({ token: data.token });
-            setUser(data.user);
+            storeSession(data.token, data.user);
             
             // Redirect to home page
             router.push('/');
@@ -108,7 +111,7 @@ export const AuthProvider = ({children}: {children: React.ReactNode}) => {
         }
         
         try {
-            const response = await fetch(`${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/auth/register`, {
+            const response = await fetch(`${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/api/v1/auth/register`, {
                 method: 'POST',
                 headers: {
                     'Content-Type': 'application/json',
@@ -119,16 +122,10 @@ export const AuthProvider = ({children}: {children: React.ReactNode}) => {
             const data = await response.json();
 
             if (!response.ok) {
-                throw new Error(data.error || 'Registration failed');
+                throw new Error(data.detail || 'Registration failed');
             }
 
-            // Store token and user info in localStorage
-            localStorage.setItem('token', data.token);
-            localStorage.setItem('user', JSON.stringify(data.user));
-            
-            // Update context state
-            setAuthTokens({ token: data.token });
-            setUser(data.user);
+            storeSession(data.token, data.user);
             
             // Redirect to home page
             router.push('/');
@@ -171,6 +168,7 @@ export const AuthProvider = ({children}: {children: React.ReactNode}) => {
         loginUser,
         signupUser,
         logoutUser,
+        storeSession,
         authTokens,
         isLoading,
         error,